@@ -33,7 +33,9 @@ import (
 	"github.com/dominant-strategies/go-quai/core/rawdb"
 	"github.com/dominant-strategies/go-quai/core/state"
 	"github.com/dominant-strategies/go-quai/core/types"
+	"github.com/dominant-strategies/go-quai/core/vm"
 	"github.com/dominant-strategies/go-quai/crypto"
+	"github.com/dominant-strategies/go-quai/eth/ethconfig"
 	"github.com/dominant-strategies/go-quai/ethdb"
 	"github.com/dominant-strategies/go-quai/log"
 	"github.com/dominant-strategies/go-quai/metrics"
@@ -41,6 +43,13 @@ import (
 	"gopkg.in/urfave/cli.v1"
 )
 
+var (
+	exportArchiveFlag = cli.BoolFlag{
+		Name:  "archive",
+		Usage: "Export a chain archive bundling the hierarchy data of every block",
+	}
+)
+
 var (
 	initCommand = cli.Command{
 		Action:    utils.MigrateFlags(initGenesis),
@@ -116,6 +125,7 @@ processing will proceed even if an individual RLP-file import failure occurs.`,
 			utils.DataDirFlag,
 			utils.CacheFlag,
 			utils.SyncModeFlag,
+			exportArchiveFlag,
 		},
 		Category: "BLOCKCHAIN COMMANDS",
 		Description: `
@@ -123,7 +133,9 @@ Requires a first argument of the file to write to.
 Optional second and third arguments control the first and
 last block to write. In this mode, the file will be appended
 if already existing. If the file ends with .gz, the output will
-be gzipped.`,
+be gzipped. With --archive, the blocks are written as a chain
+archive bundling the hierarchy data needed to import them
+without the dom and sub nodes.`,
 	}
 	importPreimagesCommand = cli.Command{
 		Action:    utils.MigrateFlags(importPreimages),
@@ -173,6 +185,27 @@ The export-preimages command export hash preimages to an RLP encoded stream`,
 This command dumps out the state for a given block (or latest, if none provided).
 `,
 	}
+	verifyChainCommand = cli.Command{
+		Action:    utils.MigrateFlags(verifyChain),
+		Name:      "verify-chain",
+		Usage:     "Re-execute stored blocks and verify them against their headers",
+		ArgsUsage: "[<blockNumFirst> [<blockNumLast>]]",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.AncientFlag,
+			utils.CacheFlag,
+			utils.ReexecFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The verify-chain command replays the canonical blocks of a zone in the given range
+(by default from the first block to the current head) and compares the resulting
+state root, receipt root, ETX root and gas used against each stored header. It
+stops at the first mismatch and prints a per-transaction diff of the receipts.
+
+The database is opened read-only, so this command can be used to validate a
+datadir after a crash or a client upgrade.`,
+	}
 )
 
 // initGenesis will initialise the given JSON format genesis file and writes it as
@@ -313,7 +346,7 @@ func exportChain(ctx *cli.Context) error {
 	var err error
 	fp := ctx.Args().First()
 	if len(ctx.Args()) < 3 {
-		err = utils.ExportChain(chain, fp)
+		err = utils.ExportChain(chain, fp, ctx.Bool(exportArchiveFlag.Name))
 	} else {
 		// This can be improved to allow for numbers larger than 9223372036854775807
 		first, ferr := strconv.ParseInt(ctx.Args().Get(1), 10, 64)
@@ -324,7 +357,7 @@ func exportChain(ctx *cli.Context) error {
 		if first < 0 || last < 0 {
 			utils.Fatalf("Export error: block number must be greater than 0\n")
 		}
-		err = utils.ExportAppendChain(chain, fp, uint64(first), uint64(last))
+		err = utils.ExportAppendChain(chain, fp, uint64(first), uint64(last), ctx.Bool(exportArchiveFlag.Name))
 	}
 
 	if err != nil {
@@ -455,6 +488,71 @@ func dump(ctx *cli.Context) error {
 	return nil
 }
 
+func verifyChain(ctx *cli.Context) error {
+	if ctx.NArg() > 2 {
+		return fmt.Errorf("expected at most 2 arguments (first and last block), got %d", ctx.NArg())
+	}
+	stack, cfg := makeConfigNode(ctx)
+	defer stack.Close()
+
+	if common.NodeLocation.Context() != common.ZONE_CTX {
+		utils.Fatalf("Chain verification is only supported for zone chains")
+	}
+	vm.InitializePrecompiles()
+
+	db := utils.MakeChainDatabase(ctx, stack, true)
+	defer db.Close()
+
+	genesisHash := rawdb.ReadCanonicalHash(db, 0)
+	config := rawdb.ReadChainConfig(db, genesisHash)
+	if config == nil {
+		if cfg.Eth.Genesis == nil {
+			utils.Fatalf("No chain config found for genesis %x", genesisHash)
+		}
+		config = cfg.Eth.Genesis.Config
+	}
+	// Snapshots and cache journals are disabled so that nothing is written to disk
+	cache := &core.CacheConfig{
+		TrieCleanLimit: ethconfig.Defaults.TrieCleanCache,
+		TrieDirtyLimit: ethconfig.Defaults.TrieDirtyCache,
+		TrieTimeLimit:  ethconfig.Defaults.TrieTimeout,
+	}
+	hc, err := core.NewHeaderChain(db, utils.MakeEngine(ctx), nil, nil, config, cache, nil, vm.Config{}, cfg.Eth.SlicesRunning)
+	if err != nil {
+		utils.Fatalf("Failed to load chain: %v", err)
+	}
+	processor := hc.Processor()
+	if processor == nil {
+		utils.Fatalf("State is not processed for %s, check --%s", common.NodeLocation.Name(), utils.SlicesRunningFlag.Name)
+	}
+
+	first, last := uint64(1), hc.CurrentBlock().NumberU64()
+	if ctx.NArg() > 0 {
+		if first, err = strconv.ParseUint(ctx.Args().Get(0), 10, 64); err != nil {
+			return fmt.Errorf("invalid first block number: %v", err)
+		}
+	}
+	if ctx.NArg() > 1 {
+		if last, err = strconv.ParseUint(ctx.Args().Get(1), 10, 64); err != nil {
+			return fmt.Errorf("invalid last block number: %v", err)
+		}
+	}
+	start := time.Now()
+	mismatch, err := processor.VerifyChain(first, last, ctx.Uint64(utils.ReexecFlag.Name))
+	if err != nil {
+		return err
+	}
+	if mismatch != nil {
+		fmt.Println(mismatch)
+		for _, tx := range mismatch.Txs {
+			fmt.Printf("  tx %d [%x]: %s (remote: %s local: %s)\n", tx.Index, tx.Hash, tx.Field, tx.Remote, tx.Local)
+		}
+		return errors.New("chain verification failed")
+	}
+	fmt.Printf("Verified blocks %d to %d in %v\n", first, last, time.Since(start))
+	return nil
+}
+
 // hashish returns true for strings that look like hashes.
 func hashish(x string) bool {
 	_, err := strconv.Atoi(x)
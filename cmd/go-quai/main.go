@@ -65,6 +65,10 @@ var (
 		utils.CacheTrieRejournalFlag,
 		utils.ColosseumFlag,
 		utils.ConsensusEngineFlag,
+		utils.ProgpowDatasetDirFlag,
+		utils.ProgpowDatasetsInMemoryFlag,
+		utils.ProgpowDatasetsOnDiskFlag,
+		utils.ProgpowDatasetsLockMmapFlag,
 		utils.DNSDiscoveryFlag,
 		utils.DataDirFlag,
 		utils.DBEngineFlag,
@@ -94,9 +98,14 @@ var (
 		utils.MinFreeDiskSpaceFlag,
 		utils.MinerEtherbaseFlag,
 		utils.MinerGasPriceFlag,
+		utils.MinerPolicyFlag,
+		utils.MinerAllowlistFlag,
+		utils.MinerExcludeFlag,
+		utils.MinerEtxReserveFlag,
 		utils.NATFlag,
 		utils.NetrestrictFlag,
 		utils.NetworkIdFlag,
+		utils.ParallelTxWorkersFlag,
 		utils.NoCompactionFlag,
 		utils.NoDiscoverFlag,
 		utils.NoUSBFlag,
@@ -112,6 +121,8 @@ var (
 		utils.SubUrls,
 		utils.SyncModeFlag,
 		utils.TxLookupLimitFlag,
+		utils.AddressIndexFlag,
+		utils.StateDiffsFlag,
 		utils.TxPoolAccountQueueFlag,
 		utils.TxPoolAccountSlotsFlag,
 		utils.TxPoolGlobalQueueFlag,
@@ -147,6 +158,13 @@ var (
 		utils.LegacyRPCVirtualHostsFlag,
 		utils.RPCGlobalGasCapFlag,
 		utils.RPCGlobalTxFeeCapFlag,
+		utils.RPCBatchItemsFlag,
+		utils.RPCBatchResponseBytesFlag,
+		utils.RPCResponseBytesFlag,
+		utils.RPCSubscriptionsFlag,
+		utils.RPCRateFlag,
+		utils.RPCBurstFlag,
+		utils.RPCMethodRatesFlag,
 		utils.WSAllowedOriginsFlag,
 		utils.WSApiFlag,
 		utils.WSEnabledFlag,
@@ -183,7 +201,10 @@ func init() {
 		exportPreimagesCommand,
 		dumpCommand,
 		dumpGenesisCommand,
+		verifyChainCommand,
 		// See misccmd.go:
+		makecacheCommand,
+		makedagCommand,
 		versionCommand,
 		versionCheckCommand,
 		licenseCommand,
@@ -191,6 +212,10 @@ func init() {
 		dumpConfigCommand,
 		// See snapshot.go
 		snapshotCommand,
+		// See simcmd.go
+		simulateCommand,
+		// See p2pcmd.go
+		p2pCommand,
 	}
 	sort.Sort(cli.CommandsByName(app.Commands))
 
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dominant-strategies/go-quai/common"
+	"github.com/dominant-strategies/go-quai/p2p/enode"
+	"github.com/dominant-strategies/go-quai/rlp"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	p2pCommand = cli.Command{
+		Name:     "p2p",
+		Usage:    "Node record, DNS discovery and crawler tooling",
+		Category: "MISCELLANEOUS COMMANDS",
+		Description: `
+The p2p commands maintain the per location node lists published through DNS
+discovery. A node set is a JSON file of node records grouped by location name
+(prime, cyprus, cyprus1, ...), it is filled by "p2p crawl" and published with
+"p2p dns sign" and "p2p dns to-txt".`,
+		Subcommands: []cli.Command{
+			enrCommand,
+			dnsCommand,
+			crawlCommand,
+		},
+	}
+	enrCommand = cli.Command{
+		Name:  "enr",
+		Usage: "Operations on node records",
+		Subcommands: []cli.Command{
+			{
+				Name:      "decode",
+				Usage:     "Print the contents of a node record",
+				ArgsUsage: "<base64 record | enode URL | file>",
+				Action:    enrDecode,
+			},
+		},
+	}
+)
+
+// nodeSet is the set of nodes known in every location, keyed by the location
+// name.
+type nodeSet map[string]locationNodes
+
+// locationNodes is the set of nodes known in one location.
+type locationNodes map[enode.ID]nodeJSON
+
+// nodeJSON is a node of a node set along with its liveness information.
+type nodeJSON struct {
+	Seq   uint64      `json:"seq"`
+	N     *enode.Node `json:"record"`
+	Score int         `json:"score,omitempty"`
+
+	FirstResponse time.Time `json:"firstResponse,omitempty"`
+	LastResponse  time.Time `json:"lastResponse,omitempty"`
+	LastCheck     time.Time `json:"lastCheck,omitempty"`
+}
+
+// loadNodeSet reads a node set from a JSON file.
+func loadNodeSet(file string) (nodeSet, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var set nodeSet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("invalid node set %s: %v", file, err)
+	}
+	for location, nodes := range set {
+		if !isLocationName(location) {
+			return nil, fmt.Errorf("invalid node set %s: unknown location %q", file, location)
+		}
+		for id, n := range nodes {
+			if n.N == nil {
+				return nil, fmt.Errorf("invalid node set %s: node %v in %s has no record", file, id, location)
+			}
+			if n.N.ID() != id {
+				return nil, fmt.Errorf("invalid node set %s: node %v in %s is keyed by %v", file, n.N.ID(), location, id)
+			}
+		}
+	}
+	return set, nil
+}
+
+// writeNodeSet writes a node set to a JSON file.
+func writeNodeSet(file string, set nodeSet) error {
+	data, err := json.MarshalIndent(set, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, append(data, '\n'), 0644)
+}
+
+// locations returns the location names of the set in a stable order.
+func (set nodeSet) locations() []string {
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// nodes returns the records of a location sorted by score.
+func (ns locationNodes) nodes() []*enode.Node {
+	list := make([]nodeJSON, 0, len(ns))
+	for _, n := range ns {
+		list = append(list, n)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Score != list[j].Score {
+			return list[i].Score > list[j].Score
+		}
+		return bytes.Compare(list[i].N.ID().Bytes(), list[j].N.ID().Bytes()) < 0
+	})
+	nodes := make([]*enode.Node, len(list))
+	for i := range list {
+		nodes[i] = list[i].N
+	}
+	return nodes
+}
+
+// add inserts or updates a node of a location.
+func (ns locationNodes) add(nodes ...*enode.Node) {
+	for _, n := range nodes {
+		v := ns[n.ID()]
+		v.N = n
+		v.Seq = n.Seq()
+		ns[n.ID()] = v
+	}
+}
+
+// hierarchyLocations returns the names of every location of the hierarchy.
+func hierarchyLocations() []string {
+	names := []string{common.Location{}.Name()}
+	for region := 0; region < common.NumRegionsInPrime; region++ {
+		names = append(names, common.Location{byte(region)}.Name())
+		for zone := 0; zone < common.NumZonesInRegion; zone++ {
+			names = append(names, common.Location{byte(region), byte(zone)}.Name())
+		}
+	}
+	return names
+}
+
+// isLocationName reports whether the name is the name of a location of the
+// hierarchy.
+func isLocationName(name string) bool {
+	for _, location := range hierarchyLocations() {
+		if location == name {
+			return true
+		}
+	}
+	return false
+}
+
+// selectLocations returns the locations of the set restricted to the ones
+// given as a comma separated list, if any.
+func selectLocations(set nodeSet, list string) ([]string, error) {
+	if list == "" {
+		return set.locations(), nil
+	}
+	var names []string
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if !isLocationName(name) {
+			return nil, fmt.Errorf("unknown location %q", name)
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+func enrDecode(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("need a node record, enode URL or file as argument")
+	}
+	input := ctx.Args().First()
+	if data, err := ioutil.ReadFile(input); err == nil {
+		input = strings.TrimSpace(string(data))
+	}
+	n, err := parseNodeRecord(input)
+	if err != nil {
+		return err
+	}
+	r := n.Record()
+	fmt.Printf("Node ID: %v\n", n.ID())
+	fmt.Printf("URLv4:   %s\n", n.URLv4())
+	if sig := r.Signature(); sig != nil {
+		fmt.Printf("Signature: %x\n", sig)
+	}
+	kv := r.AppendElements(nil)[1:]
+	fmt.Printf("Record has sequence number %d and %d key/value pairs.\n", r.Seq(), len(kv)/2)
+	for i := 0; i < len(kv); i += 2 {
+		key := kv[i].(string)
+		fmt.Printf("  %-10s %s\n", fmt.Sprintf("%q", key), formatRecordValue(key, kv[i+1].(rlp.RawValue)))
+	}
+	return nil
+}
+
+// parseNodeRecord parses a node record given as "enr:" text, as bare base64 or
+// as an enode URL.
+func parseNodeRecord(input string) (*enode.Node, error) {
+	if strings.HasPrefix(input, "enode://") || strings.HasPrefix(input, "enr:") {
+		return enode.Parse(enode.ValidSchemes, input)
+	}
+	if _, err := base64.RawURLEncoding.DecodeString(input); err == nil {
+		return enode.Parse(enode.ValidSchemes, "enr:"+input)
+	}
+	return nil, fmt.Errorf("invalid node record %q", input)
+}
+
+// formatRecordValue formats the value of a well known record key, or returns
+// its raw encoding.
+func formatRecordValue(key string, value rlp.RawValue) string {
+	switch key {
+	case "id":
+		var scheme string
+		if rlp.DecodeBytes(value, &scheme) == nil {
+			return scheme
+		}
+	case "ip", "ip6":
+		var ip net.IP
+		if rlp.DecodeBytes(value, &ip) == nil {
+			return ip.String()
+		}
+	case "tcp", "tcp6", "udp", "udp6":
+		var port uint16
+		if rlp.DecodeBytes(value, &port) == nil {
+			return fmt.Sprint(port)
+		}
+	case "secp256k1":
+		var pubkey []byte
+		if rlp.DecodeBytes(value, &pubkey) == nil {
+			return hex.EncodeToString(pubkey)
+		}
+	}
+	return hex.EncodeToString(value) + " (raw)"
+}
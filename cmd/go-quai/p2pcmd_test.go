@@ -0,0 +1,84 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dominant-strategies/go-quai/crypto"
+	"github.com/dominant-strategies/go-quai/p2p/enode"
+	"github.com/dominant-strategies/go-quai/p2p/enr"
+)
+
+// newTestNode creates a signed node record with the given entries.
+func newTestNode(t *testing.T, entries ...enr.Entry) *enode.Node {
+	t.Helper()
+	db, err := enode.OpenDB("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln := enode.NewLocalNode(db, key)
+	ln.Set(enr.TCP(30303))
+	for _, entry := range entries {
+		ln.Set(entry)
+	}
+	return ln.Node()
+}
+
+func TestNodeSetRoundTrip(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "nodes.json")
+	set := nodeSet{"prime": make(locationNodes), "cyprus1": make(locationNodes)}
+	set["prime"].add(newTestNode(t), newTestNode(t))
+	set["cyprus1"].add(newTestNode(t))
+
+	if err := writeNodeSet(file, set); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := loadNodeSet(file)
+	if err != nil {
+		t.Fatalf("failed to load node set: %v", err)
+	}
+	if len(loaded) != len(set) {
+		t.Fatalf("location count mismatch: have %d, want %d", len(loaded), len(set))
+	}
+	for location, nodes := range set {
+		if len(loaded[location]) != len(nodes) {
+			t.Fatalf("%s: node count mismatch: have %d, want %d", location, len(loaded[location]), len(nodes))
+		}
+		for id, n := range nodes {
+			if have := loaded[location][id]; have.N == nil || have.N.String() != n.N.String() || have.Seq != n.Seq {
+				t.Errorf("%s: node %v mismatch", location, id)
+			}
+		}
+	}
+}
+
+func TestLoadNodeSetInvalid(t *testing.T) {
+	n, other := newTestNode(t), newTestNode(t)
+	tests := []struct {
+		name string
+		json string
+		err  string
+	}{
+		{"malformed", `{"prime": [`, "invalid node set"},
+		{"location", `{"atlantis": {}}`, "unknown location"},
+		{"record", `{"prime": {"` + n.ID().String() + `": {"seq": 1}}}`, "has no record"},
+		{"key", `{"prime": {"` + other.ID().String() + `": {"seq": 1, "record": "` + n.String() + `"}}}`, "is keyed by"},
+	}
+	for _, tt := range tests {
+		file := filepath.Join(t.TempDir(), "nodes.json")
+		if err := os.WriteFile(file, []byte(tt.json), 0600); err != nil {
+			t.Fatal(err)
+		}
+		_, err := loadNodeSet(file)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: error mismatch: have %v, want %q", tt.name, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/dominant-strategies/go-quai/cmd/utils"
+	"github.com/dominant-strategies/go-quai/core"
+	"github.com/dominant-strategies/go-quai/core/forkid"
+	"github.com/dominant-strategies/go-quai/crypto"
+	"github.com/dominant-strategies/go-quai/p2p/discover"
+	"github.com/dominant-strategies/go-quai/p2p/enode"
+	"github.com/dominant-strategies/go-quai/rlp"
+	"gopkg.in/urfave/cli.v1"
+)
+
+const (
+	// crawlRevalidateInterval is how long a node is trusted without being
+	// checked again.
+	crawlRevalidateInterval = 10 * time.Minute
+
+	// crawlMinScore is the score below which unresponsive nodes are dropped.
+	crawlMinScore = -5
+)
+
+var (
+	crawlCommand = cli.Command{
+		Name:      "crawl",
+		Usage:     "Update a node set by crawling the discovery network of every location",
+		ArgsUsage: "<nodes.json>",
+		Action:    crawlNodes,
+		Flags: []cli.Flag{
+			crawlTimeoutFlag,
+			crawlLocationFlag,
+			crawlListenFlag,
+			utils.ColosseumFlag,
+			utils.GardenFlag,
+			utils.OrchardFlag,
+			utils.LighthouseFlag,
+			utils.LocalFlag,
+			utils.ConsensusEngineFlag,
+		},
+		Description: `
+The nodes of every location of the set are used as the bootnodes of a separate
+discovery crawl, so each location should list at least one reachable node.
+Known nodes are checked again, nodes found during the crawl are added to their
+location and the updated set is written back to the file. Only nodes whose
+"eth" record entry carries a fork ID compatible with the selected network
+(colosseum by default) are kept.`,
+	}
+)
+
+var (
+	crawlTimeoutFlag = cli.DurationFlag{
+		Name:  "timeout",
+		Usage: "Time spent crawling each location",
+		Value: 30 * time.Second,
+	}
+	crawlLocationFlag = cli.StringFlag{
+		Name:  "location",
+		Usage: "Comma separated list of locations to crawl (defaults to all locations of the set)",
+	}
+	crawlListenFlag = cli.StringFlag{
+		Name:  "addr",
+		Usage: "Listening address of the discovery endpoint",
+		Value: "0.0.0.0:0",
+	}
+)
+
+func crawlNodes(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("need node set file as argument")
+	}
+	file := ctx.Args().First()
+	set, err := loadNodeSet(file)
+	if err != nil {
+		return err
+	}
+	locations, err := selectLocations(set, ctx.String(crawlLocationFlag.Name))
+	if err != nil {
+		return err
+	}
+	filter := crawlForkFilter(ctx)
+	for _, location := range locations {
+		nodes := set[location]
+		if len(nodes) == 0 {
+			return fmt.Errorf("no bootnodes known in %s", location)
+		}
+		before := len(nodes)
+		if err := crawlLocation(nodes, filter, ctx.String(crawlListenFlag.Name), ctx.Duration(crawlTimeoutFlag.Name)); err != nil {
+			return fmt.Errorf("can't crawl %s: %v", location, err)
+		}
+		fmt.Printf("%-10s %d nodes (%+d)\n", location, len(nodes), len(nodes)-before)
+	}
+	return writeNodeSet(file, set)
+}
+
+// crawlForkFilter returns the filter of the fork IDs of the network selected
+// by the network flags.
+func crawlForkFilter(ctx *cli.Context) forkid.Filter {
+	engine := ctx.String(utils.ConsensusEngineFlag.Name)
+	var genesis *core.Genesis
+	switch {
+	case ctx.Bool(utils.GardenFlag.Name):
+		genesis = core.DefaultGardenGenesisBlock(engine)
+	case ctx.Bool(utils.OrchardFlag.Name):
+		genesis = core.DefaultOrchardGenesisBlock(engine)
+	case ctx.Bool(utils.LighthouseFlag.Name):
+		genesis = core.DefaultLighthouseGenesisBlock(engine)
+	case ctx.Bool(utils.LocalFlag.Name):
+		genesis = core.DefaultLocalGenesisBlock(engine)
+	default:
+		genesis = core.DefaultColosseumGenesisBlock(engine)
+	}
+	return forkid.NewStaticFilter(genesis.Config, genesis.Config.GenesisHash)
+}
+
+// ethEntry is the "eth" entry of a node record, advertising the fork ID of the
+// chain the node follows.
+type ethEntry struct {
+	ForkID forkid.ID
+	Rest   []rlp.RawValue `rlp:"tail"`
+}
+
+// ENRKey implements enr.Entry.
+func (e ethEntry) ENRKey() string {
+	return "eth"
+}
+
+// checkForkID reports whether the node record advertises a fork ID accepted by
+// the filter.
+func checkForkID(n *enode.Node, filter forkid.Filter) error {
+	var entry ethEntry
+	if err := n.Load(&entry); err != nil {
+		return fmt.Errorf("no eth entry: %v", err)
+	}
+	return filter(entry.ForkID)
+}
+
+// crawlLocation revalidates the known nodes of a location and adds the nodes
+// found through discovery until the timeout expires. Nodes of another network
+// are dropped.
+func crawlLocation(nodes locationNodes, filter forkid.Filter, addr string, timeout time.Duration) error {
+	disc, closeDisc, err := startDiscovery(addr, nodes.nodes())
+	if err != nil {
+		return err
+	}
+	defer closeDisc()
+
+	now := time.Now()
+	for id, n := range nodes {
+		if now.Sub(n.LastCheck) < crawlRevalidateInterval {
+			continue
+		}
+		n.LastCheck = now
+		if updated, err := disc.RequestENR(n.N); err != nil {
+			n.Score--
+		} else {
+			if n.FirstResponse.IsZero() {
+				n.FirstResponse = now
+			}
+			n.LastResponse = now
+			n.Score++
+			if updated.Seq() > n.N.Seq() {
+				n.N, n.Seq = updated, updated.Seq()
+			}
+			if checkForkID(n.N, filter) != nil {
+				delete(nodes, id)
+				continue
+			}
+		}
+		if n.Score < crawlMinScore {
+			delete(nodes, id)
+			continue
+		}
+		nodes[id] = n
+	}
+
+	it := disc.RandomNodes()
+	deadline := time.AfterFunc(timeout, it.Close)
+	defer deadline.Stop()
+	for it.Next() {
+		n := it.Node()
+		if _, ok := nodes[n.ID()]; ok || n.TCP() == 0 || checkForkID(n, filter) != nil {
+			continue
+		}
+		nodes.add(n)
+		v := nodes[n.ID()]
+		v.FirstResponse, v.LastResponse, v.LastCheck = time.Now(), time.Now(), time.Now()
+		nodes[n.ID()] = v
+	}
+	return nil
+}
+
+// startDiscovery starts a discovery v4 endpoint with a throwaway identity. The
+// returned function stops the endpoint and releases its socket and node
+// database.
+func startDiscovery(addr string, bootnodes []*enode.Node) (*discover.UDPv4, func(), error) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, nil, err
+	}
+	db, err := enode.OpenDB("")
+	if err != nil {
+		return nil, nil, err
+	}
+	socket, err := listenUDP(addr)
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+	ln := enode.NewLocalNode(db, key)
+	cfg := discover.Config{
+		PrivateKey: key,
+		Bootnodes:  bootnodes,
+	}
+	disc, err := discover.ListenV4(socket, ln, cfg)
+	if err != nil {
+		socket.Close()
+		db.Close()
+		return nil, nil, err
+	}
+	return disc, func() {
+		disc.Close()
+		db.Close()
+	}, nil
+}
+
+func listenUDP(addr string) (*net.UDPConn, error) {
+	udpAddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return net.ListenUDP("udp", udpAddr)
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/dominant-strategies/go-quai/core"
+	"github.com/dominant-strategies/go-quai/core/forkid"
+)
+
+func TestCheckForkID(t *testing.T) {
+	colosseum := core.DefaultColosseumGenesisBlock("progpow").Config
+	garden := core.DefaultGardenGenesisBlock("progpow").Config
+	filter := forkid.NewStaticFilter(colosseum, colosseum.GenesisHash)
+
+	tests := []struct {
+		name  string
+		entry *ethEntry
+		ok    bool
+	}{
+		{"same network", &ethEntry{ForkID: forkid.NewID(colosseum, colosseum.GenesisHash, 0)}, true},
+		{"other network", &ethEntry{ForkID: forkid.NewID(garden, garden.GenesisHash, 0)}, false},
+		{"no entry", nil, false},
+	}
+	for _, tt := range tests {
+		n := newTestNode(t)
+		if tt.entry != nil {
+			n = newTestNode(t, tt.entry)
+		}
+		if err := checkForkID(n, filter); (err == nil) != tt.ok {
+			t.Errorf("%s: fork ID check mismatch: have %v, want ok %v", tt.name, err, tt.ok)
+		}
+	}
+}
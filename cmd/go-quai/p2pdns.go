@@ -0,0 +1,220 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dominant-strategies/go-quai/crypto"
+	"github.com/dominant-strategies/go-quai/p2p/dnsdisc"
+	"gopkg.in/urfave/cli.v1"
+)
+
+const (
+	nodesFile    = "nodes.json"
+	treeInfoFile = "enrtree-info.json"
+
+	// maxTXTString is the maximum length of a single character-string of
+	// a TXT record.
+	maxTXTString = 255
+)
+
+var (
+	dnsCommand = cli.Command{
+		Name:  "dns",
+		Usage: "DNS discovery tree operations",
+		Subcommands: []cli.Command{
+			dnsSignCommand,
+			dnsToTXTCommand,
+		},
+	}
+	dnsSignCommand = cli.Command{
+		Name:      "sign",
+		Usage:     "Sign the DNS discovery tree of every location of a node set",
+		ArgsUsage: "<tree directory> <key file>",
+		Action:    dnsSign,
+		Flags: []cli.Flag{
+			dnsDomainFlag,
+			dnsSeqFlag,
+		},
+		Description: `
+The tree directory must contain a nodes.json node set. A tree is built for
+every location of the set and signed for <location>.<domain>, the resulting
+URLs and signatures are written to enrtree-info.json in the same directory.
+The key file holds the hex encoded secp256k1 signing key.`,
+	}
+	dnsToTXTCommand = cli.Command{
+		Name:      "to-txt",
+		Usage:     "Create a DNS zone file from a signed tree directory",
+		ArgsUsage: "<tree directory> <output file>",
+		Action:    dnsToTXT,
+		Flags: []cli.Flag{
+			dnsTTLFlag,
+		},
+	}
+)
+
+var (
+	dnsDomainFlag = cli.StringFlag{
+		Name:  "domain",
+		Usage: "Parent domain of the location trees (e.g. colosseum.quainodes.io)",
+	}
+	dnsSeqFlag = cli.UintFlag{
+		Name:  "seq",
+		Usage: "Sequence number of the trees (defaults to the current unix time)",
+	}
+	dnsTTLFlag = cli.UintFlag{
+		Name:  "ttl",
+		Usage: "Time to live of the TXT records in seconds",
+		Value: 30 * 60,
+	}
+)
+
+// treeInfo is the signed state of the tree of one location.
+type treeInfo struct {
+	URL          string    `json:"url"`
+	Seq          uint      `json:"seq"`
+	Signature    string    `json:"signature"`
+	Links        []string  `json:"links,omitempty"`
+	LastModified time.Time `json:"lastModified"`
+}
+
+func dnsSign(ctx *cli.Context) error {
+	if ctx.NArg() != 2 {
+		return fmt.Errorf("need tree directory and key file as arguments")
+	}
+	dir, keyfile := ctx.Args().Get(0), ctx.Args().Get(1)
+	domain := strings.Trim(ctx.String(dnsDomainFlag.Name), ".")
+	if domain == "" {
+		return fmt.Errorf("missing --%s", dnsDomainFlag.Name)
+	}
+	key, err := crypto.LoadECDSA(keyfile)
+	if err != nil {
+		return fmt.Errorf("can't load signing key: %v", err)
+	}
+	set, err := loadNodeSet(filepath.Join(dir, nodesFile))
+	if err != nil {
+		return err
+	}
+	infos, err := loadTreeInfo(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if infos == nil {
+		infos = make(map[string]treeInfo)
+	}
+	seq := uint(time.Now().Unix())
+	if ctx.IsSet(dnsSeqFlag.Name) {
+		seq = ctx.Uint(dnsSeqFlag.Name)
+	}
+	for _, location := range set.locations() {
+		info := infos[location]
+		if info.Seq >= seq {
+			return fmt.Errorf("sequence number %d of %s is not above the current %d", seq, location, info.Seq)
+		}
+		t, err := dnsdisc.MakeTree(seq, set[location].nodes(), info.Links)
+		if err != nil {
+			return fmt.Errorf("can't create tree of %s: %v", location, err)
+		}
+		url, err := t.Sign(key, location+"."+domain)
+		if err != nil {
+			return fmt.Errorf("can't sign tree of %s: %v", location, err)
+		}
+		infos[location] = treeInfo{
+			URL:          url,
+			Seq:          t.Seq(),
+			Signature:    t.Signature(),
+			Links:        t.Links(),
+			LastModified: time.Now().UTC(),
+		}
+		fmt.Printf("%-10s %s\n", location, url)
+	}
+	return writeTreeInfo(dir, infos)
+}
+
+func dnsToTXT(ctx *cli.Context) error {
+	if ctx.NArg() != 2 {
+		return fmt.Errorf("need tree directory and output file as arguments")
+	}
+	dir, output := ctx.Args().Get(0), ctx.Args().Get(1)
+	set, err := loadNodeSet(filepath.Join(dir, nodesFile))
+	if err != nil {
+		return err
+	}
+	infos, err := loadTreeInfo(dir)
+	if err != nil {
+		return err
+	}
+	records := make(map[string]string)
+	for _, location := range set.locations() {
+		info, ok := infos[location]
+		if !ok {
+			return fmt.Errorf("tree of %s is not signed", location)
+		}
+		domain, pubkey, err := dnsdisc.ParseURL(info.URL)
+		if err != nil {
+			return fmt.Errorf("invalid tree URL of %s: %v", location, err)
+		}
+		t, err := dnsdisc.MakeTree(info.Seq, set[location].nodes(), info.Links)
+		if err != nil {
+			return fmt.Errorf("can't create tree of %s: %v", location, err)
+		}
+		if err := t.SetSignature(pubkey, info.Signature); err != nil {
+			return fmt.Errorf("tree of %s changed since signing: %v", location, err)
+		}
+		for name, value := range t.ToTXT(domain) {
+			records[name] = value
+		}
+	}
+	return ioutil.WriteFile(output, zoneFile(records, ctx.Uint(dnsTTLFlag.Name)), 0644)
+}
+
+// zoneFile formats TXT records as a DNS zone file, splitting values which don't
+// fit into a single character-string.
+func zoneFile(records map[string]string, ttl uint) []byte {
+	names := make([]string, 0, len(records))
+	for name := range records {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		value := records[name]
+		var parts []string
+		for len(value) > maxTXTString {
+			parts = append(parts, fmt.Sprintf("%q", value[:maxTXTString]))
+			value = value[maxTXTString:]
+		}
+		parts = append(parts, fmt.Sprintf("%q", value))
+		fmt.Fprintf(&b, "%s.\t%d\tIN\tTXT\t%s\n", name, ttl, strings.Join(parts, " "))
+	}
+	return []byte(b.String())
+}
+
+// loadTreeInfo reads the signed tree state of a tree directory.
+func loadTreeInfo(dir string) (map[string]treeInfo, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, treeInfoFile))
+	if err != nil {
+		return nil, err
+	}
+	var infos map[string]treeInfo
+	if err := json.Unmarshal(data, &infos); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", treeInfoFile, err)
+	}
+	return infos, nil
+}
+
+// writeTreeInfo writes the signed tree state of a tree directory.
+func writeTreeInfo(dir string, infos map[string]treeInfo) error {
+	data, err := json.MarshalIndent(infos, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, treeInfoFile), append(data, '\n'), 0644)
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestZoneFile(t *testing.T) {
+	records := map[string]string{
+		"n.nodes.example.org":      "enrtree-root:v1 e=X",
+		"abcd.n.nodes.example.org": strings.Repeat("a", maxTXTString) + strings.Repeat("b", 10),
+	}
+	// Records are sorted by name and values over a character-string are split.
+	want := "abcd.n.nodes.example.org.\t300\tIN\tTXT\t\"" + strings.Repeat("a", maxTXTString) + "\" \"" + strings.Repeat("b", 10) + "\"\n" +
+		"n.nodes.example.org.\t300\tIN\tTXT\t\"enrtree-root:v1 e=X\"\n"
+	if have := string(zoneFile(records, 300)); have != want {
+		t.Fatalf("zone file mismatch:\nhave %q\nwant %q", have, want)
+	}
+	if have := zoneFile(nil, 300); len(have) != 0 {
+		t.Fatalf("zone file of no records not empty: %q", have)
+	}
+}
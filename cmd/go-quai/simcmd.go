@@ -0,0 +1,533 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"math/rand"
+	"os"
+	"strconv"
+
+	"github.com/dominant-strategies/go-quai/common"
+	"github.com/dominant-strategies/go-quai/common/prque"
+	"github.com/dominant-strategies/go-quai/consensus"
+	"github.com/dominant-strategies/go-quai/consensus/progpow"
+	"github.com/dominant-strategies/go-quai/core/types"
+	"github.com/dominant-strategies/go-quai/params"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	simDurationFlag = cli.Float64Flag{
+		Name:  "duration",
+		Usage: "Simulated time in seconds",
+		Value: 24 * 60 * 60,
+	}
+	simBlocksFlag = cli.Uint64Flag{
+		Name:  "blocks",
+		Usage: "Maximum number of zone blocks to simulate across all locations (0 = no limit)",
+	}
+	simRegionsFlag = cli.IntFlag{
+		Name:  "regions",
+		Usage: "Number of regions in the simulated hierarchy",
+		Value: common.NumRegionsInPrime,
+	}
+	simZonesFlag = cli.IntFlag{
+		Name:  "zones",
+		Usage: "Number of zones in each simulated region",
+		Value: common.NumZonesInRegion,
+	}
+	simHashrateFlag = cli.Float64Flag{
+		Name:  "hashrate",
+		Usage: "Hashrate in hashes per second of locations missing from the profile",
+		Value: 1e6,
+	}
+	simLatencyFlag = cli.Float64Flag{
+		Name:  "latency",
+		Usage: "Latency in seconds to propagate blocks of locations missing from the profile",
+		Value: 0.5,
+	}
+	simProfileFlag = cli.StringFlag{
+		Name:  "profile",
+		Usage: "JSON file with the hashrate and latency profile of each location",
+	}
+	simDifficultyFlag = cli.Uint64Flag{
+		Name:  "difficulty",
+		Usage: "Difficulty of the genesis block",
+		Value: 1e7,
+	}
+	simDurationLimitFlag = cli.Uint64Flag{
+		Name:  "durationlimit",
+		Usage: "Block time target of the difficulty adjustment in seconds",
+		Value: params.DurationLimit.Uint64(),
+	}
+	simMinDifficultyFlag = cli.Uint64Flag{
+		Name:  "mindifficulty",
+		Usage: "Minimum difficulty of the difficulty adjustment",
+		Value: params.MinimumDifficulty.Uint64(),
+	}
+	simSeedFlag = cli.Int64Flag{
+		Name:  "seed",
+		Usage: "Seed of the random source",
+		Value: 1,
+	}
+	simOutputFlag = cli.StringFlag{
+		Name:  "output",
+		Usage: "File to write the per block CSV to (default = stdout)",
+	}
+	simSummaryFlag = cli.StringFlag{
+		Name:  "summary",
+		Usage: "File to write the per location summary CSV to",
+	}
+
+	simulateCommand = cli.Command{
+		Action:    simulate,
+		Name:      "simulate",
+		Usage:     "Simulate difficulty adjustment and entropy growth across the hierarchy",
+		ArgsUsage: " ",
+		Category:  "MISCELLANEOUS COMMANDS",
+		Flags: []cli.Flag{
+			simDurationFlag,
+			simBlocksFlag,
+			simRegionsFlag,
+			simZonesFlag,
+			simHashrateFlag,
+			simLatencyFlag,
+			simProfileFlag,
+			simDifficultyFlag,
+			simDurationLimitFlag,
+			simMinDifficultyFlag,
+			simSeedFlag,
+			simOutputFlag,
+			simSummaryFlag,
+		},
+		Description: `
+The simulate command drives CalcDifficulty, CalcOrder, TotalLogS, DeltaLogS and
+IntrinsicLogS of the consensus engine with synthetic hashrate and latency
+profiles for each zone, without running a network. Block times are sampled from
+the hashrate of each zone and proof-of-work hashes are drawn uniformly below the
+target, so coincidence with region and prime follows the real distribution.
+
+One CSV row is written per zone block with its time, difficulty, order and
+entropy. The optional summary CSV contains block times, coincidence ratios and
+entropy growth for each location.
+
+The profile is a JSON list of locations, for example:
+
+  [{"location": [0, 1], "hashrate": 2e6, "latency": 0.2,
+    "schedule": [{"time": 3600, "hashrate": 4e6}]}]
+
+where schedule changes the hashrate of the location at the given time.`,
+	}
+)
+
+// simHashrateStep changes the hashrate of a location at the given time.
+type simHashrateStep struct {
+	Time     float64 `json:"time"`
+	Hashrate float64 `json:"hashrate"`
+}
+
+// simProfile is the hashrate and latency profile of a single location.
+type simProfile struct {
+	Location []int             `json:"location"`
+	Hashrate float64           `json:"hashrate"`
+	Latency  float64           `json:"latency"`
+	Schedule []simHashrateStep `json:"schedule"`
+}
+
+// hashrateAt returns the hashrate of the location at the given time.
+func (p *simProfile) hashrateAt(t float64) float64 {
+	hashrate, latest := p.Hashrate, math.Inf(-1)
+	for _, step := range p.Schedule {
+		if step.Time <= t && step.Time >= latest {
+			hashrate, latest = step.Hashrate, step.Time
+		}
+	}
+	return hashrate
+}
+
+// nextStep returns the time of the first hashrate change after the given time,
+// or +Inf if the hashrate does not change anymore.
+func (p *simProfile) nextStep(t float64) float64 {
+	next := math.Inf(1)
+	for _, step := range p.Schedule {
+		if step.Time > t && step.Time < next {
+			next = step.Time
+		}
+	}
+	return next
+}
+
+// simView is the knowledge a zone has of its dominant chains.
+type simView struct {
+	primeEntropy  *big.Int
+	regionEntropy *big.Int
+	regionDeltaS  *big.Int
+}
+
+// simZone is the state of a single simulated zone chain.
+type simZone struct {
+	location   common.Location
+	profile    *simProfile
+	view       simView
+	parent     *types.Header
+	difficulty *big.Int
+	started    float64
+
+	blocks, regions, primes uint64
+	blockTime               float64
+}
+
+// simEvent is either a block found by a zone, a hashrate change of a zone or
+// the arrival of a dominant update at a zone.
+type simEvent struct {
+	time   float64
+	zone   int
+	resume bool // Hashrate of the zone changed, redraw the time to find the block
+	update *simUpdate
+}
+
+// simUpdate carries the entropy of a dominant coincident block.
+type simUpdate struct {
+	region       int // Region the update applies to, -1 for prime updates
+	entropy      *big.Int
+	regionDeltaS *big.Int
+}
+
+// simChain is the minimal header reader needed by the difficulty adjustment.
+type simChain struct {
+	config  *params.ChainConfig
+	headers map[common.Hash]*types.Header
+}
+
+func (c *simChain) Config() *params.ChainConfig                             { return c.config }
+func (c *simChain) CurrentHeader() *types.Header                            { return nil }
+func (c *simChain) GetHeader(hash common.Hash, number uint64) *types.Header { return c.headers[hash] }
+func (c *simChain) GetHeaderByNumber(number uint64) *types.Header           { return nil }
+func (c *simChain) GetHeaderByHash(hash common.Hash) *types.Header          { return c.headers[hash] }
+func (c *simChain) GetTerminiByHash(hash common.Hash) *types.Termini        { return nil }
+func (c *simChain) ProcessingState() bool                                   { return false }
+
+// simulator drives the consensus engine over a synthetic hierarchy.
+type simulator struct {
+	engine consensus.Engine
+	chain  *simChain
+	rand   *rand.Rand
+	zones  []*simZone
+	events *prque.Prque
+	nonce  uint64
+	prime  uint64
+
+	out *csv.Writer
+}
+
+func simulate(ctx *cli.Context) error {
+	profiles := make(map[string]*simProfile)
+	if file := ctx.String(simProfileFlag.Name); file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		var list []*simProfile
+		if err := json.Unmarshal(data, &list); err != nil {
+			return fmt.Errorf("invalid profile %s: %v", file, err)
+		}
+		for _, profile := range list {
+			if len(profile.Location) != common.HierarchyDepth-1 {
+				return fmt.Errorf("invalid profile location %v", profile.Location)
+			}
+			location := common.Location{byte(profile.Location[0]), byte(profile.Location[1])}
+			profiles[location.Name()] = profile
+		}
+	}
+	out := os.Stdout
+	if file := ctx.String(simOutputFlag.Name); file != "" {
+		f, err := os.Create(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	// The engine functions operate on the context of the node location
+	common.NodeLocation = common.Location{0, 0}
+
+	engine := progpow.New(progpow.Config{
+		PowMode:       progpow.ModeNormal,
+		CachesInMem:   1,
+		DurationLimit: new(big.Int).SetUint64(ctx.Uint64(simDurationLimitFlag.Name)),
+		MinDifficulty: new(big.Int).SetUint64(ctx.Uint64(simMinDifficultyFlag.Name)),
+	}, nil, false)
+	defer engine.Close()
+
+	genesis := types.EmptyHeader()
+	genesis.SetDifficulty(new(big.Int).SetUint64(ctx.Uint64(simDifficultyFlag.Name)))
+	sim := &simulator{
+		engine: engine,
+		chain: &simChain{
+			config:  &params.ChainConfig{ChainID: big.NewInt(1337), Progpow: new(params.ProgpowConfig), GenesisHash: genesis.Hash()},
+			headers: map[common.Hash]*types.Header{genesis.Hash(): genesis},
+		},
+		rand:   rand.New(rand.NewSource(ctx.Int64(simSeedFlag.Name))),
+		events: prque.New(nil),
+		out:    csv.NewWriter(out),
+	}
+	for r := 0; r < ctx.Int(simRegionsFlag.Name); r++ {
+		for z := 0; z < ctx.Int(simZonesFlag.Name); z++ {
+			location := common.Location{byte(r), byte(z)}
+			profile, ok := profiles[location.Name()]
+			if !ok {
+				profile = &simProfile{Location: []int{r, z}, Hashrate: ctx.Float64(simHashrateFlag.Name), Latency: ctx.Float64(simLatencyFlag.Name)}
+			}
+			sim.zones = append(sim.zones, &simZone{
+				location: location,
+				profile:  profile,
+				view:     simView{primeEntropy: new(big.Int), regionEntropy: new(big.Int), regionDeltaS: new(big.Int)},
+				parent:   genesis,
+			})
+		}
+	}
+	if len(sim.zones) == 0 {
+		return fmt.Errorf("no locations to simulate")
+	}
+	sim.out.Write([]string{"time", "location", "number", "difficulty", "blockTime", "order", "intrinsicS", "totalS", "deltaS", "hashrate"})
+	for i := range sim.zones {
+		sim.startMining(i, 0)
+	}
+	duration, limit := ctx.Float64(simDurationFlag.Name), ctx.Uint64(simBlocksFlag.Name)
+	var mined uint64
+	for !sim.events.Empty() {
+		item, _ := sim.events.Pop()
+		ev := item.(*simEvent)
+		if ev.time > duration || (limit > 0 && mined >= limit) {
+			break
+		}
+		if ev.update != nil {
+			sim.applyUpdate(sim.zones[ev.zone], ev.update)
+			continue
+		}
+		if ev.resume {
+			sim.schedule(ev.zone, ev.time)
+			continue
+		}
+		if err := sim.mineBlock(ev.zone, ev.time); err != nil {
+			return err
+		}
+		mined++
+	}
+	sim.out.Flush()
+	if err := sim.out.Error(); err != nil {
+		return err
+	}
+	if file := ctx.String(simSummaryFlag.Name); file != "" {
+		f, err := os.Create(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return sim.writeSummary(f, duration)
+	}
+	return nil
+}
+
+// push schedules an event at the given time.
+func (sim *simulator) push(ev *simEvent) {
+	sim.events.Push(ev, -int64(ev.time*1e6))
+}
+
+// startMining computes the difficulty of the next block of a zone and
+// schedules the time at which it is found.
+func (sim *simulator) startMining(i int, now float64) {
+	zone := sim.zones[i]
+	zone.difficulty = sim.engine.CalcDifficulty(sim.chain, zone.parent)
+	zone.started = now
+
+	sim.schedule(i, now)
+}
+
+// schedule schedules the time at which the zone finds its next block at the
+// current hashrate. Should the hashrate change before, the search is resumed
+// at the time of the change instead, which is exact as the search is memoryless.
+func (sim *simulator) schedule(i int, now float64) {
+	zone := sim.zones[i]
+	next := zone.profile.nextStep(now)
+
+	// The number of hashes until a block is found is geometrically distributed,
+	// so the time to find it is exponential with a mean of difficulty/hashrate.
+	hashrate := zone.profile.hashrateAt(now)
+	if hashrate <= 0 {
+		if !math.IsInf(next, 1) {
+			sim.push(&simEvent{time: next, zone: i, resume: true})
+		}
+		return
+	}
+	mean, _ := new(big.Float).Quo(new(big.Float).SetInt(zone.difficulty), big.NewFloat(hashrate)).Float64()
+	if found := now + sim.rand.ExpFloat64()*mean; found < next {
+		sim.push(&simEvent{time: found, zone: i})
+	} else {
+		sim.push(&simEvent{time: next, zone: i, resume: true})
+	}
+}
+
+// mineBlock seals a block for the zone with a proof-of-work hash drawn below
+// the target and propagates its entropy to the dominant chains.
+func (sim *simulator) mineBlock(i int, now float64) error {
+	zone := sim.zones[i]
+	parent := zone.parent
+
+	header := types.EmptyHeader()
+	header.SetParentHash(parent.Hash(), common.ZONE_CTX)
+	header.SetNumber(new(big.Int).Add(parent.Number(common.ZONE_CTX), common.Big1), common.ZONE_CTX)
+	header.SetParentEntropy(zone.view.primeEntropy, common.PRIME_CTX)
+	header.SetParentEntropy(zone.view.regionEntropy, common.REGION_CTX)
+	header.SetParentDeltaS(zone.view.regionDeltaS, common.REGION_CTX)
+	if parent.NumberU64(common.ZONE_CTX) > 0 {
+		_, order, err := sim.engine.CalcOrder(parent)
+		if err != nil {
+			return err
+		}
+		header.SetParentEntropy(sim.engine.TotalLogS(parent), common.ZONE_CTX)
+		if order < common.ZONE_CTX {
+			header.SetParentDeltaS(big.NewInt(0), common.ZONE_CTX)
+		} else {
+			header.SetParentDeltaS(sim.engine.DeltaLogS(parent), common.ZONE_CTX)
+		}
+	}
+	header.SetDifficulty(zone.difficulty)
+	header.SetTime(uint64(math.Round(now)))
+	header.SetLocation(zone.location)
+	header.SetNonce(types.EncodeNonce(sim.nonce))
+	sim.nonce++
+
+	// A valid proof-of-work hash is uniformly distributed below the target
+	target := new(big.Int).Div(common.Big2e256, zone.difficulty)
+	powHash := common.BytesToHash(new(big.Int).Rand(sim.rand, target).Bytes())
+	header.PowHash.Store(powHash)
+	header.PowDigest.Store(header.MixHash())
+
+	intrinsicS, order, err := sim.engine.CalcOrder(header)
+	if err != nil {
+		return err
+	}
+	totalS, deltaS := sim.engine.TotalLogS(header), sim.engine.DeltaLogS(header)
+	sim.chain.headers[header.Hash()] = header
+
+	blockTime := now - zone.started
+	if parent.NumberU64(common.ZONE_CTX) > 0 {
+		blockTime = now - float64(parent.Time())
+	}
+	zone.blocks++
+	zone.blockTime += blockTime
+	zone.parent = header
+
+	sim.out.Write([]string{
+		strconv.FormatFloat(now, 'f', 3, 64),
+		zone.location.Name(),
+		strconv.FormatUint(header.NumberU64(common.ZONE_CTX), 10),
+		zone.difficulty.String(),
+		strconv.FormatFloat(blockTime, 'f', 3, 64),
+		strconv.Itoa(order),
+		bigBitsString(intrinsicS),
+		bigBitsString(totalS),
+		bigBitsString(deltaS),
+		strconv.FormatFloat(zone.profile.hashrateAt(now), 'g', -1, 64),
+	})
+
+	// Propagate dominant coincident blocks to every zone that observes them
+	if order <= common.REGION_CTX {
+		zone.regions++
+		update := &simUpdate{region: zone.location.Region(), entropy: totalS, regionDeltaS: deltaS}
+		if order == common.PRIME_CTX {
+			update.regionDeltaS = big.NewInt(0)
+		}
+		sim.broadcast(i, now, update, func(other *simZone) bool { return other.location.Region() == zone.location.Region() })
+	}
+	if order == common.PRIME_CTX {
+		zone.primes++
+		sim.prime++
+		sim.broadcast(i, now, &simUpdate{region: -1, entropy: totalS}, func(*simZone) bool { return true })
+	}
+	sim.startMining(i, now)
+	return nil
+}
+
+// broadcast applies an update to the originating zone and schedules its
+// arrival at the other matching zones after the latency of both ends.
+func (sim *simulator) broadcast(origin int, now float64, update *simUpdate, match func(*simZone) bool) {
+	for j, other := range sim.zones {
+		if !match(other) {
+			continue
+		}
+		if j == origin {
+			sim.applyUpdate(other, update)
+			continue
+		}
+		latency := sim.zones[origin].profile.Latency + other.profile.Latency
+		sim.push(&simEvent{time: now + latency, zone: j, update: update})
+	}
+}
+
+// applyUpdate updates the view of a zone if the update carries more entropy
+// than the dominant block it currently builds on.
+func (sim *simulator) applyUpdate(zone *simZone, update *simUpdate) {
+	if update.region < 0 {
+		if update.entropy.Cmp(zone.view.primeEntropy) > 0 {
+			zone.view.primeEntropy = update.entropy
+		}
+		return
+	}
+	if update.entropy.Cmp(zone.view.regionEntropy) > 0 {
+		zone.view.regionEntropy = update.entropy
+		zone.view.regionDeltaS = update.regionDeltaS
+	}
+}
+
+// writeSummary writes the block times, coincidence ratios and entropy growth
+// of every location.
+func (sim *simulator) writeSummary(w io.Writer, duration float64) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"location", "blocks", "meanBlockTime", "regionBlocks", "primeBlocks", "regionRatio", "primeRatio", "difficulty", "entropyPerSecond"})
+	for _, zone := range sim.zones {
+		var totalS *big.Int
+		if zone.parent.NumberU64(common.ZONE_CTX) > 0 {
+			totalS = sim.engine.TotalLogS(zone.parent)
+		} else {
+			totalS = new(big.Int)
+		}
+		out.Write([]string{
+			zone.location.Name(),
+			strconv.FormatUint(zone.blocks, 10),
+			strconv.FormatFloat(ratio(zone.blockTime, float64(zone.blocks)), 'f', 3, 64),
+			strconv.FormatUint(zone.regions, 10),
+			strconv.FormatUint(zone.primes, 10),
+			strconv.FormatFloat(ratio(float64(zone.regions), float64(zone.blocks)), 'f', 6, 64),
+			strconv.FormatFloat(ratio(float64(zone.primes), float64(zone.blocks)), 'f', 6, 64),
+			zone.parent.Difficulty().String(),
+			strconv.FormatFloat(ratio(bigBitsFloat(totalS), duration), 'f', 6, 64),
+		})
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// ratio returns a/b, or zero if b is zero.
+func ratio(a, b float64) float64 {
+	if b == 0 {
+		return 0
+	}
+	return a / b
+}
+
+// bigBitsFloat converts an entropy value in big bits to bits.
+func bigBitsFloat(x *big.Int) float64 {
+	f, _ := new(big.Float).Quo(new(big.Float).SetInt(x), new(big.Float).SetFloat64(math.Exp2(64))).Float64()
+	return f
+}
+
+// bigBitsString formats an entropy value in big bits as bits.
+func bigBitsString(x *big.Int) string {
+	return strconv.FormatFloat(bigBitsFloat(x), 'f', 6, 64)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/csv"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/dominant-strategies/go-quai/common"
+	"gopkg.in/urfave/cli.v1"
+)
+
+func TestSimProfileSchedule(t *testing.T) {
+	profile := &simProfile{
+		Hashrate: 1,
+		Schedule: []simHashrateStep{{Time: 200, Hashrate: 3}, {Time: 100, Hashrate: 2}},
+	}
+	tests := []struct {
+		time     float64
+		hashrate float64
+		next     float64
+	}{
+		{0, 1, 100},
+		{99, 1, 100},
+		{100, 2, 200},
+		{150, 2, 200},
+		{200, 3, math.Inf(1)},
+	}
+	for i, tt := range tests {
+		if hashrate := profile.hashrateAt(tt.time); hashrate != tt.hashrate {
+			t.Errorf("test %d: hashrate mismatch: have %v, want %v", i, hashrate, tt.hashrate)
+		}
+		if next := profile.nextStep(tt.time); next != tt.next {
+			t.Errorf("test %d: next step mismatch: have %v, want %v", i, next, tt.next)
+		}
+	}
+}
+
+// Tests that a zone without hashrate resumes mining once its schedule gives it
+// hashrate, and that a zone whose hashrate drops to zero stops mining.
+func TestSimulateHashrateSchedule(t *testing.T) {
+	dir := t.TempDir()
+	profile := filepath.Join(dir, "profile.json")
+	if err := os.WriteFile(profile, []byte(`[
+		{"location": [0, 0], "hashrate": 0, "latency": 0.1, "schedule": [{"time": 3600, "hashrate": 1e6}]},
+		{"location": [0, 1], "hashrate": 1e6, "latency": 0.1, "schedule": [{"time": 3600, "hashrate": 0}]}
+	]`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(dir, "blocks.csv")
+
+	app := cli.NewApp()
+	app.Commands = []cli.Command{simulateCommand}
+	args := []string{"quai", "simulate", "--regions", "1", "--zones", "2", "--duration", "7200",
+		"--profile", profile, "--output", output}
+	if err := app.Run(args); err != nil {
+		t.Fatalf("simulation failed: %v", err)
+	}
+	f, err := os.Open(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	paused, stopped := common.Location{0, 0}.Name(), common.Location{0, 1}.Name()
+	blocks := make(map[string]int)
+	for _, record := range records[1:] {
+		time, err := strconv.ParseFloat(record[0], 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch {
+		case record[1] == paused && time < 3600:
+			t.Errorf("paused zone mined a block at %v", time)
+		case record[1] == stopped && time > 3600:
+			t.Errorf("stopped zone mined a block at %v", time)
+		}
+		blocks[record[1]]++
+	}
+	for _, name := range []string{paused, stopped} {
+		if blocks[name] == 0 {
+			t.Errorf("zone %s mined no blocks", name)
+		}
+	}
+}
@@ -50,6 +50,8 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.ExitWhenSyncedFlag,
 			utils.GCModeFlag,
 			utils.TxLookupLimitFlag,
+			utils.AddressIndexFlag,
+			utils.StateDiffsFlag,
 			utils.QuaiStatsURLFlag,
 			utils.IdentityFlag,
 			utils.LightKDFFlag,
@@ -91,6 +93,7 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.CacheSnapshotFlag,
 			utils.CacheNoPrefetchFlag,
 			utils.CachePreimagesFlag,
+			utils.ParallelTxWorkersFlag,
 		},
 	},
 	{
@@ -120,6 +123,13 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.WSAllowedOriginsFlag,
 			utils.RPCGlobalGasCapFlag,
 			utils.RPCGlobalTxFeeCapFlag,
+			utils.RPCBatchItemsFlag,
+			utils.RPCBatchResponseBytesFlag,
+			utils.RPCResponseBytesFlag,
+			utils.RPCSubscriptionsFlag,
+			utils.RPCRateFlag,
+			utils.RPCBurstFlag,
+			utils.RPCMethodRatesFlag,
 			utils.JSpathFlag,
 			utils.ExecFlag,
 			utils.PreloadJSFlag,
@@ -148,12 +158,20 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 		Flags: []cli.Flag{
 			utils.MinerGasPriceFlag,
 			utils.MinerEtherbaseFlag,
+			utils.MinerPolicyFlag,
+			utils.MinerAllowlistFlag,
+			utils.MinerExcludeFlag,
+			utils.MinerEtxReserveFlag,
 		},
 	},
 	{
 		Name: "CONSENSUS",
 		Flags: []cli.Flag{
 			utils.ConsensusEngineFlag,
+			utils.ProgpowDatasetDirFlag,
+			utils.ProgpowDatasetsInMemoryFlag,
+			utils.ProgpowDatasetsOnDiskFlag,
+			utils.ProgpowDatasetsLockMmapFlag,
 		},
 	},
 	{
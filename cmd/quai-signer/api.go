@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/dominant-strategies/go-quai/common"
+	"github.com/dominant-strategies/go-quai/core/types"
+	"github.com/dominant-strategies/go-quai/crypto"
+	"github.com/dominant-strategies/go-quai/log"
+	"github.com/dominant-strategies/go-quai/signer"
+)
+
+var errRejected = errors.New("request rejected")
+
+// signerAPI implements the "account" namespace of the external signer
+// protocol.
+type signerAPI struct {
+	keys     map[common.AddressBytes]*ecdsa.PrivateKey
+	accounts []common.Address
+	rules    *rules // nil if every request needs approval
+
+	interactive bool
+	promptLock  sync.Mutex // Serializes questions to the operator
+}
+
+func newSignerAPI(keys []*ecdsa.PrivateKey, rules *rules, interactive bool) *signerAPI {
+	api := &signerAPI{
+		keys:        make(map[common.AddressBytes]*ecdsa.PrivateKey),
+		rules:       rules,
+		interactive: interactive,
+	}
+	for _, key := range keys {
+		addr := crypto.PubkeyToAddress(key.PublicKey)
+		api.keys[addr.Bytes20()] = key
+		api.accounts = append(api.accounts, addr)
+	}
+	return api
+}
+
+// Version returns the version of the external signer protocol.
+func (api *signerAPI) Version() string {
+	return signer.Version
+}
+
+// List returns the accounts the signer holds keys for.
+func (api *signerAPI) List() []common.Address {
+	return api.accounts
+}
+
+// SignTransaction signs the transaction described by args if it is approved.
+func (api *signerAPI) SignTransaction(args signer.SendTxArgs) (*signer.SignTxResponse, error) {
+	key, ok := api.keys[args.From.Bytes20()]
+	if !ok {
+		return nil, fmt.Errorf("unknown account %v", args.From.Hex())
+	}
+	tx, err := args.ToTransaction()
+	if err != nil {
+		return nil, err
+	}
+	if err := args.CheckLocation(); err != nil {
+		return nil, err
+	}
+	if err := api.approve(&args); err != nil {
+		log.Warn("Rejected signing request", "request", args.String(), "reason", err)
+		return nil, err
+	}
+	signed, err := types.SignTx(tx, types.NewSigner((*big.Int)(args.ChainID)), key)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := signed.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	log.Info("Signed transaction", "hash", signed.Hash(), "request", args.String())
+	return &signer.SignTxResponse{Raw: raw, Tx: signed}, nil
+}
+
+// approve checks the request against the rules, asking the operator about
+// requests the rules don't allow if running interactively.
+func (api *signerAPI) approve(args *signer.SendTxArgs) error {
+	reason := errors.New("no rules configured")
+	if api.rules != nil {
+		if reason = api.rules.check(args); reason == nil {
+			return nil
+		}
+	}
+	if !api.interactive {
+		return reason
+	}
+	api.promptLock.Lock()
+	defer api.promptLock.Unlock()
+
+	fmt.Printf("\nSigning request not approved by the rules (%v):\n%s\n", reason, describe(args))
+	fmt.Print("Approve? [y/N] ")
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return errRejected
+	}
+	if answer = strings.ToLower(strings.TrimSpace(answer)); answer != "y" && answer != "yes" {
+		return errRejected
+	}
+	return nil
+}
+
+// describe formats a signing request for the operator.
+func describe(args *signer.SendTxArgs) string {
+	var b strings.Builder
+	to := "<contract creation>"
+	if args.To != nil {
+		to = args.To.Hex()
+	}
+	fmt.Fprintf(&b, "  from:     %s\n", args.From.Hex())
+	fmt.Fprintf(&b, "  to:       %s\n", to)
+	fmt.Fprintf(&b, "  value:    %v\n", args.Value.ToInt())
+	fmt.Fprintf(&b, "  chain:    %v (%s)\n", args.ChainID.ToInt(), common.Location(args.Location).Name())
+	fmt.Fprintf(&b, "  nonce:    %d\n", args.Nonce)
+	fmt.Fprintf(&b, "  gas:      %d\n", args.Gas)
+	fmt.Fprintf(&b, "  fee cap:  %v\n", args.MaxFeePerGas.ToInt())
+	fmt.Fprintf(&b, "  tip:      %v\n", args.MaxPriorityFeePerGas.ToInt())
+	fmt.Fprintf(&b, "  input:    %d bytes", len(args.Input))
+	if args.Type == types.InternalToExternalTxType {
+		fmt.Fprintf(&b, "\n  etx gas:  %d\n", args.ETXGasLimit)
+		fmt.Fprintf(&b, "  etx fee:  %v\n", args.ETXGasPrice.ToInt())
+		fmt.Fprintf(&b, "  etx tip:  %v\n", args.ETXGasTip.ToInt())
+		fmt.Fprintf(&b, "  etx data: %d bytes", len(args.ETXData))
+	}
+	return b.String()
+}
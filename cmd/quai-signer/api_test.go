@@ -0,0 +1,103 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/dominant-strategies/go-quai/common"
+	"github.com/dominant-strategies/go-quai/common/hexutil"
+	"github.com/dominant-strategies/go-quai/core/types"
+	"github.com/dominant-strategies/go-quai/crypto"
+	"github.com/dominant-strategies/go-quai/signer"
+)
+
+var testLocation = common.Location{0, 0}
+
+// newZoneKey generates a key whose address is part of the test location.
+func newZoneKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	for {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if testLocation.ContainsAddress(crypto.PubkeyToAddress(key.PublicKey)) {
+			return key
+		}
+	}
+}
+
+// newSignRequest returns a request of the key to send value to the recipient,
+// approved by rules allowing exactly that.
+func newSignRequest(key *ecdsa.PrivateKey, to common.Address) (*signer.SendTxArgs, *rules) {
+	args := testArgs()
+	args.From = crypto.PubkeyToAddress(key.PublicKey)
+	args.To = &to
+	args.Location = hexutil.Bytes(testLocation)
+
+	r := testRules()
+	r.Accounts = []common.Address{args.From}
+	r.Recipients = []common.Address{to}
+	return args, r
+}
+
+func TestSignerAPISignTransaction(t *testing.T) {
+	key, recipient := newZoneKey(t), newZoneKey(t)
+	to := crypto.PubkeyToAddress(recipient.PublicKey)
+	args, r := newSignRequest(key, to)
+
+	api := newSignerAPI([]*ecdsa.PrivateKey{key}, r, false)
+	if accounts := api.List(); len(accounts) != 1 || !accounts[0].Equal(args.From) {
+		t.Fatalf("account list mismatch: have %v, want [%v]", accounts, args.From)
+	}
+	res, err := api.SignTransaction(*args)
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(res.Raw); err != nil {
+		t.Fatalf("invalid signed transaction: %v", err)
+	}
+	if tx.Hash() != res.Tx.Hash() {
+		t.Errorf("raw and decoded transaction differ")
+	}
+	from, err := types.Sender(types.NewSigner(big.NewInt(1)), tx)
+	if err != nil || !from.Equal(args.From) {
+		t.Errorf("sender mismatch: have %v (%v), want %v", from, err, args.From)
+	}
+	if !tx.To().Equal(to) || tx.Value().Cmp(args.Value.ToInt()) != 0 || tx.Gas() != uint64(args.Gas) {
+		t.Errorf("transaction mismatch: %v", args)
+	}
+}
+
+func TestSignerAPIReject(t *testing.T) {
+	key, recipient := newZoneKey(t), newZoneKey(t)
+	to := crypto.PubkeyToAddress(recipient.PublicKey)
+
+	tests := []struct {
+		name  string
+		rules bool
+		args  func(args *signer.SendTxArgs)
+		err   string
+	}{
+		{name: "no rules", err: "no rules configured"},
+		{name: "rules", rules: true, args: func(args *signer.SendTxArgs) { args.Value = hexutil.Big(*big.NewInt(1001)) }, err: "value"},
+		{name: "unknown account", rules: true, args: func(args *signer.SendTxArgs) { args.From = to }, err: "unknown account"},
+		{name: "location", rules: true, args: func(args *signer.SendTxArgs) { args.Location = hexutil.Bytes{0, 1} }, err: "is not part of"},
+	}
+	for _, tt := range tests {
+		args, r := newSignRequest(key, to)
+		if !tt.rules {
+			r = nil
+		}
+		if tt.args != nil {
+			tt.args(args)
+		}
+		api := newSignerAPI([]*ecdsa.PrivateKey{key}, r, false)
+		if _, err := api.SignTransaction(*args); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: error mismatch: have %v, want %q", tt.name, err, tt.err)
+		}
+	}
+}
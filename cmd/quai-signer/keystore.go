@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/dominant-strategies/go-quai/crypto"
+	"github.com/peterh/liner"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+var errDecrypt = errors.New("could not decrypt key with given passphrase")
+
+// encryptedKey is a key file in the Web3 Secret Storage format, as written by
+// the keystores of go-quai and the other Ethereum derived clients.
+type encryptedKey struct {
+	Address string     `json:"address"`
+	Crypto  cryptoJSON `json:"crypto"`
+	Version int        `json:"version"`
+}
+
+type cryptoJSON struct {
+	Cipher       string       `json:"cipher"`
+	CipherText   string       `json:"ciphertext"`
+	CipherParams cipherParams `json:"cipherparams"`
+	KDF          string       `json:"kdf"`
+	KDFParams    kdfParams    `json:"kdfparams"`
+	MAC          string       `json:"mac"`
+}
+
+type cipherParams struct {
+	IV string `json:"iv"`
+}
+
+type kdfParams struct {
+	DKLen int    `json:"dklen"`
+	Salt  string `json:"salt"`
+	N     int    `json:"n,omitempty"` // scrypt
+	R     int    `json:"r,omitempty"` // scrypt
+	P     int    `json:"p,omitempty"` // scrypt
+	C     int    `json:"c,omitempty"` // pbkdf2
+	PRF   string `json:"prf,omitempty"`
+}
+
+// loadKeys decrypts every key file of the keystore directory. The i'th file
+// is decrypted with the i'th passphrase, the last passphrase being used for
+// the remaining files. Without passphrases they are asked on the terminal.
+func loadKeys(dir string, passphrases []string) ([]*ecdsa.PrivateKey, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var keys []*ecdsa.PrivateKey
+	for _, file := range files {
+		// Skip directories and the editor and system files of the keystore
+		if file.IsDir() || strings.HasPrefix(file.Name(), ".") || strings.HasSuffix(file.Name(), "~") {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var passphrase string
+		switch {
+		case len(keys) < len(passphrases):
+			passphrase = passphrases[len(keys)]
+		case len(passphrases) > 0:
+			passphrase = passphrases[len(passphrases)-1]
+		default:
+			if passphrase, err = promptPassphrase(file.Name()); err != nil {
+				return nil, err
+			}
+		}
+		key, err := decryptKey(data, passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key file %s: %v", file.Name(), err)
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no keys found in %s", dir)
+	}
+	return keys, nil
+}
+
+// loadPassphrases reads the passphrases of the key files from a file holding
+// one passphrase per line.
+func loadPassphrases(file string) ([]string, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(string(data), "\n")
+	// Sanitise DOS line endings and drop the empty line after the last one
+	for i := range lines {
+		lines[i] = strings.TrimRight(lines[i], "\r")
+	}
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines, nil
+}
+
+// promptPassphrase asks the operator for the passphrase of a key file.
+func promptPassphrase(file string) (string, error) {
+	line := liner.NewLiner()
+	defer line.Close()
+	return line.PasswordPrompt(fmt.Sprintf("Passphrase of %s: ", file))
+}
+
+// decryptKey decrypts a key file with the given passphrase.
+func decryptKey(data []byte, passphrase string) (*ecdsa.PrivateKey, error) {
+	var k encryptedKey
+	if err := json.Unmarshal(data, &k); err != nil {
+		return nil, err
+	}
+	if k.Version != 3 {
+		return nil, fmt.Errorf("unsupported key version %d", k.Version)
+	}
+	if k.Crypto.Cipher != "aes-128-ctr" {
+		return nil, fmt.Errorf("unsupported cipher %s", k.Crypto.Cipher)
+	}
+	mac, err := hex.DecodeString(k.Crypto.MAC)
+	if err != nil {
+		return nil, err
+	}
+	iv, err := hex.DecodeString(k.Crypto.CipherParams.IV)
+	if err != nil {
+		return nil, err
+	}
+	cipherText, err := hex.DecodeString(k.Crypto.CipherText)
+	if err != nil {
+		return nil, err
+	}
+	derivedKey, err := deriveKey(k.Crypto, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(crypto.Keccak256(derivedKey[16:32], cipherText), mac) {
+		return nil, errDecrypt
+	}
+	block, err := aes.NewCipher(derivedKey[:16])
+	if err != nil {
+		return nil, err
+	}
+	plainText := make([]byte, len(cipherText))
+	cipher.NewCTR(block, iv).XORKeyStream(plainText, cipherText)
+
+	key, err := crypto.ToECDSA(plainText)
+	if err != nil {
+		return nil, err
+	}
+	// The address is optional, but must match the key if present
+	if k.Address != "" && !strings.EqualFold(strings.TrimPrefix(k.Address, "0x"), hex.EncodeToString(crypto.PubkeyToAddress(key.PublicKey).Bytes())) {
+		return nil, fmt.Errorf("key does not match address %s", k.Address)
+	}
+	return key, nil
+}
+
+// deriveKey derives the encryption key of a key file from the passphrase.
+func deriveKey(c cryptoJSON, passphrase string) ([]byte, error) {
+	params := c.KDFParams
+	salt, err := hex.DecodeString(params.Salt)
+	if err != nil {
+		return nil, err
+	}
+	if params.DKLen < 32 {
+		return nil, fmt.Errorf("derived key length %d too short", params.DKLen)
+	}
+	switch c.KDF {
+	case "scrypt":
+		return scrypt.Key([]byte(passphrase), salt, params.N, params.R, params.P, params.DKLen)
+	case "pbkdf2":
+		if params.PRF != "hmac-sha256" {
+			return nil, fmt.Errorf("unsupported PBKDF2 PRF %s", params.PRF)
+		}
+		return pbkdf2.Key([]byte(passphrase), salt, params.C, params.DKLen, sha256.New), nil
+	default:
+		return nil, fmt.Errorf("unsupported KDF %s", c.KDF)
+	}
+}
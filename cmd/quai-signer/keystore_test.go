@@ -0,0 +1,114 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dominant-strategies/go-quai/crypto"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// encryptTestKey encrypts a key into a key file with the given KDF, using
+// light parameters to keep the tests fast.
+func encryptTestKey(t *testing.T, key *ecdsa.PrivateKey, passphrase string, kdf string) []byte {
+	t.Helper()
+	salt, iv := make([]byte, 32), make([]byte, aes.BlockSize)
+	rand.Read(salt)
+	rand.Read(iv)
+
+	params := kdfParams{DKLen: 32, Salt: hex.EncodeToString(salt)}
+	var (
+		derivedKey []byte
+		err        error
+	)
+	switch kdf {
+	case "scrypt":
+		params.N, params.R, params.P = 1<<12, 8, 1
+		derivedKey, err = scrypt.Key([]byte(passphrase), salt, params.N, params.R, params.P, params.DKLen)
+	case "pbkdf2":
+		params.C, params.PRF = 1<<10, "hmac-sha256"
+		derivedKey = pbkdf2.Key([]byte(passphrase), salt, params.C, params.DKLen, sha256.New)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := aes.NewCipher(derivedKey[:16])
+	if err != nil {
+		t.Fatal(err)
+	}
+	plainText := crypto.FromECDSA(key)
+	cipherText := make([]byte, len(plainText))
+	cipher.NewCTR(block, iv).XORKeyStream(cipherText, plainText)
+
+	data, err := json.Marshal(encryptedKey{
+		Address: hex.EncodeToString(crypto.PubkeyToAddress(key.PublicKey).Bytes()),
+		Crypto: cryptoJSON{
+			Cipher:       "aes-128-ctr",
+			CipherText:   hex.EncodeToString(cipherText),
+			CipherParams: cipherParams{IV: hex.EncodeToString(iv)},
+			KDF:          kdf,
+			KDFParams:    params,
+			MAC:          hex.EncodeToString(crypto.Keccak256(derivedKey[16:32], cipherText)),
+		},
+		Version: 3,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestLoadKeys(t *testing.T) {
+	dir, err := ioutil.TempDir("", "quai-signer-keystore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	scryptKey, pbkdf2Key := newZoneKey(t), newZoneKey(t)
+	if err := ioutil.WriteFile(filepath.Join(dir, "a-scrypt"), encryptTestKey(t, scryptKey, "foo", "scrypt"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "b-pbkdf2"), encryptTestKey(t, pbkdf2Key, "bar", "pbkdf2"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	passwords := filepath.Join(dir, ".passwords")
+	if err := ioutil.WriteFile(passwords, []byte("foo\r\nbar\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	passphrases, err := loadPassphrases(passwords)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := loadKeys(dir, passphrases)
+	if err != nil {
+		t.Fatalf("failed to load keys: %v", err)
+	}
+	if len(keys) != 2 || !keys[0].Equal(scryptKey) || !keys[1].Equal(pbkdf2Key) {
+		t.Fatalf("loaded keys mismatch: have %d keys", len(keys))
+	}
+	// The last passphrase is used for the remaining files
+	if _, err := loadKeys(dir, []string{"foo"}); err == nil {
+		t.Errorf("key decrypted with the wrong passphrase")
+	}
+	if _, err := loadKeys(dir, []string{"foo", "baz"}); err == nil {
+		t.Errorf("key decrypted with the wrong passphrase")
+	}
+}
+
+func TestDecryptKeyRejectsPlaintext(t *testing.T) {
+	key := newZoneKey(t)
+	if _, err := decryptKey([]byte(hex.EncodeToString(crypto.FromECDSA(key))), ""); err == nil {
+		t.Errorf("plaintext key file accepted")
+	}
+}
@@ -0,0 +1,218 @@
+// quai-signer is a reference implementation of the external signer protocol.
+// It holds private keys outside of the node and signs the transactions the
+// node forwards to it, approving them automatically according to a rule file
+// or by asking the operator.
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/dominant-strategies/go-quai/internal/flags"
+	"github.com/dominant-strategies/go-quai/log"
+	"github.com/dominant-strategies/go-quai/node"
+	"github.com/dominant-strategies/go-quai/rpc"
+	"github.com/dominant-strategies/go-quai/signer"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	// Git SHA1 commit hash of the release (set via linker flags)
+	gitCommit = ""
+	gitDate   = ""
+)
+
+var (
+	keystoreFlag = cli.StringFlag{
+		Name:  "keystore",
+		Usage: "Keystore directory of the encrypted key files to sign with",
+	}
+	passwordFlag = cli.StringFlag{
+		Name:  "password",
+		Usage: "File holding the passphrases of the key files, one per line (asked on the terminal if not set)",
+	}
+	rulesFlag = cli.StringFlag{
+		Name:  "rules",
+		Usage: "JSON file with the rules for automatic approval",
+	}
+	interactiveFlag = cli.BoolFlag{
+		Name:  "interactive",
+		Usage: "Ask on the terminal about requests the rules don't approve",
+	}
+	ipcPathFlag = cli.StringFlag{
+		Name:  "ipcpath",
+		Usage: "Path of the unix socket to serve the signer on",
+		Value: filepath.Join(os.TempDir(), "quai-signer.ipc"),
+	}
+	ipcDisableFlag = cli.BoolFlag{
+		Name:  "ipcdisable",
+		Usage: "Don't serve the signer over IPC",
+	}
+	httpFlag = cli.BoolFlag{
+		Name:  "http",
+		Usage: "Serve the signer over HTTP",
+	}
+	httpAddrFlag = cli.StringFlag{
+		Name:  "http.addr",
+		Usage: "HTTP listening interface",
+		Value: "localhost",
+	}
+	httpPortFlag = cli.IntFlag{
+		Name:  "http.port",
+		Usage: "HTTP listening port",
+		Value: 8550,
+	}
+	httpVirtualHostsFlag = cli.StringFlag{
+		Name:  "http.vhosts",
+		Usage: "Comma separated list of virtual hostnames from which to accept requests (server enforced). Accepts '*' wildcard.",
+		Value: "localhost",
+	}
+	httpCORSDomainFlag = cli.StringFlag{
+		Name:  "http.corsdomain",
+		Usage: "Comma separated list of domains from which to accept cross origin requests (browser enforced)",
+		Value: "http://localhost",
+	}
+	verbosityFlag = cli.IntFlag{
+		Name:  "verbosity",
+		Usage: "Logging verbosity: 0=panic, 1=fatal, 2=error, 3=warn, 4=info, 5=debug, 6=trace",
+		Value: 4,
+	}
+)
+
+// newApp creates the command line application. It isn't created on package
+// initialization, as that reads the version file of the working directory.
+func newApp() *cli.App {
+	app := flags.NewApp(gitCommit, gitDate, "reference external signer for go-quai")
+	app.Action = runSigner
+	app.Flags = []cli.Flag{
+		keystoreFlag,
+		passwordFlag,
+		rulesFlag,
+		interactiveFlag,
+		ipcPathFlag,
+		ipcDisableFlag,
+		httpFlag,
+		httpAddrFlag,
+		httpPortFlag,
+		httpVirtualHostsFlag,
+		httpCORSDomainFlag,
+		verbosityFlag,
+	}
+	app.Description = `
+The signer serves the "account" namespace of the external signer protocol, see
+the documentation of the signer package. Point a node at it with --signer.
+
+Requests are approved automatically if they satisfy the rule file, for example
+
+  {
+    "accounts": ["0x1930e0b28d7a5f8b4d9ed3cbd19a8c1ab9ea7d97"],
+    "recipients": ["0x1a3c4e85ab1b2e3c8e5c1d0d5e2bb1c9d4a5f6e7"],
+    "chainIds": ["0x2328"],
+    "methods": ["0xa9059cbb"],
+    "maxValue": "0xde0b6b3a7640000",
+    "maxGas": "0x30d40",
+    "maxFeePerGas": "0x174876e800",
+    "allowEtx": true,
+    "allowContractCreation": false
+  }
+
+The rules fail closed: only the listed senders, recipients and chains are
+allowed, calldata must start with one of the listed method selectors and
+missing limits allow nothing above zero. Requests failing
+the rules are rejected, unless --interactive is set and the operator approves
+them on the terminal. Without a rule file every request needs approval.`
+	return app
+}
+
+func main() {
+	if err := newApp().Run(os.Args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func runSigner(ctx *cli.Context) error {
+	log.SetLevelInt(ctx.Int(verbosityFlag.Name))
+	if !ctx.IsSet(keystoreFlag.Name) {
+		return fmt.Errorf("--%s is required", keystoreFlag.Name)
+	}
+	var passphrases []string
+	if file := ctx.String(passwordFlag.Name); file != "" {
+		var err error
+		if passphrases, err = loadPassphrases(file); err != nil {
+			return err
+		}
+	}
+	keys, err := loadKeys(ctx.String(keystoreFlag.Name), passphrases)
+	if err != nil {
+		return err
+	}
+	var ruleset *rules
+	if file := ctx.String(rulesFlag.Name); file != "" {
+		if ruleset, err = loadRules(file); err != nil {
+			return err
+		}
+	}
+	api := newSignerAPI(keys, ruleset, ctx.Bool(interactiveFlag.Name))
+	apis := []rpc.API{{
+		Namespace: "account",
+		Version:   signer.Version,
+		Service:   api,
+		Public:    false,
+	}}
+	if !ctx.Bool(ipcDisableFlag.Name) {
+		listener, srv, err := rpc.StartIPCEndpoint(ctx.String(ipcPathFlag.Name), apis)
+		if err != nil {
+			return fmt.Errorf("can't start IPC endpoint: %v", err)
+		}
+		defer func() {
+			listener.Close()
+			srv.Stop()
+		}()
+		log.Info("IPC endpoint opened", "url", ctx.String(ipcPathFlag.Name))
+	}
+	if ctx.Bool(httpFlag.Name) {
+		srv := rpc.NewServer()
+		for _, api := range apis {
+			if err := srv.RegisterName(api.Namespace, api.Service); err != nil {
+				return err
+			}
+		}
+		addr := net.JoinHostPort(ctx.String(httpAddrFlag.Name), fmt.Sprint(ctx.Int(httpPortFlag.Name)))
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			return fmt.Errorf("can't start HTTP endpoint: %v", err)
+		}
+		handler := node.NewHTTPHandlerStack(srv, splitAndTrim(ctx.String(httpCORSDomainFlag.Name)), splitAndTrim(ctx.String(httpVirtualHostsFlag.Name)))
+		httpSrv := &http.Server{Handler: handler}
+		go httpSrv.Serve(listener)
+		defer func() {
+			httpSrv.Close()
+			srv.Stop()
+		}()
+		log.Info("HTTP endpoint opened", "url", "http://"+listener.Addr().String())
+	}
+	log.Info("Signer started", "accounts", len(keys), "rules", ruleset != nil, "interactive", ctx.Bool(interactiveFlag.Name))
+
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, syscall.SIGINT, syscall.SIGTERM)
+	<-sigc
+	log.Info("Signer shutting down")
+	return nil
+}
+
+// splitAndTrim splits a comma separated flag value, dropping empty entries.
+func splitAndTrim(input string) (ret []string) {
+	for _, r := range strings.Split(input, ",") {
+		if r = strings.TrimSpace(r); r != "" {
+			ret = append(ret, r)
+		}
+	}
+	return ret
+}
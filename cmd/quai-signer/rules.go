@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+
+	"github.com/dominant-strategies/go-quai/common"
+	"github.com/dominant-strategies/go-quai/common/hexutil"
+	"github.com/dominant-strategies/go-quai/core/types"
+	"github.com/dominant-strategies/go-quai/signer"
+)
+
+// rules decide which signing requests are approved without asking the
+// operator. They fail closed: only the listed accounts, recipients, chains and
+// methods are allowed, and a missing limit allows nothing above zero.
+type rules struct {
+	Accounts              []common.Address `json:"accounts"`   // Senders allowed to sign
+	Recipients            []common.Address `json:"recipients"` // Recipients allowed to receive
+	ChainIDs              []*hexutil.Big   `json:"chainIds"`   // Chains transactions may be signed for
+	Methods               []hexutil.Bytes  `json:"methods"`    // Selectors of the contract calls allowed, also for ETX data
+	MaxValue              *hexutil.Big     `json:"maxValue"`
+	MaxGas                *hexutil.Uint64  `json:"maxGas"`
+	MaxFeePerGas          *hexutil.Big     `json:"maxFeePerGas"` // Also limits the fee cap of ETXs
+	AllowETX              bool             `json:"allowEtx"`
+	AllowContractCreation bool             `json:"allowContractCreation"`
+}
+
+// loadRules reads a rule file. Unknown fields are rejected, so a misspelled
+// rule can't silently fall back to its default.
+func loadRules(file string) (*rules, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	r := new(rules)
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(r); err != nil {
+		return nil, fmt.Errorf("invalid rule file %s: %v", file, err)
+	}
+	for _, method := range r.Methods {
+		if len(method) != selectorLength {
+			return nil, fmt.Errorf("invalid rule file %s: method %v is not a %d byte selector", file, method, selectorLength)
+		}
+	}
+	return r, nil
+}
+
+// selectorLength is the length of the method selector starting calldata.
+const selectorLength = 4
+
+// check returns nil if the request satisfies the rules, or the reason it
+// doesn't.
+func (r *rules) check(args *signer.SendTxArgs) error {
+	if !containsAddress(r.Accounts, args.From) {
+		return fmt.Errorf("sender %v not allowed", args.From.Hex())
+	}
+	if args.ChainID == nil || !containsBig(r.ChainIDs, args.ChainID.ToInt()) {
+		return fmt.Errorf("chain %v not allowed", args.ChainID)
+	}
+	if args.To == nil {
+		if !r.AllowContractCreation {
+			return fmt.Errorf("contract creation not allowed")
+		}
+	} else {
+		if !containsAddress(r.Recipients, *args.To) {
+			return fmt.Errorf("recipient %v not allowed", args.To.Hex())
+		}
+		if err := r.checkCalldata(args.Input); err != nil {
+			return err
+		}
+	}
+	if args.Type == types.InternalToExternalTxType {
+		if !r.AllowETX {
+			return fmt.Errorf("external transactions not allowed")
+		}
+		if err := r.checkCalldata(args.ETXData); err != nil {
+			return fmt.Errorf("etx %v", err)
+		}
+	}
+	if args.Value.ToInt().Cmp(limit(r.MaxValue)) > 0 {
+		return fmt.Errorf("value %v above limit %v", args.Value.ToInt(), limit(r.MaxValue))
+	}
+	var maxGas uint64
+	if r.MaxGas != nil {
+		maxGas = uint64(*r.MaxGas)
+	}
+	if uint64(args.Gas) > maxGas || uint64(args.ETXGasLimit) > maxGas {
+		return fmt.Errorf("gas above limit %d", maxGas)
+	}
+	if args.MaxFeePerGas == nil || args.MaxFeePerGas.ToInt().Cmp(limit(r.MaxFeePerGas)) > 0 {
+		return fmt.Errorf("fee cap %v above limit %v", args.MaxFeePerGas, limit(r.MaxFeePerGas))
+	}
+	if args.ETXGasPrice != nil && args.ETXGasPrice.ToInt().Cmp(limit(r.MaxFeePerGas)) > 0 {
+		return fmt.Errorf("etx gas price %v above limit %v", args.ETXGasPrice.ToInt(), limit(r.MaxFeePerGas))
+	}
+	return nil
+}
+
+// checkCalldata returns nil if the data is empty or calls one of the allowed
+// methods.
+func (r *rules) checkCalldata(data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	if len(data) < selectorLength {
+		return fmt.Errorf("calldata %x shorter than a method selector", data)
+	}
+	for _, method := range r.Methods {
+		if bytes.Equal(method, data[:selectorLength]) {
+			return nil
+		}
+	}
+	return fmt.Errorf("method %x not allowed", data[:selectorLength])
+}
+
+// limit returns the value of a limit, missing limits are zero.
+func limit(l *hexutil.Big) *big.Int {
+	if l == nil {
+		return new(big.Int)
+	}
+	return l.ToInt()
+}
+
+func containsAddress(list []common.Address, addr common.Address) bool {
+	for _, a := range list {
+		if a.Equal(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsBig(list []*hexutil.Big, n *big.Int) bool {
+	for _, b := range list {
+		if b != nil && b.ToInt().Cmp(n) == 0 {
+			return true
+		}
+	}
+	return false
+}
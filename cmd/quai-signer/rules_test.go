@@ -0,0 +1,144 @@
+package main
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dominant-strategies/go-quai/common"
+	"github.com/dominant-strategies/go-quai/common/hexutil"
+	"github.com/dominant-strategies/go-quai/core/types"
+	"github.com/dominant-strategies/go-quai/signer"
+)
+
+var (
+	testSender    = common.HexToAddress("0x0a00000000000000000000000000000000000001")
+	testRecipient = common.HexToAddress("0x0a00000000000000000000000000000000000002")
+	testTransfer  = hexutil.Bytes{0xa9, 0x05, 0x9c, 0xbb}
+)
+
+// testRules allows transfers between the test accounts on chain 1.
+func testRules() *rules {
+	maxGas := hexutil.Uint64(100000)
+	return &rules{
+		Accounts:     []common.Address{testSender},
+		Recipients:   []common.Address{testRecipient},
+		ChainIDs:     []*hexutil.Big{(*hexutil.Big)(big.NewInt(1))},
+		Methods:      []hexutil.Bytes{testTransfer},
+		MaxValue:     (*hexutil.Big)(big.NewInt(1000)),
+		MaxGas:       &maxGas,
+		MaxFeePerGas: (*hexutil.Big)(big.NewInt(10)),
+	}
+}
+
+// testArgs returns a request satisfying testRules.
+func testArgs() *signer.SendTxArgs {
+	to := testRecipient
+	return &signer.SendTxArgs{
+		Type:                 types.InternalTxType,
+		From:                 testSender,
+		To:                   &to,
+		ChainID:              (*hexutil.Big)(big.NewInt(1)),
+		Gas:                  21000,
+		MaxFeePerGas:         (*hexutil.Big)(big.NewInt(10)),
+		MaxPriorityFeePerGas: (*hexutil.Big)(big.NewInt(1)),
+		Value:                hexutil.Big(*big.NewInt(1000)),
+	}
+}
+
+func TestRulesCheck(t *testing.T) {
+	other := common.HexToAddress("0x0a00000000000000000000000000000000000003")
+	tests := []struct {
+		name   string
+		rules  func(r *rules)
+		args   func(args *signer.SendTxArgs)
+		reason string // empty if approved
+	}{
+		{name: "allowed"},
+		{name: "allowed call", args: func(args *signer.SendTxArgs) { args.Input = append(testTransfer, 1, 2) }},
+		{name: "sender", args: func(args *signer.SendTxArgs) { args.From = other }, reason: "sender"},
+		{name: "no senders", rules: func(r *rules) { r.Accounts = nil }, reason: "sender"},
+		{name: "recipient", args: func(args *signer.SendTxArgs) { args.To = &other }, reason: "recipient"},
+		{name: "no recipients", rules: func(r *rules) { r.Recipients = nil }, reason: "recipient"},
+		{name: "chain", args: func(args *signer.SendTxArgs) { args.ChainID = (*hexutil.Big)(big.NewInt(2)) }, reason: "chain"},
+		{name: "no chains", rules: func(r *rules) { r.ChainIDs = nil }, reason: "chain"},
+		{name: "method", args: func(args *signer.SendTxArgs) { args.Input = hexutil.Bytes{1, 2, 3, 4} }, reason: "method"},
+		{name: "no methods", rules: func(r *rules) { r.Methods = nil }, args: func(args *signer.SendTxArgs) { args.Input = testTransfer }, reason: "method"},
+		{name: "short calldata", args: func(args *signer.SendTxArgs) { args.Input = testTransfer[:3] }, reason: "shorter"},
+		{name: "creation", args: func(args *signer.SendTxArgs) { args.To = nil }, reason: "contract creation"},
+		{name: "allowed creation", rules: func(r *rules) { r.AllowContractCreation = true }, args: func(args *signer.SendTxArgs) { args.To, args.Input = nil, hexutil.Bytes{0x60} }},
+		{name: "value", args: func(args *signer.SendTxArgs) { args.Value = hexutil.Big(*big.NewInt(1001)) }, reason: "value"},
+		{name: "no value limit", rules: func(r *rules) { r.MaxValue = nil }, reason: "value"},
+		{name: "gas", args: func(args *signer.SendTxArgs) { args.Gas = 100001 }, reason: "gas"},
+		{name: "no gas limit", rules: func(r *rules) { r.MaxGas = nil }, reason: "gas"},
+		{name: "fee", args: func(args *signer.SendTxArgs) { args.MaxFeePerGas = (*hexutil.Big)(big.NewInt(11)) }, reason: "fee cap"},
+		{name: "no fee limit", rules: func(r *rules) { r.MaxFeePerGas = nil }, reason: "fee cap"},
+		{name: "etx", args: setETX, reason: "external transactions"},
+		{name: "allowed etx", rules: func(r *rules) { r.AllowETX = true }, args: setETX},
+		{name: "etx method", rules: func(r *rules) { r.AllowETX = true }, args: func(args *signer.SendTxArgs) { setETX(args); args.ETXData = hexutil.Bytes{1, 2, 3, 4} }, reason: "etx method"},
+		{name: "etx gas", rules: func(r *rules) { r.AllowETX = true }, args: func(args *signer.SendTxArgs) { setETX(args); args.ETXGasLimit = 100001 }, reason: "gas"},
+		{name: "etx fee", rules: func(r *rules) { r.AllowETX = true }, args: func(args *signer.SendTxArgs) { setETX(args); args.ETXGasPrice = (*hexutil.Big)(big.NewInt(11)) }, reason: "etx gas price"},
+	}
+	for _, tt := range tests {
+		r, args := testRules(), testArgs()
+		if tt.rules != nil {
+			tt.rules(r)
+		}
+		if tt.args != nil {
+			tt.args(args)
+		}
+		err := r.check(args)
+		switch {
+		case tt.reason == "" && err != nil:
+			t.Errorf("%s: request rejected: %v", tt.name, err)
+		case tt.reason != "" && (err == nil || !strings.Contains(err.Error(), tt.reason)):
+			t.Errorf("%s: rejection mismatch: have %v, want %q", tt.name, err, tt.reason)
+		}
+	}
+	// Rules without any entry allow nothing
+	if err := new(rules).check(testArgs()); err == nil {
+		t.Errorf("empty rules approved a request")
+	}
+}
+
+// setETX turns a request into an external transaction calling the allowed
+// method.
+func setETX(args *signer.SendTxArgs) {
+	args.Type = types.InternalToExternalTxType
+	args.ETXGasLimit = 50000
+	args.ETXGasPrice = (*hexutil.Big)(big.NewInt(10))
+	args.ETXGasTip = (*hexutil.Big)(big.NewInt(1))
+	args.ETXData = testTransfer
+}
+
+func TestLoadRules(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		err  string
+	}{
+		{"valid", `{"accounts": ["0x0a00000000000000000000000000000000000001"], "methods": ["0xa9059cbb"], "maxGas": "0x5208"}`, ""},
+		{"unknown field", `{"acounts": []}`, "unknown field"},
+		{"selector", `{"methods": ["0xa9059c"]}`, "selector"},
+	}
+	for _, tt := range tests {
+		file := filepath.Join(t.TempDir(), "rules.json")
+		if err := os.WriteFile(file, []byte(tt.json), 0600); err != nil {
+			t.Fatal(err)
+		}
+		r, err := loadRules(file)
+		if tt.err == "" {
+			if err != nil {
+				t.Errorf("%s: failed to load rules: %v", tt.name, err)
+			} else if len(r.Accounts) != 1 || len(r.Methods) != 1 || r.MaxGas == nil || *r.MaxGas != 21000 {
+				t.Errorf("%s: rules mismatch: %+v", tt.name, r)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: error mismatch: have %v, want %q", tt.name, err, tt.err)
+		}
+	}
+}
@@ -18,6 +18,7 @@
 package utils
 
 import (
+	"bufio"
 	"compress/gzip"
 	"fmt"
 	"io"
@@ -155,7 +156,15 @@ func ImportChain(chain *core.Core, fn string) error {
 			return err
 		}
 	}
-	stream := rlp.NewStream(reader, 0)
+	// Chain archives bundle the hierarchy data of every block, import them
+	// directly and fall back to a plain stream of blocks otherwise.
+	buffered := bufio.NewReader(reader)
+	if core.IsArchive(buffered) {
+		n, err := chain.ImportArchive(buffered, checkInterrupt)
+		log.Info("Imported chain archive", "file", fn, "blocks", n)
+		return err
+	}
+	stream := rlp.NewStream(buffered, 0)
 
 	// Run actual the import.
 	blocks := make(types.Blocks, importBatchSize)
@@ -219,8 +228,9 @@ func missingBlocks(chain *core.Core, blocks []*types.Block) []*types.Block {
 }
 
 // ExportChain exports a blockchain into the specified file, truncating any data
-// already present in the file.
-func ExportChain(chain *core.Core, fn string) error {
+// already present in the file. If archive is set, the blocks are written as a
+// chain archive.
+func ExportChain(chain *core.Core, fn string, archive bool) error {
 	log.Info("Exporting blockchain", "file", fn)
 
 	// Open the file handle and potentially wrap with a gzip stream
@@ -236,7 +246,12 @@ func ExportChain(chain *core.Core, fn string) error {
 		defer writer.(*gzip.Writer).Close()
 	}
 	// Iterate over the blocks and export them
-	if err := chain.Export(writer); err != nil {
+	if archive {
+		err = chain.ExportArchive(writer, 0, chain.CurrentHeader().NumberU64())
+	} else {
+		err = chain.Export(writer)
+	}
+	if err != nil {
 		return err
 	}
 	log.Info("Exported blockchain", "file", fn)
@@ -245,8 +260,9 @@ func ExportChain(chain *core.Core, fn string) error {
 }
 
 // ExportAppendChain exports a blockchain into the specified file, appending to
-// the file if data already exists in it.
-func ExportAppendChain(chain *core.Core, fn string, first uint64, last uint64) error {
+// the file if data already exists in it. If archive is set, the blocks are
+// appended as a segment of a chain archive.
+func ExportAppendChain(chain *core.Core, fn string, first uint64, last uint64, archive bool) error {
 	log.Info("Exporting blockchain", "file", fn)
 
 	// Open the file handle and potentially wrap with a gzip stream
@@ -262,7 +278,12 @@ func ExportAppendChain(chain *core.Core, fn string, first uint64, last uint64) e
 		defer writer.(*gzip.Writer).Close()
 	}
 	// Iterate over the blocks and export them
-	if err := chain.ExportN(writer, first, last); err != nil {
+	if archive {
+		err = chain.ExportArchive(writer, first, last)
+	} else {
+		err = chain.ExportN(writer, first, last)
+	}
+	if err != nil {
 		return err
 	}
 	log.Info("Exported blockchain to", "file", fn)
@@ -212,10 +212,15 @@ var (
 		Usage: "Max number of elements (0 = no limit)",
 		Value: 0,
 	}
+	ReexecFlag = cli.Uint64Flag{
+		Name:  "reexec",
+		Usage: "Max number of blocks to re-execute to regenerate a missing starting state",
+		Value: 128,
+	}
 	defaultSyncMode = ethconfig.Defaults.SyncMode
 	SyncModeFlag    = TextMarshalerFlag{
 		Name:  "syncmode",
-		Usage: `Blockchain sync mode ("fast", "full", "snap" or "light")`,
+		Usage: `Blockchain sync mode ("full" or "light", light is only available in prime and region)`,
 		Value: &defaultSyncMode,
 	}
 	GCModeFlag = cli.StringFlag{
@@ -232,6 +237,14 @@ var (
 		Usage: "Number of recent blocks to maintain transactions index for (default = about one year, 0 = entire chain)",
 		Value: ethconfig.Defaults.TxLookupLimit,
 	}
+	AddressIndexFlag = cli.BoolFlag{
+		Name:  "addressindex",
+		Usage: "Index the transactions and ETXs touching every address, served by quai_getAddressHistory",
+	}
+	StateDiffsFlag = cli.BoolFlag{
+		Name:  "statediffs",
+		Usage: "Store the state changes made by every block, served by debug_getStateDiff (pruned with --txlookuplimit)",
+	}
 	LightKDFFlag = cli.BoolFlag{
 		Name:  "lightkdf",
 		Usage: "Reduce key-derivation RAM & CPU usage at some expense of KDF strength",
@@ -343,12 +356,37 @@ var (
 		Name:  "cache.preimages",
 		Usage: "Enable recording the SHA3/keccak preimages of trie keys",
 	}
+	ParallelTxWorkersFlag = cli.IntFlag{
+		Name:  "parallel.workers",
+		Usage: "Number of workers executing block transactions speculatively in parallel (0 = sequential)",
+		Value: ethconfig.Defaults.ParallelTxWorkers,
+	}
 	// Consensus settings
 	ConsensusEngineFlag = cli.StringFlag{
 		Name:  "consensus.engine",
 		Usage: "Consensus engine that the blockchain will run and verify blocks using",
 		Value: "progpow",
 	}
+	// Progpow settings
+	ProgpowDatasetDirFlag = DirectoryFlag{
+		Name:  "progpow.dagdir",
+		Usage: "Directory to store the progpow mining DAGs (default = in memory only)",
+		Value: DirectoryString(ethconfig.Defaults.Progpow.DatasetDir),
+	}
+	ProgpowDatasetsInMemoryFlag = cli.IntFlag{
+		Name:  "progpow.dagsinmem",
+		Usage: "Number of recent progpow mining DAGs to keep in memory (1+GB each, 0 = light mode only)",
+		Value: ethconfig.Defaults.Progpow.DatasetsInMem,
+	}
+	ProgpowDatasetsOnDiskFlag = cli.IntFlag{
+		Name:  "progpow.dagsondisk",
+		Usage: "Number of recent progpow mining DAGs to keep on disk (1+GB each)",
+		Value: ethconfig.Defaults.Progpow.DatasetsOnDisk,
+	}
+	ProgpowDatasetsLockMmapFlag = cli.BoolFlag{
+		Name:  "progpow.dagslockmmap",
+		Usage: "Lock memory maps for recent progpow mining DAGs",
+	}
 	// Miner settings
 	MinerGasPriceFlag = BigFlag{
 		Name:  "miner.gasprice",
@@ -360,6 +398,24 @@ var (
 		Usage: "Public address for block mining rewards (default = first account)",
 		Value: "0",
 	}
+	MinerPolicyFlag = cli.StringFlag{
+		Name:  "miner.policy",
+		Usage: "Block template policy ordering transactions (" + strings.Join(core.TemplatePolicies, ", ") + ")",
+		Value: core.FeePolicyName,
+	}
+	MinerAllowlistFlag = cli.StringFlag{
+		Name:  "miner.allowlist",
+		Usage: "Comma separated senders to include first with the local-first policy",
+	}
+	MinerExcludeFlag = cli.StringFlag{
+		Name:  "miner.exclude",
+		Usage: "Comma separated senders whose internal transactions are never mined",
+	}
+	MinerEtxReserveFlag = cli.Uint64Flag{
+		Name:  "miner.etxreserve",
+		Usage: "Gas of each mined block reserved for ETXs",
+		Value: ethconfig.Defaults.Miner.EtxReserve,
+	}
 	// Account settings
 	UnlockedAccountFlag = cli.StringFlag{
 		Name:  "unlock",
@@ -394,6 +450,41 @@ var (
 		Usage: "Sets a cap on transaction fee (in ether) that can be sent via the RPC APIs (0 = no cap)",
 		Value: ethconfig.Defaults.RPCTxFeeCap,
 	}
+	RPCBatchItemsFlag = cli.IntFlag{
+		Name:  "rpc.batch-items",
+		Usage: "Maximum number of requests in a JSON-RPC batch (0 = no limit)",
+		Value: node.DefaultConfig.RPCLimits.BatchItems,
+	}
+	RPCBatchResponseBytesFlag = cli.IntFlag{
+		Name:  "rpc.batch-response-bytes",
+		Usage: "Maximum size in bytes of the responses to a JSON-RPC batch (0 = no limit)",
+		Value: node.DefaultConfig.RPCLimits.BatchResponseBytes,
+	}
+	RPCResponseBytesFlag = cli.IntFlag{
+		Name:  "rpc.response-bytes",
+		Usage: "Maximum size in bytes of the response to a single JSON-RPC request (0 = no limit)",
+		Value: node.DefaultConfig.RPCLimits.ResponseBytes,
+	}
+	RPCSubscriptionsFlag = cli.IntFlag{
+		Name:  "rpc.subscriptions",
+		Usage: "Maximum number of subscriptions per websocket connection (0 = no limit)",
+		Value: node.DefaultConfig.RPCLimits.Subscriptions,
+	}
+	RPCRateFlag = cli.Float64Flag{
+		Name:  "rpc.rate",
+		Usage: "Maximum number of JSON-RPC requests per second per client IP (0 = no limit)",
+		Value: node.DefaultConfig.RPCLimits.RequestsPerSecond,
+	}
+	RPCBurstFlag = cli.IntFlag{
+		Name:  "rpc.burst",
+		Usage: "Number of JSON-RPC requests a client IP may send over its rate in bursts",
+		Value: node.DefaultConfig.RPCLimits.RequestBurst,
+	}
+	RPCMethodRatesFlag = cli.StringFlag{
+		Name:  "rpc.method-rates",
+		Usage: "Comma separated maximum rates of requests per second per client IP to single methods (e.g. quai_getLogs=2,txpool_content=1)",
+		Value: "",
+	}
 	// Logging and debug settings
 	QuaiStatsURLFlag = cli.StringFlag{
 		Name:  "quaistats",
@@ -907,6 +998,42 @@ func setWS(ctx *cli.Context, cfg *node.Config) {
 	}
 }
 
+// setRPCLimits applies the limits on the use of the RPC servers by clients.
+func setRPCLimits(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalIsSet(RPCBatchItemsFlag.Name) {
+		cfg.RPCLimits.BatchItems = ctx.GlobalInt(RPCBatchItemsFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCBatchResponseBytesFlag.Name) {
+		cfg.RPCLimits.BatchResponseBytes = ctx.GlobalInt(RPCBatchResponseBytesFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCResponseBytesFlag.Name) {
+		cfg.RPCLimits.ResponseBytes = ctx.GlobalInt(RPCResponseBytesFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCSubscriptionsFlag.Name) {
+		cfg.RPCLimits.Subscriptions = ctx.GlobalInt(RPCSubscriptionsFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCRateFlag.Name) {
+		cfg.RPCLimits.RequestsPerSecond = ctx.GlobalFloat64(RPCRateFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCBurstFlag.Name) {
+		cfg.RPCLimits.RequestBurst = ctx.GlobalInt(RPCBurstFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCMethodRatesFlag.Name) {
+		cfg.RPCLimits.MethodRates = make(map[string]float64)
+		for _, entry := range SplitAndTrim(ctx.GlobalString(RPCMethodRatesFlag.Name)) {
+			parts := strings.SplitN(entry, "=", 2)
+			if len(parts) != 2 {
+				Fatalf("Invalid method rate %q, expected method=rate", entry)
+			}
+			limit, err := strconv.ParseFloat(parts[1], 64)
+			if err != nil {
+				Fatalf("Invalid rate of method %s: %v", parts[0], err)
+			}
+			cfg.RPCLimits.MethodRates[parts[0]] = limit
+		}
+	}
+}
+
 // setDomUrl sets the dominant chain websocket url.
 func setDomUrl(ctx *cli.Context, cfg *ethconfig.Config) {
 	// only set the dom url if the node is not prime
@@ -1107,6 +1234,7 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	SetP2PConfig(ctx, &cfg.P2P)
 	setHTTP(ctx, cfg)
 	setWS(ctx, cfg)
+	setRPCLimits(ctx, cfg)
 	setNodeUserIdent(ctx, cfg)
 	setDataDir(ctx, cfg)
 
@@ -1188,6 +1316,36 @@ func setGPO(ctx *cli.Context, cfg *gasprice.Config, light bool) {
 	}
 }
 
+func setMiner(ctx *cli.Context, cfg *core.Config) {
+	if ctx.GlobalIsSet(MinerPolicyFlag.Name) {
+		cfg.Policy = ctx.GlobalString(MinerPolicyFlag.Name)
+		if _, err := core.NewTemplatePolicy(cfg.Policy, nil); err != nil {
+			Fatalf("Invalid --%s: %v", MinerPolicyFlag.Name, err)
+		}
+	}
+	if ctx.GlobalIsSet(MinerAllowlistFlag.Name) {
+		cfg.Allowlist = makeMinerAccounts(ctx, MinerAllowlistFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerExcludeFlag.Name) {
+		cfg.Exclude = makeMinerAccounts(ctx, MinerExcludeFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerEtxReserveFlag.Name) {
+		cfg.EtxReserve = ctx.GlobalUint64(MinerEtxReserveFlag.Name)
+	}
+}
+
+// makeMinerAccounts parses the comma separated accounts of the given flag.
+func makeMinerAccounts(ctx *cli.Context, name string) []common.Address {
+	var accounts []common.Address
+	for _, account := range SplitAndTrim(ctx.GlobalString(name)) {
+		if !common.IsHexAddress(account) {
+			Fatalf("Invalid account in --%s: %s", name, account)
+		}
+		accounts = append(accounts, common.HexToAddress(account))
+	}
+	return accounts
+}
+
 func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.GlobalIsSet(TxPoolLocalsFlag.Name) {
 		locals := strings.Split(ctx.GlobalString(TxPoolLocalsFlag.Name), ",")
@@ -1306,6 +1464,23 @@ func setConsensusEngineConfig(ctx *cli.Context, cfg *ethconfig.Config) {
 	}
 }
 
+// setProgpow configures the full dataset handling of the progpow engine from
+// the command line flags.
+func setProgpow(ctx *cli.Context, cfg *ethconfig.Config) {
+	if ctx.GlobalIsSet(ProgpowDatasetDirFlag.Name) {
+		cfg.Progpow.DatasetDir = ctx.GlobalString(ProgpowDatasetDirFlag.Name)
+	}
+	if ctx.GlobalIsSet(ProgpowDatasetsInMemoryFlag.Name) {
+		cfg.Progpow.DatasetsInMem = ctx.GlobalInt(ProgpowDatasetsInMemoryFlag.Name)
+	}
+	if ctx.GlobalIsSet(ProgpowDatasetsOnDiskFlag.Name) {
+		cfg.Progpow.DatasetsOnDisk = ctx.GlobalInt(ProgpowDatasetsOnDiskFlag.Name)
+	}
+	if ctx.GlobalIsSet(ProgpowDatasetsLockMmapFlag.Name) {
+		cfg.Progpow.DatasetsLockMmap = ctx.GlobalBool(ProgpowDatasetsLockMmapFlag.Name)
+	}
+}
+
 func setWhitelist(ctx *cli.Context, cfg *ethconfig.Config) {
 	whitelist := ctx.GlobalString(WhitelistFlag.Name)
 	if whitelist == "" {
@@ -1402,6 +1577,7 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	}
 	setGPO(ctx, &cfg.GPO, ctx.GlobalString(SyncModeFlag.Name) == "light")
 	setTxPool(ctx, &cfg.TxPool)
+	setMiner(ctx, &cfg.Miner)
 
 	// If blake3 consensus engine is specifically asked use the blake3 engine
 	if ctx.GlobalString(ConsensusEngineFlag.Name) == "blake3" {
@@ -1410,6 +1586,7 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 		cfg.ConsensusEngine = "progpow"
 	}
 	setConsensusEngineConfig(ctx, cfg)
+	setProgpow(ctx, cfg)
 
 	setWhitelist(ctx, cfg)
 
@@ -1468,6 +1645,9 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.GlobalIsSet(CacheNoPrefetchFlag.Name) {
 		cfg.NoPrefetch = ctx.GlobalBool(CacheNoPrefetchFlag.Name)
 	}
+	if ctx.GlobalIsSet(ParallelTxWorkersFlag.Name) {
+		cfg.ParallelTxWorkers = ctx.GlobalInt(ParallelTxWorkersFlag.Name)
+	}
 	// Read the value from the flag no matter if it's set or not.
 	cfg.Preimages = ctx.GlobalBool(CachePreimagesFlag.Name)
 	if cfg.NoPruning && !cfg.Preimages {
@@ -1477,6 +1657,12 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.GlobalIsSet(TxLookupLimitFlag.Name) {
 		cfg.TxLookupLimit = ctx.GlobalUint64(TxLookupLimitFlag.Name)
 	}
+	if ctx.GlobalIsSet(AddressIndexFlag.Name) {
+		cfg.AddressIndex = ctx.GlobalBool(AddressIndexFlag.Name)
+	}
+	if ctx.GlobalIsSet(StateDiffsFlag.Name) {
+		cfg.StateDiffs = ctx.GlobalBool(StateDiffsFlag.Name)
+	}
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheTrieFlag.Name) {
 		cfg.TrieCleanCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheTrieFlag.Name) / 100
 	}
@@ -1720,6 +1906,19 @@ func MakeGenesis(ctx *cli.Context) *core.Genesis {
 	return genesis
 }
 
+// MakeEngine creates a consensus engine for offline chain operations from set
+// command line flags.
+func MakeEngine(ctx *cli.Context) consensus.Engine {
+	// If blake3 consensus engine is selected use the blake3 engine
+	if ctx.GlobalString(ConsensusEngineFlag.Name) == "blake3" {
+		return blake3pow.New(blake3pow.Config{}, nil, false)
+	}
+	if ctx.GlobalBool(FakePoWFlag.Name) {
+		return progpow.NewFaker()
+	}
+	return progpow.New(progpow.Config{}, nil, false)
+}
+
 // MakeChain creates a chain manager from set command line flags.
 func MakeChain(ctx *cli.Context, stack *node.Node) (*core.Core, ethdb.Database) {
 	var err error
@@ -1728,17 +1927,7 @@ func MakeChain(ctx *cli.Context, stack *node.Node) (*core.Core, ethdb.Database)
 	if err != nil {
 		Fatalf("%v", err)
 	}
-	var engine consensus.Engine
-
-	engine = progpow.NewFaker()
-	if !ctx.GlobalBool(FakePoWFlag.Name) {
-		engine = progpow.New(progpow.Config{}, nil, false)
-	}
-
-	// If blake3 consensus engine is selected use the blake3 engine
-	if ctx.GlobalString(ConsensusEngineFlag.Name) == "blake3" {
-		engine = blake3pow.New(blake3pow.Config{}, nil, false)
-	}
+	engine := MakeEngine(ctx)
 
 	if gcmode := ctx.GlobalString(GCModeFlag.Name); gcmode != "full" && gcmode != "archive" {
 		Fatalf("--%s must be either 'full' or 'archive'", GCModeFlag.Name)
@@ -1751,6 +1940,8 @@ func MakeChain(ctx *cli.Context, stack *node.Node) (*core.Core, ethdb.Database)
 		TrieTimeLimit:       ethconfig.Defaults.TrieTimeout,
 		SnapshotLimit:       ethconfig.Defaults.SnapshotCache,
 		Preimages:           ctx.GlobalBool(CachePreimagesFlag.Name),
+		ParallelTxWorkers:   ctx.GlobalInt(ParallelTxWorkersFlag.Name),
+		StateDiffs:          ctx.GlobalBool(StateDiffsFlag.Name),
 	}
 	if cache.TrieDirtyDisabled && !cache.Preimages {
 		cache.Preimages = true
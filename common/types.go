@@ -478,6 +478,34 @@ func (l Location) ContainsAddress(a Address) bool {
 	return uint8(prefix) >= prefixRange.lo && uint8(prefix) <= prefixRange.hi
 }
 
+// RefundAddress returns the address a zone sends ETX refunds from. It is the
+// lowest address of the zone's prefix range after the zero address, so it is
+// in the scope of that zone only.
+func (l Location) RefundAddress() Address {
+	var refund [AddressLength]byte
+	prefixRange, ok := locationToPrefixRange[l.Name()]
+	if !ok {
+		log.Fatal("unable to get address prefix range for location")
+	}
+	refund[0] = prefixRange.lo
+	refund[AddressLength-1] = 1
+	return BytesToAddress(refund[:])
+}
+
+// IsRefundAddress returns whether the address is the refund address of the zone
+// it is in the scope of. No key or contract controls a refund address, so only
+// the zone itself sends ETXs from it.
+func (a Address) IsRefundAddress() bool {
+	location := a.Location()
+	if location == nil || location.Context() != ZONE_CTX {
+		return false
+	}
+	if _, ok := locationToPrefixRange[location.Name()]; !ok {
+		return false
+	}
+	return a.Equal(location.RefundAddress())
+}
+
 func (l Location) RPCMarshal() []hexutil.Uint64 {
 	res := make([]hexutil.Uint64, 0)
 	for _, i := range l {
@@ -83,6 +83,9 @@ type Engine interface {
 	// DeltaLogS returns the log of the entropy delta for a chain since its prior coincidence
 	DeltaLogS(header *types.Header) *big.Int
 
+	// UncledLogS returns the log of the total entropy reduction of the given uncles
+	UncledLogS(uncles []*types.Header) *big.Int
+
 	ComputePowLight(header *types.Header) (mixHash, powHash common.Hash)
 
 	// VerifyHeader checks whether a header conforms to the consensus rules of a
@@ -1,14 +1,172 @@
 package misc
 
 import (
+	"math"
 	"math/big"
 
+	"github.com/dominant-strategies/go-quai/common"
 	"github.com/dominant-strategies/go-quai/core/types"
+	"github.com/dominant-strategies/go-quai/params"
 )
 
+// UncleReward is the reward credited to the coinbase of an included uncle.
+type UncleReward struct {
+	Hash     common.Hash
+	Coinbase common.Address
+	Order    int // Highest context the uncle is coincident with
+	Reward   *big.Int
+}
+
+// BlockReward is the breakdown of the rewards credited when finalizing a block.
+//
+// The order of a block is only known once it is sealed, while the state root
+// committed to by the block has to be computed before. Under an emission
+// schedule the block reward is thus credited by the child of the rewarded
+// block, so that miners and validators apply the same reward. The legacy
+// schedule does not depend on the order and rewards the block itself.
+type BlockReward struct {
+	Hash         common.Hash    // Hash of the block the block reward is credited for
+	Coinbase     common.Address // Coinbase credited with the block reward
+	Order        int            // Highest context the rewarded block is coincident with
+	Era          uint64         // Era of the rewarded block in the emission schedule
+	BaseReward   *big.Int       // Reward for the order of the block before decay
+	BlockReward  *big.Int       // Reward for the block after decay
+	Uncles       []UncleReward  // Rewards credited to the included uncles
+	NephewReward *big.Int       // Reward credited to the coinbase for including uncles
+}
+
 // CalculateReward calculates the coinbase rewards depending on the type of the block
-func CalculateReward(header *types.Header) *big.Int {
-	//// This Reward Schedule is only for Iron Age Testnet and has nothing to do
-	//// with the Mainnet Schedule
-	return new(big.Int).Mul(header.Difficulty(), big.NewInt(10e8))
+func CalculateReward(config *params.ChainConfig, header *types.Header, order int) *big.Int {
+	if config.Reward == nil {
+		//// This Reward Schedule is only for Iron Age Testnet and has nothing to do
+		//// with the Mainnet Schedule
+		return new(big.Int).Mul(header.Difficulty(), big.NewInt(10e8))
+	}
+	return decay(config.Reward, baseReward(config.Reward, order), era(config.Reward, header.NumberU64()))
+}
+
+// CalculateRewards calculates the block, uncle and nephew rewards credited when
+// finalizing the given header. Under an emission schedule the block reward is
+// credited to the parent of the header for its order, the legacy schedule
+// rewards the header itself and ignores the order. From the uncled entropy fork
+// on, each uncle is rewarded as a block of its own order, so that side blocks of
+// the dominant chains earn the reward of their context, and uncleOrders has to
+// hold the order of each uncle. All orders are computed from sealed headers.
+func CalculateRewards(config *params.ChainConfig, header *types.Header, parent *types.Header, parentOrder int, uncles []*types.Header, uncleOrders []int) *BlockReward {
+	reward := &BlockReward{NephewReward: new(big.Int)}
+	depth, divisor := uint64(8), uint64(32)
+	nephewBase := new(big.Int)
+	if config.Reward == nil {
+		reward.Hash, reward.Coinbase, reward.Order = header.Hash(), header.Coinbase(), common.ZONE_CTX
+		reward.BlockReward = CalculateReward(config, header, common.ZONE_CTX)
+		reward.BaseReward = new(big.Int).Set(reward.BlockReward)
+		nephewBase.Set(reward.BlockReward)
+	} else {
+		depth, divisor = config.Reward.UncleDepth, config.Reward.NephewDivisor
+		reward.Hash, reward.Coinbase, reward.Order = parent.Hash(), parent.Coinbase(), parentOrder
+		reward.Era = era(config.Reward, parent.NumberU64())
+		reward.BaseReward = baseReward(config.Reward, parentOrder)
+		if parent.NumberU64() == 0 {
+			// Nobody mined the genesis block
+			reward.BlockReward = new(big.Int)
+		} else {
+			reward.BlockReward = CalculateReward(config, parent, parentOrder)
+		}
+		nephewBase = CalculateReward(config, header, common.ZONE_CTX)
+	}
+	// Before the uncled entropy fork, uncles are rewarded as zone blocks out of
+	// the block reward of the header, whatever their seal
+	uncledEntropy := config.IsUncledEntropy(header.Number())
+	for i, uncle := range uncles {
+		uncleOrder, uncleBase := common.ZONE_CTX, nephewBase
+		if uncledEntropy {
+			uncleOrder = uncleOrders[i]
+		}
+		// Uncles with an out of scope coinbase, or whose order could not be
+		// computed from their seal, are neither rewarded nor count towards the
+		// nephew reward
+		if _, err := uncle.Coinbase().InternalAddress(); err != nil || uncleOrder < common.PRIME_CTX {
+			reward.Uncles = append(reward.Uncles, UncleReward{Hash: uncle.Hash(), Coinbase: uncle.Coinbase(), Order: uncleOrder, Reward: new(big.Int)})
+			continue
+		}
+		if uncledEntropy {
+			uncleBase = CalculateReward(config, uncle, uncleOrder)
+		}
+		// Uncles earn (uncle + depth - number) / depth of their base reward
+		r := new(big.Int)
+		if depth > 0 && uncle.NumberU64()+depth > header.NumberU64() {
+			r.SetUint64(uncle.NumberU64() + depth - header.NumberU64())
+			r.Mul(r, uncleBase)
+			r.Div(r, new(big.Int).SetUint64(depth))
+		}
+		reward.Uncles = append(reward.Uncles, UncleReward{Hash: uncle.Hash(), Coinbase: uncle.Coinbase(), Order: uncleOrder, Reward: r})
+		if divisor > 0 {
+			reward.NephewReward.Add(reward.NephewReward, new(big.Int).Div(nephewBase, new(big.Int).SetUint64(divisor)))
+		}
+	}
+	return reward
+}
+
+// UncleOrders returns the order of each of the given uncles of the header, as
+// computed from their seal by calcOrder. The order of an uncle whose seal does
+// not verify is negative, so that the uncle is not rewarded. Before the uncled
+// entropy fork the order of the uncles is ignored, and nil is returned.
+func UncleOrders(config *params.ChainConfig, header *types.Header, uncles []*types.Header, calcOrder func(*types.Header) (*big.Int, int, error)) []int {
+	if !config.IsUncledEntropy(header.Number()) {
+		return nil
+	}
+	orders := make([]int, len(uncles))
+	for i, uncle := range uncles {
+		_, order, err := calcOrder(uncle)
+		if err != nil {
+			order = -1
+		}
+		orders[i] = order
+	}
+	return orders
+}
+
+// baseReward returns the undecayed reward for a block of the given order.
+func baseReward(config *params.RewardConfig, order int) *big.Int {
+	var reward *big.Int
+	switch order {
+	case common.PRIME_CTX:
+		reward = config.PrimeBlockReward
+	case common.REGION_CTX:
+		reward = config.RegionBlockReward
+	default:
+		reward = config.ZoneBlockReward
+	}
+	if reward == nil {
+		return new(big.Int)
+	}
+	return new(big.Int).Set(reward)
+}
+
+// era returns the era of the emission schedule the block number falls in.
+func era(config *params.RewardConfig, number uint64) uint64 {
+	if config.EraLength == 0 {
+		return 0
+	}
+	return number / config.EraLength
+}
+
+// decay applies the per-era decay factor to the reward for the given era,
+// computing reward * numerator^era / denominator^era.
+func decay(config *params.RewardConfig, reward *big.Int, era uint64) *big.Int {
+	if era == 0 || config.DecayDenominator == 0 || config.DecayNumerator >= config.DecayDenominator {
+		return reward
+	}
+	if config.DecayNumerator == 0 {
+		return reward.SetUint64(0)
+	}
+	// Every era removes log2(denominator/numerator) bits from the reward, skip
+	// the exponentiation once the reward has certainly decayed to nothing
+	bitsPerEra := math.Log2(float64(config.DecayDenominator)) - math.Log2(float64(config.DecayNumerator))
+	if float64(era)*bitsPerEra > float64(reward.BitLen()+64) {
+		return reward.SetUint64(0)
+	}
+	exp := new(big.Int).SetUint64(era)
+	reward.Mul(reward, new(big.Int).Exp(new(big.Int).SetUint64(config.DecayNumerator), exp, nil))
+	return reward.Div(reward, new(big.Int).Exp(new(big.Int).SetUint64(config.DecayDenominator), exp, nil))
 }
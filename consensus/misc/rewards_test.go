@@ -0,0 +1,170 @@
+package misc
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/dominant-strategies/go-quai/common"
+	"github.com/dominant-strategies/go-quai/core/types"
+	"github.com/dominant-strategies/go-quai/params"
+)
+
+// rewardHeader returns a zone header at the given number and difficulty, with
+// a coinbase in scope of the first zone.
+func rewardHeader(number uint64, difficulty int64) *types.Header {
+	header := types.EmptyHeader()
+	header.SetNumber(new(big.Int).SetUint64(number))
+	header.SetDifficulty(big.NewInt(difficulty))
+	header.SetCoinbase(common.HexToAddress("0x0000000000000000000000000000000000000001"))
+	return header
+}
+
+// Tests that the legacy schedule rewards uncles out of the block reward of the
+// including header before the uncled entropy fork, regardless of their seal.
+func TestLegacyUncleRewards(t *testing.T) {
+	defer func(loc common.Location) { common.NodeLocation = loc }(common.NodeLocation)
+	common.NodeLocation = common.Location{0, 0}
+
+	config := &params.ChainConfig{ChainID: big.NewInt(1)}
+
+	header := rewardHeader(10, 1000)
+	uncles := []*types.Header{rewardHeader(9, 4000), rewardHeader(6, 2000)}
+	rewards := CalculateRewards(config, header, nil, 0, uncles, nil)
+
+	blockReward := new(big.Int).Mul(header.Difficulty(), big.NewInt(10e8))
+	if rewards.Coinbase != header.Coinbase() || rewards.BlockReward.Cmp(blockReward) != 0 {
+		t.Errorf("block reward mismatch: have %v to %x, want %v to %x", rewards.BlockReward, rewards.Coinbase, blockReward, header.Coinbase())
+	}
+	for i, uncle := range uncles {
+		want := new(big.Int).SetUint64(uncle.NumberU64() + 8 - header.NumberU64())
+		want.Mul(want, blockReward)
+		want.Div(want, big.NewInt(8))
+		if have := rewards.Uncles[i].Reward; have.Cmp(want) != 0 {
+			t.Errorf("uncle %d reward mismatch: have %v, want %v", i, have, want)
+		}
+	}
+	if want := new(big.Int).Div(blockReward, big.NewInt(16)); rewards.NephewReward.Cmp(want) != 0 {
+		t.Errorf("nephew reward mismatch: have %v, want %v", rewards.NephewReward, want)
+	}
+}
+
+// testRewardConfig halves the rewards every ten zone blocks.
+var testRewardConfig = &params.RewardConfig{
+	ZoneBlockReward:   big.NewInt(1000),
+	RegionBlockReward: big.NewInt(3000),
+	PrimeBlockReward:  big.NewInt(9000),
+	EraLength:         10,
+	DecayNumerator:    1,
+	DecayDenominator:  2,
+	UncleDepth:        8,
+	NephewDivisor:     32,
+}
+
+// Tests that the block reward for each order decays once per era, and that the
+// era of a block changes exactly at the era boundaries.
+func TestRewardDecay(t *testing.T) {
+	noDecay := *testRewardConfig
+	noDecay.EraLength = 0
+	burnt := *testRewardConfig
+	burnt.DecayNumerator = 0
+
+	tests := []struct {
+		reward *params.RewardConfig
+		number uint64
+		order  int
+		want   int64
+	}{
+		{testRewardConfig, 1, common.ZONE_CTX, 1000},
+		{testRewardConfig, 9, common.ZONE_CTX, 1000},
+		{testRewardConfig, 10, common.ZONE_CTX, 500},
+		{testRewardConfig, 19, common.ZONE_CTX, 500},
+		{testRewardConfig, 20, common.ZONE_CTX, 250},
+		{testRewardConfig, 9, common.REGION_CTX, 3000},
+		{testRewardConfig, 10, common.REGION_CTX, 1500},
+		{testRewardConfig, 25, common.PRIME_CTX, 2250},
+		{testRewardConfig, 100, common.ZONE_CTX, 0},
+		{testRewardConfig, 1 << 40, common.PRIME_CTX, 0},
+		{&noDecay, 1 << 40, common.REGION_CTX, 3000},
+		{&burnt, 9, common.ZONE_CTX, 1000},
+		{&burnt, 10, common.ZONE_CTX, 0},
+	}
+	for i, tt := range tests {
+		config := &params.ChainConfig{ChainID: big.NewInt(1), Reward: tt.reward}
+		if have := CalculateReward(config, rewardHeader(tt.number, 1000), tt.order); have.Cmp(big.NewInt(tt.want)) != 0 {
+			t.Errorf("test %d: reward mismatch for order %d at block %d: have %v, want %d", i, tt.order, tt.number, have, tt.want)
+		}
+	}
+	// Decaying a reward must not change the configured base reward
+	if testRewardConfig.ZoneBlockReward.Int64() != 1000 {
+		t.Errorf("base reward modified: have %v, want 1000", testRewardConfig.ZoneBlockReward)
+	}
+}
+
+// Tests that under an emission schedule the parent is rewarded for its order
+// and era, the uncles for their own order and era, and the header for including
+// the uncles.
+func TestEmissionRewards(t *testing.T) {
+	defer func(loc common.Location) { common.NodeLocation = loc }(common.NodeLocation)
+	common.NodeLocation = common.Location{0, 0}
+
+	config := &params.ChainConfig{ChainID: big.NewInt(1), Reward: testRewardConfig, UncledEntropyBlock: big.NewInt(0)}
+
+	// The parent is the last block of the first era, the header the first block
+	// of the second one
+	header, parent := rewardHeader(10, 1000), rewardHeader(9, 1000)
+	parent.SetCoinbase(common.HexToAddress("0x0000000000000000000000000000000000000002"))
+
+	outOfScope := rewardHeader(8, 1000)
+	outOfScope.SetCoinbase(common.HexToAddress("0xff00000000000000000000000000000000000001"))
+	uncles := []*types.Header{rewardHeader(8, 1000), rewardHeader(3, 1000), outOfScope, rewardHeader(7, 1000)}
+	orders := []int{common.REGION_CTX, common.ZONE_CTX, common.ZONE_CTX, -1}
+
+	rewards := CalculateRewards(config, header, parent, common.REGION_CTX, uncles, orders)
+	if rewards.Hash != parent.Hash() || rewards.Coinbase != parent.Coinbase() {
+		t.Errorf("rewarded block mismatch: have %x to %x, want %x to %x", rewards.Hash, rewards.Coinbase, parent.Hash(), parent.Coinbase())
+	}
+	if rewards.Order != common.REGION_CTX || rewards.Era != 0 {
+		t.Errorf("rewarded order and era mismatch: have %d/%d, want %d/0", rewards.Order, rewards.Era, common.REGION_CTX)
+	}
+	if rewards.BaseReward.Int64() != 3000 || rewards.BlockReward.Int64() != 3000 {
+		t.Errorf("block reward mismatch: have %v/%v, want 3000/3000", rewards.BaseReward, rewards.BlockReward)
+	}
+	// A region uncle two blocks back earns 6/8 of the region reward, a zone uncle
+	// seven blocks back 1/8 of the zone reward. The rest earn nothing.
+	for i, want := range []int64{3000 * 6 / 8, 1000 * 1 / 8, 0, 0} {
+		if have := rewards.Uncles[i].Reward; have.Cmp(big.NewInt(want)) != 0 {
+			t.Errorf("uncle %d reward mismatch: have %v, want %d", i, have, want)
+		}
+	}
+	// Only the rewarded uncles earn the header a share of its own reward
+	if want := int64(2 * (500 / 32)); rewards.NephewReward.Cmp(big.NewInt(want)) != 0 {
+		t.Errorf("nephew reward mismatch: have %v, want %d", rewards.NephewReward, want)
+	}
+	// Nobody mined the genesis block, so it is not rewarded
+	rewards = CalculateRewards(config, rewardHeader(1, 1000), rewardHeader(0, 1000), common.PRIME_CTX, nil, nil)
+	if rewards.BlockReward.Sign() != 0 {
+		t.Errorf("genesis block rewarded %v", rewards.BlockReward)
+	}
+}
+
+// Tests that uncle orders are only computed after the uncled entropy fork, and
+// that an uncle whose order cannot be computed gets a negative order.
+func TestUncleOrders(t *testing.T) {
+	calcOrder := func(header *types.Header) (*big.Int, int, error) {
+		if header.NumberU64() == 7 {
+			return big.NewInt(0), -1, errors.New("invalid seal")
+		}
+		return big.NewInt(0), common.REGION_CTX, nil
+	}
+	config := &params.ChainConfig{ChainID: big.NewInt(1), UncledEntropyBlock: big.NewInt(10)}
+	uncles := []*types.Header{rewardHeader(8, 1000), rewardHeader(7, 1000)}
+
+	if orders := UncleOrders(config, rewardHeader(9, 1000), uncles, calcOrder); orders != nil {
+		t.Errorf("uncle orders computed before the fork: %v", orders)
+	}
+	orders := UncleOrders(config, rewardHeader(10, 1000), uncles, calcOrder)
+	if len(orders) != 2 || orders[0] != common.REGION_CTX || orders[1] >= 0 {
+		t.Errorf("uncle orders mismatch: have %v, want [%d <0]", orders, common.REGION_CTX)
+	}
+}
@@ -57,6 +57,9 @@ var (
 	errDuplicateUncle      = errors.New("duplicate uncle")
 	errUncleIsAncestor     = errors.New("uncle is ancestor")
 	errDanglingUncle       = errors.New("uncle's parent is not ancestor")
+	errUnknownUncleTermini = errors.New("uncle's parent termini unknown")
+	errUncleBeforeTerminus = errors.New("uncle precedes its dom terminus")
+	errInvalidUncledS      = errors.New("invalid uncled entropy")
 	errInvalidDifficulty   = errors.New("non-positive difficulty")
 	errDifficultyCrossover = errors.New("sub's difficulty exceeds dom's")
 	errInvalidMixHash      = errors.New("invalid mixHash")
@@ -180,11 +183,17 @@ func (progpow *Progpow) VerifyUncles(chain consensus.ChainReader, block *types.B
 	if len(block.Uncles()) > maxUncles {
 		return errTooManyUncles
 	}
+	// The uncles only commit to their entropy after the uncled entropy fork
+	uncledEntropy := chain.Config().IsUncledEntropy(block.Number())
 	if len(block.Uncles()) == 0 {
+		if uncledS := block.Header().UncledS(); uncledS != nil && uncledS.Sign() != 0 {
+			return fmt.Errorf("invalid uncled entropy: have %v, want %v", uncledS, common.Big0)
+		}
 		return nil
 	}
 	// Gather the set of past uncles and ancestors
 	uncles, ancestors := mapset.NewSet(), make(map[common.Hash]*types.Header)
+	uncledS := new(big.Int)
 
 	number, parent := block.NumberU64()-1, block.ParentHash()
 	for i := 0; i < 7; i++ {
@@ -228,6 +237,59 @@ func (progpow *Progpow) VerifyUncles(chain consensus.ChainReader, block *types.B
 		if err := progpow.verifyHeader(chain, uncle, ancestors[uncle.ParentHash()], true, time.Now().Unix()); err != nil {
 			return err
 		}
+		if !uncledEntropy {
+			continue
+		}
+		// Side blocks of any order may be included, as long as they are
+		// consistent with the termini their parent was appended with
+		intrinsicS, order, err := progpow.CalcOrder(uncle)
+		if err != nil {
+			return err
+		}
+		if err := verifyUncleTermini(chain, uncle, order); err != nil {
+			return err
+		}
+		uncledS.Add(uncledS, intrinsicS)
+	}
+	// The entropy of the uncles counts towards the entropy of the block
+	have := block.Header().UncledS()
+	if !uncledEntropy {
+		if have != nil && have.Sign() != 0 {
+			return fmt.Errorf("invalid uncled entropy: have %v, want %v", have, common.Big0)
+		}
+		return nil
+	}
+	if have == nil || uncledS.Cmp(have) != 0 {
+		return fmt.Errorf("invalid uncled entropy: have %v, want %v", have, uncledS)
+	}
+	return nil
+}
+
+// verifyUncleTermini checks that the parent of an uncle passed the previous
+// coincident reference check. An uncle which is also a block of a dominant
+// chain must come after the dom terminus its parent was built on, otherwise it
+// references a dominant chain which is not an ancestor of the block.
+func verifyUncleTermini(chain consensus.ChainHeaderReader, uncle *types.Header, order int) error {
+	nodeCtx := common.NodeLocation.Context()
+	termini := chain.GetTerminiByHash(uncle.ParentHash())
+	if termini == nil {
+		return errUnknownUncleTermini
+	}
+	if order >= nodeCtx {
+		return nil
+	}
+	terminus := chain.GetHeaderByHash(termini.DomTerminus())
+	if terminus == nil {
+		return errUnknownUncleTermini
+	}
+	for ctx := order; ctx < nodeCtx; ctx++ {
+		// The terminus is coincident with the immediate dom, so the uncle has
+		// to be a later block there. In the chains above, the uncle may share
+		// its pending number with the terminus.
+		cmp := uncle.Number(ctx).Cmp(terminus.Number(ctx))
+		if cmp < 0 || (cmp == 0 && ctx == nodeCtx-1) {
+			return errUncleBeforeTerminus
+		}
 	}
 	return nil
 }
@@ -268,6 +330,10 @@ func (progpow *Progpow) verifyHeader(chain consensus.ChainHeaderReader, header,
 	if !common.NodeLocation.InSameSliceAs(header.Location()) {
 		return fmt.Errorf("block location is not in the same slice as the node location")
 	}
+	// The uncled entropy is checked against the header in every context
+	if err := progpow.verifyUncledS(chain.Config(), header); err != nil {
+		return err
+	}
 	// Verify that the parent entropy is calculated correctly on the header
 	parentEntropy := progpow.TotalLogS(parent)
 	if parentEntropy.Cmp(header.ParentEntropy()) != 0 {
@@ -408,6 +474,32 @@ func (progpow *Progpow) ComputePowLight(header *types.Header) (mixHash, powHash
 	return mixHash, powHash
 }
 
+// computePowFull computes the mix digest and pow hash of the header from the
+// given full dataset.
+func (progpow *Progpow) computePowFull(header *types.Header, dataset *dataset) (mixHash, powHash common.Hash) {
+	digest, result := progpowFull(dataset.dataset, header.SealHash().Bytes(), header.NonceU64(), header.NumberU64(common.ZONE_CTX))
+	mixHash = common.BytesToHash(digest)
+	powHash = common.BytesToHash(result)
+	header.PowDigest.Store(mixHash)
+	header.PowHash.Store(powHash)
+
+	// Datasets are unmapped in a finalizer. Ensure that the dataset stays alive
+	// until after the call to progpowFull so it's not unmapped while being used.
+	runtime.KeepAlive(dataset)
+
+	return mixHash, powHash
+}
+
+// computePow computes the mix digest and pow hash of the header, hashing in
+// full mode if the dataset of its epoch is already generated and falling back
+// to the light verification cache otherwise.
+func (progpow *Progpow) computePow(header *types.Header) (mixHash, powHash common.Hash) {
+	if dataset := progpow.fullDataset(header.NumberU64()); dataset != nil {
+		return progpow.computePowFull(header, dataset)
+	}
+	return progpow.ComputePowLight(header)
+}
+
 // VerifySeal returns the PowHash and the verifySeal output
 func (progpow *Progpow) VerifySeal(header *types.Header) (common.Hash, error) {
 	return progpow.verifySeal(header)
@@ -423,7 +515,12 @@ func (progpow *Progpow) verifySeal(header *types.Header) (common.Hash, error) {
 		if progpow.fakeFail == header.Number().Uint64() {
 			return common.Hash{}, errInvalidPoW
 		}
-		return common.Hash{}, nil
+		// Pretend the seal exactly met the difficulty, so the entropy of the
+		// header can still be computed from its pow hash
+		if header.Difficulty().Sign() <= 0 {
+			return common.Hash{}, nil
+		}
+		return common.BytesToHash(new(big.Int).Div(big2e256, header.Difficulty()).Bytes()), nil
 	}
 	// If we're running a shared PoW, delegate verification to it
 	if progpow.shared != nil {
@@ -437,7 +534,7 @@ func (progpow *Progpow) verifySeal(header *types.Header) (common.Hash, error) {
 	mixHash := header.PowDigest.Load()
 	powHash := header.PowHash.Load()
 	if powHash == nil || mixHash == nil {
-		mixHash, powHash = progpow.ComputePowLight(header)
+		mixHash, powHash = progpow.computePow(header)
 	}
 	// Verify the calculated values against the ones provided in the header
 	if !bytes.Equal(header.MixHash().Bytes(), mixHash.(common.Hash).Bytes()) {
@@ -460,8 +557,21 @@ func (progpow *Progpow) Prepare(chain consensus.ChainHeaderReader, header *types
 // Finalize implements consensus.Engine, accumulating the block and uncle rewards,
 // setting the final state on the header
 func (progpow *Progpow) Finalize(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header) {
-	// Accumulate any block and uncle rewards and commit the final state root
-	accumulateRewards(chain.Config(), state, header, uncles)
+	// Accumulate any block and uncle rewards and commit the final state root.
+	// The legacy schedule rewards the block itself, whatever its order. Under
+	// an emission schedule the header is not sealed yet, so the block reward is
+	// credited for the order of the parent.
+	config := chain.Config()
+	uncleOrders := misc.UncleOrders(config, header, uncles, progpow.CalcOrder)
+	if config.Reward == nil {
+		accumulateRewards(config, state, header, nil, common.ZONE_CTX, uncles, uncleOrders)
+	} else if parent := chain.GetHeader(header.ParentHash(), header.NumberU64()-1); parent == nil {
+		log.Error("Failed to find parent of block, skipping block reward", "Hash", header.Hash().String())
+	} else if _, parentOrder, err := progpow.CalcOrder(parent); err != nil {
+		log.Error("Failed to calculate parent order, skipping block reward", "Hash", header.Hash().String(), "err", err)
+	} else {
+		accumulateRewards(config, state, header, parent, parentOrder, uncles, uncleOrders)
+	}
 
 	if common.NodeLocation.Context() == common.ZONE_CTX && header.ParentHash() == chain.Config().GenesisHash {
 		alloc := core.ReadGenesisAlloc("genallocs/gen_alloc_" + common.NodeLocation.Name() + ".json")
@@ -498,36 +608,35 @@ func (progpow *Progpow) FinalizeAndAssemble(chain consensus.ChainHeaderReader, h
 	return types.NewBlock(header, txs, uncles, etxs, subManifest, receipts, trie.NewStackTrie(nil)), nil
 }
 
-// AccumulateRewards credits the coinbase of the given block with the mining
-// reward. The total reward consists of the static block reward and rewards for
-// included uncles. The coinbase of each uncle block is also rewarded.
-func accumulateRewards(config *params.ChainConfig, state *state.StateDB, header *types.Header, uncles []*types.Header) {
+// AccumulateRewards credits the mining rewards when finalizing the given
+// block. The block reward for the order of the parent is credited to the
+// coinbase of the parent, or the block itself is rewarded under the legacy
+// schedule. The coinbase of the block is credited for included uncles, and the
+// coinbase of each uncle block is also rewarded.
+func accumulateRewards(config *params.ChainConfig, state *state.StateDB, header *types.Header, parent *types.Header, parentOrder int, uncles []*types.Header, uncleOrders []int) {
 	// Select the correct block reward based on chain progression
-	blockReward := misc.CalculateReward(header)
+	rewards := misc.CalculateRewards(config, header, parent, parentOrder, uncles, uncleOrders)
+
+	if coinbase, err := rewards.Coinbase.InternalAddress(); err != nil {
+		log.Error("Block has out-of-scope coinbase, skipping block reward: " + rewards.Hash.String())
+	} else {
+		state.AddBalance(coinbase, rewards.BlockReward)
+	}
 
 	coinbase, err := header.Coinbase().InternalAddress()
 	if err != nil {
-		log.Error("Block has out-of-scope coinbase, skipping block reward: " + header.Hash().String())
+		log.Error("Block has out-of-scope coinbase, skipping uncle rewards: " + header.Hash().String())
 		return
 	}
 
 	// Accumulate the rewards for the miner and any included uncles
-	reward := new(big.Int).Set(blockReward)
-	r := new(big.Int)
-	for _, uncle := range uncles {
+	for i, uncle := range uncles {
 		coinbase, err := uncle.Coinbase().InternalAddress()
 		if err != nil {
 			log.Error("Found uncle with out-of-scope coinbase, skipping reward: " + uncle.Hash().String())
 			continue
 		}
-		r.Add(uncle.Number(), big8)
-		r.Sub(r, header.Number())
-		r.Mul(r, blockReward)
-		r.Div(r, big8)
-		state.AddBalance(coinbase, r)
-
-		r.Div(blockReward, big32)
-		reward.Add(reward, r)
+		state.AddBalance(coinbase, rewards.Uncles[i].Reward)
 	}
-	state.AddBalance(coinbase, reward)
+	state.AddBalance(coinbase, rewards.NephewReward)
 }
@@ -1,6 +1,7 @@
 package progpow
 
 import (
+	"fmt"
 	"math/big"
 
 	"github.com/dominant-strategies/go-quai/common"
@@ -63,6 +64,58 @@ func (progpow *Progpow) IntrinsicLogS(powHash common.Hash) *big.Int {
 	return bigBits
 }
 
+// UncledLogS returns the total entropy reduction of the given uncles
+func (progpow *Progpow) UncledLogS(uncles []*types.Header) *big.Int {
+	uncledS := new(big.Int)
+	for _, uncle := range uncles {
+		intrinsicS, _, err := progpow.CalcOrder(uncle)
+		if err != nil {
+			continue
+		}
+		uncledS.Add(uncledS, intrinsicS)
+	}
+	return uncledS
+}
+
+// verifyUncledS checks the uncled entropy of the header against the header
+// alone. The uncles are only known in zone, where VerifyUncles recomputes the
+// uncled entropy. A header without uncles, or from before the uncled entropy
+// fork, has no uncled entropy, and no uncle can reduce more entropy than a pow
+// hash of one.
+func (progpow *Progpow) verifyUncledS(config *params.ChainConfig, header *types.Header) error {
+	uncledS := header.UncledS()
+	if uncledS == nil {
+		uncledS = big0
+	}
+	if uncledS.Sign() < 0 {
+		return fmt.Errorf("%w: have %v, want non-negative", errInvalidUncledS, uncledS)
+	}
+	if header.UncleHash() == types.EmptyUncleHash || !config.IsUncledEntropy(header.Number()) {
+		if uncledS.Sign() != 0 {
+			return fmt.Errorf("%w: have %v, want %v", errInvalidUncledS, uncledS, common.Big0)
+		}
+		return nil
+	}
+	maxUncledS := new(big.Int).Mul(progpow.IntrinsicLogS(common.BytesToHash([]byte{1})), big.NewInt(int64(maxUncles)))
+	if uncledS.Cmp(maxUncledS) > 0 {
+		return fmt.Errorf("%w: have %v, max %v", errInvalidUncledS, uncledS, maxUncledS)
+	}
+	return nil
+}
+
+// zoneLogS returns the entropy reduction a zone block contributes to the
+// entropy of its zone, which is its intrinsic entropy and the entropy of the
+// uncles it includes. The uncles are only known in zone, so the uncled entropy
+// never counts towards the entropy of a dominant chain, nor towards the delta
+// entropy which the dominant chains accumulate.
+func zoneLogS(header *types.Header, intrinsicS *big.Int) *big.Int {
+	zoneS := new(big.Int).Set(intrinsicS)
+	if uncledS := header.UncledS(); uncledS != nil {
+		zoneS.Add(zoneS, uncledS)
+	}
+	return zoneS
+}
+
 // TotalLogS() returns the total entropy reduction if the chain since genesis to the given header
 func (progpow *Progpow) TotalLogS(header *types.Header) *big.Int {
 	intrinsicS, order, err := progpow.CalcOrder(header)
@@ -80,7 +133,7 @@ func (progpow *Progpow) TotalLogS(header *types.Header) *big.Int {
 		totalS.Add(totalS, intrinsicS)
 		return totalS
 	case common.ZONE_CTX:
-		totalS := new(big.Int).Add(header.ParentEntropy(common.ZONE_CTX), intrinsicS)
+		totalS := new(big.Int).Add(header.ParentEntropy(common.ZONE_CTX), zoneLogS(header, intrinsicS))
 		return totalS
 	}
 	return big.NewInt(0)
@@ -0,0 +1,170 @@
+package progpow
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/dominant-strategies/go-quai/common"
+	"github.com/dominant-strategies/go-quai/consensus/misc"
+	"github.com/dominant-strategies/go-quai/core/types"
+	"github.com/dominant-strategies/go-quai/params"
+)
+
+// testUncle returns a zone block at the given number and difficulty, which a
+// fake engine seals with exactly the entropy of its difficulty.
+func testUncle(number uint64, difficulty int64) *types.Header {
+	uncle := testHeader(number)
+	uncle.SetDifficulty(big.NewInt(difficulty))
+	uncle.SetCoinbase(common.HexToAddress("0x0000000000000000000000000000000000000001"))
+	return uncle
+}
+
+// Tests that the uncled entropy is the sum of the intrinsic entropy of the
+// uncles, and that uncles whose seal does not verify count for nothing.
+func TestUncledLogS(t *testing.T) {
+	progpow := NewFakeFailer(3)
+	defer progpow.Close()
+
+	uncles := []*types.Header{testUncle(1, 1000), testUncle(2, 4000)}
+	want := new(big.Int)
+	for _, uncle := range uncles {
+		intrinsicS, _, err := progpow.CalcOrder(uncle)
+		if err != nil {
+			t.Fatalf("failed to calculate uncle order: %v", err)
+		}
+		want.Add(want, intrinsicS)
+	}
+	if have := progpow.UncledLogS(uncles); have.Cmp(want) != 0 {
+		t.Errorf("uncled entropy mismatch: have %v, want %v", have, want)
+	}
+	if have := progpow.UncledLogS(append(uncles, testUncle(3, 1000))); have.Cmp(want) != 0 {
+		t.Errorf("uncled entropy with unsealed uncle mismatch: have %v, want %v", have, want)
+	}
+	if have := progpow.UncledLogS(nil); have.Sign() != 0 {
+		t.Errorf("uncled entropy without uncles: have %v, want 0", have)
+	}
+}
+
+// Tests that the uncled entropy of a zone block is weighted into its total
+// entropy on top of its intrinsic entropy, but not into the delta entropy which
+// the dominant chains accumulate.
+func TestUncledEntropyWeighting(t *testing.T) {
+	progpow := NewFaker()
+	defer progpow.Close()
+
+	header := testUncle(10, 1000)
+	header.SetParentEntropy(big.NewInt(1<<40), common.ZONE_CTX)
+	header.SetParentDeltaS(big.NewInt(1<<20), common.ZONE_CTX)
+	if _, order, _ := progpow.CalcOrder(header); order != common.ZONE_CTX {
+		t.Fatalf("header order mismatch: have %d, want %d", order, common.ZONE_CTX)
+	}
+	totalS, deltaS := progpow.TotalLogS(header), progpow.DeltaLogS(header)
+
+	uncledS := progpow.UncledLogS([]*types.Header{testUncle(9, 1000), testUncle(8, 2000)})
+	header.SetUncleHash(common.Hash{0x01})
+	header.SetUncledS(uncledS)
+	if have, want := progpow.TotalLogS(header), new(big.Int).Add(totalS, uncledS); have.Cmp(want) != 0 {
+		t.Errorf("total entropy mismatch: have %v, want %v", have, want)
+	}
+	if have := progpow.DeltaLogS(header); have.Cmp(deltaS) != 0 {
+		t.Errorf("delta entropy mismatch: have %v, want %v", have, deltaS)
+	}
+	// A block with more uncled entropy is heavier, all else being equal
+	heavier := types.CopyHeader(header)
+	heavier.SetUncledS(new(big.Int).Add(uncledS, common.Big1))
+	if progpow.TotalLogS(heavier).Cmp(progpow.TotalLogS(header)) <= 0 {
+		t.Errorf("more uncled entropy did not increase the total entropy")
+	}
+}
+
+// Tests the checks on the uncled entropy which apply to a header in every
+// context.
+func TestVerifyUncledS(t *testing.T) {
+	progpow := NewFaker()
+	defer progpow.Close()
+
+	forked := &params.ChainConfig{UncledEntropyBlock: big.NewInt(0)}
+	maxUncledS := new(big.Int).Mul(progpow.IntrinsicLogS(common.BytesToHash([]byte{1})), big.NewInt(int64(maxUncles)))
+	tests := []struct {
+		config  *params.ChainConfig
+		uncles  bool
+		uncledS *big.Int
+		valid   bool
+	}{
+		{forked, false, big.NewInt(0), true},
+		{forked, false, big.NewInt(1), false},
+		{forked, true, big.NewInt(1), true},
+		{forked, true, maxUncledS, true},
+		{forked, true, new(big.Int).Add(maxUncledS, common.Big1), false},
+		{forked, true, big.NewInt(-1), false},
+		// Before the fork, the header carries no uncled entropy
+		{&params.ChainConfig{}, true, big.NewInt(0), true},
+		{&params.ChainConfig{}, true, big.NewInt(1), false},
+		{&params.ChainConfig{UncledEntropyBlock: big.NewInt(2)}, true, big.NewInt(1), false},
+	}
+	for i, tt := range tests {
+		header := testUncle(1, 1000)
+		if tt.uncles {
+			header.SetUncleHash(common.Hash{0x01})
+		}
+		header.SetUncledS(tt.uncledS)
+		err := progpow.verifyUncledS(tt.config, header)
+		if tt.valid && err != nil {
+			t.Errorf("test %d: valid uncled entropy %v rejected: %v", i, tt.uncledS, err)
+		}
+		if !tt.valid && !errors.Is(err, errInvalidUncledS) {
+			t.Errorf("test %d: invalid uncled entropy %v error mismatch: have %v, want %v", i, tt.uncledS, err, errInvalidUncledS)
+		}
+	}
+}
+
+// Tests that uncles whose seal does not verify are not rewarded when a block
+// is finalized after the uncled entropy fork, rather than being rewarded as zone
+// blocks.
+func TestUncleOrders(t *testing.T) {
+	defer func(loc common.Location) { common.NodeLocation = loc }(common.NodeLocation)
+	common.NodeLocation = common.Location{0, 0}
+
+	progpow := NewFakeFailer(7)
+	defer progpow.Close()
+
+	config := &params.ChainConfig{
+		Reward: &params.RewardConfig{
+			ZoneBlockReward:   big.NewInt(1000),
+			RegionBlockReward: big.NewInt(2000),
+			PrimeBlockReward:  big.NewInt(4000),
+			UncleDepth:        8,
+			NephewDivisor:     32,
+		},
+		UncledEntropyBlock: big.NewInt(0),
+	}
+	header, parent := testUncle(9, 1000), testUncle(8, 1000)
+	uncles := []*types.Header{testUncle(8, 1000), testUncle(7, 1000)}
+	orders := misc.UncleOrders(config, header, uncles, progpow.CalcOrder)
+	if orders[0] != common.ZONE_CTX {
+		t.Errorf("sealed uncle order mismatch: have %d, want %d", orders[0], common.ZONE_CTX)
+	}
+	if orders[1] >= 0 {
+		t.Errorf("unsealed uncle order mismatch: have %d, want negative", orders[1])
+	}
+	rewards := misc.CalculateRewards(config, header, parent, common.ZONE_CTX, uncles, orders)
+	if rewards.Uncles[0].Reward.Sign() <= 0 {
+		t.Errorf("sealed uncle was not rewarded")
+	}
+	if rewards.Uncles[1].Reward.Sign() != 0 {
+		t.Errorf("unsealed uncle was rewarded %v", rewards.Uncles[1].Reward)
+	}
+	if want := new(big.Int).Div(misc.CalculateReward(config, header, common.ZONE_CTX), big.NewInt(32)); rewards.NephewReward.Cmp(want) != 0 {
+		t.Errorf("nephew reward mismatch: have %v, want %v", rewards.NephewReward, want)
+	}
+	// Before the fork, the seal of the uncles is not checked
+	config.UncledEntropyBlock = big.NewInt(10)
+	if orders := misc.UncleOrders(config, header, uncles, progpow.CalcOrder); orders != nil {
+		t.Errorf("uncle orders computed before the fork: %v", orders)
+	}
+	rewards = misc.CalculateRewards(config, header, parent, common.ZONE_CTX, uncles, nil)
+	if rewards.Uncles[1].Reward.Sign() <= 0 {
+		t.Errorf("unsealed uncle was not rewarded before the fork")
+	}
+}
@@ -3,6 +3,7 @@ package progpow
 import (
 	"errors"
 	"fmt"
+	"math"
 	"math/big"
 	"math/rand"
 	"os"
@@ -11,6 +12,7 @@ import (
 	"runtime"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 	"unsafe"
 
@@ -156,9 +158,15 @@ type Config struct {
 	CachesInMem    int
 	CachesOnDisk   int
 	CachesLockMmap bool
-	DurationLimit  *big.Int
-	GasCeil        uint64
-	MinDifficulty  *big.Int
+
+	DatasetDir       string
+	DatasetsInMem    int
+	DatasetsOnDisk   int
+	DatasetsLockMmap bool
+
+	DurationLimit *big.Int
+	GasCeil       uint64
+	MinDifficulty *big.Int
 
 	// When set, notifications sent by the remote sealer will
 	// be block header JSON objects instead of work package arrays.
@@ -171,7 +179,8 @@ type Config struct {
 type Progpow struct {
 	config Config
 
-	caches *lru // In memory caches to avoid regenerating too often
+	caches   *lru // In memory caches to avoid regenerating too often
+	datasets *lru // In memory datasets for full mode hashing, nil if disabled
 
 	// Mining related fields
 	rand     *rand.Rand    // Properly seeded random source for nonces
@@ -203,12 +212,18 @@ func New(config Config, notify []string, noverify bool) *Progpow {
 	if config.CacheDir != "" && config.CachesOnDisk > 0 {
 		config.Log.Info("Disk storage enabled for ethash caches", "dir", config.CacheDir, "count", config.CachesOnDisk)
 	}
+	if config.DatasetDir != "" && config.DatasetsInMem > 0 && config.DatasetsOnDisk > 0 {
+		config.Log.Info("Disk storage enabled for ethash DAGs", "dir", config.DatasetDir, "count", config.DatasetsOnDisk)
+	}
 	progpow := &Progpow{
 		config:   config,
 		caches:   newlru("cache", config.CachesInMem, newCache),
 		update:   make(chan struct{}),
 		hashrate: metrics.NewMeterForced(),
 	}
+	if config.DatasetsInMem > 0 {
+		progpow.datasets = newlru("dataset", config.DatasetsInMem, newDataset)
+	}
 	if config.PowMode == ModeShared {
 		progpow.shared = sharedProgpow
 	}
@@ -342,6 +357,21 @@ func (lru *lru) get(epoch uint64) (item, future interface{}) {
 	return item, future
 }
 
+// peek retrieves the item for the given epoch if the lru holds one, without
+// creating it.
+func (lru *lru) peek(epoch uint64) interface{} {
+	lru.mu.Lock()
+	defer lru.mu.Unlock()
+
+	if item, ok := lru.cache.Peek(epoch); ok {
+		return item
+	}
+	if lru.future > 0 && lru.future == epoch {
+		return lru.futureItem
+	}
+	return nil
+}
+
 // cache wraps an ethash cache with some metadata to allow easier concurrent use.
 type cache struct {
 	epoch uint64    // Epoch for which this cache is relevant
@@ -380,7 +410,6 @@ func (c *cache) generate(dir string, limit int, lock bool, test bool) {
 			endian = ".be"
 		}
 		path := filepath.Join(dir, fmt.Sprintf("cache-R%d-%x%s", algorithmRevision, seed[:8], endian))
-		logger := log.New("epoch")
 
 		// We're about to mmap the file, ensure that the mapping is cleaned up when the
 		// cache becomes unused.
@@ -390,17 +419,17 @@ func (c *cache) generate(dir string, limit int, lock bool, test bool) {
 		var err error
 		c.dump, c.mmap, c.cache, err = memoryMap(path, lock)
 		if err == nil {
-			logger.Debug("Loaded old ethash cache from disk")
+			log.Debug("Loaded old ethash cache from disk")
 			c.cDag = make([]uint32, progpowCacheWords)
 			generateCDag(c.cDag, c.cache, c.epoch)
 			return
 		}
-		logger.Debug("Failed to load old ethash cache", "err", err)
+		log.Debug("Failed to load old ethash cache", "err", err)
 
 		// No previous cache available, create a new cache file to fill
 		c.dump, c.mmap, c.cache, err = memoryMapAndGenerate(path, size, lock, func(buffer []uint32) { generateCache(buffer, c.epoch, seed) })
 		if err != nil {
-			logger.Error("Failed to generate mapped ethash cache", "err", err)
+			log.Error("Failed to generate mapped ethash cache", "err", err)
 
 			c.cache = make([]uint32, size/4)
 			generateCache(c.cache, c.epoch, seed)
@@ -444,6 +473,166 @@ func (progpow *Progpow) cache(block uint64) *cache {
 	return current
 }
 
+// dataset wraps an ethash dataset with some metadata to allow easier concurrent use.
+type dataset struct {
+	epoch   uint64    // Epoch for which this dataset is relevant
+	dump    *os.File  // File descriptor of the memory mapped dataset
+	mmap    mmap.MMap // Memory map itself to unmap before releasing
+	dataset []uint32  // The actual dataset content (may be memory mapped)
+	once    sync.Once // Ensures the dataset is generated only once
+	done    uint32    // Atomic flag to determine generation status
+}
+
+// newDataset creates a new ethash mining dataset and returns it as a plain Go
+// interface to be usable in an LRU cache.
+func newDataset(epoch uint64) interface{} {
+	return &dataset{epoch: epoch}
+}
+
+// generate ensures that the dataset content is generated before use.
+func (d *dataset) generate(dir string, limit int, lock bool, test bool) {
+	d.once.Do(func() {
+		// Mark the dataset generated after we're done. This is needed for remote
+		// callers that only want to use the dataset once it is ready.
+		defer atomic.StoreUint32(&d.done, 1)
+
+		csize := cacheSize(d.epoch*epochLength + 1)
+		dsize := datasetSize(d.epoch*epochLength + 1)
+		seed := seedHash(d.epoch*epochLength + 1)
+		if test {
+			// Light verification in test mode still uses the full dataset
+			// size, so only the cache shrinks.
+			csize = 1024
+		}
+		// If we don't store anything on disk, generate and return
+		if dir == "" {
+			cache := make([]uint32, csize/4)
+			generateCache(cache, d.epoch, seed)
+
+			d.dataset = make([]uint32, dsize/4)
+			generateDataset(d.dataset, d.epoch, cache)
+
+			return
+		}
+		// Disk storage is needed, this will get fancy
+		var endian string
+		if !isLittleEndian() {
+			endian = ".be"
+		}
+		path := filepath.Join(dir, fmt.Sprintf("full-R%d-%x%s", algorithmRevision, seed[:8], endian))
+
+		// We're about to mmap the file, ensure that the mapping is cleaned up when the
+		// dataset becomes unused.
+		runtime.SetFinalizer(d, (*dataset).finalizer)
+
+		// Try to load the file from disk and memory map it
+		var err error
+		d.dump, d.mmap, d.dataset, err = memoryMap(path, lock)
+		if err == nil {
+			log.Debug("Loaded old ethash dataset from disk")
+			return
+		}
+		log.Debug("Failed to load old ethash dataset", "err", err)
+
+		// No previous dataset available, create a new dataset file to fill
+		cache := make([]uint32, csize/4)
+		generateCache(cache, d.epoch, seed)
+
+		d.dump, d.mmap, d.dataset, err = memoryMapAndGenerate(path, dsize, lock, func(buffer []uint32) { generateDataset(buffer, d.epoch, cache) })
+		if err != nil {
+			log.Error("Failed to generate mapped ethash dataset", "err", err)
+
+			d.dataset = make([]uint32, dsize/4)
+			generateDataset(d.dataset, d.epoch, cache)
+		}
+		// Iterate over all previous instances and delete old ones
+		for ep := int(d.epoch) - limit; ep >= 0; ep-- {
+			seed := seedHash(uint64(ep)*epochLength + 1)
+			path := filepath.Join(dir, fmt.Sprintf("full-R%d-%x%s", algorithmRevision, seed[:8], endian))
+			os.Remove(path)
+		}
+	})
+}
+
+// generated returns whether this particular dataset finished generating already
+// or not (it may not have been started at all). This is useful for remote miners
+// to default to verification caches instead of blocking on DAG generations.
+func (d *dataset) generated() bool {
+	return atomic.LoadUint32(&d.done) == 1
+}
+
+// finalizer closes any file handlers and memory maps open.
+func (d *dataset) finalizer() {
+	if d.mmap != nil {
+		d.mmap.Unmap()
+		d.dump.Close()
+		d.mmap, d.dump = nil, nil
+	}
+}
+
+// MakeCache generates a new ethash cache and optionally stores it to disk.
+func MakeCache(block uint64, dir string) {
+	c := cache{epoch: block / epochLength}
+	c.generate(dir, math.MaxInt32, false, false)
+}
+
+// MakeDataset generates a new ethash dataset and optionally stores it to disk.
+func MakeDataset(block uint64, dir string) {
+	d := dataset{epoch: block / epochLength}
+	d.generate(dir, math.MaxInt32, false, false)
+}
+
+// dataset tries to retrieve a mining dataset for the specified block number
+// by first checking against a list of in-memory datasets, then against DAGs
+// stored on disk, and finally generating one if none can be found.
+//
+// If async is specified, not only the future but the current DAG is also
+// generated on a background thread.
+func (progpow *Progpow) dataset(block uint64, async bool) *dataset {
+	// Retrieve the requested ethash dataset
+	epoch := block / epochLength
+	currentI, futureI := progpow.datasets.get(epoch)
+	current := currentI.(*dataset)
+
+	// If async is specified, generate everything in a background thread
+	if async && !current.generated() {
+		go func() {
+			current.generate(progpow.config.DatasetDir, progpow.config.DatasetsOnDisk, progpow.config.DatasetsLockMmap, progpow.config.PowMode == ModeTest)
+
+			if futureI != nil {
+				future := futureI.(*dataset)
+				future.generate(progpow.config.DatasetDir, progpow.config.DatasetsOnDisk, progpow.config.DatasetsLockMmap, progpow.config.PowMode == ModeTest)
+			}
+		}()
+	} else {
+		// Either blocking generation was requested, or already done
+		current.generate(progpow.config.DatasetDir, progpow.config.DatasetsOnDisk, progpow.config.DatasetsLockMmap, progpow.config.PowMode == ModeTest)
+
+		if futureI != nil {
+			future := futureI.(*dataset)
+			go future.generate(progpow.config.DatasetDir, progpow.config.DatasetsOnDisk, progpow.config.DatasetsLockMmap, progpow.config.PowMode == ModeTest)
+		}
+	}
+	return current
+}
+
+// fullDataset returns the mining dataset for the specified block number if
+// full mode hashing is enabled and the dataset is already generated, or nil
+// so that callers fall back to the light verification cache. Datasets are only
+// generated by Seal, for the epoch being sealed and the next one, so verifying
+// headers of other epochs never starts a DAG generation.
+func (progpow *Progpow) fullDataset(block uint64) *dataset {
+	if progpow.datasets == nil {
+		return nil
+	}
+	if item := progpow.datasets.peek(block / epochLength); item != nil {
+		if dataset := item.(*dataset); dataset.generated() {
+			return dataset
+		}
+	}
+	return nil
+}
+
 // Threads returns the number of mining threads currently enabled. This doesn't
 // necessarily mean that mining is running!
 func (progpow *Progpow) Threads() int {
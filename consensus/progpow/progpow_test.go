@@ -0,0 +1,61 @@
+package progpow
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/dominant-strategies/go-quai/common"
+	"github.com/dominant-strategies/go-quai/core/types"
+)
+
+// testHeader returns a header at the given number in every context.
+func testHeader(number uint64) *types.Header {
+	header := types.EmptyHeader()
+	for ctx := 0; ctx < common.HierarchyDepth; ctx++ {
+		header.SetNumber(new(big.Int).SetUint64(number), ctx)
+	}
+	header.SetDifficulty(big.NewInt(1))
+	header.SetNonce(types.EncodeNonce(0x1234))
+	return header
+}
+
+// Tests that hashing in full mode yields the same digest and pow hash as light
+// mode for the same header. The dataset is generated from the verification
+// cache of the header's epoch, but kept small so the test runs quickly.
+func TestComputePowFull(t *testing.T) {
+	progpow := NewTester(nil, false)
+	defer progpow.Close()
+
+	header := testHeader(1)
+	cache := progpow.cache(header.NumberU64())
+
+	full := make([]uint32, 1024*1024/4)
+	generateDataset(full, cache.epoch, cache.cache)
+	mixHash, powHash := progpow.computePowFull(header, &dataset{epoch: cache.epoch, dataset: full})
+
+	digest, result := progpowLight(uint64(len(full))*4, cache.cache, header.SealHash().Bytes(), header.NonceU64(), header.NumberU64(common.ZONE_CTX), cache.cDag)
+	if mixHash != common.BytesToHash(digest) {
+		t.Errorf("mix digest mismatch: full %x, light %x", mixHash, digest)
+	}
+	if powHash != common.BytesToHash(result) {
+		t.Errorf("pow hash mismatch: full %x, light %x", powHash, result)
+	}
+}
+
+// Tests that verifying a header only hashes against datasets generated for
+// sealing and never starts generating one itself.
+func TestFullDatasetVerification(t *testing.T) {
+	progpow := New(Config{PowMode: ModeTest, CachesInMem: 1, DatasetsInMem: 2}, nil, false)
+	defer progpow.Close()
+
+	header := testHeader(1)
+	if dataset := progpow.fullDataset(header.NumberU64()); dataset != nil {
+		t.Fatalf("dataset returned before sealing")
+	}
+	progpow.computePow(header)
+	for epoch := uint64(0); epoch < 3; epoch++ {
+		if item := progpow.datasets.peek(epoch); item != nil {
+			t.Errorf("verification created the dataset of epoch %d", epoch)
+		}
+	}
+}
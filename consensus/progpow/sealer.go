@@ -47,6 +47,11 @@ func (progpow *Progpow) Seal(header *types.Header, results chan<- *types.Header,
 	if progpow.shared != nil {
 		return progpow.shared.Seal(header, results, stop)
 	}
+	// Generate the datasets of the sealing epoch and the next one in the
+	// background, hashing in light mode until they are done
+	if progpow.datasets != nil {
+		progpow.dataset(header.NumberU64(), true)
+	}
 	// Create a runner and the multiple search threads it directs
 	abort := make(chan struct{})
 
@@ -115,7 +120,8 @@ func (progpow *Progpow) Seal(header *types.Header, results chan<- *types.Header,
 func (progpow *Progpow) mine(header *types.Header, id int, seed uint64, abort chan struct{}, found chan *types.Header) {
 	// Extract some data from the header
 	var (
-		target = new(big.Int).Div(big2e256, header.Difficulty())
+		target  = new(big.Int).Div(big2e256, header.Difficulty())
+		dataset = progpow.fullDataset(header.NumberU64())
 	)
 	// Start generating random nonces until we abort or find a good one
 	var (
@@ -146,10 +152,15 @@ search:
 				}
 				return progpowLight(size, cache, hash, nonce, blockNumber, ethashCache.cDag)
 			}
-			cache := progpow.cache(header.NumberU64())
-			size := datasetSize(header.NumberU64())
-			// Compute the PoW value of this nonce
-			digest, result := powLight(size, cache.cache, header.SealHash().Bytes(), nonce, header.NumberU64(common.ZONE_CTX))
+			// Compute the PoW value of this nonce, from the full dataset if it is ready
+			var digest, result []byte
+			if dataset != nil {
+				digest, result = progpowFull(dataset.dataset, header.SealHash().Bytes(), nonce, header.NumberU64(common.ZONE_CTX))
+			} else {
+				cache := progpow.cache(header.NumberU64())
+				size := datasetSize(header.NumberU64())
+				digest, result = powLight(size, cache.cache, header.SealHash().Bytes(), nonce, header.NumberU64(common.ZONE_CTX))
+			}
 			if new(big.Int).SetBytes(result).Cmp(target) <= 0 {
 				// Correct nonce found, create a new header with it
 				header = types.CopyHeader(header)
@@ -162,6 +173,9 @@ search:
 			nonce++
 		}
 	}
+	// Datasets are unmapped in a finalizer. Ensure that the dataset stays alive
+	// during sealing so it's not unmapped while being read.
+	runtime.KeepAlive(dataset)
 }
 
 // This is the timeout for HTTP requests to notify external miners.
@@ -0,0 +1,150 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dominant-strategies/go-quai/common"
+	"github.com/dominant-strategies/go-quai/core/rawdb"
+	"github.com/dominant-strategies/go-quai/core/types"
+	"github.com/dominant-strategies/go-quai/ethdb"
+	"github.com/dominant-strategies/go-quai/params"
+)
+
+const (
+	// AddressIndexSectionSize is the number of blocks indexed at once by the
+	// address activity indexer.
+	AddressIndexSectionSize = 128
+
+	// AddressIndexConfirms is the number of confirmations a section needs
+	// before it is indexed, reorgs deeper than this roll sections back.
+	AddressIndexConfirms = 32
+
+	// addressThrottling is the time to wait between processing two consecutive
+	// index sections.
+	addressThrottling = 100 * time.Millisecond
+)
+
+// AddressIndexer implements a core.ChainIndexer, mapping every address to the
+// transactions, emitted ETXs and received ETXs touching it.
+type AddressIndexer struct {
+	size    uint64         // section size to index
+	db      ethdb.Database // database instance to write index data into
+	signer  types.Signer   // signer recovering the senders of transactions
+	batch   ethdb.Batch    // batch of the section being processed
+	section uint64         // section number being processed currently
+}
+
+// NewAddressIndexer returns a chain indexer that builds the address activity
+// index of the canonical chain.
+func NewAddressIndexer(db ethdb.Database, config *params.ChainConfig) *ChainIndexer {
+	backend := &AddressIndexer{
+		db:     db,
+		size:   AddressIndexSectionSize,
+		signer: types.LatestSigner(config),
+	}
+	table := rawdb.NewTable(db, string(rawdb.AddressIndexPrefix))
+
+	return NewChainIndexer(db, table, backend, AddressIndexSectionSize, AddressIndexConfirms, addressThrottling, "addresses")
+}
+
+// Reset implements core.ChainIndexerBackend, starting a new section. Any entry
+// left over from an earlier run of the section, e.g. one that was rolled back
+// by a reorg, is removed.
+func (a *AddressIndexer) Reset(ctx context.Context, section uint64, lastSectionHead common.Hash) error {
+	a.batch, a.section = a.db.NewBatch(), section
+	for number := section * a.size; number < (section+1)*a.size; number++ {
+		addresses := rawdb.ReadAddressJournal(a.db, number)
+		if addresses == nil {
+			continue
+		}
+		for _, address := range addresses {
+			rawdb.DeleteAddressActivity(a.db, address, number)
+		}
+		rawdb.DeleteAddressJournal(a.db, number)
+	}
+	return nil
+}
+
+// Process implements core.ChainIndexerBackend, adding the activity of the
+// block of a header into the index.
+func (a *AddressIndexer) Process(ctx context.Context, header *types.Header, bloom types.Bloom) error {
+	number, hash := header.NumberU64(), header.Hash()
+	block := rawdb.ReadBlock(a.db, hash, number)
+	if block == nil {
+		return fmt.Errorf("block #%d [%x..] not found", number, hash[:4])
+	}
+	receipts := rawdb.ReadRawReceipts(a.db, hash, number)
+	if len(receipts) != len(block.Transactions()) {
+		return fmt.Errorf("receipts of block #%d [%x..] not found", number, hash[:4])
+	}
+
+	var (
+		seqs      = make(map[common.AddressBytes]uint32)
+		addresses []common.Address
+	)
+	record := func(address *common.Address, kind uint8, txHash common.Hash, index int) {
+		if address == nil {
+			return
+		}
+		key := address.Bytes20()
+		seq, seen := seqs[key]
+		if !seen {
+			addresses = append(addresses, *address)
+		}
+		seqs[key] = seq + 1
+		rawdb.WriteAddressActivity(a.batch, *address, seq, rawdb.AddressActivity{
+			Kind:        kind,
+			BlockNumber: number,
+			BlockHash:   hash,
+			TxHash:      txHash,
+			TxIndex:     uint64(index),
+		})
+	}
+	for i, tx := range block.Transactions() {
+		if tx.Type() == types.ExternalTxType {
+			record(tx.To(), rawdb.AddressActivityEtxReceived, tx.Hash(), i)
+			if refund := types.FindRefund(tx, receipts[i].Etxs); refund != nil {
+				record(refund.To(), rawdb.AddressActivityEtxEmitted, refund.Hash(), i)
+			}
+			continue
+		}
+		from, err := types.Sender(a.signer, tx)
+		if err != nil {
+			return err
+		}
+		record(&from, rawdb.AddressActivityTx, tx.Hash(), i)
+		if to := tx.To(); to == nil || !to.Equal(from) {
+			record(to, rawdb.AddressActivityTx, tx.Hash(), i)
+		}
+		for _, etx := range receipts[i].Etxs {
+			etxSender := etx.ETXSender()
+			record(&etxSender, rawdb.AddressActivityEtxEmitted, etx.Hash(), i)
+			if to := etx.To(); to == nil || !to.Equal(etxSender) {
+				record(to, rawdb.AddressActivityEtxEmitted, etx.Hash(), i)
+			}
+		}
+	}
+	if len(addresses) > 0 {
+		rawdb.WriteAddressJournal(a.batch, number, addresses)
+	}
+	if a.batch.ValueSize() > ethdb.IdealBatchSize {
+		if err := a.batch.Write(); err != nil {
+			return err
+		}
+		a.batch.Reset()
+	}
+	return nil
+}
+
+// Commit implements core.ChainIndexerBackend, writing the index of the section
+// into the database.
+func (a *AddressIndexer) Commit() error {
+	return a.batch.Write()
+}
+
+// Prune returns an empty error since we don't support pruning here.
+func (a *AddressIndexer) Prune(threshold uint64) error {
+	return nil
+}
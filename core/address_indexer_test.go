@@ -0,0 +1,66 @@
+package core
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/dominant-strategies/go-quai/common"
+	"github.com/dominant-strategies/go-quai/core/rawdb"
+	"github.com/dominant-strategies/go-quai/core/types"
+)
+
+// Tests that the address indexer records the senders and recipients of the
+// transactions of a block, and that resetting the section of the block rolls
+// its entries back.
+func TestAddressIndexer(t *testing.T) {
+	defer func(loc common.Location) { common.NodeLocation = loc }(common.NodeLocation)
+	common.NodeLocation = common.Location{0, 0}
+
+	db := rawdb.NewMemoryDatabase()
+	indexer := &AddressIndexer{db: db, size: AddressIndexSectionSize, signer: types.LatestSigner(policyConfig)}
+
+	key, sender := newScopedKey(t)
+	tx := policyTx(t, key, 0, 1)
+	header := types.EmptyHeader()
+	header.SetNumber(big.NewInt(AddressIndexSectionSize + 1))
+	block := types.NewBlockWithHeader(header).WithBody(types.Transactions{tx}, nil, nil, nil)
+	rawdb.WriteBlock(db, block)
+	rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), types.Receipts{{Status: types.ReceiptStatusSuccessful, TxHash: tx.Hash()}})
+
+	if err := indexer.Reset(context.Background(), 1, common.Hash{}); err != nil {
+		t.Fatalf("failed to reset section: %v", err)
+	}
+	if err := indexer.Process(context.Background(), block.Header(), types.Bloom{}); err != nil {
+		t.Fatalf("failed to process block: %v", err)
+	}
+	if err := indexer.Commit(); err != nil {
+		t.Fatalf("failed to commit section: %v", err)
+	}
+	for _, address := range []common.Address{sender, *tx.To()} {
+		activity, _, _ := rawdb.ReadAddressActivity(db, address, 0, 0, 10)
+		if len(activity) != 1 {
+			t.Fatalf("activity of %x mismatch: have %d entries, want 1", address, len(activity))
+		}
+		want := rawdb.AddressActivity{Kind: rawdb.AddressActivityTx, BlockNumber: block.NumberU64(), BlockHash: block.Hash(), TxHash: tx.Hash()}
+		if activity[0] != want {
+			t.Errorf("activity of %x mismatch: have %+v, want %+v", address, activity[0], want)
+		}
+	}
+	if journal := rawdb.ReadAddressJournal(db, block.NumberU64()); len(journal) != 2 {
+		t.Errorf("journal mismatch: have %d addresses, want 2", len(journal))
+	}
+
+	// Indexing the section again, e.g. after a reorg, starts from scratch
+	if err := indexer.Reset(context.Background(), 1, common.Hash{}); err != nil {
+		t.Fatalf("failed to reset section: %v", err)
+	}
+	for _, address := range []common.Address{sender, *tx.To()} {
+		if activity, _, _ := rawdb.ReadAddressActivity(db, address, 0, 0, 10); len(activity) != 0 {
+			t.Errorf("activity of %x kept after reset: %d entries", address, len(activity))
+		}
+	}
+	if journal := rawdb.ReadAddressJournal(db, block.NumberU64()); journal != nil {
+		t.Errorf("journal kept after reset: %d addresses", len(journal))
+	}
+}
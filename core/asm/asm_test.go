@@ -0,0 +1,130 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package asm
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// Tests disassembling the instructions for valid evm code
+func TestInstructionIteratorValid(t *testing.T) {
+	cnt := 0
+	script, _ := hex.DecodeString("61000000")
+
+	it := NewInstructionIterator(script)
+	for it.Next() {
+		cnt++
+	}
+
+	if err := it.Error(); err != nil {
+		t.Errorf("Expected 2, but encountered error %v instead.", err)
+	}
+	if cnt != 2 {
+		t.Errorf("Expected 2, but got %v instead.", cnt)
+	}
+}
+
+// Tests disassembling the instructions for invalid evm code
+func TestInstructionIteratorInvalid(t *testing.T) {
+	cnt := 0
+	script, _ := hex.DecodeString("6100")
+
+	it := NewInstructionIterator(script)
+	for it.Next() {
+		cnt++
+	}
+
+	if it.Error() == nil {
+		t.Errorf("Expected an error, but got %v instead.", cnt)
+	}
+}
+
+// Tests disassembling PUSH0, which has no immediate data
+func TestInstructionIteratorPush0(t *testing.T) {
+	script, _ := hex.DecodeString("5f6001")
+
+	it := NewInstructionIterator(script)
+	var ops []string
+	for it.Next() {
+		if it.Op().String() == "PUSH0" && it.Arg() != nil {
+			t.Errorf("PUSH0 has immediate data %x", it.Arg())
+		}
+		ops = append(ops, it.Op().String())
+	}
+	if err := it.Error(); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(ops) != 2 || ops[0] != "PUSH0" || ops[1] != "PUSH1" {
+		t.Errorf("Expected [PUSH0 PUSH1], but got %v instead.", ops)
+	}
+}
+
+// Tests disassembling the transient storage and MCOPY instructions
+func TestDisassembleCancun(t *testing.T) {
+	script, _ := hex.DecodeString("5f5c5d5e")
+
+	instrs, err := Disassemble(script)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	want := []string{"00000: PUSH0\n", "00001: TLOAD\n", "00002: TSTORE\n", "00003: MCOPY\n"}
+	if len(instrs) != len(want) {
+		t.Fatalf("Expected %d instructions, but got %v instead.", len(want), instrs)
+	}
+	for i := range want {
+		if instrs[i] != want[i] {
+			t.Errorf("Instruction %d: expected %q, got %q", i, want[i], instrs[i])
+		}
+	}
+}
+
+func TestCompiler(t *testing.T) {
+	tests := []struct {
+		input, output string
+	}{
+		{
+			input:  "push 1\npush 2\nadd\n",
+			output: "6001600201",
+		},
+		{
+			input:  "push0\ntload\n",
+			output: "5f5c",
+		},
+		{
+			input:  "push 0x2a\npush0\ntstore\n",
+			output: "602a5f5d",
+		},
+		{
+			input:  "push 0x20\npush0\npush 0x20\nmcopy\n",
+			output: "60205f60205e",
+		},
+	}
+	for _, test := range tests {
+		ch := Lex([]byte(test.input), false)
+		c := NewCompiler(false)
+		c.Feed(ch)
+		output, err := c.Compile()
+		if len(err) != 0 {
+			t.Errorf("compile error: %v\ninput: %s", err, test.input)
+			continue
+		}
+		if output != test.output {
+			t.Errorf("incorrect output\ninput: %sgot:  %s\nwant: %s\n", test.input, output, test.output)
+		}
+	}
+}
@@ -122,14 +122,11 @@ func (v *BlockValidator) ValidateState(block *types.Block, statedb *state.StateD
 		return fmt.Errorf("invalid merkle root (remote: %x local: %x)", header.Root(), root)
 	}
 	time5 := common.PrettyDuration(time.Since(start))
-	// Collect ETXs emitted from each successful transaction
+	// Collect ETXs emitted from each successful transaction and the refunds of
+	// failed ETXs
 	var emittedEtxs types.Transactions
 	for _, receipt := range receipts {
-		if receipt.Status == types.ReceiptStatusSuccessful {
-			for _, etx := range receipt.Etxs {
-				emittedEtxs = append(emittedEtxs, etx)
-			}
-		}
+		emittedEtxs = append(emittedEtxs, receipt.EmittedEtxs()...)
 	}
 	time6 := common.PrettyDuration(time.Since(start))
 	// Confirm the ETXs emitted by the transactions in this block exactly match the
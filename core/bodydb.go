@@ -27,12 +27,14 @@ type BodyDb struct {
 
 	db ethdb.Database // Low level persistent database to store final content in
 
-	chainFeed     event.Feed
-	chainSideFeed event.Feed
-	rmLogsFeed    event.Feed
-	logsFeed      event.Feed
-	blockProcFeed event.Feed
-	scope         event.SubscriptionScope
+	chainFeed      event.Feed
+	chainSideFeed  event.Feed
+	rmLogsFeed     event.Feed
+	logsFeed       event.Feed
+	blockProcFeed  event.Feed
+	expiredEtxFeed event.Feed
+	inboundEtxFeed event.Feed
+	scope          event.SubscriptionScope
 
 	engine       consensus.Engine
 	chainmu      sync.RWMutex
@@ -197,6 +199,16 @@ func (bc *BodyDb) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscription
 	return bc.scope.Track(bc.logsFeed.Subscribe(ch))
 }
 
+// SubscribeInboundEtxsEvent registers a subscription of InboundEtxsEvent.
+func (bc *BodyDb) SubscribeInboundEtxsEvent(ch chan<- InboundEtxsEvent) event.Subscription {
+	return bc.scope.Track(bc.inboundEtxFeed.Subscribe(ch))
+}
+
+// SubscribeExpiredEtxsEvent registers a subscription of ExpiredEtxsEvent.
+func (bc *BodyDb) SubscribeExpiredEtxsEvent(ch chan<- ExpiredEtxsEvent) event.Subscription {
+	return bc.scope.Track(bc.expiredEtxFeed.Subscribe(ch))
+}
+
 // SubscribeBlockProcessingEvent registers a subscription of bool where true means
 // block processing has started while false means it has stopped.
 func (bc *BodyDb) SubscribeBlockProcessingEvent(ch chan<- bool) event.Subscription {
@@ -0,0 +1,531 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/dominant-strategies/go-quai/common"
+	"github.com/dominant-strategies/go-quai/consensus"
+	"github.com/dominant-strategies/go-quai/core/rawdb"
+	"github.com/dominant-strategies/go-quai/core/types"
+	"github.com/dominant-strategies/go-quai/crypto"
+	"github.com/dominant-strategies/go-quai/log"
+	"github.com/dominant-strategies/go-quai/rlp"
+	"github.com/dominant-strategies/go-quai/trie"
+)
+
+const (
+	// ArchiveVersion is the version of the chain archive format written by
+	// this node. Archives with a higher version are rejected on import.
+	ArchiveVersion = 1
+
+	// ArchiveMagic identifies a chain archive, as opposed to a plain stream
+	// of RLP encoded blocks written by older nodes.
+	ArchiveMagic = "quai-chain-archive"
+)
+
+const (
+	archiveKindHeader uint8 = iota
+	archiveKindRecord
+	archiveKindIndex
+)
+
+var (
+	errArchiveChecksum = errors.New("archive checksum mismatch")
+	errArchiveIndex    = errors.New("archive index does not match records")
+)
+
+// ArchiveHeader opens a segment of a chain archive. An archive is made of one
+// or more segments, each holding a contiguous range of blocks followed by an
+// index of the records in the segment.
+type ArchiveHeader struct {
+	Magic       string
+	Version     uint64
+	Location    common.Location
+	GenesisHash common.Hash
+	First       uint64
+	Last        uint64
+}
+
+// ArchiveRecord bundles a block with the hierarchy data needed to append it
+// without asking the dom or sub nodes for it.
+type ArchiveRecord struct {
+	Block       *types.Block
+	InboundEtxs types.Transactions
+	Termini     *types.Termini
+	DomTerminus common.Hash // Previous coincident block the dom referenced when the block was appended
+	Manifest    types.BlockManifest
+	PendingEtxs []types.PendingEtxs
+	Rollups     []types.PendingEtxsRollup
+}
+
+// ArchiveIndexEntry locates a record within a segment.
+type ArchiveIndexEntry struct {
+	Number   uint64
+	Hash     common.Hash
+	Checksum common.Hash
+}
+
+// ArchiveIndex closes a segment of a chain archive.
+type ArchiveIndex struct {
+	Entries []ArchiveIndexEntry
+	Root    common.Hash // Checksum over the checksums of all the entries
+}
+
+// archiveItem is the envelope of every item written to an archive.
+type archiveItem struct {
+	Kind     uint8
+	Payload  rlp.RawValue
+	Checksum common.Hash
+}
+
+// archiveRoot computes the checksum of an index over its entries.
+func archiveRoot(entries []ArchiveIndexEntry) common.Hash {
+	checksums := make([][]byte, len(entries))
+	for i, entry := range entries {
+		checksums[i] = entry.Checksum.Bytes()
+	}
+	return crypto.Keccak256Hash(checksums...)
+}
+
+// writeArchiveItem wraps an item into its envelope and writes it, returning
+// the checksum of the payload.
+func writeArchiveItem(w io.Writer, kind uint8, val interface{}) (common.Hash, error) {
+	payload, err := rlp.EncodeToBytes(val)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	checksum := crypto.Keccak256Hash(payload)
+	return checksum, rlp.Encode(w, archiveItem{Kind: kind, Payload: payload, Checksum: checksum})
+}
+
+// ArchiveWriter writes the segments of a chain archive.
+type ArchiveWriter struct {
+	w       io.Writer
+	header  *ArchiveHeader
+	entries []ArchiveIndexEntry
+}
+
+// NewArchiveWriter creates a writer of a chain archive to w.
+func NewArchiveWriter(w io.Writer) *ArchiveWriter {
+	return &ArchiveWriter{w: w}
+}
+
+// Begin opens a segment holding the blocks first to last of the given
+// location, setting the magic and version of the header.
+func (aw *ArchiveWriter) Begin(header ArchiveHeader) error {
+	if aw.header != nil {
+		return fmt.Errorf("archive segment %d-%d is not closed", aw.header.First, aw.header.Last)
+	}
+	header.Magic, header.Version = ArchiveMagic, ArchiveVersion
+	if _, err := writeArchiveItem(aw.w, archiveKindHeader, header); err != nil {
+		return err
+	}
+	aw.header, aw.entries = &header, nil
+	return nil
+}
+
+// Write adds a record to the open segment.
+func (aw *ArchiveWriter) Write(record *ArchiveRecord) error {
+	if aw.header == nil {
+		return errors.New("archive record outside of a segment")
+	}
+	checksum, err := writeArchiveItem(aw.w, archiveKindRecord, record)
+	if err != nil {
+		return err
+	}
+	aw.entries = append(aw.entries, ArchiveIndexEntry{Number: record.Block.NumberU64(), Hash: record.Block.Hash(), Checksum: checksum})
+	return nil
+}
+
+// End closes the open segment with the index of its records.
+func (aw *ArchiveWriter) End() error {
+	if aw.header == nil {
+		return errors.New("archive index outside of a segment")
+	}
+	_, err := writeArchiveItem(aw.w, archiveKindIndex, ArchiveIndex{Entries: aw.entries, Root: archiveRoot(aw.entries)})
+	aw.header, aw.entries = nil, nil
+	return err
+}
+
+// IsArchive reports whether the buffered stream starts with a chain archive,
+// without consuming any of it.
+func IsArchive(r *bufio.Reader) bool {
+	data, err := r.Peek(256)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return false
+	}
+	content, _, err := rlp.SplitList(data)
+	if err != nil {
+		return false
+	}
+	// The header is small enough to be fully peeked, so only the kind and
+	// payload need to be split out of the envelope.
+	kind, rest, err := rlp.SplitUint64(content)
+	if err != nil || uint8(kind) != archiveKindHeader {
+		return false
+	}
+	_, _, tail, err := rlp.Split(rest)
+	if err != nil {
+		return false
+	}
+	payload := rest[:len(rest)-len(tail)]
+	var header ArchiveHeader
+	if err := rlp.DecodeBytes(payload, &header); err != nil {
+		return false
+	}
+	return header.Magic == ArchiveMagic
+}
+
+// ArchiveReader reads the records of a chain archive, verifying the checksum
+// of every record and the index closing every segment.
+type ArchiveReader struct {
+	stream  *rlp.Stream
+	header  *ArchiveHeader
+	entries []ArchiveIndexEntry
+}
+
+// NewArchiveReader creates a reader of the chain archive in r.
+func NewArchiveReader(r io.Reader) *ArchiveReader {
+	return &ArchiveReader{stream: rlp.NewStream(r, 0)}
+}
+
+// Header returns the header of the segment currently being read.
+func (ar *ArchiveReader) Header() *ArchiveHeader {
+	return ar.header
+}
+
+// Next returns the next record of the archive, or io.EOF once all of the
+// segments have been read.
+func (ar *ArchiveReader) Next() (*ArchiveRecord, error) {
+	for {
+		var item archiveItem
+		if err := ar.stream.Decode(&item); err == io.EOF {
+			if ar.header != nil {
+				return nil, fmt.Errorf("archive segment %d-%d has no index", ar.header.First, ar.header.Last)
+			}
+			return nil, io.EOF
+		} else if err != nil {
+			return nil, err
+		}
+		if crypto.Keccak256Hash(item.Payload) != item.Checksum {
+			return nil, errArchiveChecksum
+		}
+		switch item.Kind {
+		case archiveKindHeader:
+			if ar.header != nil {
+				return nil, fmt.Errorf("archive segment %d-%d has no index", ar.header.First, ar.header.Last)
+			}
+			header := new(ArchiveHeader)
+			if err := rlp.DecodeBytes(item.Payload, header); err != nil {
+				return nil, err
+			}
+			if header.Magic != ArchiveMagic {
+				return nil, errors.New("not a chain archive")
+			}
+			if header.Version > ArchiveVersion {
+				return nil, fmt.Errorf("unsupported archive version %d, expected at most %d", header.Version, ArchiveVersion)
+			}
+			if !header.Location.Equal(common.NodeLocation) {
+				return nil, fmt.Errorf("archive of location %v cannot be imported in %v", header.Location, common.NodeLocation)
+			}
+			ar.header, ar.entries = header, nil
+
+		case archiveKindRecord:
+			if ar.header == nil {
+				return nil, errors.New("archive record outside of a segment")
+			}
+			record := new(ArchiveRecord)
+			if err := rlp.DecodeBytes(item.Payload, record); err != nil {
+				return nil, err
+			}
+			if record.Block == nil {
+				return nil, errors.New("archive record has no block")
+			}
+			ar.entries = append(ar.entries, ArchiveIndexEntry{
+				Number:   record.Block.NumberU64(),
+				Hash:     record.Block.Hash(),
+				Checksum: item.Checksum,
+			})
+			return record, nil
+
+		case archiveKindIndex:
+			if ar.header == nil {
+				return nil, errors.New("archive index outside of a segment")
+			}
+			var index ArchiveIndex
+			if err := rlp.DecodeBytes(item.Payload, &index); err != nil {
+				return nil, err
+			}
+			if len(index.Entries) != len(ar.entries) || index.Root != archiveRoot(ar.entries) {
+				return nil, errArchiveIndex
+			}
+			for i, entry := range index.Entries {
+				if entry != ar.entries[i] {
+					return nil, errArchiveIndex
+				}
+			}
+			ar.header, ar.entries = nil, nil
+
+		default:
+			return nil, fmt.Errorf("unknown archive item kind %d", item.Kind)
+		}
+	}
+}
+
+// archiveRecord bundles a block with its hierarchy data from the database.
+func (hc *HeaderChain) archiveRecord(block *types.Block) (*ArchiveRecord, error) {
+	hash := block.Hash()
+	termini := hc.GetTerminiByHash(hash)
+	if termini == nil {
+		return nil, fmt.Errorf("export failed on #%d: termini not found", block.NumberU64())
+	}
+	record := &ArchiveRecord{
+		Block:       block,
+		InboundEtxs: rawdb.ReadInboundEtxs(hc.headerDb, hash),
+		Termini:     termini,
+		Manifest:    rawdb.ReadManifest(hc.headerDb, hash),
+	}
+	// The dom terminus a block was appended under is the one its parent
+	// handed down, the genesis block is never imported.
+	if hash != hc.config.GenesisHash {
+		parentTermini := hc.GetTerminiByHash(block.ParentHash())
+		if parentTermini == nil {
+			return nil, fmt.Errorf("export failed on #%d: parent termini not found", block.NumberU64())
+		}
+		record.DomTerminus = parentTermini.DomTerminus()
+	}
+	// The pending ETXs and rollups of the block and of its subordinate blocks
+	// are what a dom collects the newly confirmed ETXs from.
+	for _, h := range append(types.BlockManifest{hash}, block.SubManifest()...) {
+		if pEtxs := rawdb.ReadPendingEtxs(hc.headerDb, h); pEtxs != nil {
+			record.PendingEtxs = append(record.PendingEtxs, *pEtxs)
+		}
+		if rollup := rawdb.ReadPendingEtxsRollup(hc.headerDb, h); rollup != nil {
+			record.Rollups = append(record.Rollups, *rollup)
+		}
+	}
+	return record, nil
+}
+
+// ExportArchive writes a segment of the chain archive holding the canonical
+// blocks first to last.
+func (hc *HeaderChain) ExportArchive(w io.Writer, first uint64, last uint64) error {
+	hc.headermu.RLock()
+	defer hc.headermu.RUnlock()
+
+	if first > last {
+		return fmt.Errorf("export failed: first (%d) is greater than last (%d)", first, last)
+	}
+	log.Info("Exporting batch of blocks", "count", last-first+1)
+
+	aw := NewArchiveWriter(w)
+	header := ArchiveHeader{
+		Location:    common.NodeLocation,
+		GenesisHash: hc.config.GenesisHash,
+		First:       first,
+		Last:        last,
+	}
+	if err := aw.Begin(header); err != nil {
+		return err
+	}
+	start, reported := time.Now(), time.Now()
+	for nr := first; nr <= last; nr++ {
+		block := hc.GetBlockByNumber(nr)
+		if block == nil {
+			return fmt.Errorf("export failed on #%d: not found", nr)
+		}
+		record, err := hc.archiveRecord(block)
+		if err != nil {
+			return err
+		}
+		if err := aw.Write(record); err != nil {
+			return err
+		}
+		if time.Since(reported) >= statsReportLimit {
+			log.Info("Exporting blocks", "exported", block.NumberU64()-first, "elapsed", common.PrettyDuration(time.Since(start)))
+			reported = time.Now()
+		}
+	}
+	return aw.End()
+}
+
+// ImportArchiveRecord appends a block read from a chain archive. The bundled
+// hierarchy data stands in for what the dom and sub nodes would provide, and
+// is only stored once the block and the data have been validated against each
+// other. The checksums of an archive only prove its integrity, so the inbound
+// ETXs of a zone block coincident with the dom are checked against those the
+// dom handed down, which requires the dom to have imported the block first.
+// The pending header cache is left untouched, it catches up with the first
+// block appended afterwards.
+func (sl *Slice) ImportArchiveRecord(record *ArchiveRecord) (bool, error) {
+	nodeCtx := common.NodeLocation.Context()
+	block := record.Block
+	header := block.Header()
+
+	if block.Hash() == sl.config.GenesisHash {
+		return false, nil
+	}
+	if sl.hc.HasHeader(block.Hash(), block.NumberU64()) && sl.hc.GetTerminiByHash(block.Hash()) != nil {
+		return false, nil
+	}
+	if sl.IsBlockHashABadHash(block.Hash()) {
+		return false, ErrBadBlockHash
+	}
+	if !record.Termini.IsValid() {
+		return false, errors.New("archive record has invalid termini")
+	}
+	_, order, err := sl.engine.CalcOrder(header)
+	if err != nil {
+		return false, err
+	}
+	parentTermini := sl.hc.GetTerminiByHash(block.ParentHash())
+	if !parentTermini.IsValid() {
+		return false, consensus.ErrUnknownAncestor
+	}
+	if err := sl.hc.AppendHeader(header); err != nil {
+		return false, err
+	}
+	if err := sl.validator.ValidateBody(block); err != nil {
+		return false, err
+	}
+	if err := sl.verifyArchiveRecord(record, order); err != nil {
+		return false, err
+	}
+
+	// The dom terminus of the record stands in for the one the dom would hand
+	// down with a coincident block, which has to match the terminus of the
+	// parent if the graph has no cycles.
+	batch := sl.sliceDb.NewBatch()
+	_, newTermini, err := sl.pcrc(batch, header, record.DomTerminus, order < nodeCtx)
+	if err != nil {
+		return false, err
+	}
+	if !terminiEqual(newTermini, *record.Termini) {
+		return false, fmt.Errorf("archive termini of block %s do not match, have %v want %v", block.Hash(), record.Termini, newTermini)
+	}
+	// The manifest is needed by the children of the block and the pending ETXs
+	// and rollups by the dom blocks confirming them.
+	rawdb.WriteManifest(batch, block.Hash(), record.Manifest)
+	for _, pEtxs := range record.PendingEtxs {
+		rawdb.WritePendingEtxs(batch, pEtxs)
+	}
+	for _, rollup := range record.Rollups {
+		rawdb.WritePendingEtxsRollup(batch, rollup)
+	}
+	if nodeCtx == common.ZONE_CTX && order < nodeCtx {
+		rawdb.WriteInboundEtxs(batch, block.Hash(), record.InboundEtxs)
+	}
+	sl.WriteBlock(block)
+	if err := batch.Write(); err != nil {
+		return false, err
+	}
+	for _, pEtxs := range record.PendingEtxs {
+		sl.hc.pendingEtxs.Add(pEtxs.Header.Hash(), pEtxs)
+	}
+	for _, rollup := range record.Rollups {
+		sl.hc.pendingEtxsRollup.Add(rollup.Header.Hash(), rollup)
+	}
+
+	setHead := sl.poem(sl.engine.TotalLogS(header), sl.engine.TotalLogS(sl.hc.CurrentHeader()))
+	if setHead {
+		if err := sl.hc.SetCurrentState(header); err != nil {
+			return false, err
+		}
+		if err := sl.hc.SetCurrentHeader(header); err != nil {
+			return false, err
+		}
+	}
+	return setHead, nil
+}
+
+// verifyArchiveRecord checks the hierarchy data bundled with a block against
+// the block, which has been validated already. The manifest has to be the one
+// the block commits its children to, the pending ETXs and rollups have to
+// belong to the block or to one of its subordinate blocks and match the hashes
+// in their headers, and the inbound ETXs have to be the ones the dom handed
+// down.
+func (sl *Slice) verifyArchiveRecord(record *ArchiveRecord, order int) error {
+	nodeCtx := common.NodeLocation.Context()
+	block := record.Block
+
+	// Prime has no dom, so its manifests are empty
+	manifest := types.BlockManifest{}
+	if nodeCtx != common.PRIME_CTX {
+		if order < nodeCtx {
+			manifest = types.BlockManifest{block.Hash()}
+		} else {
+			manifest = append(rawdb.ReadManifest(sl.sliceDb, block.ParentHash()), block.Hash())
+		}
+	}
+	if types.DeriveSha(record.Manifest, trie.NewStackTrie(nil)) != types.DeriveSha(manifest, trie.NewStackTrie(nil)) {
+		return fmt.Errorf("archive manifest of block %s does not match", block.Hash())
+	}
+
+	referenced := make(map[common.Hash]bool)
+	for _, hash := range append(types.BlockManifest{block.Hash()}, block.SubManifest()...) {
+		referenced[hash] = true
+	}
+	for _, pEtxs := range record.PendingEtxs {
+		if !referenced[pEtxs.Header.Hash()] {
+			return fmt.Errorf("archive pending etxs of block %s are not referenced by block %s", pEtxs.Header.Hash(), block.Hash())
+		}
+		if !pEtxs.IsValid(trie.NewStackTrie(nil)) {
+			return ErrPendingEtxNotValid
+		}
+	}
+	for _, rollup := range record.Rollups {
+		if !referenced[rollup.Header.Hash()] {
+			return fmt.Errorf("archive pending etxs rollup of block %s is not referenced by block %s", rollup.Header.Hash(), block.Hash())
+		}
+		if !rollup.IsValid(trie.NewStackTrie(nil)) {
+			return ErrPendingEtxRollupNotValid
+		}
+	}
+
+	if nodeCtx != common.ZONE_CTX || order == nodeCtx {
+		if len(record.InboundEtxs) > 0 {
+			return fmt.Errorf("archive record of block %s has inbound etxs", block.Hash())
+		}
+		return nil
+	}
+	// Missing inbound ETXs would make the following blocks fail to apply, but
+	// forged ones would go unnoticed without asking the dom for them.
+	if sl.domClient == nil {
+		if len(record.InboundEtxs) > 0 {
+			return fmt.Errorf("inbound etxs of block %s cannot be verified without a dom", block.Hash())
+		}
+		return nil
+	}
+	domInboundEtxs, err := sl.domClient.GetInboundEtxs(context.Background(), block.Hash())
+	if err != nil {
+		return fmt.Errorf("unable to verify inbound etxs of block %s with the dom: %w", block.Hash(), err)
+	}
+	inboundEtxs := domInboundEtxs.FilterToLocation(common.NodeLocation)
+	if types.DeriveSha(record.InboundEtxs, trie.NewStackTrie(nil)) != types.DeriveSha(inboundEtxs, trie.NewStackTrie(nil)) {
+		return fmt.Errorf("archive inbound etxs of block %s do not match the dom", block.Hash())
+	}
+	return nil
+}
+
+// terminiEqual reports whether two termini reference the same blocks.
+func terminiEqual(a, b types.Termini) bool {
+	if len(a.DomTermini()) != len(b.DomTermini()) || len(a.SubTermini()) != len(b.SubTermini()) {
+		return false
+	}
+	for i, hash := range a.DomTermini() {
+		if b.DomTerminiAtIndex(i) != hash {
+			return false
+		}
+	}
+	for i, hash := range a.SubTermini() {
+		if b.SubTerminiAtIndex(i) != hash {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,222 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"math/big"
+	"testing"
+
+	"github.com/dominant-strategies/go-quai/common"
+	"github.com/dominant-strategies/go-quai/core/rawdb"
+	"github.com/dominant-strategies/go-quai/core/types"
+	"github.com/dominant-strategies/go-quai/rlp"
+)
+
+// makeArchiveRecords creates records of a chain of n empty blocks, each
+// referencing its parent as dom terminus.
+func makeArchiveRecords(first uint64, n int) []*ArchiveRecord {
+	records := make([]*ArchiveRecord, n)
+	parent := common.Hash{}
+	for i := range records {
+		header := types.EmptyHeader()
+		header.SetNumber(new(big.Int).SetUint64(first + uint64(i)))
+		header.SetParentHash(parent)
+		block := types.NewBlockWithHeader(header)
+
+		termini := types.EmptyTermini()
+		termini.SetDomTerminiAtIndex(block.Hash(), 0)
+		records[i] = &ArchiveRecord{
+			Block:       block,
+			InboundEtxs: types.Transactions{},
+			Termini:     &termini,
+			DomTerminus: parent,
+			Manifest:    types.BlockManifest{parent},
+		}
+		parent = block.Hash()
+	}
+	return records
+}
+
+// writeArchive writes the records as one segment per given range.
+func writeArchive(t *testing.T, w io.Writer, segments ...[]*ArchiveRecord) {
+	aw := NewArchiveWriter(w)
+	for _, records := range segments {
+		header := ArchiveHeader{
+			Location: common.NodeLocation,
+			First:    records[0].Block.NumberU64(),
+			Last:     records[len(records)-1].Block.NumberU64(),
+		}
+		if err := aw.Begin(header); err != nil {
+			t.Fatalf("failed to begin segment: %v", err)
+		}
+		for _, record := range records {
+			if err := aw.Write(record); err != nil {
+				t.Fatalf("failed to write record: %v", err)
+			}
+		}
+		if err := aw.End(); err != nil {
+			t.Fatalf("failed to end segment: %v", err)
+		}
+	}
+}
+
+// readArchive reads all of the records of an archive.
+func readArchive(r io.Reader) ([]*ArchiveRecord, error) {
+	var records []*ArchiveRecord
+	ar := NewArchiveReader(r)
+	for {
+		record, err := ar.Next()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return records, err
+		}
+		records = append(records, record)
+	}
+}
+
+// Tests that the records written to an archive of several segments are read
+// back unchanged.
+func TestArchiveRoundTrip(t *testing.T) {
+	records := makeArchiveRecords(1, 8)
+
+	var buf bytes.Buffer
+	writeArchive(t, &buf, records[:3], records[3:])
+	if !IsArchive(bufio.NewReader(bytes.NewReader(buf.Bytes()))) {
+		t.Fatalf("archive not detected")
+	}
+	read, err := readArchive(&buf)
+	if err != nil {
+		t.Fatalf("failed to read archive: %v", err)
+	}
+	if len(read) != len(records) {
+		t.Fatalf("record count mismatch: have %d, want %d", len(read), len(records))
+	}
+	for i, record := range read {
+		want, _ := rlp.EncodeToBytes(records[i])
+		have, _ := rlp.EncodeToBytes(record)
+		if !bytes.Equal(have, want) {
+			t.Errorf("record %d mismatch", i)
+		}
+		if record.Block.Hash() != records[i].Block.Hash() {
+			t.Errorf("record %d: block hash mismatch: have %x, want %x", i, record.Block.Hash(), records[i].Block.Hash())
+		}
+		if record.DomTerminus != records[i].DomTerminus {
+			t.Errorf("record %d: dom terminus mismatch: have %x, want %x", i, record.DomTerminus, records[i].DomTerminus)
+		}
+	}
+}
+
+// Tests that a plain stream of RLP encoded blocks, as written by ExportN, is
+// not mistaken for an archive and can still be appended to.
+func TestArchivePlainExport(t *testing.T) {
+	records := makeArchiveRecords(0, 4)
+
+	var buf bytes.Buffer
+	for _, record := range records {
+		if err := record.Block.EncodeRLP(&buf); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if IsArchive(bufio.NewReader(bytes.NewReader(buf.Bytes()))) {
+		t.Fatalf("plain export detected as an archive")
+	}
+	stream := rlp.NewStream(&buf, 0)
+	for i := range records {
+		var block types.Block
+		if err := stream.Decode(&block); err != nil {
+			t.Fatalf("block %d: failed to decode: %v", i, err)
+		}
+		if block.Hash() != records[i].Block.Hash() {
+			t.Errorf("block %d: hash mismatch", i)
+		}
+	}
+}
+
+// Tests that corrupted and truncated archives are rejected.
+func TestArchiveCorruption(t *testing.T) {
+	records := makeArchiveRecords(1, 4)
+
+	var buf bytes.Buffer
+	writeArchive(t, &buf, records)
+	data := buf.Bytes()
+
+	// Flip a byte within the payload of the second record
+	var offset int
+	content := data
+	for i := 0; i < 2; i++ {
+		_, _, rest, err := rlp.Split(content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		offset += len(content) - len(rest)
+		content = rest
+	}
+	corrupted := common.CopyBytes(data)
+	corrupted[offset+16] ^= 0xff
+	if _, err := readArchive(bytes.NewReader(corrupted)); err == nil {
+		t.Errorf("corrupted archive accepted")
+	}
+
+	// Drop the index closing the segment
+	_, _, tail, err := rlp.Split(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range records[2:] {
+		if _, _, tail, err = rlp.Split(tail); err != nil {
+			t.Fatal(err)
+		}
+	}
+	truncated := data[:len(data)-len(tail)]
+	if _, err := readArchive(bytes.NewReader(truncated)); err == nil {
+		t.Errorf("archive without index accepted")
+	}
+}
+
+// Tests that the hierarchy data of an archive record is checked against its
+// block before anything is imported.
+func TestVerifyArchiveRecord(t *testing.T) {
+	defer func(loc common.Location) { common.NodeLocation = loc }(common.NodeLocation)
+	common.NodeLocation = common.Location{0, 0}
+
+	sl := &Slice{sliceDb: rawdb.NewMemoryDatabase()}
+	parent := common.Hash{0x01}
+	rawdb.WriteManifest(sl.sliceDb, parent, types.BlockManifest{parent})
+
+	header := types.EmptyHeader()
+	header.SetNumber(big.NewInt(1))
+	header.SetParentHash(parent)
+	block := types.NewBlockWithHeader(header)
+	hash := block.Hash()
+
+	other := types.EmptyHeader()
+	other.SetNumber(big.NewInt(2))
+	to := common.HexToAddress("0x0000000000000000000000000000000000000001")
+	etx := types.NewTx(&types.ExternalTx{ChainID: big.NewInt(1), Gas: 21000, To: &to, Value: big.NewInt(1)})
+
+	tests := []struct {
+		name   string
+		order  int
+		record ArchiveRecord
+		valid  bool
+	}{
+		{"valid", common.ZONE_CTX, ArchiveRecord{Manifest: types.BlockManifest{parent, hash}, PendingEtxs: []types.PendingEtxs{{Header: header, Etxs: types.Transactions{}}}}, true},
+		{"manifest mismatch", common.ZONE_CTX, ArchiveRecord{Manifest: types.BlockManifest{hash}}, false},
+		{"foreign pending etxs", common.ZONE_CTX, ArchiveRecord{Manifest: types.BlockManifest{parent, hash}, PendingEtxs: []types.PendingEtxs{{Header: other, Etxs: types.Transactions{}}}}, false},
+		{"forged pending etxs", common.ZONE_CTX, ArchiveRecord{Manifest: types.BlockManifest{parent, hash}, PendingEtxs: []types.PendingEtxs{{Header: header, Etxs: types.Transactions{etx}}}}, false},
+		{"foreign rollup", common.ZONE_CTX, ArchiveRecord{Manifest: types.BlockManifest{parent, hash}, Rollups: []types.PendingEtxsRollup{{Header: other, Manifest: types.BlockManifest{}}}}, false},
+		{"inbound etxs of zone block", common.ZONE_CTX, ArchiveRecord{Manifest: types.BlockManifest{parent, hash}, InboundEtxs: types.Transactions{etx}}, false},
+		{"coincident without inbound etxs", common.REGION_CTX, ArchiveRecord{Manifest: types.BlockManifest{hash}}, true},
+		{"unverifiable inbound etxs", common.REGION_CTX, ArchiveRecord{Manifest: types.BlockManifest{hash}, InboundEtxs: types.Transactions{etx}}, false},
+	}
+	for _, tt := range tests {
+		record := tt.record
+		record.Block = block
+		if err := sl.verifyArchiveRecord(&record, tt.order); (err == nil) != tt.valid {
+			t.Errorf("%s: validity mismatch: have %v, want valid %v", tt.name, err, tt.valid)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dominant-strategies/go-quai/common"
+	"github.com/dominant-strategies/go-quai/core/rawdb"
+	"github.com/dominant-strategies/go-quai/core/state"
+	"github.com/dominant-strategies/go-quai/core/types"
+	"github.com/dominant-strategies/go-quai/log"
+	"github.com/dominant-strategies/go-quai/trie"
+)
+
+// TxMismatch describes a difference between a stored receipt and the receipt
+// produced by re-executing its transaction.
+type TxMismatch struct {
+	Index  int
+	Hash   common.Hash
+	Field  string
+	Remote string
+	Local  string
+}
+
+// BlockMismatch describes the first divergence found while re-executing a
+// stored block. Remote values are taken from the stored header and Local
+// values from the re-execution.
+type BlockMismatch struct {
+	Number uint64
+	Hash   common.Hash
+	Field  string
+	Remote string
+	Local  string
+	Txs    []TxMismatch
+}
+
+func (m *BlockMismatch) String() string {
+	return fmt.Sprintf("block %d [%x] invalid %s (remote: %s local: %s)", m.Number, m.Hash, m.Field, m.Remote, m.Local)
+}
+
+// VerifyChain re-executes the canonical blocks in the range [from, to] with
+// their stored EtxSets and compares the state root, receipt root, etx root and
+// gas used against each header. The starting state is obtained through
+// StateAtBlock, re-executing at most reexec blocks if it is not available.
+// Nothing is written to the database.
+//
+// The returned mismatch is nil if every block in the range verified, and
+// otherwise describes the first failing block along with a per-transaction
+// diff of its receipts.
+func (p *StateProcessor) VerifyChain(from, to uint64, reexec uint64) (*BlockMismatch, error) {
+	if from == 0 {
+		from = 1 // The genesis block has no transactions to execute
+	}
+	if from > to {
+		return nil, fmt.Errorf("invalid range: first block %d is above last block %d", from, to)
+	}
+	parent := p.hc.GetBlockByNumber(from - 1)
+	if parent == nil {
+		return nil, fmt.Errorf("block %d not found", from-1)
+	}
+	statedb, err := p.StateAtBlock(parent, reexec, nil, true)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		database = statedb.Database()
+		prevRoot common.Hash
+		start    = time.Now()
+		logged   = time.Now()
+	)
+	for number := from; number <= to; number++ {
+		block := p.hc.GetBlockByNumber(number)
+		if block == nil {
+			return nil, fmt.Errorf("block %d not found", number)
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Verifying chain", "number", number, "hash", block.Hash(), "remaining", to-number, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+		etxSet := rawdb.ReadEtxSet(p.hc.bc.db, block.ParentHash(), number-1)
+		if etxSet == nil {
+			return nil, errors.New("failed to load etx set")
+		}
+		etxSet.Update(rawdb.ReadInboundEtxs(p.hc.bc.db, block.Hash()), number)
+
+		receipts, _, statedb, usedGas, err := p.processWithState(block, parent, etxSet, statedb)
+		if err != nil {
+			return &BlockMismatch{Number: number, Hash: block.Hash(), Field: "execution", Remote: "success", Local: err.Error()}, nil
+		}
+		if mismatch := p.compareBlock(block, statedb, receipts, usedGas); mismatch != nil {
+			return mismatch, nil
+		}
+		// Commit the state so the next block can be applied on top of it
+		root, err := statedb.Commit(true)
+		if err != nil {
+			return nil, fmt.Errorf("state commit failed, number %d root %v: %w", number, block.Root().Hex(), err)
+		}
+		database.TrieDB().Reference(root, common.Hash{})
+		if prevRoot != (common.Hash{}) {
+			database.TrieDB().Dereference(prevRoot)
+		}
+		prevRoot = root
+		if statedb, err = state.New(root, database, nil); err != nil {
+			return nil, fmt.Errorf("state reset after block %d failed: %v", number, err)
+		}
+		parent = block
+	}
+	if prevRoot != (common.Hash{}) {
+		database.TrieDB().Dereference(prevRoot)
+	}
+	log.Info("Chain verified", "first", from, "last", to, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil, nil
+}
+
+// compareBlock checks the results of executing a block against its header,
+// performing the same checks as ValidateState.
+func (p *StateProcessor) compareBlock(block *types.Block, statedb *state.StateDB, receipts types.Receipts, usedGas uint64) *BlockMismatch {
+	header := block.Header()
+	mismatch := &BlockMismatch{Number: block.NumberU64(), Hash: block.Hash()}
+	if header.GasUsed() != usedGas {
+		mismatch.Field, mismatch.Remote, mismatch.Local = "gas used", fmt.Sprint(header.GasUsed()), fmt.Sprint(usedGas)
+	} else if receiptSha := types.DeriveSha(receipts, trie.NewStackTrie(nil)); receiptSha != header.ReceiptHash() {
+		mismatch.Field, mismatch.Remote, mismatch.Local = "receipt root", header.ReceiptHash().Hex(), receiptSha.Hex()
+	} else if root := statedb.IntermediateRoot(true); root != header.Root() {
+		mismatch.Field, mismatch.Remote, mismatch.Local = "state root", header.Root().Hex(), root.Hex()
+	} else if etxHash := types.DeriveSha(emittedEtxs(receipts), trie.NewStackTrie(nil)); etxHash != header.EtxHash() {
+		mismatch.Field, mismatch.Remote, mismatch.Local = "etx root", header.EtxHash().Hex(), etxHash.Hex()
+	} else {
+		return nil
+	}
+	stored := rawdb.ReadReceipts(p.hc.bc.db, block.Hash(), block.NumberU64(), p.config)
+	mismatch.Txs = diffReceipts(block.Transactions(), stored, receipts)
+	return mismatch
+}
+
+// emittedEtxs collects the ETXs emitted by each successful transaction and
+// the refunds of failed ETXs.
+func emittedEtxs(receipts types.Receipts) types.Transactions {
+	var etxs types.Transactions
+	for _, receipt := range receipts {
+		etxs = append(etxs, receipt.EmittedEtxs()...)
+	}
+	return etxs
+}
+
+// diffReceipts returns every field in which the stored receipts differ from
+// the locally computed ones.
+func diffReceipts(txs types.Transactions, stored, local types.Receipts) []TxMismatch {
+	var diffs []TxMismatch
+	if stored == nil {
+		return []TxMismatch{{Index: -1, Field: "receipts", Remote: "missing", Local: fmt.Sprint(len(local))}}
+	}
+	if len(stored) != len(local) {
+		diffs = append(diffs, TxMismatch{Index: -1, Field: "receipt count", Remote: fmt.Sprint(len(stored)), Local: fmt.Sprint(len(local))})
+	}
+	for i := 0; i < len(stored) && i < len(local) && i < len(txs); i++ {
+		have, want := local[i], stored[i]
+		add := func(field string, remote, local interface{}) {
+			diffs = append(diffs, TxMismatch{Index: i, Hash: txs[i].Hash(), Field: field, Remote: fmt.Sprint(remote), Local: fmt.Sprint(local)})
+		}
+		if have.Status != want.Status {
+			add("status", want.Status, have.Status)
+		}
+		if have.GasUsed != want.GasUsed {
+			add("gas used", want.GasUsed, have.GasUsed)
+		}
+		if have.CumulativeGasUsed != want.CumulativeGasUsed {
+			add("cumulative gas used", want.CumulativeGasUsed, have.CumulativeGasUsed)
+		}
+		if len(have.Logs) != len(want.Logs) {
+			add("logs", len(want.Logs), len(have.Logs))
+		}
+		if have.Bloom != want.Bloom {
+			add("bloom", want.Bloom.Big().Text(16), have.Bloom.Big().Text(16))
+		}
+		if len(have.Etxs) != len(want.Etxs) {
+			add("etxs", len(want.Etxs), len(have.Etxs))
+		} else {
+			for j := range have.Etxs {
+				if have.Etxs[j].Hash() != want.Etxs[j].Hash() {
+					add(fmt.Sprintf("etx %d", j), want.Etxs[j].Hash().Hex(), have.Etxs[j].Hash().Hex())
+				}
+			}
+		}
+	}
+	return diffs
+}
@@ -0,0 +1,112 @@
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/dominant-strategies/go-quai/common"
+	"github.com/dominant-strategies/go-quai/consensus"
+	"github.com/dominant-strategies/go-quai/core/rawdb"
+	"github.com/dominant-strategies/go-quai/core/state"
+	"github.com/dominant-strategies/go-quai/core/types"
+	"github.com/dominant-strategies/go-quai/params"
+	"github.com/dominant-strategies/go-quai/trie"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// plainFinalizer is a consensus engine finalizing blocks without rewards, and
+// without touching their headers like the real engines.
+type plainFinalizer struct {
+	rootFinalizer
+}
+
+func (plainFinalizer) Finalize(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header) {
+}
+
+// Tests that re-executing stored blocks verifies a consistent chain, and
+// reports the first field in which a block diverges along with the receipts of
+// its transactions which differ from the stored ones.
+func TestVerifyChain(t *testing.T) {
+	defer func(loc common.Location) { common.NodeLocation = loc }(common.NodeLocation)
+	common.NodeLocation = common.Location{0, 0}
+
+	engine := plainFinalizer{}
+	hc := newTestHeaderChain()
+	hc.engine = engine
+	hc.bc.blockCache, _ = lru.New(16)
+	p := &StateProcessor{config: policyConfig, hc: hc, engine: engine, stateCache: state.NewDatabase(hc.headerDb), cacheConfig: &CacheConfig{}}
+
+	key, addr := newScopedKey(t)
+	internal, _ := addr.InternalAddress()
+	genesisState, _ := state.New(common.Hash{}, p.stateCache, nil)
+	genesisState.AddBalance(internal, big.NewInt(params.Ether))
+	root, err := genesisState.Commit(true)
+	if err != nil {
+		t.Fatalf("failed to commit genesis state: %v", err)
+	}
+	genesisHeader := types.EmptyHeader()
+	genesisHeader.SetRoot(root)
+	genesis := types.NewBlockWithHeader(genesisHeader)
+	writeCanonicalBlock := func(block *types.Block, receipts types.Receipts) {
+		rawdb.WriteBlock(hc.headerDb, block)
+		rawdb.WriteTermini(hc.headerDb, block.Hash(), types.EmptyTermini())
+		rawdb.WriteCanonicalHash(hc.headerDb, block.Hash(), block.NumberU64())
+		rawdb.WriteReceipts(hc.headerDb, block.Hash(), block.NumberU64(), receipts)
+	}
+	writeCanonicalBlock(genesis, nil)
+	rawdb.WriteEtxSet(hc.headerDb, genesis.Hash(), 0, types.NewEtxSet())
+
+	// Execute the block once to fill in the results its header commits to
+	_, coinbase := newScopedKey(t)
+	header := types.EmptyHeader()
+	header.SetParentHash(genesis.Hash())
+	header.SetNumber(big.NewInt(1))
+	header.SetGasLimit(params.GenesisGasLimit)
+	header.SetBaseFee(policyBaseFee)
+	header.SetCoinbase(coinbase)
+	header.SetLocation(common.NodeLocation)
+	txs := types.Transactions{policyTx(t, key, 0, 1)}
+
+	statedb, err := p.StateAt(root)
+	if err != nil {
+		t.Fatalf("failed to open genesis state: %v", err)
+	}
+	receipts, _, statedb, usedGas, err := p.processWithState(types.NewBlockWithHeader(header).WithBody(txs, nil, nil, nil), genesis, types.NewEtxSet(), statedb)
+	if err != nil {
+		t.Fatalf("failed to process block: %v", err)
+	}
+	header.SetRoot(statedb.IntermediateRoot(true))
+	header.SetGasUsed(usedGas)
+	header.SetReceiptHash(types.DeriveSha(receipts, trie.NewStackTrie(nil)))
+	header.SetEtxHash(types.EmptyRootHash)
+
+	block := types.NewBlockWithHeader(header).WithBody(txs, nil, nil, nil)
+	writeCanonicalBlock(block, receipts)
+	if mismatch, err := p.VerifyChain(0, 1, 0); err != nil || mismatch != nil {
+		t.Fatalf("consistent chain failed to verify: mismatch %v, err %v", mismatch, err)
+	}
+	if _, err := p.VerifyChain(1, 2, 0); err == nil {
+		t.Errorf("missing block verified")
+	}
+	if _, err := p.VerifyChain(2, 1, 0); err == nil {
+		t.Errorf("inverted range verified")
+	}
+
+	// A block whose header and stored receipts diverge from the re-execution
+	failed := types.CopyHeader(header)
+	failed.SetRoot(common.Hash{0x01})
+	stored := types.Receipts{{Status: types.ReceiptStatusFailed, CumulativeGasUsed: usedGas, GasUsed: usedGas, TxHash: txs[0].Hash(), Logs: []*types.Log{}}}
+	block = types.NewBlockWithHeader(failed).WithBody(txs, nil, nil, nil)
+	writeCanonicalBlock(block, stored)
+
+	mismatch, err := p.VerifyChain(1, 1, 0)
+	if err != nil {
+		t.Fatalf("failed to verify chain: %v", err)
+	}
+	if mismatch == nil || mismatch.Number != 1 || mismatch.Hash != block.Hash() || mismatch.Field != "state root" {
+		t.Fatalf("state root mismatch not reported: %v", mismatch)
+	}
+	if len(mismatch.Txs) != 1 || mismatch.Txs[0].Index != 0 || mismatch.Txs[0].Hash != txs[0].Hash() || mismatch.Txs[0].Field != "status" {
+		t.Errorf("receipt diff mismatch: have %+v", mismatch.Txs)
+	}
+}
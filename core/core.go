@@ -3,6 +3,7 @@ package core
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"math/big"
 	"sort"
@@ -23,6 +24,7 @@ import (
 	"github.com/dominant-strategies/go-quai/event"
 	"github.com/dominant-strategies/go-quai/log"
 	"github.com/dominant-strategies/go-quai/params"
+	"github.com/dominant-strategies/go-quai/quaiclient"
 	"github.com/dominant-strategies/go-quai/rlp"
 	"github.com/dominant-strategies/go-quai/trie"
 	lru "github.com/hnlq715/golang-lru"
@@ -491,6 +493,14 @@ func (c *Core) SubscribeMissingBlockEvent(ch chan<- types.BlockRequest) event.Su
 	return c.sl.SubscribeMissingBlockEvent(ch)
 }
 
+func (c *Core) SubscribeMissingManifestEvent(ch chan<- types.BlockRequest) event.Subscription {
+	return c.sl.SubscribeMissingManifestEvent(ch)
+}
+
+func (c *Core) SubscribeMissingPendingEtxsEvent(ch chan<- types.BlockRequest) event.Subscription {
+	return c.sl.SubscribeMissingPendingEtxsEvent(ch)
+}
+
 // InsertChainWithoutSealVerification works exactly the same
 // except for seal verification, seal verification is omitted
 func (c *Core) InsertChainWithoutSealVerification(block *types.Block) (int, error) {
@@ -539,6 +549,12 @@ func (c *Core) WriteBlock(block *types.Block) {
 	if c.sl.IsBlockHashABadHash(block.Hash()) {
 		return
 	}
+	if c.LightMode() {
+		if _, err := c.InsertHeaderChain([]*types.Header{block.Header()}); err != nil {
+			log.Debug("Failed to append light header", "hash", block.Hash(), "err", err)
+		}
+		return
+	}
 	if c.GetHeaderByHash(block.Hash()) == nil {
 		// Only add non dom blocks to the append queue
 		_, order, err := c.CalcOrder(block.Header())
@@ -570,6 +586,59 @@ func (c *Core) WriteBlock(block *types.Block) {
 	}
 }
 
+// SetLightMode switches the core to follow the chain by headers only. Bodies
+// are never appended, manifests and pending ETXs are fetched from full peers
+// when they are asked for.
+func (c *Core) SetLightMode() {
+	c.sl.hc.SetLightMode()
+}
+
+// LightMode returns true if the core only follows headers.
+func (c *Core) LightMode() bool {
+	return c.sl.hc.LightMode()
+}
+
+// InsertHeaderChain appends a batch of headers, ordered by number, to a light
+// chain. It returns the number of headers appended before an error occurred.
+func (c *Core) InsertHeaderChain(headers []*types.Header) (int, error) {
+	for i, header := range headers {
+		if c.sl.IsBlockHashABadHash(header.Hash()) {
+			return i, ErrBadBlockHash
+		}
+		if err := c.sl.hc.AppendLightHeader(header); err != nil {
+			return i, err
+		}
+	}
+	return len(headers), nil
+}
+
+// AddManifest verifies a manifest received from a full peer and writes it.
+func (c *Core) AddManifest(blockHash common.Hash, manifest types.BlockManifest) error {
+	if err := c.sl.hc.VerifyManifest(blockHash, manifest); err != nil {
+		return err
+	}
+	rawdb.WriteManifest(c.sl.sliceDb, blockHash, manifest)
+	return nil
+}
+
+// AddLightPendingEtxs verifies pending ETXs received by a light chain from a
+// full peer and writes them.
+func (c *Core) AddLightPendingEtxs(pEtxs types.PendingEtxs) error {
+	if err := c.sl.hc.VerifyPendingEtxsProof(pEtxs); err != nil {
+		return err
+	}
+	if err := c.sl.hc.AddPendingEtxs(pEtxs); err != nil && err != ErrPendingEtxAlreadyKnown {
+		return err
+	}
+	return nil
+}
+
+// requestFromFullPeers asks the full peers for data of a light chain, only
+// peers ahead of our current head are asked.
+func (c *Core) requestFromFullPeers(feed *event.Feed, hash common.Hash) {
+	feed.Send(types.BlockRequest{Hash: hash, Entropy: c.CurrentLogEntropy()})
+}
+
 func (c *Core) Append(header *types.Header, manifest types.BlockManifest, domPendingHeader *types.Header, domTerminus common.Hash, domOrigin bool, newInboundEtxs types.Transactions) (types.Transactions, bool, bool, error) {
 	newPendingEtxs, subReorg, setHead, err := c.sl.Append(header, domPendingHeader, domTerminus, domOrigin, newInboundEtxs)
 	if err != nil {
@@ -642,8 +711,28 @@ func (c *Core) GetPendingHeader() (*types.Header, error) {
 	return c.sl.GetPendingHeader()
 }
 
+func (c *Core) LinkStatus() []quaiclient.LinkStatus {
+	return c.sl.LinkStatus()
+}
+
+func (c *Core) PendingHeaders() (map[common.Hash]types.PendingHeader, common.Hash) {
+	return c.sl.PendingHeaders()
+}
+
+func (c *Core) PrunePendingHeaders() int {
+	return c.sl.PrunePendingHeaders()
+}
+
 func (c *Core) GetManifest(blockHash common.Hash) (types.BlockManifest, error) {
-	return c.sl.GetManifest(blockHash)
+	manifest, err := c.sl.GetManifest(blockHash)
+	if err != nil && c.LightMode() {
+		c.requestFromFullPeers(&c.sl.missingManifestFeed, blockHash)
+	}
+	return manifest, err
+}
+
+func (c *Core) GetInboundEtxs(blockHash common.Hash) (types.Transactions, error) {
+	return c.sl.GetInboundEtxs(blockHash)
 }
 
 func (c *Core) GetSubManifest(slice common.Location, blockHash common.Hash) (types.BlockManifest, error) {
@@ -651,7 +740,12 @@ func (c *Core) GetSubManifest(slice common.Location, blockHash common.Hash) (typ
 }
 
 func (c *Core) GetPendingEtxs(hash common.Hash) *types.PendingEtxs {
-	return rawdb.ReadPendingEtxs(c.sl.sliceDb, hash)
+	pEtxs := rawdb.ReadPendingEtxs(c.sl.sliceDb, hash)
+	if pEtxs == nil && c.LightMode() {
+		c.sl.hc.RequestLightPendingEtxs(hash)
+		c.requestFromFullPeers(&c.sl.missingPendingEtxFeed, hash)
+	}
+	return pEtxs
 }
 
 func (c *Core) GetPendingEtxsRollup(hash common.Hash) *types.PendingEtxsRollup {
@@ -882,6 +976,26 @@ func (c *Core) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscription {
 	return c.sl.hc.bc.SubscribeLogsEvent(ch)
 }
 
+// SubscribeInboundEtxsEvent registers a subscription of InboundEtxsEvent.
+func (c *Core) SubscribeInboundEtxsEvent(ch chan<- InboundEtxsEvent) event.Subscription {
+	return c.sl.hc.bc.SubscribeInboundEtxsEvent(ch)
+}
+
+// SubscribeCoincidentBlockEvent registers a subscription of CoincidentBlockEvent.
+func (c *Core) SubscribeCoincidentBlockEvent(ch chan<- CoincidentBlockEvent) event.Subscription {
+	return c.sl.SubscribeCoincidentBlockEvent(ch)
+}
+
+// SubscribeSubReorgEvent registers a subscription of SubReorgEvent.
+func (c *Core) SubscribeSubReorgEvent(ch chan<- SubReorgEvent) event.Subscription {
+	return c.sl.SubscribeSubReorgEvent(ch)
+}
+
+// SubscribeExpiredEtxsEvent registers a subscription of ExpiredEtxsEvent.
+func (c *Core) SubscribeExpiredEtxsEvent(ch chan<- ExpiredEtxsEvent) event.Subscription {
+	return c.sl.hc.bc.SubscribeExpiredEtxsEvent(ch)
+}
+
 // SubscribeBlockProcessingEvent registers a subscription of bool where true means
 // block processing has started while false means it has stopped.
 func (c *Core) SubscribeBlockProcessingEvent(ch chan<- bool) event.Subscription {
@@ -898,6 +1012,38 @@ func (c *Core) ExportN(w io.Writer, first uint64, last uint64) error {
 	return c.sl.hc.ExportN(w, first, last)
 }
 
+// ExportArchive writes a subset of the active chain to the given writer as a
+// segment of a chain archive, bundling the hierarchy data of every block.
+func (c *Core) ExportArchive(w io.Writer, first uint64, last uint64) error {
+	return c.sl.hc.ExportArchive(w, first, last)
+}
+
+// ImportArchive appends the blocks of a chain archive, using the hierarchy
+// data bundled with every block instead of the dom and sub nodes. It returns
+// the number of blocks appended.
+func (c *Core) ImportArchive(r io.Reader, interrupt func() bool) (int, error) {
+	reader := NewArchiveReader(r)
+	appended := 0
+	for {
+		if interrupt != nil && interrupt() {
+			return appended, errors.New("interrupted")
+		}
+		record, err := reader.Next()
+		if err == io.EOF {
+			return appended, nil
+		} else if err != nil {
+			return appended, fmt.Errorf("at block %d: %v", appended, err)
+		}
+		if genesis := reader.Header().GenesisHash; genesis != c.sl.config.GenesisHash {
+			return appended, fmt.Errorf("archive genesis %s does not match %s", genesis, c.sl.config.GenesisHash)
+		}
+		if _, err := c.sl.ImportArchiveRecord(record); err != nil {
+			return appended, fmt.Errorf("invalid block #%d %s: %v", record.Block.NumberU64(), record.Block.Hash(), err)
+		}
+		appended++
+	}
+}
+
 // Snapshots returns the blockchain snapshot tree.
 func (c *Core) Snapshots() *snapshot.Tree {
 	return nil
@@ -939,6 +1085,22 @@ func (c *Core) SetGasCeil(ceil uint64) {
 	c.sl.miner.SetGasCeil(ceil)
 }
 
+// SetTemplatePolicy sets the policy selecting and ordering the transactions
+// of mined blocks.
+func (c *Core) SetTemplatePolicy(name string, allowlist []common.Address) error {
+	return c.sl.miner.SetTemplatePolicy(name, allowlist)
+}
+
+// SetExcludedSenders sets the senders whose internal transactions are never mined.
+func (c *Core) SetExcludedSenders(senders []common.Address) {
+	c.sl.miner.SetExcludedSenders(senders)
+}
+
+// SetEtxReserve sets the gas of each mined block reserved for ETXs.
+func (c *Core) SetEtxReserve(gas uint64) {
+	c.sl.miner.SetEtxReserve(gas)
+}
+
 // EnablePreseal turns on the preseal mining feature. It's enabled by default.
 // Note this function shouldn't be exposed to API, it's unnecessary for users
 // (miners) to actually know the underlying detail. It's only for outside project
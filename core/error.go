@@ -73,6 +73,12 @@ var (
 
 	// ErrPendingHeaderNotInCache is returned when a coord gives an update but the slice has not yet created the referenced ph
 	ErrPendingHeaderNotInCache = errors.New("no pending header found in cache")
+
+	// ErrLightZone is returned when a zone chain is asked to run in light mode
+	ErrLightZone = errors.New("light mode is only supported in prime and region")
+
+	// ErrManifestNotVerifiable is returned when a manifest arrives for a block without a known child committing to it
+	ErrManifestNotVerifiable = errors.New("no child header to verify the manifest against")
 )
 
 // List of evm-call-message pre-checking errors. All state transition messages will
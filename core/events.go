@@ -41,3 +41,35 @@ type ChainSideEvent struct {
 }
 
 type ChainHeadEvent struct{ Block *types.Block }
+
+// ExpiredEtxsEvent is posted when a block removing ETXs from the EtxSet without
+// mining them becomes canonical, or is rolled back in a reorg.
+type ExpiredEtxsEvent struct {
+	Block   *types.Block
+	Etxs    []*types.ExpiredEtx
+	Removed bool // The block was rolled back, the ETXs did not expire after all
+}
+
+// InboundEtxsEvent is posted when a block adds ETXs from other chains to the
+// EtxSet.
+type InboundEtxsEvent struct {
+	Block *types.Block
+	Etxs  types.Transactions
+}
+
+// CoincidentBlockEvent is posted when an appended block is coincident with a
+// block of a dominant chain of the node. Order is the order of the block given
+// by CalcOrder.
+type CoincidentBlockEvent struct {
+	Block *types.Block
+	Order int
+}
+
+// SubReorgEvent is posted when an appended block changes the best pending
+// header of the slice, i.e. the terminus mining builds upon.
+type SubReorgEvent struct {
+	Block    *types.Block
+	Order    int
+	Terminus common.Hash
+	SetHead  bool
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/dominant-strategies/go-quai/consensus"
 	"github.com/dominant-strategies/go-quai/core/types"
 	"github.com/dominant-strategies/go-quai/core/vm"
+	"github.com/dominant-strategies/go-quai/params"
 )
 
 // ChainContext supports retrieving headers and consensus parameters from the
@@ -52,19 +53,23 @@ func NewEVMBlockContext(header *types.Header, chain ChainContext, author *common
 		baseFee = new(big.Int).Set(header.BaseFee())
 	}
 	return vm.BlockContext{
-		CanTransfer: CanTransfer,
-		Transfer:    Transfer,
-		GetHash:     GetHashFn(header, chain),
-		Coinbase:    beneficiary,
-		BlockNumber: new(big.Int).Set(header.Number()),
-		Time:        new(big.Int).SetUint64(header.Time()),
-		Difficulty:  new(big.Int).Set(header.Difficulty()),
-		BaseFee:     baseFee,
-		GasLimit:    header.GasLimit(),
+		CanTransfer:  CanTransfer,
+		Transfer:     Transfer,
+		GetHash:      GetHashFn(header, chain),
+		GetDomParent: GetDomParentFn(header, chain),
+		Coinbase:     beneficiary,
+		BlockNumber:  new(big.Int).Set(header.Number()),
+		Time:         new(big.Int).SetUint64(header.Time()),
+		Difficulty:   new(big.Int).Set(header.Difficulty()),
+		BaseFee:      baseFee,
+		GasLimit:     header.GasLimit(),
+		ParentHash:   header.ParentHash(),
 	}
 }
 
 // NewEVMTxContext creates a new transaction context for a single transaction.
+// The ETX limits default to the minimum every block allows, block processing
+// replaces them with the remaining limits of the block.
 func NewEVMTxContext(msg Message) vm.TxContext {
 	return vm.TxContext{
 		Origin:        msg.From(),
@@ -77,9 +82,76 @@ func NewEVMTxContext(msg Message) vm.TxContext {
 		TXGasTip:      msg.GasTipCap(),
 		ETXData:       msg.ETXData(),
 		ETXAccessList: msg.ETXAccessList(),
+		ETXRLimit:     params.ETXRLimitMin,
+		ETXPLimit:     params.ETXPLimitMin,
 	}
 }
 
+// terminiReader is implemented by the chains keeping the termini of their
+// blocks, through which the dominant parents of a block are found.
+type terminiReader interface {
+	GetHeaderByHash(hash common.Hash) *types.Header
+	GetTerminiByHash(hash common.Hash) *types.Termini
+}
+
+// GetDomParentFn returns a GetDomParentFunc which looks up the latest blocks
+// coincident with the dominant contexts among the ancestors of the header. The
+// lookup only depends on blocks appended before the header, so unlike the
+// dominant parents in the header itself it is known when the transactions of
+// the header are executed.
+func GetDomParentFn(ref *types.Header, chain ChainContext) func(ctx int) (common.Hash, *big.Int) {
+	var parents []*types.Header
+
+	return func(ctx int) (common.Hash, *big.Int) {
+		nodeCtx := common.NodeLocation.Context()
+		if ctx < 0 || ctx >= nodeCtx {
+			return common.Hash{}, nil
+		}
+		// Look up the parents of all contexts at once, the walk is shared
+		if parents == nil {
+			parents = domParents(ref, chain, nodeCtx)
+		}
+		if parent := parents[ctx]; parent != nil {
+			return parent.Hash(), new(big.Int).Set(parent.Number(ctx))
+		}
+		return common.Hash{}, nil
+	}
+}
+
+// domParents returns the latest block coincident with each dominant context
+// among the ancestors of the header. The dom terminus of the parent is the
+// latest block coincident with the dom of the chain, and stepping back through
+// the dom termini of its ancestors reaches the blocks coincident with the
+// higher contexts.
+func domParents(ref *types.Header, chain ChainContext, nodeCtx int) []*types.Header {
+	parents := make([]*types.Header, nodeCtx)
+	reader, ok := chain.(terminiReader)
+	if !ok {
+		return parents
+	}
+	hash := ref.ParentHash()
+	for ctx := nodeCtx - 1; ctx >= 0; {
+		termini := reader.GetTerminiByHash(hash)
+		if termini == nil {
+			return parents
+		}
+		terminus := reader.GetHeaderByHash(termini.DomTerminus())
+		if terminus == nil {
+			return parents
+		}
+		_, order, err := chain.Engine().CalcOrder(terminus)
+		if err != nil {
+			return parents
+		}
+		// The terminus is coincident with every context down to its order
+		for ; ctx >= 0 && ctx >= order; ctx-- {
+			parents[ctx] = terminus
+		}
+		hash = terminus.ParentHash()
+	}
+	return parents
+}
+
 // GetHashFn returns a GetHashFunc which retrieves header hashes by number
 func GetHashFn(ref *types.Header, chain ChainContext) func(n uint64) common.Hash {
 	// Cache will initially contain [refHash.parent],
@@ -0,0 +1,132 @@
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/dominant-strategies/go-quai/common"
+	"github.com/dominant-strategies/go-quai/consensus"
+	"github.com/dominant-strategies/go-quai/core/types"
+)
+
+// orderEngine is a consensus engine only answering orders, which are looked up
+// by hash.
+type orderEngine struct {
+	consensus.Engine
+	orders map[common.Hash]int
+}
+
+func (e *orderEngine) CalcOrder(header *types.Header) (*big.Int, int, error) {
+	return new(big.Int), e.orders[header.Hash()], nil
+}
+
+// terminiChain is a chain of headers along with their termini and orders.
+type terminiChain struct {
+	engine  *orderEngine
+	headers map[common.Hash]*types.Header
+	termini map[common.Hash]*types.Termini
+}
+
+// newTerminiChain creates a chain of blocks of the given orders in the chain of
+// the node location, starting with the genesis block, and computes the termini
+// of the blocks like pcrc does.
+func newTerminiChain(orders []int) (*terminiChain, []*types.Header) {
+	nodeCtx := common.NodeLocation.Context()
+	chain := &terminiChain{
+		engine:  &orderEngine{orders: make(map[common.Hash]int)},
+		headers: make(map[common.Hash]*types.Header),
+		termini: make(map[common.Hash]*types.Termini),
+	}
+	var (
+		headers []*types.Header
+		numbers = make([]int64, common.HierarchyDepth)
+		parent  common.Hash
+	)
+	for i, order := range orders {
+		header := types.EmptyHeader()
+		for ctx := 0; ctx < common.HierarchyDepth; ctx++ {
+			if i > 0 && ctx >= order {
+				numbers[ctx]++
+			}
+			header.SetNumber(big.NewInt(numbers[ctx]), ctx)
+		}
+		header.SetParentHash(parent)
+		hash := header.Hash()
+
+		termini := types.EmptyTermini()
+		if i > 0 {
+			termini = types.CopyTermini(*chain.termini[parent])
+		}
+		if order < nodeCtx || i == 0 {
+			termini.SetDomTerminiAtIndex(hash, common.NodeLocation.DomIndex())
+		}
+		chain.headers[hash] = header
+		chain.termini[hash] = &termini
+		chain.engine.orders[hash] = order
+		headers = append(headers, header)
+		parent = hash
+	}
+	return chain, headers
+}
+
+func (c *terminiChain) Engine() consensus.Engine { return c.engine }
+
+func (c *terminiChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	return c.headers[hash]
+}
+
+func (c *terminiChain) GetHeaderByHash(hash common.Hash) *types.Header {
+	return c.headers[hash]
+}
+
+func (c *terminiChain) GetTerminiByHash(hash common.Hash) *types.Termini {
+	return c.termini[hash]
+}
+
+// Tests that the dominant parents of a block are the latest blocks coincident
+// with each dominant context among its ancestors, in every context.
+func TestGetDomParentFn(t *testing.T) {
+	defer func(loc common.Location) { common.NodeLocation = loc }(common.NodeLocation)
+
+	tests := []struct {
+		location common.Location
+		orders   []int
+		want     []int // Index of the dominant parent in each dominant context
+	}{
+		// A prime chain has no dominant contexts
+		{common.Location{}, []int{0, 0, 0}, nil},
+		// The latest prime block of a region chain
+		{common.Location{0}, []int{0, 1, 0, 1, 1}, []int{2}},
+		// The parent itself is coincident with prime
+		{common.Location{0}, []int{0, 1, 0}, []int{2}},
+		// The latest prime and region blocks of a zone chain
+		{common.Location{0, 0}, []int{0, 2, 1, 2, 0, 2, 1, 2}, []int{4, 6}},
+		// A prime block is the latest region block as well
+		{common.Location{0, 0}, []int{0, 2, 1, 0, 2, 2}, []int{3, 3}},
+		// Only the genesis block is coincident with the dom
+		{common.Location{0, 0}, []int{0, 2, 2}, []int{0, 0}},
+	}
+	for i, tt := range tests {
+		common.NodeLocation = tt.location
+		nodeCtx := tt.location.Context()
+		chain, headers := newTerminiChain(tt.orders)
+
+		block := types.EmptyHeader()
+		block.SetParentHash(headers[len(headers)-1].Hash())
+		getDomParent := GetDomParentFn(block, chain)
+
+		for ctx := 0; ctx < common.HierarchyDepth; ctx++ {
+			hash, number := getDomParent(ctx)
+			if ctx >= nodeCtx {
+				if hash != (common.Hash{}) || number != nil {
+					t.Errorf("test %d: context %d: unexpected dominant parent %x #%v", i, ctx, hash, number)
+				}
+				continue
+			}
+			want := headers[tt.want[ctx]]
+			if hash != want.Hash() || number == nil || number.Cmp(want.Number(ctx)) != 0 {
+				t.Errorf("test %d: context %d: dominant parent mismatch: have %x #%v, want %x #%v", i, ctx, hash, number, want.Hash(), want.Number(ctx))
+			}
+		}
+	}
+}
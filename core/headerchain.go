@@ -62,6 +62,7 @@ type HeaderChain struct {
 	pendingEtxsRollup *lru.Cache
 	pendingEtxs       *lru.Cache
 	blooms            *lru.Cache
+	lightPendingEtxs  *lru.Cache // Hashes of the pending ETXs a light chain asked its peers for
 
 	wg            sync.WaitGroup // chain processing wait group for shutting down
 	running       int32          // 0 if chain is running, 1 when stopped
@@ -70,6 +71,8 @@ type HeaderChain struct {
 	headermu      sync.RWMutex
 	heads         []*types.Header
 	slicesRunning []common.Location
+
+	light int32 // 1 if the chain only follows headers, see AppendLightHeader
 }
 
 // NewHeaderChain creates a new HeaderChain structure. ProcInterrupt points
@@ -98,6 +101,9 @@ func NewHeaderChain(db ethdb.Database, engine consensus.Engine, pEtxsRollupFetch
 	blooms, _ := lru.New(c_maxBloomFilters)
 	hc.blooms = blooms
 
+	lightPendingEtxs, _ := lru.New(c_maxPendingEtxBatches)
+	hc.lightPendingEtxs = lightPendingEtxs
+
 	hc.genesisHeader = hc.GetHeaderByNumber(0)
 	if hc.genesisHeader.Hash() != chainConfig.GenesisHash {
 		return nil, fmt.Errorf("genesis block mismatch: have %x, want %x", hc.genesisHeader.Hash(), chainConfig.GenesisHash)
@@ -285,10 +291,178 @@ func (hc *HeaderChain) AppendHeader(header *types.Header) error {
 
 	return nil
 }
+
+// SetLightMode switches the header chain to light mode, in which the canonical
+// chain is followed by headers only and bodies are never appended.
+func (hc *HeaderChain) SetLightMode() {
+	atomic.StoreInt32(&hc.light, 1)
+}
+
+// LightMode returns true if the header chain only follows headers.
+func (hc *HeaderChain) LightMode() bool {
+	return atomic.LoadInt32(&hc.light) == 1
+}
+
+// VerifyLightHeader verifies a header which is appended without its body and
+// returns its termini. On top of the consensus checks of the engine, which
+// cover the parent entropy and delta S fields, the seal of the header itself
+// is verified, as well as its references to the previous coincident blocks.
+func (hc *HeaderChain) VerifyLightHeader(header *types.Header) (types.Termini, error) {
+	nodeCtx := common.NodeLocation.Context()
+	if nodeCtx == common.ZONE_CTX {
+		return types.EmptyTermini(), ErrLightZone
+	}
+	if err := hc.engine.VerifyHeader(hc, header); err != nil {
+		return types.EmptyTermini(), err
+	}
+	_, order, err := hc.engine.CalcOrder(header)
+	if err != nil {
+		return types.EmptyTermini(), err
+	}
+	if order > nodeCtx {
+		return types.EmptyTermini(), fmt.Errorf("order of the header is greater than the context: have %d, want <= %d", order, nodeCtx)
+	}
+	termini := hc.GetTerminiByHash(header.ParentHash())
+	if !termini.IsValid() {
+		return types.EmptyTermini(), ErrSubNotSyncedToDom
+	}
+	if err := hc.verifyLightTermini(header, *termini, order); err != nil {
+		return types.EmptyTermini(), err
+	}
+	return lightTermini(header, *termini, order), nil
+}
+
+// verifyLightTermini runs the cyclic reference check of pcrc without the dom
+// handing down its terminus. A dom parent which is a block of this chain can
+// only be the previous coincident block, i.e. the dom terminus of the parent,
+// and a sub parent which is a block of this chain can only be the latest
+// block of the sub, i.e. its sub terminus.
+func (hc *HeaderChain) verifyLightTermini(header *types.Header, parentTermini types.Termini, order int) error {
+	nodeCtx := common.NodeLocation.Context()
+	if nodeCtx != common.PRIME_CTX && order < nodeCtx {
+		if err := hc.verifyLightReference(header, nodeCtx-1, parentTermini.DomTerminus()); err != nil {
+			return err
+		}
+	}
+	return hc.verifyLightReference(header, nodeCtx+1, parentTermini.SubTerminiAtIndex(header.Location().SubIndex()))
+}
+
+// verifyLightReference checks the parent of a header in another context
+// against the terminus of the chain in that context, i.e. the latest block of
+// the chain which is a block of that context as well. A parent other than the
+// terminus is a block of that context only, so it must be unknown to the chain
+// and newer than the terminus. The terminus not being known defers the header
+// until it is.
+func (hc *HeaderChain) verifyLightReference(header *types.Header, ctx int, terminus common.Hash) error {
+	parent := header.ParentHash(ctx)
+	if parent == terminus {
+		return nil
+	}
+	if hc.GetHeaderByHash(parent) != nil {
+		return fmt.Errorf("termini do not match, block rejected due to cyclic reference: parent %x in context %d is not the terminus %x", parent, ctx, terminus)
+	}
+	terminusHeader := hc.GetHeaderByHash(terminus)
+	if terminusHeader == nil {
+		return ErrSubNotSyncedToDom
+	}
+	if header.Number(ctx).Cmp(new(big.Int).Add(terminusHeader.Number(ctx), common.Big1)) <= 0 {
+		return fmt.Errorf("termini do not match, block rejected due to cyclic reference: unknown parent %x in context %d is not newer than the terminus %x", parent, ctx, terminus)
+	}
+	return nil
+}
+
+// AppendLightHeader verifies a header and writes it, along with its termini,
+// without requiring the body or the manifest of its parent. The header becomes
+// the new head if it carries more entropy than the current head.
+func (hc *HeaderChain) AppendLightHeader(header *types.Header) error {
+	hash, number := header.Hash(), header.NumberU64()
+	if hc.GetHeader(hash, number) != nil {
+		return nil
+	}
+	newTermini, err := hc.VerifyLightHeader(header)
+	if err != nil {
+		return err
+	}
+
+	batch := hc.headerDb.NewBatch()
+	rawdb.WriteHeader(batch, header)
+	rawdb.WriteTermini(batch, hash, newTermini)
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	// Ties are broken towards the new header, the same as poem does
+	if hc.engine.TotalLogS(hc.CurrentHeader()).Cmp(hc.engine.TotalLogS(header)) <= 0 {
+		return hc.SetCurrentHeader(header)
+	}
+	return nil
+}
+
+// lightTermini computes the termini of a header from the termini of its
+// parent, the same way pcrc does for full blocks.
+func lightTermini(header *types.Header, parentTermini types.Termini, order int) types.Termini {
+	nodeCtx := common.NodeLocation.Context()
+	location := header.Location()
+
+	newTermini := types.CopyTermini(parentTermini)
+	newTermini.SetSubTerminiAtIndex(header.Hash(), location.SubIndex())
+	if nodeCtx == common.PRIME_CTX || order < nodeCtx {
+		newTermini.SetDomTerminiAtIndex(header.Hash(), location.DomIndex())
+	} else {
+		newTermini.SetDomTerminiAtIndex(parentTermini.DomTerminus(), location.DomIndex())
+	}
+	return newTermini
+}
+
+// VerifyManifest checks a block manifest received from a peer against the
+// manifest hash committed to by the canonical child of the block.
+func (hc *HeaderChain) VerifyManifest(hash common.Hash, manifest types.BlockManifest) error {
+	number := hc.GetBlockNumber(hash)
+	if number == nil {
+		return consensus.ErrUnknownAncestor
+	}
+	child := hc.GetHeaderByNumber(*number + 1)
+	if child == nil || child.ParentHash() != hash {
+		return ErrManifestNotVerifiable
+	}
+	if child.ManifestHash(common.NodeLocation.Context()) != types.DeriveSha(manifest, trie.NewStackTrie(nil)) {
+		return errors.New("manifest does not match hash")
+	}
+	return nil
+}
+
+// RequestLightPendingEtxs records that a light chain asked its peers for the
+// pending ETXs of the block with the given hash.
+func (hc *HeaderChain) RequestLightPendingEtxs(hash common.Hash) {
+	hc.lightPendingEtxs.Add(hash, struct{}{})
+}
+
+// VerifyPendingEtxsProof checks pending ETXs received from a peer. Their header
+// has to be a block of this chain or one the chain asked for, the ETXs have to
+// match the ETX root of the header, and the header has to carry a valid seal.
+func (hc *HeaderChain) VerifyPendingEtxsProof(pEtxs types.PendingEtxs) error {
+	hash := pEtxs.Header.Hash()
+	if hc.GetHeaderByHash(hash) == nil && !hc.lightPendingEtxs.Contains(hash) {
+		return ErrPendingEtxNotValid
+	}
+	if !pEtxs.IsValid(trie.NewStackTrie(nil)) {
+		return ErrPendingEtxNotValid
+	}
+	if _, err := hc.engine.VerifySeal(pEtxs.Header); err != nil {
+		return err
+	}
+	return nil
+}
+
 func (hc *HeaderChain) ProcessingState() bool {
 	return hc.bc.ProcessingState()
 }
 
+// Processor returns the state processor of the chain, which is nil unless
+// the node is processing state for its zone.
+func (hc *HeaderChain) Processor() *StateProcessor {
+	return hc.bc.processor
+}
+
 // Append
 func (hc *HeaderChain) AppendBlock(block *types.Block, newInboundEtxs types.Transactions) error {
 	blockappend := time.Now()
@@ -303,12 +477,29 @@ func (hc *HeaderChain) AppendBlock(block *types.Block, newInboundEtxs types.Tran
 	if len(logs) > 0 {
 		hc.bc.logsFeed.Send(logs)
 	}
-
+	if len(newInboundEtxs) > 0 {
+		hc.bc.inboundEtxFeed.Send(InboundEtxsEvent{Block: block, Etxs: newInboundEtxs})
+	}
 	return nil
 }
 
 // SetCurrentHeader sets the current header based on the POEM choice
 func (hc *HeaderChain) SetCurrentHeader(head *types.Header) error {
+	// Blocks dropped from the canonical chain are announced as side blocks, and
+	// the ETXs expiring or no longer expiring in the canonical chain as such,
+	// once the header lock has been released
+	var (
+		sideBlocks    []*types.Block
+		expiredEvents []ExpiredEtxsEvent
+	)
+	defer func() {
+		for _, block := range sideBlocks {
+			hc.chainSideFeed.Send(ChainSideEvent{Block: block})
+		}
+		for _, ev := range expiredEvents {
+			hc.bc.expiredEtxFeed.Send(ev)
+		}
+	}()
 	hc.headermu.Lock()
 	defer hc.headermu.Unlock()
 
@@ -326,6 +517,7 @@ func (hc *HeaderChain) SetCurrentHeader(head *types.Header) error {
 	// If head is the normal extension of canonical head, we can return by just wiring the canonical hash.
 	if prevHeader.Hash() == head.ParentHash() {
 		rawdb.WriteCanonicalHash(hc.headerDb, head.Hash(), head.NumberU64())
+		expiredEvents = hc.appendExpiredEtxsEvent(expiredEvents, head, hc.writeExpiredEtxs(head), false)
 		return nil
 	}
 
@@ -354,6 +546,10 @@ func (hc *HeaderChain) SetCurrentHeader(head *types.Header) error {
 			break
 		}
 		rawdb.DeleteCanonicalHash(hc.headerDb, prevHeader.NumberU64())
+		expiredEvents = hc.appendExpiredEtxsEvent(expiredEvents, prevHeader, hc.deleteExpiredEtxs(prevHeader), true)
+		if block := hc.GetBlockOrCandidate(prevHeader.Hash(), prevHeader.NumberU64()); block != nil {
+			sideBlocks = append(sideBlocks, block)
+		}
 		prevHeader = hc.GetHeader(prevHeader.ParentHash(), prevHeader.NumberU64()-1)
 
 		// genesis check to not delete the genesis block
@@ -365,11 +561,71 @@ func (hc *HeaderChain) SetCurrentHeader(head *types.Header) error {
 	// Run through the hash stack to update canonicalHash and forward state processor
 	for i := len(hashStack) - 1; i >= 0; i-- {
 		rawdb.WriteCanonicalHash(hc.headerDb, hashStack[i].Hash(), hashStack[i].NumberU64())
+		expiredEvents = hc.appendExpiredEtxsEvent(expiredEvents, hashStack[i], hc.writeExpiredEtxs(hashStack[i]), false)
 	}
 
 	return nil
 }
 
+// expiredEtxs returns the ETXs which expired without being mined in the given
+// block, which are the expired entries of the EtxSet of the parent missing from
+// the EtxSet of the block.
+func (hc *HeaderChain) expiredEtxs(header *types.Header) []*types.ExpiredEtx {
+	if common.NodeLocation.Context() != common.ZONE_CTX || !hc.ProcessingState() || header.NumberU64() == 0 {
+		return nil
+	}
+	parentSet := rawdb.ReadEtxSet(hc.headerDb, header.ParentHash(), header.NumberU64()-1)
+	etxSet := rawdb.ReadEtxSet(hc.headerDb, header.Hash(), header.NumberU64())
+	if parentSet == nil || etxSet == nil {
+		return nil
+	}
+	var expired []*types.ExpiredEtx
+	for hash, entry := range parentSet {
+		if _, ok := etxSet[hash]; ok || header.NumberU64() <= entry.ExpirationHeight() {
+			continue
+		}
+		expired = append(expired, &types.ExpiredEtx{ETX: entry.ETX, AvailableAt: entry.Height, ExpiredAt: header.NumberU64(), BlockHash: header.Hash()})
+	}
+	return expired
+}
+
+// writeExpiredEtxs indexes the ETXs which expired in a block that became
+// canonical and returns them.
+func (hc *HeaderChain) writeExpiredEtxs(header *types.Header) []*types.ExpiredEtx {
+	expired := hc.expiredEtxs(header)
+	for _, expiredEtx := range expired {
+		rawdb.WriteExpiredEtx(hc.headerDb, expiredEtx)
+	}
+	return expired
+}
+
+// deleteExpiredEtxs removes the index entries of the ETXs which expired in a
+// block that was rolled back, unless the ETX expired again in another block,
+// and returns the removed entries.
+func (hc *HeaderChain) deleteExpiredEtxs(header *types.Header) []*types.ExpiredEtx {
+	var removed []*types.ExpiredEtx
+	for _, expiredEtx := range hc.expiredEtxs(header) {
+		if stored := rawdb.ReadExpiredEtx(hc.headerDb, expiredEtx.ETX.Hash()); stored != nil && stored.BlockHash == header.Hash() {
+			rawdb.DeleteExpiredEtx(hc.headerDb, expiredEtx.ETX.Hash())
+			removed = append(removed, expiredEtx)
+		}
+	}
+	return removed
+}
+
+// appendExpiredEtxsEvent appends the event announcing the ETXs which expired
+// in a block that became canonical or was rolled back, if there are any.
+func (hc *HeaderChain) appendExpiredEtxsEvent(events []ExpiredEtxsEvent, header *types.Header, etxs []*types.ExpiredEtx, removed bool) []ExpiredEtxsEvent {
+	if len(etxs) == 0 {
+		return events
+	}
+	block := hc.GetBlockOrCandidate(header.Hash(), header.NumberU64())
+	if block == nil {
+		return events
+	}
+	return append(events, ExpiredEtxsEvent{Block: block, Etxs: etxs, Removed: removed})
+}
+
 // SetCurrentHeader sets the in-memory head header marker of the canonical chan
 // as the given header.
 func (hc *HeaderChain) SetCurrentState(head *types.Header) error {
@@ -0,0 +1,118 @@
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/dominant-strategies/go-quai/common"
+	"github.com/dominant-strategies/go-quai/core/rawdb"
+	"github.com/dominant-strategies/go-quai/core/types"
+	"github.com/dominant-strategies/go-quai/params"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// newTestHeaderChain creates a header chain processing the state of the zone
+// on top of an in-memory database, without a genesis block.
+func newTestHeaderChain() *HeaderChain {
+	db := rawdb.NewMemoryDatabase()
+	hc := &HeaderChain{config: &params.ChainConfig{ChainID: big.NewInt(1)}, headerDb: db}
+	hc.headerCache, _ = lru.New(16)
+	hc.numberCache, _ = lru.New(16)
+	hc.bc = &BodyDb{db: db, slicesRunning: []common.Location{common.NodeLocation}}
+	return hc
+}
+
+// writeTestBlock stores a block with the given extra data and EtxSet and
+// returns its header.
+func writeTestBlock(hc *HeaderChain, parent common.Hash, number uint64, extra string, etxSet types.EtxSet) *types.Header {
+	header := types.EmptyHeader()
+	header.SetParentHash(parent)
+	header.SetNumber(new(big.Int).SetUint64(number))
+	header.SetExtra([]byte(extra))
+	block := types.NewBlockWithHeader(header)
+
+	rawdb.WriteBlock(hc.headerDb, block)
+	rawdb.WriteTermini(hc.headerDb, block.Hash(), types.EmptyTermini())
+	rawdb.WriteEtxSet(hc.headerDb, block.Hash(), number, etxSet)
+	return block.Header()
+}
+
+// Tests that the ETXs expiring in a block are indexed and announced once the
+// block becomes canonical, and unindexed and announced as removed once it is
+// rolled back.
+func TestExpiredEtxsEvents(t *testing.T) {
+	defer func(loc common.Location) { common.NodeLocation = loc }(common.NodeLocation)
+	common.NodeLocation = common.Location{0, 0}
+
+	to := common.HexToAddress("0x0000000000000000000000000000000000000001")
+	newETX := func(nonce uint64) types.Transaction {
+		return *types.NewTx(&types.ExternalTx{ChainID: big.NewInt(1), Nonce: nonce, Gas: params.TxGas, To: &to, Value: big.NewInt(1)})
+	}
+	first, second := newETX(1), newETX(2)
+
+	// The first ETX expires in the blocks after the root, the second one in the
+	// grandchildren of the root.
+	hc := newTestHeaderChain()
+	number := params.EtxExpirationAge + 1
+	root := writeTestBlock(hc, common.Hash{}, number, "", types.EtxSet{first.Hash(): {1, first}, second.Hash(): {2, second}})
+	a1 := writeTestBlock(hc, root.Hash(), number+1, "a", types.EtxSet{second.Hash(): {2, second}})
+	a2 := writeTestBlock(hc, a1.Hash(), number+2, "a", types.EtxSet{})
+	b1 := writeTestBlock(hc, root.Hash(), number+1, "b", types.EtxSet{second.Hash(): {2, second}})
+
+	rawdb.WriteCanonicalHash(hc.headerDb, root.Hash(), root.NumberU64())
+	hc.currentHeader.Store(root)
+
+	events := make(chan ExpiredEtxsEvent, 8)
+	sub := hc.bc.SubscribeExpiredEtxsEvent(events)
+	defer sub.Unsubscribe()
+
+	type expiry struct {
+		block   common.Hash
+		etx     common.Hash
+		removed bool
+	}
+	check := func(name string, want []expiry, indexed map[common.Hash]common.Hash) {
+		t.Helper()
+		for _, w := range want {
+			select {
+			case ev := <-events:
+				if ev.Block.Hash() != w.block || len(ev.Etxs) != 1 || ev.Etxs[0].ETX.Hash() != w.etx || ev.Removed != w.removed {
+					t.Errorf("%s: event mismatch: have block %x with %d ETXs, removed %v", name, ev.Block.Hash(), len(ev.Etxs), ev.Removed)
+				}
+			default:
+				t.Fatalf("%s: missing event for block %x", name, w.block)
+			}
+		}
+		select {
+		case ev := <-events:
+			t.Errorf("%s: unexpected event for block %x", name, ev.Block.Hash())
+		default:
+		}
+		for _, etx := range []common.Hash{first.Hash(), second.Hash()} {
+			stored := rawdb.ReadExpiredEtx(hc.headerDb, etx)
+			if block, ok := indexed[etx]; !ok && stored != nil {
+				t.Errorf("%s: ETX %x indexed as expired in %x", name, etx, stored.BlockHash)
+			} else if ok && (stored == nil || stored.BlockHash != block) {
+				t.Errorf("%s: ETX %x not indexed as expired in %x", name, etx, block)
+			}
+		}
+	}
+	if err := hc.SetCurrentHeader(a1); err != nil {
+		t.Fatal(err)
+	}
+	check("extend", []expiry{{a1.Hash(), first.Hash(), false}}, map[common.Hash]common.Hash{first.Hash(): a1.Hash()})
+
+	if err := hc.SetCurrentHeader(a2); err != nil {
+		t.Fatal(err)
+	}
+	check("extend again", []expiry{{a2.Hash(), second.Hash(), false}}, map[common.Hash]common.Hash{first.Hash(): a1.Hash(), second.Hash(): a2.Hash()})
+
+	if err := hc.SetCurrentHeader(b1); err != nil {
+		t.Fatal(err)
+	}
+	check("reorg", []expiry{
+		{a2.Hash(), second.Hash(), true},
+		{a1.Hash(), first.Hash(), true},
+		{b1.Hash(), first.Hash(), false},
+	}, map[common.Hash]common.Hash{first.Hash(): b1.Hash()})
+}
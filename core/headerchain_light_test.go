@@ -0,0 +1,167 @@
+package core
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/dominant-strategies/go-quai/common"
+	"github.com/dominant-strategies/go-quai/consensus"
+	"github.com/dominant-strategies/go-quai/core/rawdb"
+	"github.com/dominant-strategies/go-quai/core/types"
+)
+
+// errReject marks light headers expected to be rejected for good.
+var errReject = errors.New("rejected")
+
+// lightEngine is a consensus engine accepting every header, which answers
+// orders by hash and weighs headers by their number.
+type lightEngine struct {
+	*orderEngine
+}
+
+func (e lightEngine) VerifyHeader(chain consensus.ChainHeaderReader, header *types.Header) error {
+	return nil
+}
+
+func (e lightEngine) TotalLogS(header *types.Header) *big.Int { return header.Number() }
+
+// newLightTestChain creates a light header chain of the node location holding
+// only the genesis block, whose termini reference the genesis block itself.
+func newLightTestChain() (*HeaderChain, lightEngine, *types.Header) {
+	engine := lightEngine{&orderEngine{orders: make(map[common.Hash]int)}}
+	hc := newTestHeaderChain()
+	hc.engine = engine
+	hc.SetLightMode()
+
+	genesis := types.EmptyHeader()
+	rawdb.WriteHeader(hc.headerDb, genesis)
+	rawdb.WriteCanonicalHash(hc.headerDb, genesis.Hash(), 0)
+	termini := types.EmptyTermini()
+	for i := range termini.SubTermini() {
+		termini.SetSubTerminiAtIndex(genesis.Hash(), i)
+	}
+	for i := range termini.DomTermini() {
+		termini.SetDomTerminiAtIndex(genesis.Hash(), i)
+	}
+	rawdb.WriteTermini(hc.headerDb, genesis.Hash(), termini)
+	hc.currentHeader.Store(genesis)
+	return hc, engine, genesis
+}
+
+// newLightHeader creates a header of the given order in the first zone of the
+// node region, with the given parents and numbers in every context.
+func newLightHeader(engine lightEngine, order int, parents []common.Hash, numbers []int64) *types.Header {
+	header := types.EmptyHeader()
+	for ctx := 0; ctx < common.HierarchyDepth; ctx++ {
+		header.SetParentHash(parents[ctx], ctx)
+		header.SetNumber(big.NewInt(numbers[ctx]), ctx)
+	}
+	header.SetLocation(common.Location{0, 0})
+	engine.orders[header.Hash()] = order
+	return header
+}
+
+// Tests that the references of a light header to the previous coincident
+// blocks are checked against the termini of its parent, and that headers whose
+// termini reference unknown blocks are deferred.
+func TestVerifyLightHeader(t *testing.T) {
+	defer func(loc common.Location) { common.NodeLocation = loc }(common.NodeLocation)
+	common.NodeLocation = common.Location{0}
+
+	hc, engine, genesis := newLightTestChain()
+	g := genesis.Hash()
+	known := newLightHeader(engine, common.REGION_CTX, []common.Hash{g, g, g}, []int64{0, 1, 1})
+	if err := hc.AppendLightHeader(known); err != nil {
+		t.Fatalf("failed to append header: %v", err)
+	}
+	// A block whose dom terminus is not known to the chain
+	orphan := newLightHeader(engine, common.REGION_CTX, []common.Hash{g, g, g}, []int64{0, 1, 2})
+	rawdb.WriteHeader(hc.headerDb, orphan)
+	termini := types.CopyTermini(*hc.GetTerminiByHash(g))
+	termini.SetDomTerminiAtIndex(common.Hash{0xfe}, common.NodeLocation.DomIndex())
+	rawdb.WriteTermini(hc.headerDb, orphan.Hash(), termini)
+
+	unknown := common.Hash{0xff}
+	tests := []struct {
+		name    string
+		order   int
+		parents []common.Hash
+		numbers []int64
+		err     error // errReject for any error other than a deferral
+	}{
+		{"dom parent is the terminus", common.PRIME_CTX, []common.Hash{g, g, g}, []int64{1, 1, 1}, nil},
+		{"dom parent is another block of the chain", common.PRIME_CTX, []common.Hash{known.Hash(), g, g}, []int64{2, 1, 1}, errReject},
+		{"unknown dom parent following the terminus", common.PRIME_CTX, []common.Hash{unknown, g, g}, []int64{1, 1, 1}, errReject},
+		{"unknown dom parent newer than the terminus", common.PRIME_CTX, []common.Hash{unknown, g, g}, []int64{3, 1, 1}, nil},
+		{"dom parent of a block of the context", common.REGION_CTX, []common.Hash{unknown, g, g}, []int64{0, 1, 1}, nil},
+		{"sub parent is another block of the chain", common.REGION_CTX, []common.Hash{g, g, known.Hash()}, []int64{0, 1, 2}, errReject},
+		{"unknown sub parent following the terminus", common.REGION_CTX, []common.Hash{g, g, unknown}, []int64{0, 1, 1}, errReject},
+		{"unknown sub parent newer than the terminus", common.REGION_CTX, []common.Hash{g, g, unknown}, []int64{0, 1, 4}, nil},
+		{"order below the context", common.ZONE_CTX, []common.Hash{g, g, g}, []int64{0, 1, 1}, errReject},
+		{"unknown parent", common.REGION_CTX, []common.Hash{g, unknown, g}, []int64{0, 2, 1}, ErrSubNotSyncedToDom},
+		{"unknown dom terminus", common.PRIME_CTX, []common.Hash{unknown, orphan.Hash(), g}, []int64{3, 2, 1}, ErrSubNotSyncedToDom},
+	}
+	for _, tt := range tests {
+		header := newLightHeader(engine, tt.order, tt.parents, tt.numbers)
+		_, err := hc.VerifyLightHeader(header)
+		switch {
+		case tt.err == nil && err != nil:
+			t.Errorf("%s: header rejected: %v", tt.name, err)
+		case tt.err == errReject && (err == nil || errors.Is(err, ErrSubNotSyncedToDom)):
+			t.Errorf("%s: header not rejected: %v", tt.name, err)
+		case tt.err == ErrSubNotSyncedToDom && !errors.Is(err, ErrSubNotSyncedToDom):
+			t.Errorf("%s: header not deferred: %v", tt.name, err)
+		}
+	}
+	common.NodeLocation = common.Location{0, 0}
+	if _, err := hc.VerifyLightHeader(known); err != ErrLightZone {
+		t.Errorf("light header verified by a zone: %v", err)
+	}
+}
+
+// Tests that appended light headers carry the termini pcrc would compute and
+// become the head once they carry the most entropy.
+func TestAppendLightHeader(t *testing.T) {
+	defer func(loc common.Location) { common.NodeLocation = loc }(common.NodeLocation)
+	common.NodeLocation = common.Location{0}
+
+	hc, engine, genesis := newLightTestChain()
+	g := genesis.Hash()
+	region := newLightHeader(engine, common.REGION_CTX, []common.Hash{g, g, g}, []int64{0, 1, 1})
+	prime := newLightHeader(engine, common.PRIME_CTX, []common.Hash{g, region.Hash(), region.Hash()}, []int64{1, 2, 2})
+	side := newLightHeader(engine, common.REGION_CTX, []common.Hash{g, g, g}, []int64{0, 1, 3})
+
+	for _, header := range []*types.Header{region, prime, side} {
+		if err := hc.AppendLightHeader(header); err != nil {
+			t.Fatalf("failed to append header #%d: %v", header.NumberU64(), err)
+		}
+	}
+	if head := hc.CurrentHeader(); head.Hash() != prime.Hash() {
+		t.Errorf("head mismatch: have #%d %x, want #%d %x", head.NumberU64(), head.Hash(), prime.NumberU64(), prime.Hash())
+	}
+	if hc.GetHeaderByHash(side.Hash()) == nil {
+		t.Errorf("side header not written")
+	}
+	tests := []struct {
+		header      *types.Header
+		domTerminus common.Hash
+	}{
+		{region, g},
+		{prime, prime.Hash()},
+		{side, g},
+	}
+	for _, tt := range tests {
+		termini := hc.GetTerminiByHash(tt.header.Hash())
+		if termini == nil {
+			t.Errorf("termini of #%d not written", tt.header.NumberU64())
+			continue
+		}
+		if termini.DomTerminus() != tt.domTerminus {
+			t.Errorf("dom terminus of #%d mismatch: have %x, want %x", tt.header.NumberU64(), termini.DomTerminus(), tt.domTerminus)
+		}
+		if sub := termini.SubTerminiAtIndex(0); sub != tt.header.Hash() {
+			t.Errorf("sub terminus of #%d mismatch: have %x, want %x", tt.header.NumberU64(), sub, tt.header.Hash())
+		}
+	}
+}
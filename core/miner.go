@@ -171,6 +171,27 @@ func (miner *Miner) SetGasCeil(ceil uint64) {
 	miner.worker.setGasCeil(ceil)
 }
 
+// SetTemplatePolicy sets the policy selecting and ordering the transactions
+// of mined blocks. The allowlist is only used by the local-first policy.
+func (miner *Miner) SetTemplatePolicy(name string, allowlist []common.Address) error {
+	policy, err := NewTemplatePolicy(name, allowlist)
+	if err != nil {
+		return err
+	}
+	miner.worker.setTemplatePolicy(policy)
+	return nil
+}
+
+// SetExcludedSenders sets the senders whose internal transactions are never mined.
+func (miner *Miner) SetExcludedSenders(senders []common.Address) {
+	miner.worker.setExcludedSenders(senders)
+}
+
+// SetEtxReserve sets the gas of each mined block reserved for ETXs.
+func (miner *Miner) SetEtxReserve(gas uint64) {
+	miner.worker.setEtxReserve(gas)
+}
+
 // EnablePreseal turns on the preseal mining feature. It's enabled by default.
 // Note this function shouldn't be exposed to API, it's unnecessary for users
 // (miners) to actually know the underlying detail. It's only for outside project
@@ -612,6 +612,62 @@ func DeleteBestPhKey(db ethdb.KeyValueWriter) {
 	}
 }
 
+// ReadPhCacheKeys retreive's the keys of the pending headers held in the
+// phCache when the node was last stopped.
+func ReadPhCacheKeys(db ethdb.Reader) []common.Hash {
+	data, _ := db.Get(phCacheKey)
+	if len(data) == 0 {
+		return []common.Hash{}
+	}
+	keys := []common.Hash{}
+	if err := rlp.DecodeBytes(data, &keys); err != nil {
+		return []common.Hash{}
+	}
+	return keys
+}
+
+// WritePhCacheKeys writes the keys of the pending headers held in the phCache.
+func WritePhCacheKeys(db ethdb.KeyValueWriter, keys []common.Hash) {
+	data, err := rlp.EncodeToBytes(keys)
+	if err != nil {
+		log.Fatal("Failed to RLP encode ph cache keys", "err", err)
+	}
+	if err := db.Put(phCacheKey, data); err != nil {
+		log.Fatal("Failed to store ph cache keys", "err", err)
+	}
+}
+
+// DeletePhCacheKeys deletes the keys of the pending headers held in the phCache.
+func DeletePhCacheKeys(db ethdb.KeyValueWriter) {
+	if err := db.Delete(phCacheKey); err != nil {
+		log.Fatal("Failed to delete ph cache keys", "err", err)
+	}
+}
+
+// ReadAllPendingHeaderHashes retrieves the terminus hashes of all the pending
+// headers stored in the database.
+func ReadAllPendingHeaderHashes(db ethdb.Iteratee) []common.Hash {
+	it := db.NewIterator(pendingHeaderPrefix, nil)
+	defer it.Release()
+
+	var hashes []common.Hash
+	for it.Next() {
+		// The pht prefix shares the ph prefix, so only take exact length keys
+		if key := it.Key(); len(key) == len(pendingHeaderPrefix)+common.HashLength {
+			hashes = append(hashes, common.BytesToHash(key[len(pendingHeaderPrefix):]))
+		}
+	}
+	return hashes
+}
+
+// DeletePendingHeaderTermini deletes the termini stored for the pending header
+// of the terminus hash.
+func DeletePendingHeaderTermini(db ethdb.KeyValueWriter, hash common.Hash) {
+	if err := db.Delete(phBodyTerminiKey(hash)); err != nil {
+		log.Fatal("Failed to delete pending header termini", "err", err)
+	}
+}
+
 // ReadHeadsHashes retreive's the heads hashes of the blockchain.
 func ReadHeadsHashes(db ethdb.Reader) []common.Hash {
 	data, _ := db.Get(headsHashesKey)
@@ -1108,6 +1164,38 @@ func DeleteEtxSet(db ethdb.KeyValueWriter, hash common.Hash, number uint64) {
 	}
 }
 
+// ReadExpiredEtx retrieves the record of an ETX which expired from the EtxSet.
+func ReadExpiredEtx(db ethdb.Reader, hash common.Hash) *types.ExpiredEtx {
+	data, _ := db.Get(expiredEtxKey(hash))
+	if len(data) == 0 {
+		return nil
+	}
+	expiredEtx := new(types.ExpiredEtx)
+	if err := rlp.Decode(bytes.NewReader(data), expiredEtx); err != nil {
+		log.Error("Invalid expired etx RLP", "hash", hash, "err", err)
+		return nil
+	}
+	return expiredEtx
+}
+
+// WriteExpiredEtx stores the record of an ETX which expired from the EtxSet.
+func WriteExpiredEtx(db ethdb.KeyValueWriter, expiredEtx *types.ExpiredEtx) {
+	data, err := rlp.EncodeToBytes(expiredEtx)
+	if err != nil {
+		log.Fatal("Failed to RLP encode expired etx", "err", err)
+	}
+	if err := db.Put(expiredEtxKey(expiredEtx.ETX.Hash()), data); err != nil {
+		log.Fatal("Failed to store expired etx", "err", err)
+	}
+}
+
+// DeleteExpiredEtx removes the record of an expired ETX.
+func DeleteExpiredEtx(db ethdb.KeyValueWriter, hash common.Hash) {
+	if err := db.Delete(expiredEtxKey(hash)); err != nil {
+		log.Fatal("Failed to delete expired etx", "err", err)
+	}
+}
+
 // ReadPendingEtxsRLP retrieves the set of pending ETXs for the given block, in RLP encoding
 func ReadPendingEtxsRLP(db ethdb.Reader, hash common.Hash) rlp.RawValue {
 	// Try to look up the data in leveldb.
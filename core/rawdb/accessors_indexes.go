@@ -18,6 +18,7 @@ package rawdb
 
 import (
 	"bytes"
+	"encoding/binary"
 	"math/big"
 
 	"github.com/dominant-strategies/go-quai/common"
@@ -175,3 +176,109 @@ func DeleteBloombits(db ethdb.Database, bit uint, from uint64, to uint64) {
 		log.Fatal("Failed to delete bloom bits", "err", it.Error())
 	}
 }
+
+// Kinds of activity recorded in the address activity index.
+const (
+	AddressActivityTx          uint8 = iota // The address sent or received a transaction
+	AddressActivityEtxEmitted               // The address sent or is the recipient of an ETX emitted by a transaction
+	AddressActivityEtxReceived              // The address is the recipient of an ETX executed in the block
+)
+
+// AddressActivity is an entry of the address activity index, locating a
+// transaction or ETX touching an address.
+type AddressActivity struct {
+	Kind        uint8
+	BlockNumber uint64
+	BlockHash   common.Hash
+	TxHash      common.Hash // Hash of the transaction, or of the ETX
+	TxIndex     uint64      // Index in the block of the transaction, or of the transaction emitting the ETX
+}
+
+// WriteAddressActivity stores an entry of the address activity index. The
+// sequence number orders the entries of an address within a block.
+func WriteAddressActivity(db ethdb.KeyValueWriter, address common.Address, seq uint32, activity AddressActivity) {
+	data, err := rlp.EncodeToBytes(activity)
+	if err != nil {
+		log.Fatal("Failed to RLP encode address activity", "err", err)
+	}
+	if err := db.Put(addressActivityKey(address, activity.BlockNumber, seq), data); err != nil {
+		log.Fatal("Failed to store address activity", "err", err)
+	}
+}
+
+// ReadAddressActivity retrieves up to limit entries of the address activity
+// index, starting at the given block number and sequence number. It also
+// returns the position of the entry following the last one, if any.
+func ReadAddressActivity(db ethdb.Iteratee, address common.Address, number uint64, seq uint32, limit int) ([]AddressActivity, *uint64, *uint32) {
+	prefix := addressActivityAddressKey(address)
+	start := addressActivityKey(address, number, seq)[len(prefix):]
+
+	it := db.NewIterator(prefix, start)
+	defer it.Release()
+
+	var activity []AddressActivity
+	for it.Next() {
+		key := it.Key()
+		if len(key) != len(prefix)+12 {
+			continue
+		}
+		if len(activity) == limit {
+			nextNumber := binary.BigEndian.Uint64(key[len(prefix):])
+			nextSeq := binary.BigEndian.Uint32(key[len(prefix)+8:])
+			return activity, &nextNumber, &nextSeq
+		}
+		var entry AddressActivity
+		if err := rlp.DecodeBytes(it.Value(), &entry); err != nil {
+			log.Error("Invalid address activity RLP", "address", address, "err", err)
+			continue
+		}
+		activity = append(activity, entry)
+	}
+	return activity, nil, nil
+}
+
+// DeleteAddressActivity removes the entries of the address activity index of
+// an address at the given block.
+func DeleteAddressActivity(db ethdb.Database, address common.Address, number uint64) {
+	it := db.NewIterator(addressActivityBlockKey(address, number), nil)
+	defer it.Release()
+
+	for it.Next() {
+		if err := db.Delete(it.Key()); err != nil {
+			log.Fatal("Failed to delete address activity", "err", err)
+		}
+	}
+}
+
+// ReadAddressJournal retrieves the addresses indexed at a block.
+func ReadAddressJournal(db ethdb.KeyValueReader, number uint64) []common.Address {
+	data, _ := db.Get(addressJournalKey(number))
+	if len(data) == 0 {
+		return nil
+	}
+	var addresses []common.Address
+	if err := rlp.DecodeBytes(data, &addresses); err != nil {
+		log.Error("Invalid address journal RLP", "number", number, "err", err)
+		return nil
+	}
+	return addresses
+}
+
+// WriteAddressJournal stores the addresses indexed at a block, so that their
+// entries can be removed if the block is reorged out.
+func WriteAddressJournal(db ethdb.KeyValueWriter, number uint64, addresses []common.Address) {
+	data, err := rlp.EncodeToBytes(addresses)
+	if err != nil {
+		log.Fatal("Failed to RLP encode address journal", "err", err)
+	}
+	if err := db.Put(addressJournalKey(number), data); err != nil {
+		log.Fatal("Failed to store address journal", "err", err)
+	}
+}
+
+// DeleteAddressJournal removes the addresses indexed at a block.
+func DeleteAddressJournal(db ethdb.KeyValueWriter, number uint64) {
+	if err := db.Delete(addressJournalKey(number)); err != nil {
+		log.Fatal("Failed to delete address journal", "err", err)
+	}
+}
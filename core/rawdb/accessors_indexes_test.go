@@ -0,0 +1,159 @@
+package rawdb
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dominant-strategies/go-quai/common"
+	"github.com/dominant-strategies/go-quai/ethdb"
+)
+
+// writeTestAddressActivity indexes the given number of entries of an address
+// at each of the given blocks, in ascending order, and returns them.
+func writeTestAddressActivity(db ethdb.KeyValueWriter, address common.Address, numbers []uint64, perBlock int) []AddressActivity {
+	var entries []AddressActivity
+	for _, number := range numbers {
+		for seq := 0; seq < perBlock; seq++ {
+			entry := AddressActivity{
+				Kind:        uint8(seq % 3),
+				BlockNumber: number,
+				BlockHash:   common.BytesToHash(encodeBlockNumber(number)),
+				TxHash:      common.BytesToHash(append(address.Bytes(), byte(seq))),
+				TxIndex:     uint64(seq),
+			}
+			WriteAddressActivity(db, address, uint32(seq), entry)
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// Tests that the entries of the address activity index are read back in the
+// order of their blocks and sequence numbers, only for the requested address,
+// and that deleting the entries of a block leaves the other ones untouched.
+func TestAddressActivityStorage(t *testing.T) {
+	db := NewMemoryDatabase()
+	address := common.HexToAddress("0x1e00000000000000000000000000000000000001")
+	other := common.HexToAddress("0x1e00000000000000000000000000000000000002")
+
+	// Block numbers crossing byte boundaries check the big endian ordering
+	entries := writeTestAddressActivity(db, address, []uint64{1, 2, 255, 256, 1 << 32}, 2)
+	writeTestAddressActivity(db, other, []uint64{1, 3}, 1)
+
+	activity, nextNumber, nextSeq := ReadAddressActivity(db, address, 0, 0, len(entries)+1)
+	if !reflect.DeepEqual(activity, entries) {
+		t.Fatalf("address activity mismatch: have %v, want %v", activity, entries)
+	}
+	if nextNumber != nil || nextSeq != nil {
+		t.Errorf("cursor returned past the last entry: #%d.%d", *nextNumber, *nextSeq)
+	}
+	if activity, _, _ := ReadAddressActivity(db, common.HexToAddress("0x1e00000000000000000000000000000000000003"), 0, 0, 10); len(activity) != 0 {
+		t.Errorf("activity returned for unknown address: %d entries", len(activity))
+	}
+
+	DeleteAddressActivity(db, address, 255)
+	activity, _, _ = ReadAddressActivity(db, address, 0, 0, len(entries))
+	want := append(append([]AddressActivity{}, entries[:4]...), entries[6:]...)
+	if !reflect.DeepEqual(activity, want) {
+		t.Errorf("address activity mismatch after deletion: have %d entries, want %d", len(activity), len(want))
+	}
+	if activity, _, _ := ReadAddressActivity(db, other, 0, 0, 10); len(activity) != 2 {
+		t.Errorf("activity of other address deleted: have %d entries, want 2", len(activity))
+	}
+}
+
+// Tests that paging through the address activity index returns every entry
+// exactly once, whatever the page size, and that the cursor is only returned
+// when entries are left.
+func TestAddressActivityPagination(t *testing.T) {
+	db := NewMemoryDatabase()
+	address := common.HexToAddress("0x1e00000000000000000000000000000000000001")
+	entries := writeTestAddressActivity(db, address, []uint64{1, 2, 255, 256}, 3)
+
+	for limit := 1; limit <= len(entries)+1; limit++ {
+		var (
+			activity []AddressActivity
+			number   uint64
+			seq      uint32
+			pages    int
+		)
+		for {
+			page, nextNumber, nextSeq := ReadAddressActivity(db, address, number, seq, limit)
+			pages++
+			activity = append(activity, page...)
+			if nextNumber == nil {
+				if len(page) == 0 && len(activity) > 0 {
+					t.Errorf("limit %d: empty last page", limit)
+				}
+				break
+			}
+			if len(page) != limit {
+				t.Errorf("limit %d: page %d has %d entries", limit, pages, len(page))
+			}
+			number, seq = *nextNumber, *nextSeq
+		}
+		if !reflect.DeepEqual(activity, entries) {
+			t.Errorf("limit %d: paged activity mismatch: have %d entries, want %d", limit, len(activity), len(entries))
+		}
+		if want := (len(entries) + limit - 1) / limit; pages != want {
+			t.Errorf("limit %d: page count mismatch: have %d, want %d", limit, pages, want)
+		}
+	}
+	// The cursor points at the first entry of the next page, even across
+	// blocks, and starting past the entries of a block moves to the next one
+	tests := []struct {
+		number uint64
+		seq    uint32
+		limit  int
+		first  int // Index of the first entry returned, -1 if none
+		next   int // Index of the entry the cursor points at, -1 if none
+	}{
+		{0, 0, 3, 0, 3},
+		{1, 2, 1, 2, 3},
+		{1, 3, 2, 3, 5},
+		{2, 0, 9, 3, -1},
+		{255, 5, 2, 9, 11},
+		{256, 2, 1, 11, -1},
+		{256, 3, 1, -1, -1},
+		{257, 0, 1, -1, -1},
+	}
+	for i, tt := range tests {
+		activity, nextNumber, nextSeq := ReadAddressActivity(db, address, tt.number, tt.seq, tt.limit)
+		if tt.first < 0 {
+			if len(activity) != 0 {
+				t.Errorf("test %d: activity returned past the last entry: %d entries", i, len(activity))
+			}
+		} else if len(activity) == 0 || !reflect.DeepEqual(activity[0], entries[tt.first]) {
+			t.Errorf("test %d: first entry mismatch: have %v, want %v", i, activity, entries[tt.first])
+		}
+		switch {
+		case tt.next < 0 && nextNumber != nil:
+			t.Errorf("test %d: cursor returned past the last entry: #%d.%d", i, *nextNumber, *nextSeq)
+		case tt.next >= 0 && (nextNumber == nil || *nextNumber != entries[tt.next].BlockNumber || uint64(*nextSeq) != entries[tt.next].TxIndex):
+			t.Errorf("test %d: cursor mismatch: want #%d.%d", i, entries[tt.next].BlockNumber, entries[tt.next].TxIndex)
+		}
+	}
+}
+
+// Tests that the journal of the addresses indexed at a block round-trips.
+func TestAddressJournalStorage(t *testing.T) {
+	db := NewMemoryDatabase()
+	addresses := []common.Address{
+		common.HexToAddress("0x1e00000000000000000000000000000000000001"),
+		common.HexToAddress("0x1e00000000000000000000000000000000000002"),
+	}
+	if journal := ReadAddressJournal(db, 1); journal != nil {
+		t.Fatalf("non-existent journal returned: %v", journal)
+	}
+	WriteAddressJournal(db, 1, addresses)
+	if journal := ReadAddressJournal(db, 1); !reflect.DeepEqual(journal, addresses) {
+		t.Fatalf("journal mismatch: have %v, want %v", journal, addresses)
+	}
+	if journal := ReadAddressJournal(db, 2); journal != nil {
+		t.Errorf("journal returned for another block: %v", journal)
+	}
+	DeleteAddressJournal(db, 1)
+	if journal := ReadAddressJournal(db, 1); journal != nil {
+		t.Errorf("deleted journal returned: %v", journal)
+	}
+}
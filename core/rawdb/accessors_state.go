@@ -17,9 +17,13 @@
 package rawdb
 
 import (
+	"bytes"
+
 	"github.com/dominant-strategies/go-quai/common"
+	"github.com/dominant-strategies/go-quai/core/types"
 	"github.com/dominant-strategies/go-quai/ethdb"
 	"github.com/dominant-strategies/go-quai/log"
+	"github.com/dominant-strategies/go-quai/rlp"
 )
 
 // ReadPreimage retrieves a single preimage of the provided hash.
@@ -94,3 +98,45 @@ func DeleteTrieNode(db ethdb.KeyValueWriter, hash common.Hash) {
 		log.Fatal("Failed to delete trie node", "err", err)
 	}
 }
+
+// ReadStateDiff retrieves the state changes made by a block.
+func ReadStateDiff(db ethdb.KeyValueReader, hash common.Hash, number uint64) types.StateDiff {
+	data, _ := db.Get(stateDiffKey(number, hash))
+	if len(data) == 0 {
+		return nil
+	}
+	diff := types.StateDiff{}
+	if err := rlp.DecodeBytes(data, &diff); err != nil {
+		log.Error("Invalid state diff RLP", "hash", hash, "err", err)
+		return nil
+	}
+	return diff
+}
+
+// WriteStateDiff stores the state changes made by a block.
+func WriteStateDiff(db ethdb.KeyValueWriter, hash common.Hash, number uint64, diff types.StateDiff) {
+	data, err := rlp.EncodeToBytes(diff)
+	if err != nil {
+		log.Fatal("Failed to RLP encode state diff", "err", err)
+	}
+	if err := db.Put(stateDiffKey(number, hash), data); err != nil {
+		log.Fatal("Failed to store state diff", "err", err)
+	}
+}
+
+// DeleteStateDiffs removes the state diffs of all blocks numbered below to,
+// reading the stored diffs from db and deleting them through batch.
+func DeleteStateDiffs(db ethdb.Iteratee, batch ethdb.KeyValueWriter, to uint64) {
+	end := stateDiffNumberKey(to)
+	it := db.NewIterator(stateDiffPrefix, nil)
+	defer it.Release()
+
+	for it.Next() {
+		if bytes.Compare(it.Key(), end) >= 0 {
+			break
+		}
+		if err := batch.Delete(it.Key()); err != nil {
+			log.Fatal("Failed to delete state diff", "err", err)
+		}
+	}
+}
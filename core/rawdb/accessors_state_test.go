@@ -0,0 +1,46 @@
+package rawdb
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/dominant-strategies/go-quai/common"
+	"github.com/dominant-strategies/go-quai/core/types"
+)
+
+// Tests that pruning state diffs removes the diffs of every block below the
+// given number, including side chain blocks, and only once the batch is written.
+func TestDeleteStateDiffs(t *testing.T) {
+	db := NewMemoryDatabase()
+	diff := types.StateDiff{{
+		Address: common.HexToAddress("0x0000000000000000000000000000000000000001"),
+		Balance: &types.BalanceDiff{Before: big.NewInt(1), After: big.NewInt(2)},
+	}}
+	type block struct {
+		number uint64
+		hash   common.Hash
+	}
+	var blocks []block
+	for number := uint64(1); number <= 5; number++ {
+		blocks = append(blocks, block{number, common.BigToHash(new(big.Int).SetUint64(number))})
+	}
+	blocks = append(blocks, block{3, common.HexToHash("0xff")}) // Side chain block
+	for _, b := range blocks {
+		WriteStateDiff(db, b.hash, b.number, diff)
+	}
+	batch := db.NewBatch()
+	DeleteStateDiffs(db, batch, 4)
+	for _, b := range blocks {
+		if ReadStateDiff(db, b.hash, b.number) == nil {
+			t.Fatalf("diff of block #%d deleted before the batch was written", b.number)
+		}
+	}
+	if err := batch.Write(); err != nil {
+		t.Fatalf("failed to write batch: %v", err)
+	}
+	for _, b := range blocks {
+		if stored := ReadStateDiff(db, b.hash, b.number) != nil; stored != (b.number >= 4) {
+			t.Errorf("diff of block #%d %x: stored %v, want %v", b.number, b.hash, stored, b.number >= 4)
+		}
+	}
+}
@@ -96,6 +96,11 @@ var (
 	terminiPrefix       = []byte("tk")    //terminiPrefix + hash -> []common.Hash
 	badHashesListPrefix = []byte("bh")
 	inboundEtxsPrefix   = []byte("ie") // inboundEtxsPrefix + hash -> types.Transactions
+	expiredEtxPrefix    = []byte("xe") // expiredEtxPrefix + etx hash -> types.ExpiredEtx
+
+	addressActivityPrefix = []byte("xa") // addressActivityPrefix + address + num (uint64 big endian) + seq (uint32 big endian) -> AddressActivity
+	addressJournalPrefix  = []byte("xj") // addressJournalPrefix + num (uint64 big endian) -> addresses indexed at block
+	stateDiffPrefix       = []byte("sd") // stateDiffPrefix + num (uint64 big endian) + hash -> state diff of block
 
 	blockBodyPrefix         = []byte("b")  // blockBodyPrefix + num (uint64 big endian) + hash -> block body
 	blockReceiptsPrefix     = []byte("r")  // blockReceiptsPrefix + num (uint64 big endian) + hash -> block receipts
@@ -116,6 +121,7 @@ var (
 
 	// Chain index prefixes (use `i` + single byte to avoid mixing data types).
 	BloomBitsIndexPrefix = []byte("iB") // BloomBitsIndexPrefix is the data table of a chain indexer to track its progress
+	AddressIndexPrefix   = []byte("iA") // AddressIndexPrefix is the data table of the address activity indexer to track its progress
 
 	preimageCounter    = metrics.NewRegisteredCounter("db/preimage/total", nil)
 	preimageHitCounter = metrics.NewRegisteredCounter("db/preimage/hits", nil)
@@ -308,3 +314,40 @@ func bloomKey(hash common.Hash) []byte {
 func inboundEtxsKey(hash common.Hash) []byte {
 	return append(inboundEtxsPrefix, hash.Bytes()...)
 }
+
+func expiredEtxKey(hash common.Hash) []byte {
+	return append(expiredEtxPrefix, hash.Bytes()...)
+}
+
+// addressActivityKey = addressActivityPrefix + address + num (uint64 big endian) + seq (uint32 big endian)
+func addressActivityKey(address common.Address, number uint64, seq uint32) []byte {
+	key := append(addressActivityBlockKey(address, number), make([]byte, 4)...)
+	binary.BigEndian.PutUint32(key[len(key)-4:], seq)
+	return key
+}
+
+// addressActivityBlockKey = addressActivityPrefix + address + num (uint64 big endian)
+func addressActivityBlockKey(address common.Address, number uint64) []byte {
+	return append(addressActivityAddressKey(address), encodeBlockNumber(number)...)
+}
+
+// addressActivityAddressKey = addressActivityPrefix + address
+func addressActivityAddressKey(address common.Address) []byte {
+	bytes := address.Bytes20()
+	return append(append([]byte{}, addressActivityPrefix...), bytes[:]...)
+}
+
+// addressJournalKey = addressJournalPrefix + num (uint64 big endian)
+func addressJournalKey(number uint64) []byte {
+	return append(addressJournalPrefix, encodeBlockNumber(number)...)
+}
+
+// stateDiffKey = stateDiffPrefix + num (uint64 big endian) + hash
+func stateDiffKey(number uint64, hash common.Hash) []byte {
+	return append(stateDiffNumberKey(number), hash.Bytes()...)
+}
+
+// stateDiffNumberKey = stateDiffPrefix + num (uint64 big endian)
+func stateDiffNumberKey(number uint64) []byte {
+	return append(append([]byte{}, stateDiffPrefix...), encodeBlockNumber(number)...)
+}
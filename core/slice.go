@@ -65,6 +65,10 @@ type Slice struct {
 	pendingEtxsFeed       event.Feed
 	pendingEtxsRollupFeed event.Feed
 	missingBlockFeed      event.Feed
+	missingManifestFeed   event.Feed
+	missingPendingEtxFeed event.Feed
+	coincidentBlockFeed   event.Feed
+	subReorgFeed          event.Feed
 
 	pEtxRetryCache *lru.Cache
 	asyncPhCh      chan *types.Header
@@ -132,6 +136,9 @@ func NewSlice(db ethdb.Database, config *Config, txConfig *TxPoolConfig, txLooku
 		go sl.asyncPendingHeaderLoop()
 	}
 
+	sl.wg.Add(1)
+	go sl.phCacheGCLoop()
+
 	return sl, nil
 }
 
@@ -337,9 +344,16 @@ func (sl *Slice) Append(header *types.Header, domPendingHeader *types.Header, do
 		sl.hc.SetCurrentHeader(block.Header())
 	}
 
+	// A block which did not become the head is a side block of the zone, and
+	// may be included as an uncle by a later block
+	if nodeCtx == common.ZONE_CTX && !setHead {
+		sl.hc.chainSideFeed.Send(ChainSideEvent{Block: block})
+	}
 	if subReorg {
 		sl.hc.chainHeadFeed.Send(ChainHeadEvent{Block: block})
+		sl.subReorgFeed.Send(SubReorgEvent{Block: block, Order: order, Terminus: pendingHeaderWithTermini.Termini().DomTerminus(), SetHead: setHead})
 	}
+	sl.sendCoincidentBlockEvent(block, order)
 
 	// Relay the new pendingHeader
 	sl.relayPh(block, pendingHeaderWithTermini, domOrigin, block.Location(), subReorg)
@@ -368,6 +382,15 @@ func (sl *Slice) Append(header *types.Header, domPendingHeader *types.Header, do
 	}
 }
 
+// sendCoincidentBlockEvent announces a block which is coincident with a
+// dominant chain of the slice, i.e. whose order is above the context of the
+// node.
+func (sl *Slice) sendCoincidentBlockEvent(block *types.Block, order int) {
+	if order < common.NodeLocation.Context() {
+		sl.coincidentBlockFeed.Send(CoincidentBlockEvent{Block: block, Order: order})
+	}
+}
+
 func (sl *Slice) miningStrategy(bestPh types.PendingHeader, pendingHeader types.PendingHeader) bool {
 	if bestPh.Header() == nil { // This is the case where we try to append the block before we have not initialized the bestPh
 		return true
@@ -517,7 +540,7 @@ func (sl *Slice) readPhCache(hash common.Hash) (types.PendingHeader, bool) {
 	} else {
 		ph := rawdb.ReadPendingHeader(sl.sliceDb, hash)
 		if ph != nil {
-			sl.phCache.Add(hash, ph)
+			sl.phCache.Add(hash, *ph)
 			return *types.CopyPendingHeader(ph), true
 		} else {
 			return types.PendingHeader{}, false
@@ -532,6 +555,115 @@ func (sl *Slice) writePhCache(hash common.Hash, pendingHeader types.PendingHeade
 	rawdb.WritePendingHeader(sl.sliceDb, hash, pendingHeader)
 }
 
+// PendingHeaders returns a copy of every pending header held in the phCache
+// keyed by its terminus hash, along with the key of the best pending header.
+func (sl *Slice) PendingHeaders() (map[common.Hash]types.PendingHeader, common.Hash) {
+	sl.phCacheMu.RLock()
+	defer sl.phCacheMu.RUnlock()
+
+	pendingHeaders := make(map[common.Hash]types.PendingHeader, sl.phCache.Len())
+	for _, key := range sl.phCache.Keys() {
+		hash, ok := key.(common.Hash)
+		if !ok {
+			continue
+		}
+		if ph, exists := sl.phCache.Peek(hash); exists {
+			if ph, ok := ph.(types.PendingHeader); ok && ph.Header() != nil {
+				pendingHeaders[hash] = *types.CopyPendingHeader(&ph)
+			}
+		}
+	}
+	return pendingHeaders, sl.bestPhKey
+}
+
+// phCacheGCLoop periodically prunes the pending headers that fell below the
+// current termini from the phCache and the database.
+func (sl *Slice) phCacheGCLoop() {
+	defer sl.wg.Done()
+
+	ticker := time.NewTicker(pendingHeaderGCTime * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			sl.PrunePendingHeaders()
+		case <-sl.quit:
+			return
+		}
+	}
+}
+
+// PrunePendingHeaders deletes the pending headers, and their termini, keyed by
+// unknown blocks or by blocks that are older than c_phCacheSize blocks and
+// below the dom terminus c_pendingHeaderChacheBufferFactor dom blocks behind the
+// current header. The best pending header and the genesis pending header are
+// always kept. It returns the number of pruned pending headers.
+func (sl *Slice) PrunePendingHeaders() int {
+	nodeCtx := common.NodeLocation.Context()
+	current := sl.hc.CurrentHeader()
+	if current == nil || current.NumberU64() <= c_phCacheSize {
+		return 0
+	}
+	limit := current.NumberU64() - c_phCacheSize
+
+	// Walk back the dom termini to find the oldest block the dom may still
+	// reference when updating the pending headers
+	terminus := current
+	for i := 0; i < c_pendingHeaderChacheBufferFactor; i++ {
+		termini := sl.hc.GetTerminiByHash(terminus.Hash())
+		if termini == nil {
+			break
+		}
+		dom := sl.hc.GetHeaderByHash(termini.DomTerminus())
+		if dom == nil || dom.Hash() == terminus.Hash() {
+			break
+		}
+		terminus = dom
+	}
+	if terminus.NumberU64() < limit {
+		limit = terminus.NumberU64()
+	}
+
+	// Entries keyed by unknown blocks can never be built upon and are pruned
+	// as well
+	isStale := func(hash common.Hash) bool {
+		if hash == sl.config.GenesisHash {
+			return false
+		}
+		header := sl.hc.GetHeaderByHash(hash)
+		return header == nil || header.NumberU64() < limit
+	}
+	// Collect the stale entries before taking the lock, so that pending header
+	// updates are not blocked while iterating the database
+	var stale []common.Hash
+	for _, hash := range rawdb.ReadAllPendingHeaderHashes(sl.sliceDb) {
+		if isStale(hash) {
+			stale = append(stale, hash)
+		}
+	}
+
+	sl.phCacheMu.Lock()
+	defer sl.phCacheMu.Unlock()
+
+	pruned := 0
+	for _, hash := range stale {
+		// The block of an entry may have been appended, or the entry become
+		// the best pending header, since the scan
+		if hash == sl.bestPhKey || !isStale(hash) {
+			continue
+		}
+		sl.phCache.Remove(hash)
+		rawdb.DeletePendingHeader(sl.sliceDb, hash)
+		rawdb.DeletePendingHeaderTermini(sl.sliceDb, hash)
+		pruned++
+	}
+	if pruned > 0 {
+		log.Info("Pruned pending headers", "ctx", nodeCtx, "count", pruned, "below", limit)
+	}
+	return pruned
+}
+
 // WriteBestPhKey writes the sl.bestPhKey
 func (sl *Slice) WriteBestPhKey(hash common.Hash) {
 	sl.bestPhKey = hash
@@ -703,6 +835,32 @@ func (sl *Slice) GetManifest(blockHash common.Hash) (types.BlockManifest, error)
 	return nil, errors.New("manifest not found in the disk")
 }
 
+// GetInboundEtxs returns the ETXs the block with the given hash handed down to
+// its subordinate chains when it was appended. Blocks coincident with the dom
+// handed down what the dom confirmed, so those are looked up from the dom.
+func (sl *Slice) GetInboundEtxs(blockHash common.Hash) (types.Transactions, error) {
+	nodeCtx := common.NodeLocation.Context()
+	if nodeCtx == common.ZONE_CTX {
+		return nil, errors.New("zone chains do not hand down inbound etxs")
+	}
+	block := sl.hc.GetBlockByHash(blockHash)
+	if block == nil {
+		return nil, ErrBodyNotFound
+	}
+	_, order, err := sl.engine.CalcOrder(block.Header())
+	if err != nil {
+		return nil, err
+	}
+	if order < nodeCtx {
+		if sl.domClient == nil {
+			return nil, errors.New("missing dom client to look up the inbound etxs")
+		}
+		return sl.domClient.GetInboundEtxs(context.Background(), blockHash)
+	}
+	newInboundEtxs, _, err := sl.CollectNewlyConfirmedEtxs(block, block.Location())
+	return newInboundEtxs, err
+}
+
 // GetSubManifest gets the block manifest from the subordinate node which
 // produced this block
 func (sl *Slice) GetSubManifest(slice common.Location, blockHash common.Hash) (types.BlockManifest, error) {
@@ -1146,6 +1304,7 @@ func (sl *Slice) combinePendingHeader(header *types.Header, slPendingHeader *typ
 		combinedPendingHeader.SetRoot(header.Root())
 		combinedPendingHeader.SetCoinbase(header.Coinbase())
 		combinedPendingHeader.SetBaseFee(header.BaseFee())
+		combinedPendingHeader.SetUncledS(header.UncledS())
 		combinedPendingHeader.SetGasLimit(header.GasLimit())
 		combinedPendingHeader.SetGasUsed(header.GasUsed())
 		combinedPendingHeader.SetExtra(header.Extra())
@@ -1202,12 +1361,34 @@ func (sl *Slice) SubscribeMissingBlockEvent(ch chan<- types.BlockRequest) event.
 	return sl.scope.Track(sl.missingBlockFeed.Subscribe(ch))
 }
 
+// SubscribeMissingManifestEvent registers a subscription for the manifests a
+// light chain needs from its peers.
+func (sl *Slice) SubscribeMissingManifestEvent(ch chan<- types.BlockRequest) event.Subscription {
+	return sl.scope.Track(sl.missingManifestFeed.Subscribe(ch))
+}
+
+// SubscribeMissingPendingEtxsEvent registers a subscription for the pending
+// ETXs a light chain needs from its peers.
+func (sl *Slice) SubscribeMissingPendingEtxsEvent(ch chan<- types.BlockRequest) event.Subscription {
+	return sl.scope.Track(sl.missingPendingEtxFeed.Subscribe(ch))
+}
+
+// SubscribeCoincidentBlockEvent registers a subscription of CoincidentBlockEvent.
+func (sl *Slice) SubscribeCoincidentBlockEvent(ch chan<- CoincidentBlockEvent) event.Subscription {
+	return sl.scope.Track(sl.coincidentBlockFeed.Subscribe(ch))
+}
+
+// SubscribeSubReorgEvent registers a subscription of SubReorgEvent.
+func (sl *Slice) SubscribeSubReorgEvent(ch chan<- SubReorgEvent) event.Subscription {
+	return sl.scope.Track(sl.subReorgFeed.Subscribe(ch))
+}
+
 // MakeDomClient creates the quaiclient for the given domurl
 func makeDomClient(domurl string) *quaiclient.Client {
 	if domurl == "" {
 		log.Fatal("dom client url is empty")
 	}
-	domClient, err := quaiclient.Dial(domurl)
+	domClient, err := quaiclient.DialLink("dom", domurl)
 	if err != nil {
 		log.Fatal("Error connecting to the dominant go-quai client", "err", err)
 	}
@@ -1219,7 +1400,7 @@ func makeSubClients(suburls []string) []*quaiclient.Client {
 	subClients := make([]*quaiclient.Client, 3)
 	for i, suburl := range suburls {
 		if suburl != "" {
-			subClient, err := quaiclient.Dial(suburl)
+			subClient, err := quaiclient.DialLink(fmt.Sprintf("sub%d", i), suburl)
 			if err != nil {
 				log.Fatal("Error connecting to the subordinate go-quai client for index", "index", i, " err ", err)
 			}
@@ -1229,13 +1410,36 @@ func makeSubClients(suburls []string) []*quaiclient.Client {
 	return subClients
 }
 
+// LinkStatus returns the health of the links to the dom and sub nodes.
+func (sl *Slice) LinkStatus() []quaiclient.LinkStatus {
+	var status []quaiclient.LinkStatus
+	if sl.domClient != nil {
+		status = append(status, sl.domClient.Status())
+	}
+	for _, client := range sl.subClients {
+		if client != nil {
+			status = append(status, client.Status())
+		}
+	}
+	return status
+}
+
 // loadLastState loads the phCache and the slice pending header hash from the db.
 func (sl *Slice) loadLastState() error {
 	sl.bestPhKey = rawdb.ReadBestPhKey(sl.sliceDb)
+	// Restore the pending headers held in the phCache at shutdown, oldest
+	// first so the most recently used entries survive the eviction policy
+	keys := rawdb.ReadPhCacheKeys(sl.sliceDb)
+	for _, key := range keys {
+		if ph := rawdb.ReadPendingHeader(sl.sliceDb, key); ph != nil && ph.Header() != nil {
+			sl.phCache.Add(key, *ph)
+		}
+	}
 	bestPh := rawdb.ReadPendingHeader(sl.sliceDb, sl.bestPhKey)
 	if bestPh != nil {
 		sl.writePhCache(sl.bestPhKey, *bestPh)
 	}
+	log.Info("Loaded pending header cache", "entries", sl.phCache.Len(), "stored", len(keys))
 
 	if sl.ProcessingState() {
 		sl.miner.worker.LoadPendingBlockBody()
@@ -1253,9 +1457,11 @@ func (sl *Slice) Stop() {
 	}
 	rawdb.WriteBadHashesList(sl.sliceDb, badHashes)
 	sl.miner.worker.StorePendingBlockBody()
+	sl.storePhCache()
 
 	sl.scope.Close()
 	close(sl.quit)
+	sl.wg.Wait()
 
 	sl.hc.Stop()
 	if nodeCtx == common.ZONE_CTX && sl.ProcessingState() {
@@ -1265,6 +1471,29 @@ func (sl *Slice) Stop() {
 	sl.miner.Stop()
 }
 
+// storePhCache writes every pending header held in the phCache and their keys
+// to the db, so that loadLastState can restore the cache on restart.
+func (sl *Slice) storePhCache() {
+	sl.phCacheMu.RLock()
+	defer sl.phCacheMu.RUnlock()
+
+	// Keys are ordered from oldest to newest
+	keys := make([]common.Hash, 0, sl.phCache.Len())
+	for _, key := range sl.phCache.Keys() {
+		hash, ok := key.(common.Hash)
+		if !ok {
+			continue
+		}
+		if ph, exists := sl.phCache.Peek(hash); exists {
+			if ph, ok := ph.(types.PendingHeader); ok && ph.Header() != nil {
+				rawdb.WritePendingHeader(sl.sliceDb, hash, ph)
+				keys = append(keys, hash)
+			}
+		}
+	}
+	rawdb.WritePhCacheKeys(sl.sliceDb, keys)
+}
+
 func (sl *Slice) Config() *params.ChainConfig { return sl.config }
 
 func (sl *Slice) Engine() consensus.Engine { return sl.engine }
@@ -1291,7 +1520,9 @@ func (sl *Slice) AddPendingEtxs(pEtxs types.PendingEtxs) error {
 			// Also the first time when adding the pending etx broadcast it to the peers
 			sl.pendingEtxsFeed.Send(pEtxs)
 			if sl.domClient != nil {
-				sl.domClient.SendPendingEtxsToDom(context.Background(), pEtxs)
+				if err := sl.domClient.SendPendingEtxsToDom(context.Background(), pEtxs); err != nil {
+					log.Error("failed to send ETXs to domclient", "block: ", pEtxs.Header.Hash(), "err", err)
+				}
 			}
 		}
 	} else if err.Error() == ErrPendingEtxAlreadyKnown.Error() {
@@ -1323,7 +1554,9 @@ func (sl *Slice) AddPendingEtxsRollup(pEtxsRollup types.PendingEtxsRollup) error
 			// Only in the region case, send the pending etx rollup to the dom
 		} else if nodeCtx == common.REGION_CTX {
 			if sl.domClient != nil {
-				sl.domClient.SendPendingEtxsRollupToDom(context.Background(), pEtxsRollup)
+				if err := sl.domClient.SendPendingEtxsRollupToDom(context.Background(), pEtxsRollup); err != nil {
+					log.Error("failed to send ETXs rollup to domclient", "block: ", pEtxsRollup.Header.Hash(), "err", err)
+				}
 			}
 		}
 	}
@@ -1386,6 +1619,7 @@ func (sl *Slice) cleanCacheAndDatabaseTillBlock(hash common.Hash) {
 	sl.phCache.Purge()
 	sl.miner.worker.pendingBlockBody.Purge()
 	rawdb.DeleteBestPhKey(sl.sliceDb)
+	rawdb.DeletePhCacheKeys(sl.sliceDb)
 	// headerchain caches
 	sl.hc.headerCache.Purge()
 	sl.hc.numberCache.Purge()
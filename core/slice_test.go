@@ -0,0 +1,164 @@
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/dominant-strategies/go-quai/common"
+	"github.com/dominant-strategies/go-quai/core/rawdb"
+	"github.com/dominant-strategies/go-quai/core/types"
+	"github.com/dominant-strategies/go-quai/params"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// newTestSlice creates a slice on top of the database of the given header
+// chain, holding pending headers in an empty phCache.
+func newTestSlice(hc *HeaderChain, genesis common.Hash) *Slice {
+	sl := &Slice{hc: hc, sliceDb: hc.headerDb, config: &params.ChainConfig{ChainID: big.NewInt(1), GenesisHash: genesis}}
+	sl.phCache, _ = lru.New(c_phCacheSize)
+	return sl
+}
+
+// testPendingHeader returns a pending header building on the given block.
+func testPendingHeader(parent *types.Header) types.PendingHeader {
+	header := types.EmptyHeader()
+	header.SetParentHash(parent.Hash())
+	header.SetNumber(new(big.Int).Add(parent.Number(), common.Big1))
+	return types.NewPendingHeader(header, types.EmptyTermini())
+}
+
+// Tests that only the blocks coincident with a dominant chain of the node are
+// announced as coincident blocks, in every context.
+func TestCoincidentBlockEvent(t *testing.T) {
+	defer func(loc common.Location) { common.NodeLocation = loc }(common.NodeLocation)
+
+	tests := []struct {
+		location common.Location
+		orders   []int // Orders of the appended blocks announced as coincident
+	}{
+		{common.Location{}, nil},
+		{common.Location{0}, []int{common.PRIME_CTX}},
+		{common.Location{0, 0}, []int{common.PRIME_CTX, common.REGION_CTX}},
+	}
+	for _, tt := range tests {
+		common.NodeLocation = tt.location
+
+		sl := new(Slice)
+		events := make(chan CoincidentBlockEvent, common.HierarchyDepth)
+		sub := sl.SubscribeCoincidentBlockEvent(events)
+
+		for order := common.PRIME_CTX; order < common.HierarchyDepth; order++ {
+			header := types.EmptyHeader()
+			header.SetNumber(big.NewInt(int64(order + 1)))
+			sl.sendCoincidentBlockEvent(types.NewBlockWithHeader(header), order)
+		}
+		sub.Unsubscribe()
+		close(events)
+
+		var orders []int
+		for ev := range events {
+			orders = append(orders, ev.Order)
+		}
+		if len(orders) != len(tt.orders) {
+			t.Errorf("location %v: announced orders mismatch: have %v, want %v", tt.location, orders, tt.orders)
+			continue
+		}
+		for i := range orders {
+			if orders[i] != tt.orders[i] {
+				t.Errorf("location %v: announced orders mismatch: have %v, want %v", tt.location, orders, tt.orders)
+				break
+			}
+		}
+	}
+}
+
+// Tests that the pending headers keyed by unknown or old blocks are pruned from
+// the phCache and the database, while the genesis, best and recent pending
+// headers are kept.
+func TestPrunePendingHeaders(t *testing.T) {
+	defer func(loc common.Location) { common.NodeLocation = loc }(common.NodeLocation)
+	common.NodeLocation = common.Location{0, 0}
+
+	hc := newTestHeaderChain()
+	genesis := writeTestBlock(hc, common.Hash{}, 0, "", types.EtxSet{})
+	old := writeTestBlock(hc, genesis.Hash(), 10, "old", types.EtxSet{})
+	best := writeTestBlock(hc, genesis.Hash(), 10, "best", types.EtxSet{})
+	recent := writeTestBlock(hc, genesis.Hash(), c_phCacheSize+50, "recent", types.EtxSet{})
+	current := writeTestBlock(hc, genesis.Hash(), c_phCacheSize+100, "current", types.EtxSet{})
+	unknown := types.EmptyHeader()
+	unknown.SetNumber(big.NewInt(c_phCacheSize + 90))
+	hc.currentHeader.Store(current)
+
+	sl := newTestSlice(hc, genesis.Hash())
+	for _, header := range []*types.Header{genesis, old, best, recent, current, unknown} {
+		sl.writePhCache(header.Hash(), testPendingHeader(header))
+	}
+	sl.WriteBestPhKey(best.Hash())
+
+	if pruned := sl.PrunePendingHeaders(); pruned != 2 {
+		t.Errorf("pruned pending headers mismatch: have %d, want %d", pruned, 2)
+	}
+	for _, header := range []*types.Header{old, unknown} {
+		if sl.phCache.Contains(header.Hash()) || rawdb.ReadPendingHeader(sl.sliceDb, header.Hash()) != nil {
+			t.Errorf("stale pending header #%d kept", header.NumberU64())
+		}
+	}
+	for _, header := range []*types.Header{genesis, best, recent, current} {
+		if _, exists := sl.readPhCache(header.Hash()); !exists {
+			t.Errorf("pending header #%d pruned", header.NumberU64())
+		}
+	}
+	// Nothing is left to prune once the stale entries are gone
+	if pruned := sl.PrunePendingHeaders(); pruned != 0 {
+		t.Errorf("pending headers pruned twice: %d", pruned)
+	}
+}
+
+// Tests that the pending headers of the phCache and the best pending header key
+// are restored on start, in the order they were used.
+func TestRestorePendingHeaders(t *testing.T) {
+	defer func(loc common.Location) { common.NodeLocation = loc }(common.NodeLocation)
+	common.NodeLocation = common.Location{0, 0}
+
+	hc := newTestHeaderChain()
+	hc.bc.slicesRunning = nil // Don't restore the pending block body of the worker
+
+	genesis := writeTestBlock(hc, common.Hash{}, 0, "", types.EtxSet{})
+	sl := newTestSlice(hc, genesis.Hash())
+	var hashes []common.Hash
+	for i := uint64(1); i <= 3; i++ {
+		header := writeTestBlock(hc, genesis.Hash(), i, "", types.EtxSet{})
+		sl.writePhCache(header.Hash(), testPendingHeader(header))
+		hashes = append(hashes, header.Hash())
+	}
+	sl.WriteBestPhKey(hashes[1])
+	// Use the first entry last, so that it is the most recently used one
+	sl.readPhCache(hashes[0])
+	sl.storePhCache()
+
+	restored := newTestSlice(hc, genesis.Hash())
+	if err := restored.loadLastState(); err != nil {
+		t.Fatalf("failed to load last state: %v", err)
+	}
+	if restored.bestPhKey != hashes[1] {
+		t.Errorf("best pending header key mismatch: have %x, want %x", restored.bestPhKey, hashes[1])
+	}
+	pendingHeaders, _ := restored.PendingHeaders()
+	if len(pendingHeaders) != len(hashes) {
+		t.Fatalf("restored pending headers mismatch: have %d, want %d", len(pendingHeaders), len(hashes))
+	}
+	for _, hash := range hashes {
+		ph, exists := pendingHeaders[hash]
+		if !exists || ph.Header().ParentHash() != hash {
+			t.Errorf("pending header of %x not restored", hash)
+		}
+	}
+	// The order of use is kept, apart from the best pending header which is
+	// rewritten last
+	want := []common.Hash{hashes[2], hashes[0], hashes[1]}
+	for i, key := range restored.phCache.Keys() {
+		if key.(common.Hash) != want[i] {
+			t.Errorf("entry %d: phCache order mismatch: have %x, want %x", i, key, want[i])
+		}
+	}
+}
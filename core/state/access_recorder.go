@@ -0,0 +1,181 @@
+package state
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+
+	"github.com/dominant-strategies/go-quai/common"
+)
+
+// accessKey identifies either an account or a single storage slot of an
+// account in an AccessRecorder.
+type accessKey struct {
+	addr   common.InternalAddress
+	slot   common.Hash
+	isSlot bool
+}
+
+// AccessRecorder collects the accounts and storage slots read and written by a
+// StateDB. It is used by the parallel transaction executor to detect conflicts
+// between speculatively executed transactions.
+type AccessRecorder struct {
+	reads     map[accessKey]struct{}
+	writes    map[accessKey]struct{}
+	finalised map[common.InternalAddress]struct{} // Accounts flushed by Finalise
+	fees      map[common.InternalAddress]*big.Int // Fees credited through AddFee
+	paused    bool
+}
+
+// NewAccessRecorder creates an empty access recorder.
+func NewAccessRecorder() *AccessRecorder {
+	return &AccessRecorder{
+		reads:     make(map[accessKey]struct{}),
+		writes:    make(map[accessKey]struct{}),
+		finalised: make(map[common.InternalAddress]struct{}),
+		fees:      make(map[common.InternalAddress]*big.Int),
+	}
+}
+
+func (r *AccessRecorder) readAccount(addr common.InternalAddress) {
+	if r != nil && !r.paused {
+		r.reads[accessKey{addr: addr}] = struct{}{}
+	}
+}
+
+func (r *AccessRecorder) readSlot(addr common.InternalAddress, slot common.Hash) {
+	if r != nil && !r.paused {
+		r.reads[accessKey{addr: addr, slot: slot, isSlot: true}] = struct{}{}
+	}
+}
+
+func (r *AccessRecorder) writeAccount(addr common.InternalAddress) {
+	if r != nil && !r.paused {
+		r.writes[accessKey{addr: addr}] = struct{}{}
+	}
+}
+
+func (r *AccessRecorder) writeSlot(addr common.InternalAddress, slot common.Hash) {
+	if r != nil && !r.paused {
+		r.writes[accessKey{addr: addr, slot: slot, isSlot: true}] = struct{}{}
+	}
+}
+
+func (r *AccessRecorder) finalise(addr common.InternalAddress) {
+	if r != nil {
+		r.finalised[addr] = struct{}{}
+	}
+}
+
+func (r *AccessRecorder) addFee(addr common.InternalAddress, amount *big.Int) {
+	if r == nil {
+		return
+	}
+	if fee, ok := r.fees[addr]; ok {
+		fee.Add(fee, amount)
+	} else {
+		r.fees[addr] = new(big.Int).Set(amount)
+	}
+}
+
+// touched reports whether the account was accessed other than through AddFee.
+func (r *AccessRecorder) touched(addr common.InternalAddress) bool {
+	key := accessKey{addr: addr}
+	if _, ok := r.reads[key]; ok {
+		return true
+	}
+	_, ok := r.writes[key]
+	return ok
+}
+
+// Conflicts reports whether any account or slot accessed by r was written by
+// committed. Fees credited in committed count as writes to the fee recipient.
+func (r *AccessRecorder) Conflicts(committed *AccessRecorder) bool {
+	written := func(key accessKey) bool {
+		if _, ok := committed.writes[key]; ok {
+			return true
+		}
+		if !key.isSlot {
+			_, ok := committed.fees[key.addr]
+			return ok
+		}
+		return false
+	}
+	for key := range r.reads {
+		if written(key) {
+			return true
+		}
+	}
+	for key := range r.writes {
+		if written(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetAccessRecorder attaches an access recorder to the state, or detaches the
+// current one if r is nil.
+func (s *StateDB) SetAccessRecorder(r *AccessRecorder) {
+	s.recorder = r
+}
+
+// Merge applies the finalised changes of a transaction which was executed
+// speculatively on spec to s. Both states must share the same pre-state, spec
+// must have had an access recorder attached and the transaction must not
+// conflict with any change applied to s since. The logs of the transaction are
+// re-added under the hash and index set by the last call to Prepare on s.
+//
+// Merge returns false without modifying s if the changes can not be merged,
+// in which case the transaction has to be executed on s directly.
+func (s *StateDB) Merge(spec *StateDB) bool {
+	r := spec.recorder
+	if r == nil {
+		return false
+	}
+	addrs := make([]common.InternalAddress, 0, len(r.finalised))
+	for addr := range r.finalised {
+		if spec.stateObjects[addr] == nil {
+			return false
+		}
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool { return bytes.Compare(addrs[i][:], addrs[j][:]) < 0 })
+
+	for _, addr := range addrs {
+		// Fee recipients which were not otherwise accessed are credited below
+		if _, ok := r.fees[addr]; ok && !r.touched(addr) {
+			continue
+		}
+		obj := spec.stateObjects[addr]
+		if obj.created {
+			s.CreateAccount(addr)
+		}
+		if obj.suicided {
+			s.Suicide(addr)
+			continue
+		}
+		s.SetBalance(addr, new(big.Int).Set(obj.Balance()))
+		s.SetNonce(addr, obj.Nonce())
+		if obj.dirtyCode {
+			s.SetCode(addr, obj.code)
+		}
+		for key, value := range obj.pendingStorage {
+			s.SetState(addr, key, value)
+		}
+	}
+	for _, addr := range addrs {
+		if fee, ok := r.fees[addr]; ok && !r.touched(addr) {
+			s.AddFee(addr, fee)
+		}
+	}
+	for _, l := range spec.logs[spec.thash] {
+		cpy := *l
+		s.AddLog(&cpy)
+	}
+	for hash, preimage := range spec.preimages {
+		s.AddPreimage(hash, preimage)
+	}
+	s.Finalise(true)
+	return true
+}
@@ -130,6 +130,10 @@ type (
 	touchChange struct {
 		account *common.InternalAddress
 	}
+	transientStorageChange struct {
+		account       *common.InternalAddress
+		key, prevalue common.Hash
+	}
 	// Changes to the access list
 	accessListAddAccountChange struct {
 		address *common.AddressBytes
@@ -213,6 +217,14 @@ func (ch storageChange) dirtied() *common.InternalAddress {
 	return ch.account
 }
 
+func (ch transientStorageChange) revert(s *StateDB) {
+	s.setTransientState(*ch.account, ch.key, ch.prevalue)
+}
+
+func (ch transientStorageChange) dirtied() *common.InternalAddress {
+	return nil
+}
+
 func (ch refundChange) revert(s *StateDB) {
 	s.refund = ch.prev
 }
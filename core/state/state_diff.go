@@ -0,0 +1,140 @@
+package state
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"sort"
+
+	"github.com/dominant-strategies/go-quai/common"
+	"github.com/dominant-strategies/go-quai/core/types"
+	"github.com/dominant-strategies/go-quai/trie"
+)
+
+// EnableDiffTracking makes the state remember the accounts and storage slots
+// modified from now on, so that StateDiff can report their changes.
+func (s *StateDB) EnableDiffTracking() {
+	s.diffSlots = make(map[common.InternalAddress]map[common.Hash]struct{})
+	s.diffWiped = make(map[common.InternalAddress]struct{})
+}
+
+// trackDiff records an account which is about to be finalised along with the
+// storage slots modified in the current transaction.
+func (s *StateDB) trackDiff(obj *stateObject) {
+	if s.diffSlots == nil {
+		return
+	}
+	slots := s.diffSlots[obj.address]
+	if slots == nil {
+		slots = make(map[common.Hash]struct{})
+		s.diffSlots[obj.address] = slots
+	}
+	for key := range obj.dirtyStorage {
+		slots[key] = struct{}{}
+	}
+	if obj.suicided || obj.created {
+		s.diffWiped[obj.address] = struct{}{}
+	}
+}
+
+// storageKeys returns the keys of all storage slots of the object. Slots whose
+// preimage is unknown can not be reported and are left out.
+func (s *StateDB) storageKeys(obj *stateObject) []common.Hash {
+	var keys []common.Hash
+	tr := obj.getTrie(s.db)
+	it := trie.NewIterator(tr.NodeIterator(nil))
+	for it.Next() {
+		if preimage := tr.GetKey(it.Key); preimage != nil {
+			keys = append(keys, common.BytesToHash(preimage))
+		}
+	}
+	return keys
+}
+
+// StateDiff returns the changes made to the state since the diff tracking was
+// enabled, compared against the state at the root the StateDB was opened at.
+// Accounts and slots which were modified but ended up with their original
+// values are left out.
+func (s *StateDB) StateDiff() (types.StateDiff, error) {
+	if s.diffSlots == nil {
+		return nil, errors.New("state diff tracking not enabled")
+	}
+	parent, err := New(s.originalRoot, s.db, s.snaps)
+	if err != nil {
+		return nil, err
+	}
+	addrs := make([]common.InternalAddress, 0, len(s.diffSlots))
+	for addr := range s.diffSlots {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool { return bytes.Compare(addrs[i][:], addrs[j][:]) < 0 })
+
+	var diff types.StateDiff
+	for _, addr := range addrs {
+		before, after := parent.getStateObject(addr), s.getStateObject(addr)
+		if before == nil && after == nil {
+			continue
+		}
+		internal := addr
+		account := &types.AccountDiff{
+			Address: common.NewAddressFromData(&internal),
+			Created: before == nil,
+			Deleted: after == nil,
+		}
+		var (
+			balanceBefore, balanceAfter = new(big.Int), new(big.Int)
+			nonceBefore, nonceAfter     uint64
+			codeBefore, codeAfter       []byte
+		)
+		if before != nil {
+			balanceBefore, nonceBefore = before.Balance(), before.Nonce()
+			codeBefore = before.Code(s.db)
+		}
+		if after != nil {
+			balanceAfter, nonceAfter = after.Balance(), after.Nonce()
+			codeAfter = after.Code(s.db)
+		}
+		if balanceBefore.Cmp(balanceAfter) != 0 {
+			account.Balance = &types.BalanceDiff{Before: new(big.Int).Set(balanceBefore), After: new(big.Int).Set(balanceAfter)}
+		}
+		if nonceBefore != nonceAfter {
+			account.Nonce = &types.NonceDiff{Before: nonceBefore, After: nonceAfter}
+		}
+		if !bytes.Equal(codeBefore, codeAfter) {
+			account.Code = &types.CodeDiff{Before: common.CopyBytes(codeBefore), After: common.CopyBytes(codeAfter)}
+		}
+		slots := s.diffSlots[addr]
+		if _, wiped := s.diffWiped[addr]; before != nil && before.data.Root != emptyRoot && (wiped || after == nil) {
+			// Every slot of the account was cleared, not only the modified ones
+			account.StorageWiped = true
+			slots = make(map[common.Hash]struct{}, len(slots))
+			for key := range s.diffSlots[addr] {
+				slots[key] = struct{}{}
+			}
+			for _, key := range parent.storageKeys(before) {
+				slots[key] = struct{}{}
+			}
+		}
+		keys := make([]common.Hash, 0, len(slots))
+		for key := range slots {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i][:], keys[j][:]) < 0 })
+		for _, key := range keys {
+			var valueBefore, valueAfter common.Hash
+			if before != nil {
+				valueBefore = before.GetState(s.db, key)
+			}
+			if after != nil {
+				valueAfter = after.GetState(s.db, key)
+			}
+			if valueBefore != valueAfter {
+				account.Storage = append(account.Storage, types.StorageDiff{Key: key, Before: valueBefore, After: valueAfter})
+			}
+		}
+		if account.Created || account.Deleted || account.Balance != nil || account.Nonce != nil || account.Code != nil || len(account.Storage) > 0 || account.StorageWiped {
+			diff = append(diff, account)
+		}
+	}
+	return diff, nil
+}
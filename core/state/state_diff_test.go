@@ -0,0 +1,83 @@
+package state
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/dominant-strategies/go-quai/common"
+	"github.com/dominant-strategies/go-quai/core/rawdb"
+	"github.com/dominant-strategies/go-quai/core/types"
+)
+
+// Tests that the state diff reports every storage slot cleared when an account
+// self-destructs or is recreated, not only the slots modified in the block.
+func TestStateDiffWipedStorage(t *testing.T) {
+	defer func(loc common.Location) { common.NodeLocation = loc }(common.NodeLocation)
+	common.NodeLocation = common.Location{0, 0}
+
+	var (
+		db          = NewDatabase(rawdb.NewMemoryDatabase())
+		addr        = common.HexToAddress("0x0100000000000000000000000000000000000001")
+		internal, _ = addr.InternalAddress()
+		one, two    = common.HexToHash("0x01"), common.HexToHash("0x02")
+	)
+	parent, _ := New(common.Hash{}, db, nil)
+	parent.SetBalance(internal, big.NewInt(1))
+	parent.SetState(internal, one, one)
+	parent.SetState(internal, two, two)
+	root, err := parent.Commit(true)
+	if err != nil {
+		t.Fatalf("failed to commit parent state: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		apply   func(s *StateDB)
+		deleted bool
+		storage []types.StorageDiff
+	}{
+		{
+			name:    "self-destruct",
+			apply:   func(s *StateDB) { s.Suicide(internal) },
+			deleted: true,
+			storage: []types.StorageDiff{{Key: one, Before: one}, {Key: two, Before: two}},
+		},
+		{
+			name: "recreation",
+			apply: func(s *StateDB) {
+				s.CreateAccount(internal)
+				s.SetState(internal, two, one)
+			},
+			storage: []types.StorageDiff{{Key: one, Before: one}, {Key: two, Before: two, After: one}},
+		},
+	}
+	for _, tt := range tests {
+		statedb, err := New(root, db, nil)
+		if err != nil {
+			t.Fatalf("%s: failed to open parent state: %v", tt.name, err)
+		}
+		statedb.EnableDiffTracking()
+		tt.apply(statedb)
+		statedb.Finalise(true)
+
+		diff, err := statedb.StateDiff()
+		if err != nil {
+			t.Fatalf("%s: failed to compute diff: %v", tt.name, err)
+		}
+		if len(diff) != 1 {
+			t.Fatalf("%s: diff of %d accounts, want 1", tt.name, len(diff))
+		}
+		account := diff[0]
+		if !account.StorageWiped || account.Deleted != tt.deleted {
+			t.Errorf("%s: wiped %v deleted %v, want wiped true deleted %v", tt.name, account.StorageWiped, account.Deleted, tt.deleted)
+		}
+		if len(account.Storage) != len(tt.storage) {
+			t.Fatalf("%s: %d storage diffs, want %d: %v", tt.name, len(account.Storage), len(tt.storage), account.Storage)
+		}
+		for i, slot := range account.Storage {
+			if slot != tt.storage[i] {
+				t.Errorf("%s: storage diff %d: have %+v, want %+v", tt.name, i, slot, tt.storage[i])
+			}
+		}
+	}
+}
@@ -90,6 +90,7 @@ type stateObject struct {
 	dirtyCode bool // true if the code was updated
 	suicided  bool
 	deleted   bool
+	created   bool // true if the object was created through createObject
 }
 
 // empty returns whether the account is considered empty.
@@ -462,6 +463,7 @@ func (s *stateObject) deepCopy(db *StateDB) *stateObject {
 	stateObject.suicided = s.suicided
 	stateObject.dirtyCode = s.dirtyCode
 	stateObject.deleted = s.deleted
+	stateObject.created = s.created
 	return stateObject
 }
 
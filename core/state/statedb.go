@@ -99,6 +99,17 @@ type StateDB struct {
 	// Per-transaction access list
 	accessList *accessList
 
+	// Transient storage
+	transientStorage transientStorage
+
+	// Optional recorder of the accounts and slots accessed
+	recorder *AccessRecorder
+
+	// Storage slots modified per account and accounts whose storage was wiped
+	// by a self-destruct or recreation, tracked if diff tracking is enabled
+	diffSlots map[common.InternalAddress]map[common.Hash]struct{}
+	diffWiped map[common.InternalAddress]struct{}
+
 	// Journal of state modifications. This is the backbone of
 	// Snapshot and RevertToSnapshot.
 	journal        *journal
@@ -137,6 +148,7 @@ func New(root common.Hash, db Database, snaps *snapshot.Tree) (*StateDB, error)
 		preimages:           make(map[common.Hash][]byte),
 		journal:             newJournal(),
 		accessList:          newAccessList(),
+		transientStorage:    newTransientStorage(),
 		hasher:              crypto.NewKeccakState(),
 	}
 	if sdb.snaps != nil {
@@ -301,6 +313,7 @@ func (s *StateDB) GetCodeHash(addr common.InternalAddress) common.Hash {
 
 // GetState retrieves a value from the given account's storage trie.
 func (s *StateDB) GetState(addr common.InternalAddress, hash common.Hash) common.Hash {
+	s.recorder.readSlot(addr, hash)
 	stateObject := s.getStateObject(addr)
 	if stateObject != nil {
 		return stateObject.GetState(s.db, hash)
@@ -333,6 +346,7 @@ func (s *StateDB) GetStorageProof(a common.InternalAddress, key common.Hash) ([]
 
 // GetCommittedState retrieves a value from the given account's committed storage trie.
 func (s *StateDB) GetCommittedState(addr common.InternalAddress, hash common.Hash) common.Hash {
+	s.recorder.readSlot(addr, hash)
 	stateObject := s.getStateObject(addr)
 	if stateObject != nil {
 		return stateObject.GetCommittedState(s.db, hash)
@@ -373,6 +387,9 @@ func (s *StateDB) HasSuicided(addr common.InternalAddress) bool {
 func (s *StateDB) AddBalance(addr common.InternalAddress, amount *big.Int) {
 	stateObject := s.GetOrNewStateObject(addr)
 	if stateObject != nil {
+		if amount.Sign() != 0 {
+			s.recorder.writeAccount(addr)
+		}
 		stateObject.AddBalance(amount)
 	}
 }
@@ -381,6 +398,9 @@ func (s *StateDB) AddBalance(addr common.InternalAddress, amount *big.Int) {
 func (s *StateDB) SubBalance(addr common.InternalAddress, amount *big.Int) {
 	stateObject := s.GetOrNewStateObject(addr)
 	if stateObject != nil {
+		if amount.Sign() != 0 {
+			s.recorder.writeAccount(addr)
+		}
 		stateObject.SubBalance(amount)
 	}
 }
@@ -388,13 +408,33 @@ func (s *StateDB) SubBalance(addr common.InternalAddress, amount *big.Int) {
 func (s *StateDB) SetBalance(addr common.InternalAddress, amount *big.Int) {
 	stateObject := s.GetOrNewStateObject(addr)
 	if stateObject != nil {
+		if stateObject.Balance().Cmp(amount) != 0 {
+			s.recorder.writeAccount(addr)
+		}
 		stateObject.SetBalance(amount)
 	}
 }
 
+// AddFee credits a transaction fee to the given account. The credit behaves
+// like AddBalance, but an attached access recorder tracks it as a commutative
+// fee instead of an ordinary account access.
+func (s *StateDB) AddFee(addr common.InternalAddress, amount *big.Int) {
+	if s.recorder == nil {
+		s.AddBalance(addr, amount)
+		return
+	}
+	s.recorder.paused = true
+	s.AddBalance(addr, amount)
+	s.recorder.paused = false
+	s.recorder.addFee(addr, amount)
+}
+
 func (s *StateDB) SetNonce(addr common.InternalAddress, nonce uint64) {
 	stateObject := s.GetOrNewStateObject(addr)
 	if stateObject != nil {
+		if stateObject.Nonce() != nonce {
+			s.recorder.writeAccount(addr)
+		}
 		stateObject.SetNonce(nonce)
 	}
 }
@@ -402,6 +442,7 @@ func (s *StateDB) SetNonce(addr common.InternalAddress, nonce uint64) {
 func (s *StateDB) SetCode(addr common.InternalAddress, code []byte) {
 	stateObject := s.GetOrNewStateObject(addr)
 	if stateObject != nil {
+		s.recorder.writeAccount(addr)
 		stateObject.SetCode(crypto.Keccak256Hash(code), code)
 	}
 }
@@ -409,15 +450,44 @@ func (s *StateDB) SetCode(addr common.InternalAddress, code []byte) {
 func (s *StateDB) SetState(addr common.InternalAddress, key, value common.Hash) {
 	stateObject := s.GetOrNewStateObject(addr)
 	if stateObject != nil {
+		s.recorder.writeSlot(addr, key)
 		stateObject.SetState(s.db, key, value)
 	}
 }
 
+// SetTransientState sets transient storage for a given account. It
+// adds the change to the journal so that it can be rolled back
+// to its previous value if there is a revert.
+func (s *StateDB) SetTransientState(addr common.InternalAddress, key, value common.Hash) {
+	prev := s.GetTransientState(addr, key)
+	if prev == value {
+		return
+	}
+	s.journal.append(transientStorageChange{
+		account:  &addr,
+		key:      key,
+		prevalue: prev,
+	})
+	s.setTransientState(addr, key, value)
+}
+
+// setTransientState is a lower level setter for transient storage. It
+// is called during a revert to prevent modifications to the journal.
+func (s *StateDB) setTransientState(addr common.InternalAddress, key, value common.Hash) {
+	s.transientStorage.Set(addr, key, value)
+}
+
+// GetTransientState gets transient storage for a given account.
+func (s *StateDB) GetTransientState(addr common.InternalAddress, key common.Hash) common.Hash {
+	return s.transientStorage.Get(addr, key)
+}
+
 // SetStorage replaces the entire storage for the specified account with given
 // storage. This function should only be used for debugging.
 func (s *StateDB) SetStorage(addr common.InternalAddress, storage map[common.Hash]common.Hash) {
 	stateObject := s.GetOrNewStateObject(addr)
 	if stateObject != nil {
+		s.recorder.writeAccount(addr)
 		stateObject.SetStorage(storage)
 	}
 }
@@ -432,6 +502,7 @@ func (s *StateDB) Suicide(addr common.InternalAddress) bool {
 	if stateObject == nil {
 		return false
 	}
+	s.recorder.writeAccount(addr)
 	s.journal.append(suicideChange{
 		account:     &addr,
 		prev:        stateObject.suicided,
@@ -501,6 +572,8 @@ func (s *StateDB) getStateObject(addr common.InternalAddress) *stateObject {
 // flag set. This is needed by the state journal to revert to the correct s-
 // destructed object instead of wiping all knowledge about the state object.
 func (s *StateDB) getDeletedStateObject(addr common.InternalAddress) *stateObject {
+	s.recorder.readAccount(addr)
+
 	// Prefer live objects if any is available
 	if obj := s.stateObjects[addr]; obj != nil {
 		return obj
@@ -587,7 +660,9 @@ func (s *StateDB) createObject(addr common.InternalAddress) (newobj, prev *state
 			s.snapDestructs[prev.addrHash] = struct{}{}
 		}
 	}
+	s.recorder.writeAccount(addr)
 	newobj = newObject(s, addr, Account{})
+	newobj.created = true
 	if prev == nil {
 		s.journal.append(createObjectChange{account: &addr})
 	} else {
@@ -710,6 +785,7 @@ func (s *StateDB) Copy() *StateDB {
 	// However, it doesn't cost us much to copy an empty list, so we do it anyway
 	// to not blow up if we ever decide copy it in the middle of a transaction
 	state.accessList = s.accessList.Copy()
+	state.transientStorage = s.transientStorage.Copy()
 
 	// If there's a prefetcher running, make an inactive copy of it that can
 	// only access data but does not actively preload (since the user will not
@@ -790,8 +866,11 @@ func (s *StateDB) Finalise(deleteEmptyObjects bool) {
 			// Thus, we can safely ignore it here
 			continue
 		}
+		s.recorder.finalise(addr)
+		s.trackDiff(obj)
 		if obj.suicided || (deleteEmptyObjects && obj.empty()) {
 			obj.deleted = true
+			s.recorder.writeAccount(addr)
 
 			// If state snapshotting is active, also mark the destruction there.
 			// Note, we can't do this only at the end of a block because multiple
@@ -973,6 +1052,7 @@ func (s *StateDB) Commit(deleteEmptyObjects bool) (common.Hash, error) {
 // - Add destination to access list
 // - Add precompiles to access list
 // - Add the contents of the optional tx access list
+// - Reset transient storage
 func (s *StateDB) PrepareAccessList(sender common.Address, dst *common.Address, precompiles []common.Address, list types.AccessList) {
 	s.AddAddressToAccessList(sender)
 	if dst != nil {
@@ -988,6 +1068,8 @@ func (s *StateDB) PrepareAccessList(sender common.Address, dst *common.Address,
 			s.AddSlotToAccessList(el.Address, key)
 		}
 	}
+	// Transient storage only lives for the duration of a transaction
+	s.transientStorage = newTransientStorage()
 }
 
 // AddAddressToAccessList adds the given address to the access list
@@ -100,6 +100,8 @@ type CacheConfig struct {
 	TrieTimeLimit       time.Duration // Time limit after which to flush the current in-memory trie to disk
 	SnapshotLimit       int           // Memory allowance (MB) to use for caching snapshot entries in memory
 	Preimages           bool          // Whether to store preimage of trie key to the disk
+	ParallelTxWorkers   int           // Number of workers executing transactions speculatively (0 = sequential)
+	StateDiffs          bool          // Whether to store the state changes made by every block
 }
 
 // defaultCacheConfig are the default caching values if none are specified by the
@@ -199,16 +201,6 @@ func NewStateProcessor(config *params.ChainConfig, hc *HeaderChain, engine conse
 // returns the amount of gas that was used in the process. If any of the
 // transactions failed to execute due to insufficient gas it will return an error.
 func (p *StateProcessor) Process(block *types.Block, etxSet types.EtxSet) (types.Receipts, []*types.Log, *state.StateDB, uint64, error) {
-	var (
-		receipts    types.Receipts
-		usedGas     = new(uint64)
-		header      = block.Header()
-		blockHash   = block.Hash()
-		blockNumber = block.Number()
-		allLogs     []*types.Log
-		gp          = new(GasPool).AddGas(block.GasLimit())
-	)
-
 	start := time.Now()
 	parent := p.hc.GetBlock(block.Header().ParentHash(), block.NumberU64()-1)
 	if parent == nil {
@@ -221,24 +213,47 @@ func (p *StateProcessor) Process(block *types.Block, etxSet types.EtxSet) (types
 	if err != nil {
 		return types.Receipts{}, []*types.Log{}, nil, 0, err
 	}
+	if p.cacheConfig.StateDiffs {
+		statedb.EnableDiffTracking()
+	}
 	time2 := common.PrettyDuration(time.Since(start))
+	log.Debug("Time taken to load parent state", "time1", time1, "time2", time2)
+
+	return p.processWithState(block, parent, etxSet, statedb)
+}
+
+// processWithState runs the transactions of the block on top of the given
+// statedb, which must hold the post-state of the parent block.
+func (p *StateProcessor) processWithState(block *types.Block, parent *types.Block, etxSet types.EtxSet, statedb *state.StateDB) (types.Receipts, []*types.Log, *state.StateDB, uint64, error) {
+	var (
+		receipts    types.Receipts
+		usedGas     = new(uint64)
+		header      = block.Header()
+		blockHash   = block.Hash()
+		blockNumber = block.Number()
+		allLogs     []*types.Log
+		gp          = new(GasPool).AddGas(block.GasLimit())
+	)
 
+	start := time.Now()
 	var timeSenders, timeSign, timePrepare, timeEtx, timeTx time.Duration
 	startTimeSenders := time.Now()
 	senders := make(map[common.Hash]*common.InternalAddress) // temporary cache for senders of internal txs
 	numInternalTxs := 0
-	p.hc.pool.SendersMutex.RLock()
-	for _, tx := range block.Transactions() { // get all senders of internal txs from cache - easier on the SendersMutex to do it all at once here
-		if tx.Type() == types.InternalTxType || tx.Type() == types.InternalToExternalTxType {
-			numInternalTxs++
-			if sender, ok := p.hc.pool.GetSenderThreadUnsafe(tx.Hash()); ok {
-				senders[tx.Hash()] = &sender // This pointer must never be modified
-			} else {
-				// TODO: calcuate the sender and add it to the pool senders cache in case of reorg (not necessary for now)
+	if p.hc.pool != nil {
+		p.hc.pool.SendersMutex.RLock()
+		for _, tx := range block.Transactions() { // get all senders of internal txs from cache - easier on the SendersMutex to do it all at once here
+			if tx.Type() == types.InternalTxType || tx.Type() == types.InternalToExternalTxType {
+				numInternalTxs++
+				if sender, ok := p.hc.pool.GetSenderThreadUnsafe(tx.Hash()); ok {
+					senders[tx.Hash()] = &sender // This pointer must never be modified
+				} else {
+					// TODO: calcuate the sender and add it to the pool senders cache in case of reorg (not necessary for now)
+				}
 			}
 		}
+		p.hc.pool.SendersMutex.RUnlock()
 	}
-	p.hc.pool.SendersMutex.RUnlock()
 	timeSenders = time.Since(startTimeSenders)
 	blockContext := NewEVMBlockContext(header, p.hc, nil)
 	vmenv := vm.NewEVM(blockContext, vm.TxContext{}, statedb, p.config, p.vmConfig)
@@ -254,6 +269,13 @@ func (p *StateProcessor) Process(block *types.Block, etxSet types.EtxSet) (types
 		etxPLimit = params.ETXPLimitMin
 	}
 
+	// Optionally execute the internal transactions speculatively in parallel
+	var speculator *txSpeculator
+	if workers := p.cacheConfig.ParallelTxWorkers; workers > 1 && !p.vmConfig.Debug {
+		speculator = p.newTxSpeculator(block, statedb, senders, etxRLimit, etxPLimit, workers)
+		defer speculator.stop(statedb)
+	}
+
 	var emittedEtxs types.Transactions
 	for i, tx := range block.Transactions() {
 		startProcess := time.Now()
@@ -278,7 +300,10 @@ func (p *StateProcessor) Process(block *types.Block, etxSet types.EtxSet) (types
 			}
 			prevZeroBal := prepareApplyETX(statedb, &etxEntry.ETX)
 			receipt, err = applyTransaction(msg, p.config, p.hc, nil, gp, statedb, blockNumber, blockHash, &etxEntry.ETX, usedGas, vmenv, &etxRLimit, &etxPLimit)
-			statedb.SetBalance(common.ZeroInternal, prevZeroBal) // Reset the balance to what it previously was. Residual balance will be lost
+			if err == nil {
+				refundETX(p.config, statedb, blockNumber, &etxEntry.ETX, receipt, &etxRLimit, &etxPLimit)
+			}
+			statedb.SetBalance(common.ZeroInternal, prevZeroBal) // Reset the balance to what it previously was, the residual balance has been refunded
 
 			if err != nil {
 				return nil, nil, nil, 0, fmt.Errorf("could not apply tx %d [%v]: %w", i, etxEntry.ETX.Hash().Hex(), err)
@@ -291,7 +316,12 @@ func (p *StateProcessor) Process(block *types.Block, etxSet types.EtxSet) (types
 		} else if tx.Type() == types.InternalTxType || tx.Type() == types.InternalToExternalTxType {
 			startTimeTx := time.Now()
 
-			receipt, err = applyTransaction(msg, p.config, p.hc, nil, gp, statedb, blockNumber, blockHash, tx, usedGas, vmenv, &etxRLimit, &etxPLimit)
+			if speculator != nil {
+				receipt = speculator.merge(i, statedb, blockHash, gp, usedGas, &etxRLimit, &etxPLimit)
+			}
+			if receipt == nil {
+				receipt, err = applyTransaction(msg, p.config, p.hc, nil, gp, statedb, blockNumber, blockHash, tx, usedGas, vmenv, &etxRLimit, &etxPLimit)
+			}
 			if err != nil {
 				return nil, nil, nil, 0, fmt.Errorf("could not apply tx %d [%v]: %w", i, tx.Hash().Hex(), err)
 			}
@@ -312,7 +342,7 @@ func (p *StateProcessor) Process(block *types.Block, etxSet types.EtxSet) (types
 	time5 := common.PrettyDuration(time.Since(start))
 
 	log.Debug("Total Tx Processing Time", "signing time", common.PrettyDuration(timeSign), "prepare state time", common.PrettyDuration(timePrepare), "etx time", common.PrettyDuration(timeEtx), "tx time", common.PrettyDuration(timeTx))
-	log.Debug("Time taken in Process", "time1", time1, "time2", time2, "time3", time3, "time4", time4, "time5", time5)
+	log.Debug("Time taken in Process", "time3", time3, "time4", time4, "time5", time5)
 
 	log.Debug("Total Tx Processing Time", "signing time", common.PrettyDuration(timeSign), "senders cache time", common.PrettyDuration(timeSenders), "percent cached internal txs", fmt.Sprintf("%.2f", float64(len(senders))/float64(numInternalTxs)*100), "prepare state time", common.PrettyDuration(timePrepare), "etx time", common.PrettyDuration(timeEtx), "tx time", common.PrettyDuration(timeTx))
 
@@ -322,6 +352,8 @@ func (p *StateProcessor) Process(block *types.Block, etxSet types.EtxSet) (types
 func applyTransaction(msg types.Message, config *params.ChainConfig, bc ChainContext, author *common.Address, gp *GasPool, statedb *state.StateDB, blockNumber *big.Int, blockHash common.Hash, tx *types.Transaction, usedGas *uint64, evm *vm.EVM, etxRLimit, etxPLimit *int) (*types.Receipt, error) {
 	// Create a new context to be used in the EVM environment.
 	txContext := NewEVMTxContext(msg)
+	txContext.TxHash = tx.Hash()
+	txContext.ETXRLimit, txContext.ETXPLimit = *etxRLimit, *etxPLimit
 	evm.Reset(txContext, statedb)
 
 	// Apply the transaction to the current state (included in the env).
@@ -367,6 +399,7 @@ func applyTransaction(msg types.Message, config *params.ChainConfig, bc ChainCon
 	}
 	receipt.TxHash = tx.Hash()
 	receipt.GasUsed = result.UsedGas
+	receipt.EtxOrigin = types.NewEtxOrigin(tx)
 
 	// If the transaction created a contract, store the creation address in the receipt.
 	if msg.To() == nil {
@@ -414,6 +447,17 @@ func (p *StateProcessor) Apply(batch ethdb.Batch, block *types.Block, newInbound
 	p.hc.AddBloom(bloom, block.Hash())
 	time5 := common.PrettyDuration(time.Since(start))
 	rawdb.WritePreimages(batch, statedb.Preimages())
+	if p.cacheConfig.StateDiffs {
+		diff, err := statedb.StateDiff()
+		if err != nil {
+			return nil, err
+		}
+		rawdb.WriteStateDiff(batch, block.Hash(), block.NumberU64(), diff)
+		// Diffs are kept for as many blocks as the transaction indices
+		if p.txLookupLimit > 0 && block.NumberU64() > p.txLookupLimit {
+			rawdb.DeleteStateDiffs(p.hc.bc.db, batch, block.NumberU64()-p.txLookupLimit+1)
+		}
+	}
 	time6 := common.PrettyDuration(time.Since(start))
 	// Commit all cached state changes into underlying memory database.
 	root, err := statedb.Commit(true)
@@ -503,7 +547,10 @@ func ApplyTransaction(config *params.ChainConfig, bc ChainContext, author *commo
 	if tx.Type() == types.ExternalTxType {
 		prevZeroBal := prepareApplyETX(statedb, tx)
 		receipt, err := applyTransaction(msg, config, bc, author, gp, statedb, header.Number(), header.Hash(), tx, usedGas, vmenv, etxRLimit, etxPLimit)
-		statedb.SetBalance(common.ZeroInternal, prevZeroBal) // Reset the balance to what it previously was (currently a failed external transaction removes all the sent coins from the supply and any residual balance is gone as well)
+		if err == nil {
+			refundETX(config, statedb, header.Number(), tx, receipt, etxRLimit, etxPLimit)
+		}
+		statedb.SetBalance(common.ZeroInternal, prevZeroBal) // Reset the balance to what it previously was, the residual balance has been refunded
 		return receipt, err
 	}
 	return applyTransaction(msg, config, bc, author, gp, statedb, header.Number(), header.Hash(), tx, usedGas, vmenv, etxRLimit, etxPLimit)
@@ -750,6 +797,7 @@ func (p *StateProcessor) StateAtTransaction(block *types.Block, txIndex int, ree
 		// Assemble the transaction call message and return if the requested offset
 		msg, _ := tx.AsMessage(signer, block.BaseFee())
 		txContext := NewEVMTxContext(msg)
+		txContext.TxHash = tx.Hash()
 		context := NewEVMBlockContext(block.Header(), p.hc, nil)
 		if idx == txIndex {
 			return msg, context, statedb, nil
@@ -816,6 +864,67 @@ func (p *StateProcessor) Stop() {
 	log.Info("State Processor stopped")
 }
 
+// refundETX emits an ETX returning the balance left over from applying an ETX
+// to the sender of the ETX: the value of a reverted ETX and the fees of unused
+// gas. The refund is sent from the refund address of this zone with the next
+// nonce of that address, and pays for its own execution in the origin chain out
+// of the refunded amount, so nothing is refunded if the balance doesn't cover
+// that. Refunds count against the ETX limits of the block; nothing is refunded
+// once the limit of the origin chain is exhausted. A failed refund is not
+// refunded again, as the refund would have to stay in the chain. Before the ETX
+// refund fork, the left over balance is burnt.
+func refundETX(config *params.ChainConfig, statedb *state.StateDB, blockNumber *big.Int, etx *types.Transaction, receipt *types.Receipt, etxRLimit, etxPLimit *int) {
+	if !config.IsEtxRefund(blockNumber) {
+		return
+	}
+	sender := etx.ETXSender()
+	if common.IsInChainScope(sender.Bytes()) {
+		return
+	}
+	residual := statedb.GetBalance(common.ZeroInternal)
+	fee := new(big.Int).Add(etx.GasFeeCap(), etx.GasTipCap())
+	fee.Mul(fee, new(big.Int).SetUint64(params.TxGas))
+	if residual.Cmp(fee) <= 0 {
+		return
+	}
+	switch sender.Location().CommonDom(common.NodeLocation).Context() {
+	case common.REGION_CTX:
+		if *etxRLimit <= 0 {
+			return
+		}
+		*etxRLimit--
+	case common.PRIME_CTX:
+		if *etxPLimit <= 0 {
+			return
+		}
+		*etxPLimit--
+	}
+	from := common.NodeLocation.RefundAddress()
+	internal, err := from.InternalAddress()
+	if err != nil {
+		return
+	}
+	nonce := statedb.GetNonce(internal)
+	statedb.SetNonce(internal, nonce+1)
+
+	inner := &types.ExternalTx{
+		ChainID:   config.ChainID,
+		Nonce:     nonce,
+		GasTipCap: etx.GasTipCap(),
+		GasFeeCap: etx.GasFeeCap(),
+		Gas:       params.TxGas,
+		To:        &sender,
+		Value:     new(big.Int).Sub(residual, fee),
+		Sender:    from,
+	}
+	if config.IsEtxOrigin(blockNumber) {
+		inner.OriginatingTxHash, inner.ETXIndex = etx.Hash(), uint16(len(receipt.Etxs))
+	}
+	refund := types.NewTx(inner)
+	receipt.Etxs = append(receipt.Etxs, refund)
+	receipt.Refund = refund
+}
+
 func prepareApplyETX(statedb *state.StateDB, tx *types.Transaction) *big.Int {
 	prevZeroBal := statedb.GetBalance(common.ZeroInternal)   // Get current zero address balance
 	fee := big.NewInt(0).Add(tx.GasFeeCap(), tx.GasTipCap()) // Add gas price cap to miner tip cap
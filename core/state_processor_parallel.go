@@ -0,0 +1,162 @@
+package core
+
+import (
+	"sync"
+
+	"github.com/dominant-strategies/go-quai/common"
+	"github.com/dominant-strategies/go-quai/core/state"
+	"github.com/dominant-strategies/go-quai/core/types"
+	"github.com/dominant-strategies/go-quai/core/vm"
+	"github.com/dominant-strategies/go-quai/metrics"
+)
+
+var (
+	parallelTxMergedMeter     = metrics.NewRegisteredMeter("chain/parallel/merged", nil)
+	parallelTxReexecutedMeter = metrics.NewRegisteredMeter("chain/parallel/reexecuted", nil)
+)
+
+// speculativeTx is the outcome of executing a transaction on a private copy of
+// the state the block is applied to.
+type speculativeTx struct {
+	statedb  *state.StateDB
+	recorder *state.AccessRecorder
+	receipt  *types.Receipt
+	msgGas   uint64
+	err      error
+
+	etxRLimit, etxPLimit int // ETX limits the transaction was executed with
+	etxRCount, etxPCount int // Cross-region and cross-prime ETXs emitted
+
+	done chan struct{}
+}
+
+// txSpeculator executes the internal transactions of a block optimistically in
+// parallel, each against the state at the start of the block. The results are
+// merged into the block state in transaction order, provided that none of the
+// accounts or storage slots a transaction accessed was written by a transaction
+// preceding it. ETXs and conflicting transactions are executed sequentially by
+// the caller, so the outcome is identical to sequential execution.
+type txSpeculator struct {
+	txs       []*speculativeTx
+	committed *state.AccessRecorder // Writes applied to the block state so far
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// newTxSpeculator starts speculatively executing the internal transactions of
+// the block on top of statedb using the given number of workers. The recorder
+// of committed writes is attached to statedb until stop is called.
+func (p *StateProcessor) newTxSpeculator(block *types.Block, statedb *state.StateDB, senders map[common.Hash]*common.InternalAddress, etxRLimit, etxPLimit int, workers int) *txSpeculator {
+	var (
+		header      = block.Header()
+		blockHash   = block.Hash()
+		blockNumber = block.Number()
+		signer      = types.MakeSigner(p.config, header.Number())
+		txs         = block.Transactions()
+		base        = statedb.Copy()
+		tasks       = make(chan int, len(txs))
+	)
+	s := &txSpeculator{
+		txs:       make([]*speculativeTx, len(txs)),
+		committed: state.NewAccessRecorder(),
+		quit:      make(chan struct{}),
+	}
+	for i, tx := range txs {
+		if tx.Type() == types.InternalTxType || tx.Type() == types.InternalToExternalTxType {
+			s.txs[i] = &speculativeTx{etxRLimit: etxRLimit, etxPLimit: etxPLimit, done: make(chan struct{})}
+			tasks <- i
+		}
+	}
+	close(tasks)
+
+	for n := 0; n < workers; n++ {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+
+			blockContext := NewEVMBlockContext(header, p.hc, nil)
+			vmenv := vm.NewEVM(blockContext, vm.TxContext{}, base, p.config, p.vmConfig)
+			for i := range tasks {
+				select {
+				case <-s.quit:
+					return
+				default:
+				}
+				tx, spec := txs[i], s.txs[i]
+
+				msg, err := tx.AsMessageWithSender(signer, header.BaseFee(), senders[tx.Hash()])
+				if err != nil {
+					spec.err = err
+					close(spec.done)
+					continue
+				}
+				spec.msgGas = msg.Gas()
+				spec.recorder = state.NewAccessRecorder()
+				spec.statedb = base.Copy()
+				spec.statedb.SetAccessRecorder(spec.recorder)
+				spec.statedb.Prepare(tx.Hash(), i)
+
+				var (
+					gp                   = new(GasPool).AddGas(block.GasLimit())
+					usedGas              uint64
+					etxRLimit, etxPLimit = spec.etxRLimit, spec.etxPLimit
+				)
+				spec.receipt, spec.err = applyTransaction(msg, p.config, p.hc, nil, gp, spec.statedb, blockNumber, blockHash, tx, &usedGas, vmenv, &etxRLimit, &etxPLimit)
+				spec.etxRCount, spec.etxPCount = spec.etxRLimit-etxRLimit, spec.etxPLimit-etxPLimit
+				close(spec.done)
+			}
+		}()
+	}
+	// Record every write to the block state. The base copy is taken before, so
+	// the recorder only ever sees changes made by transactions of this block.
+	statedb.SetAccessRecorder(s.committed)
+	return s
+}
+
+// merge waits for the speculative execution of the i'th transaction and merges
+// its results into statedb, which must have been prepared for the transaction.
+// If the transaction has to be executed sequentially instead, merge returns nil
+// and leaves statedb untouched.
+func (s *txSpeculator) merge(i int, statedb *state.StateDB, blockHash common.Hash, gp *GasPool, usedGas *uint64, etxRLimit, etxPLimit *int) *types.Receipt {
+	spec := s.txs[i]
+	if spec == nil {
+		return nil
+	}
+	<-spec.done
+	s.txs[i] = nil // Release the state copy once merged or discarded
+
+	if spec.err != nil || gp.Gas() < spec.msgGas || spec.recorder.Conflicts(s.committed) {
+		parallelTxReexecutedMeter.Mark(1)
+		return nil
+	}
+	// A transaction executed with a larger ETX limit than is left behaves the
+	// same as long as it emitted less ETXs than the remaining limit.
+	if (spec.etxRLimit != *etxRLimit && spec.etxRCount >= *etxRLimit) || (spec.etxPLimit != *etxPLimit && spec.etxPCount >= *etxPLimit) {
+		parallelTxReexecutedMeter.Mark(1)
+		return nil
+	}
+	if !statedb.Merge(spec.statedb) {
+		parallelTxReexecutedMeter.Mark(1)
+		return nil
+	}
+	parallelTxMergedMeter.Mark(1)
+
+	receipt := spec.receipt
+	gp.SubGas(receipt.GasUsed)
+	*usedGas += receipt.GasUsed
+	*etxRLimit -= spec.etxRCount
+	*etxPLimit -= spec.etxPCount
+
+	receipt.CumulativeGasUsed = *usedGas
+	receipt.Logs = statedb.GetLogs(receipt.TxHash, blockHash)
+	return receipt
+}
+
+// stop aborts any outstanding speculative executions and detaches the recorder
+// from statedb.
+func (s *txSpeculator) stop(statedb *state.StateDB) {
+	close(s.quit)
+	s.wg.Wait()
+	statedb.SetAccessRecorder(nil)
+}
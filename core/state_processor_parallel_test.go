@@ -0,0 +1,156 @@
+package core
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/dominant-strategies/go-quai/common"
+	"github.com/dominant-strategies/go-quai/consensus"
+	"github.com/dominant-strategies/go-quai/core/rawdb"
+	"github.com/dominant-strategies/go-quai/core/state"
+	"github.com/dominant-strategies/go-quai/core/types"
+	"github.com/dominant-strategies/go-quai/crypto"
+	"github.com/dominant-strategies/go-quai/params"
+	"github.com/dominant-strategies/go-quai/trie"
+)
+
+// rootFinalizer is a consensus engine which only commits the state root when
+// finalizing a block. Block processing uses no other part of the engine.
+type rootFinalizer struct {
+	consensus.Engine
+}
+
+func (rootFinalizer) Author(header *types.Header) (common.Address, error) {
+	return header.Coinbase(), nil
+}
+
+func (rootFinalizer) Finalize(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header) {
+	header.SetRoot(state.IntermediateRoot(true))
+}
+
+// newScopedKey generates a key whose address belongs to the node location.
+func newScopedKey(t *testing.T) (*ecdsa.PrivateKey, common.Address) {
+	for {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatalf("failed to generate key: %v", err)
+		}
+		if addr := crypto.PubkeyToAddress(key.PublicKey); common.IsInChainScope(addr.Bytes()) {
+			return key, addr
+		}
+	}
+}
+
+// Tests that executing the internal transactions of a block speculatively in
+// parallel yields the same receipts, ETXs and state root as executing them
+// sequentially, both for independent and for conflicting transactions.
+func TestParallelTxExecution(t *testing.T) {
+	defer func(loc common.Location) { common.NodeLocation = loc }(common.NodeLocation)
+	common.NodeLocation = common.Location{0, 0}
+
+	config := &params.ChainConfig{ChainID: big.NewInt(1)}
+	signer := types.LatestSigner(config)
+
+	// Fund a few senders in the genesis state
+	var (
+		keys  = make([]*ecdsa.PrivateKey, 4)
+		addrs = make([]common.Address, 4)
+		db    = state.NewDatabase(rawdb.NewMemoryDatabase())
+	)
+	genesis, _ := state.New(common.Hash{}, db, nil)
+	for i := range keys {
+		keys[i], addrs[i] = newScopedKey(t)
+		internal, _ := addrs[i].InternalAddress()
+		genesis.AddBalance(internal, new(big.Int).Mul(big.NewInt(1000), big.NewInt(params.Ether)))
+	}
+	root, err := genesis.Commit(true)
+	if err != nil {
+		t.Fatalf("failed to commit genesis state: %v", err)
+	}
+	_, coinbase := newScopedKey(t)
+	_, recipient := newScopedKey(t)
+	external := common.HexToAddress("0x2000000000000000000000000000000000000001") // cyprus2
+
+	var (
+		fee   = big.NewInt(2 * params.GWei)
+		nonce = make(map[int]uint64)
+		txs   types.Transactions
+	)
+	transfer := func(from int, to common.Address) {
+		tx := types.NewTx(&types.InternalTx{ChainID: config.ChainID, Nonce: nonce[from], GasTipCap: fee, GasFeeCap: fee, Gas: params.TxGas, To: &to, Value: big.NewInt(1)})
+		signed, err := types.SignTx(tx, signer, keys[from])
+		if err != nil {
+			t.Fatalf("failed to sign transaction: %v", err)
+		}
+		nonce[from]++
+		txs = append(txs, signed)
+	}
+	transfer(0, recipient) // Independent
+	transfer(1, addrs[3])  // Independent
+	transfer(0, addrs[2])  // Same sender as the first transaction
+	transfer(2, recipient) // Same recipient as the first transaction
+
+	// ETX fees are scaled by the number of zones the ETX is confirmed across
+	etxFee := new(big.Int).Mul(fee, big.NewInt(common.NumZonesInRegion))
+	etx := types.NewTx(&types.InternalToExternalTx{ChainID: config.ChainID, Nonce: nonce[3], GasTipCap: fee, GasFeeCap: fee, Gas: params.TxGas + params.ETXGas, To: &external, Value: big.NewInt(1), ETXGasLimit: params.TxGas, ETXGasPrice: etxFee, ETXGasTip: etxFee})
+	signed, err := types.SignTx(etx, signer, keys[3])
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	txs = append(txs, signed)
+
+	parent := types.NewBlockWithHeader(types.EmptyHeader())
+	header := types.EmptyHeader()
+	header.SetNumber(big.NewInt(1))
+	header.SetGasLimit(params.GenesisGasLimit)
+	header.SetBaseFee(big.NewInt(params.GWei))
+	header.SetCoinbase(coinbase)
+	header.SetLocation(common.NodeLocation)
+	block := types.NewBlockWithHeader(header).WithBody(txs, nil, nil, nil)
+
+	process := func(workers int) (types.Receipts, common.Hash) {
+		engine := rootFinalizer{}
+		p := &StateProcessor{
+			config:      config,
+			hc:          &HeaderChain{engine: engine},
+			engine:      engine,
+			cacheConfig: &CacheConfig{ParallelTxWorkers: workers},
+		}
+		statedb, err := state.New(root, db, nil)
+		if err != nil {
+			t.Fatalf("failed to open parent state: %v", err)
+		}
+		receipts, _, statedb, _, err := p.processWithState(block, parent, types.EtxSet{}, statedb)
+		if err != nil {
+			t.Fatalf("failed to process block with %d workers: %v", workers, err)
+		}
+		return receipts, statedb.IntermediateRoot(true)
+	}
+	seqReceipts, seqRoot := process(0)
+	for _, workers := range []int{2, 4} {
+		receipts, root := process(workers)
+		if root != seqRoot {
+			t.Errorf("%d workers: state root mismatch, have %x, want %x", workers, root, seqRoot)
+		}
+		if have, want := types.DeriveSha(receipts, trie.NewStackTrie(nil)), types.DeriveSha(seqReceipts, trie.NewStackTrie(nil)); have != want {
+			t.Errorf("%d workers: receipt root mismatch, have %x, want %x", workers, have, want)
+		}
+		for i := range receipts {
+			if receipts[i].Status != seqReceipts[i].Status || receipts[i].GasUsed != seqReceipts[i].GasUsed {
+				t.Errorf("%d workers: receipt %d mismatch, have status %d gas %d, want status %d gas %d", workers, i, receipts[i].Status, receipts[i].GasUsed, seqReceipts[i].Status, seqReceipts[i].GasUsed)
+			}
+			if have, want := types.DeriveSha(types.Transactions(receipts[i].Etxs), trie.NewStackTrie(nil)), types.DeriveSha(types.Transactions(seqReceipts[i].Etxs), trie.NewStackTrie(nil)); have != want {
+				t.Errorf("%d workers: ETXs of receipt %d mismatch", workers, i)
+			}
+		}
+	}
+	if len(seqReceipts[len(txs)-1].Etxs) != 1 {
+		t.Errorf("cross-chain transfer emitted %d ETXs, want 1", len(seqReceipts[len(txs)-1].Etxs))
+	}
+	for i, receipt := range seqReceipts {
+		if receipt.Status != types.ReceiptStatusSuccessful {
+			t.Errorf("transaction %d failed", i)
+		}
+	}
+}
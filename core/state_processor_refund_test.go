@@ -0,0 +1,98 @@
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/dominant-strategies/go-quai/common"
+	"github.com/dominant-strategies/go-quai/core/rawdb"
+	"github.com/dominant-strategies/go-quai/core/state"
+	"github.com/dominant-strategies/go-quai/core/types"
+	"github.com/dominant-strategies/go-quai/params"
+)
+
+// Tests that the balance left over from applying an ETX is refunded to its
+// sender from the refund address of the zone, and that nothing is refunded
+// before the fork, to senders in the zone, when the balance doesn't cover the
+// refund or once the ETX limit of the origin chain is exhausted.
+func TestRefundETX(t *testing.T) {
+	defer func(loc common.Location) { common.NodeLocation = loc }(common.NodeLocation)
+	common.NodeLocation = common.Location{0, 0}
+
+	var (
+		config       = &params.ChainConfig{ChainID: big.NewInt(1), EtxOriginBlock: big.NewInt(0), EtxRefundBlock: big.NewInt(10)}
+		to           = common.HexToAddress("0x0000000000000000000000000000000000000002")
+		regionSender = common.HexToAddress("0x1e00000000000000000000000000000000000001") // cyprus2
+		primeSender  = common.HexToAddress("0x5800000000000000000000000000000000000001") // paxos1
+		localSender  = common.HexToAddress("0x0000000000000000000000000000000000000003")
+		tip, feeCap  = big.NewInt(1), big.NewInt(2)
+		refundFee    = new(big.Int).Mul(big.NewInt(3), big.NewInt(int64(params.TxGas)))
+	)
+	newETX := func(sender common.Address) *types.Transaction {
+		return types.NewTx(&types.ExternalTx{ChainID: config.ChainID, Nonce: 1, GasTipCap: tip, GasFeeCap: feeCap, Gas: 50000, To: &to, Value: big.NewInt(1), Sender: sender})
+	}
+	tests := []struct {
+		name     string
+		number   int64
+		sender   common.Address
+		residual *big.Int
+		rLimit   int
+		pLimit   int
+		refunded bool
+	}{
+		{"region sender", 10, regionSender, big.NewInt(params.Ether), 1, 1, true},
+		{"prime sender", 10, primeSender, big.NewInt(params.Ether), 1, 1, true},
+		{"before fork", 9, regionSender, big.NewInt(params.Ether), 1, 1, false},
+		{"local sender", 10, localSender, big.NewInt(params.Ether), 1, 1, false},
+		{"residual equals fee", 10, regionSender, refundFee, 1, 1, false},
+		{"region limit exhausted", 10, regionSender, big.NewInt(params.Ether), 0, 1, false},
+		{"prime limit exhausted", 10, primeSender, big.NewInt(params.Ether), 1, 0, false},
+	}
+	for _, tt := range tests {
+		statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		statedb.SetBalance(common.ZeroInternal, tt.residual)
+
+		etx := newETX(tt.sender)
+		receipt := &types.Receipt{Status: types.ReceiptStatusFailed}
+		rLimit, pLimit := tt.rLimit, tt.pLimit
+		refundETX(config, statedb, big.NewInt(tt.number), etx, receipt, &rLimit, &pLimit)
+
+		if !tt.refunded {
+			if receipt.Refund != nil || len(receipt.Etxs) != 0 || rLimit != tt.rLimit || pLimit != tt.pLimit {
+				t.Errorf("%s: unexpected refund: %d ETXs, limits %d/%d", tt.name, len(receipt.Etxs), rLimit, pLimit)
+			}
+			continue
+		}
+		refund := receipt.Refund
+		if refund == nil || len(receipt.Etxs) != 1 || receipt.Etxs[0] != refund {
+			t.Fatalf("%s: refund missing: %d ETXs", tt.name, len(receipt.Etxs))
+		}
+		if refund.To() == nil || !refund.To().Equal(tt.sender) {
+			t.Errorf("%s: refund destination mismatch: have %v, want %v", tt.name, refund.To(), tt.sender)
+		}
+		if !refund.ETXSender().Equal(common.NodeLocation.RefundAddress()) || !refund.ETXSender().IsRefundAddress() {
+			t.Errorf("%s: refund sender mismatch: have %v, want %v", tt.name, refund.ETXSender(), common.NodeLocation.RefundAddress())
+		}
+		if want := new(big.Int).Sub(tt.residual, refundFee); refund.Value().Cmp(want) != 0 {
+			t.Errorf("%s: refund amount mismatch: have %v, want %v", tt.name, refund.Value(), want)
+		}
+		if refund.Gas() != params.TxGas || refund.OriginatingTxHash() != etx.Hash() {
+			t.Errorf("%s: refund gas and origin mismatch: have %d/%x, want %d/%x", tt.name, refund.Gas(), refund.OriginatingTxHash(), params.TxGas, etx.Hash())
+		}
+		if rLimit+pLimit != tt.rLimit+tt.pLimit-1 {
+			t.Errorf("%s: refund did not count against the ETX limits: have %d/%d", tt.name, rLimit, pLimit)
+		}
+		// The refund is the only ETX a failed ETX emits, and it is recognised
+		// by its sender once read back from the database
+		if emitted := receipt.EmittedEtxs(); len(emitted) != 1 || emitted[0] != refund {
+			t.Errorf("%s: emitted ETXs mismatch: have %d, want the refund", tt.name, len(emitted))
+		}
+		if found := types.FindRefund(etx, []*types.Transaction{newETX(localSender), refund}); found != refund {
+			t.Errorf("%s: refund not found among the emitted ETXs", tt.name)
+		}
+	}
+	// ETXs sent by anything but a refund address are never refunds
+	if found := types.FindRefund(newETX(regionSender), []*types.Transaction{newETX(localSender)}); found != nil {
+		t.Errorf("ETX of a contract mistaken for a refund")
+	}
+}
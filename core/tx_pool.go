@@ -562,8 +562,27 @@ func (pool *TxPool) TxPoolPending(enforceTips bool, etxSet types.EtxSet) (map[co
 		}
 	}
 
-	for _, entry := range etxSet {
+	for _, entry := range pool.pendingEtxs(enforceTips, etxSet) {
 		addr := entry.ETX.ETXSender()
+		tx := entry.ETX
+		pending[addr.Bytes20()] = append(pending[addr.Bytes20()], &tx) // ETXs do not have to be sorted by address but this way all TXs are in the same list
+	}
+	return pending, nil
+}
+
+// PendingEtxs retrieves the ETXs of the given set which can be included in the
+// next block, with the same tip enforcement as TxPoolPending.
+func (pool *TxPool) PendingEtxs(enforceTips bool, etxSet types.EtxSet) []types.EtxSetEntry {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	return pool.pendingEtxs(enforceTips, etxSet)
+}
+
+// pendingEtxs is the lock free version of PendingEtxs.
+func (pool *TxPool) pendingEtxs(enforceTips bool, etxSet types.EtxSet) []types.EtxSetEntry {
+	etxs := make([]types.EtxSetEntry, 0, len(etxSet))
+	for _, entry := range etxSet {
 		tx := entry.ETX
 		if tx.ETXSender().Location().Equal(common.NodeLocation) { // Sanity check
 			log.Error("ETX sender is in our location!", "tx", tx.Hash().String(), "sender", tx.ETXSender().String())
@@ -574,9 +593,9 @@ func (pool *TxPool) TxPoolPending(enforceTips bool, etxSet types.EtxSet) (map[co
 			log.Debug("ETX has incorrect or low miner tip", "tx", tx.Hash().String(), "gasTipCap", tx.GasTipCap().String(), "poolGasPrice", pool.gasPrice.String(), "baseFee", pool.priced.urgent.baseFee.String())
 			continue // skip this tx
 		}
-		pending[addr.Bytes20()] = append(pending[addr.Bytes20()], &tx) // ETXs do not have to be sorted by address but this way all TXs are in the same list
+		etxs = append(etxs, entry)
 	}
-	return pending, nil
+	return etxs
 }
 
 // Locals retrieves the accounts currently considered local by the pool.
@@ -101,6 +101,7 @@ type Header struct {
 	extra         []byte          `json:"extraData"            gencodec:"required"`
 	mixHash       common.Hash     `json:"mixHash"              gencodec:"required"`
 	nonce         BlockNonce      `json:"nonce"`
+	uncledS       *big.Int        `json:"uncledS"`
 
 	// caches
 	hash      atomic.Value
@@ -120,6 +121,7 @@ type headerMarshaling struct {
 	ParentDeltaS  []*hexutil.Big
 	Time          hexutil.Uint64
 	Extra         hexutil.Bytes
+	UncledS       *hexutil.Big
 	Hash          common.Hash `json:"hash"` // adds call to Hash() in MarshalJSON
 }
 
@@ -146,6 +148,7 @@ type extheader struct {
 	Extra         []byte
 	MixHash       common.Hash
 	Nonce         BlockNonce
+	UncledS       *big.Int `rlp:"optional"`
 }
 
 // Construct an empty header
@@ -164,6 +167,7 @@ func EmptyHeader() *Header {
 	h.etxRollupHash = EmptyRootHash
 	h.uncleHash = EmptyUncleHash
 	h.baseFee = big.NewInt(0)
+	h.uncledS = big.NewInt(0)
 
 	for i := 0; i < common.HierarchyDepth; i++ {
 		h.manifestHash[i] = EmptyRootHash
@@ -201,6 +205,10 @@ func (h *Header) DecodeRLP(s *rlp.Stream) error {
 	h.extra = eh.Extra
 	h.mixHash = eh.MixHash
 	h.nonce = eh.Nonce
+	h.uncledS = eh.UncledS
+	if h.uncledS == nil {
+		h.uncledS = big.NewInt(0)
+	}
 
 	return nil
 }
@@ -229,6 +237,7 @@ func (h *Header) EncodeRLP(w io.Writer) error {
 		Extra:         h.extra,
 		MixHash:       h.mixHash,
 		Nonce:         h.nonce,
+		UncledS:       h.optionalUncledS(),
 	})
 }
 
@@ -271,6 +280,9 @@ func (h *Header) RPCMarshalHeader() map[string]interface{} {
 	if h.BaseFee() != nil {
 		result["baseFeePerGas"] = (*hexutil.Big)(h.BaseFee())
 	}
+	if h.UncledS() != nil {
+		result["uncledS"] = (*hexutil.Big)(h.UncledS())
+	}
 
 	return result
 }
@@ -357,6 +369,17 @@ func (h *Header) Extra() []byte             { return common.CopyBytes(h.extra) }
 func (h *Header) MixHash() common.Hash      { return h.mixHash }
 func (h *Header) Nonce() BlockNonce         { return h.nonce }
 func (h *Header) NonceU64() uint64          { return binary.BigEndian.Uint64(h.nonce[:]) }
+func (h *Header) UncledS() *big.Int         { return h.uncledS }
+
+// optionalUncledS returns the uncled entropy of the header, or nil if no
+// uncles were included. Leaving the field out of the encoding keeps the hashes
+// of headers without uncles unchanged.
+func (h *Header) optionalUncledS() *big.Int {
+	if h.uncledS == nil || h.uncledS.Sign() == 0 {
+		return nil
+	}
+	return h.uncledS
+}
 
 func (h *Header) SetParentHash(val common.Hash, args ...int) {
 	h.hash = atomic.Value{}     // clear hash cache
@@ -487,6 +510,11 @@ func (h *Header) SetNonce(val BlockNonce) {
 	h.hash = atomic.Value{} // clear hash cache, but NOT sealHash
 	h.nonce = val
 }
+func (h *Header) SetUncledS(val *big.Int) {
+	h.hash = atomic.Value{}     // clear hash cache
+	h.sealHash = atomic.Value{} // clear sealHash cache
+	h.uncledS = new(big.Int).Set(val)
+}
 
 // Array accessors
 func (h *Header) ParentHashArray() []common.Hash   { return h.parentHash }
@@ -514,6 +542,7 @@ type sealData struct {
 	Time          uint64
 	Extra         []byte
 	Nonce         BlockNonce
+	UncledS       *big.Int `rlp:"optional"`
 }
 
 // SealHash returns the hash of a block prior to it being sealed.
@@ -542,6 +571,7 @@ func (h *Header) SealHash() (hash common.Hash) {
 		Location:      h.Location(),
 		Time:          h.Time(),
 		Extra:         h.Extra(),
+		UncledS:       h.optionalUncledS(),
 	}
 	for i := 0; i < common.HierarchyDepth; i++ {
 		hdata.ParentHash[i] = h.ParentHash(i)
@@ -802,6 +832,9 @@ func CopyHeader(h *Header) *Header {
 	cpy.SetGasLimit(h.GasLimit())
 	cpy.SetGasUsed(h.GasUsed())
 	cpy.SetBaseFee(h.BaseFee())
+	if h.uncledS != nil {
+		cpy.SetUncledS(h.uncledS)
+	}
 	cpy.SetLocation(h.location)
 	cpy.SetTime(h.time)
 	cpy.SetNonce(h.nonce)
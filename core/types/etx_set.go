@@ -2,6 +2,7 @@ package types
 
 import (
 	"github.com/dominant-strategies/go-quai/common"
+	"github.com/dominant-strategies/go-quai/common/hexutil"
 	"github.com/dominant-strategies/go-quai/log"
 	"github.com/dominant-strategies/go-quai/params"
 )
@@ -15,14 +16,59 @@ type EtxSetEntry struct {
 	ETX    Transaction
 }
 
+// ExpirationHeight returns the last block number in which the ETX can be mined.
+func (entry EtxSetEntry) ExpirationHeight() uint64 {
+	return entry.Height + params.EtxExpirationAge
+}
+
+// ExpiredEtx records an ETX which was removed from the EtxSet without being
+// mined, along with the block in which it expired.
+type ExpiredEtx struct {
+	ETX         Transaction
+	AvailableAt uint64      // Block number in which the ETX became available
+	ExpiredAt   uint64      // Block number in which the ETX was removed
+	BlockHash   common.Hash // Hash of the block in which the ETX was removed
+}
+
+// RPCMarshalExpiredEtx converts the record of an expired ETX to the RPC
+// representation.
+func (e *ExpiredEtx) RPCMarshalExpiredEtx() map[string]interface{} {
+	return map[string]interface{}{
+		"hash":             e.ETX.Hash(),
+		"sender":           e.ETX.ETXSender(),
+		"to":               e.ETX.To(),
+		"value":            (*hexutil.Big)(e.ETX.Value()),
+		"availableAt":      hexutil.Uint64(e.AvailableAt),
+		"expirationHeight": hexutil.Uint64(e.AvailableAt + params.EtxExpirationAge),
+		"expiredAt":        hexutil.Uint64(e.ExpiredAt),
+		"blockHash":        e.BlockHash,
+		"etx":              &e.ETX,
+	}
+}
+
+// RPCMarshalInboundEtx converts an ETX added to the EtxSet by the given block
+// into the RPC representation.
+func RPCMarshalInboundEtx(etx *Transaction, block *Block) map[string]interface{} {
+	return map[string]interface{}{
+		"hash":             etx.Hash(),
+		"sender":           etx.ETXSender(),
+		"to":               etx.To(),
+		"value":            (*hexutil.Big)(etx.Value()),
+		"availableAt":      hexutil.Uint64(block.NumberU64()),
+		"expirationHeight": hexutil.Uint64(block.NumberU64() + params.EtxExpirationAge),
+		"blockHash":        block.Hash(),
+		"etx":              etx,
+	}
+}
+
 func NewEtxSet() EtxSet {
 	return make(EtxSet)
 }
 
 // updateInboundEtxs updates the set of inbound ETXs available to be mined into
 // a block in this location. This method adds any new ETXs to the set and
-// removes expired ETXs.
-func (set *EtxSet) Update(newInboundEtxs Transactions, currentHeight uint64) {
+// removes expired ETXs, which are returned to the caller.
+func (set *EtxSet) Update(newInboundEtxs Transactions, currentHeight uint64) []EtxSetEntry {
 	// Add new ETX entries to the inbound set
 	for _, etx := range newInboundEtxs {
 		if etx.To().Location().Equal(common.NodeLocation) {
@@ -33,12 +79,15 @@ func (set *EtxSet) Update(newInboundEtxs Transactions, currentHeight uint64) {
 	}
 
 	// Remove expired ETXs
+	var expired []EtxSetEntry
 	for txHash, entry := range *set {
 		availableAtBlock := entry.Height
-		etxExpirationHeight := availableAtBlock + params.EtxExpirationAge
+		etxExpirationHeight := entry.ExpirationHeight()
 		if currentHeight > etxExpirationHeight {
 			log.Warn("ETX expired", "hash", txHash, "gasTipCap", entry.ETX.GasTipCap(), "gasFeeCap", entry.ETX.GasFeeCap(), "gasLimit", entry.ETX.Gas(), "availableAtBlock", availableAtBlock, "etxExpirationHeight", etxExpirationHeight, "currentHeight", currentHeight)
+			expired = append(expired, entry)
 			delete(*set, txHash)
 		}
 	}
+	return expired
 }
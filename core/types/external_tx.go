@@ -19,6 +19,13 @@ type ExternalTx struct {
 	AccessList AccessList
 	Sender     common.Address
 
+	// OriginatingTxHash is the hash of the transaction which emitted the ETX
+	// and ETXIndex is the position of the ETX among the ETXs it emitted. ETXs
+	// emitted before the ETX origin fork leave both unset, which keeps their
+	// encoding and hash unchanged.
+	OriginatingTxHash common.Hash `rlp:"optional"`
+	ETXIndex          uint16      `rlp:"optional"`
+
 	// External transactions do not have signatures. The origin chain will
 	// emit an ETX, and consequently 'authorization' of this transaction comes
 	// from chain consensus and not from an account signature.
@@ -73,6 +80,9 @@ func (tx *ExternalTx) copy() TxData {
 		Gas:    tx.Gas,
 		Sender: tx.Sender,
 
+		OriginatingTxHash: tx.OriginatingTxHash,
+		ETXIndex:          tx.ETXIndex,
+
 		// These are copied below.
 		AccessList: make(AccessList, len(tx.AccessList)),
 		Value:      new(big.Int),
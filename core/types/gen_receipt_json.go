@@ -29,6 +29,8 @@ func (r Receipt) MarshalJSON() ([]byte, error) {
 		BlockNumber       *hexutil.Big   `json:"blockNumber,omitempty"`
 		TransactionIndex  hexutil.Uint   `json:"transactionIndex"`
 		Etxs              []*Transaction `json:"etxs"`
+		EtxOrigin         *EtxOrigin     `json:"etxOrigin,omitempty"`
+		Refund            *Transaction   `json:"refund,omitempty"`
 	}
 	var enc Receipt
 	enc.Type = hexutil.Uint64(r.Type)
@@ -44,6 +46,8 @@ func (r Receipt) MarshalJSON() ([]byte, error) {
 	enc.BlockHash = r.BlockHash
 	enc.BlockNumber = (*hexutil.Big)(r.BlockNumber)
 	enc.TransactionIndex = hexutil.Uint(r.TransactionIndex)
+	enc.EtxOrigin = r.EtxOrigin
+	enc.Refund = r.Refund
 	return json.Marshal(&enc)
 }
 
@@ -63,6 +67,8 @@ func (r *Receipt) UnmarshalJSON(input []byte) error {
 		BlockNumber       *hexutil.Big    `json:"blockNumber,omitempty"`
 		TransactionIndex  *hexutil.Uint   `json:"transactionIndex"`
 		Etxs              []*Transaction  `json:"etxs"`
+		EtxOrigin         *EtxOrigin      `json:"etxOrigin,omitempty"`
+		Refund            *Transaction    `json:"refund,omitempty"`
 	}
 	var dec Receipt
 	if err := json.Unmarshal(input, &dec); err != nil {
@@ -113,5 +119,11 @@ func (r *Receipt) UnmarshalJSON(input []byte) error {
 		return errors.New("missing required field 'etxs' for Receipt")
 	}
 	r.Etxs = dec.Etxs
+	if dec.EtxOrigin != nil {
+		r.EtxOrigin = dec.EtxOrigin
+	}
+	if dec.Refund != nil {
+		r.Refund = dec.Refund
+	}
 	return nil
 }
@@ -18,6 +18,7 @@ package types
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -71,6 +72,62 @@ type Receipt struct {
 	BlockNumber      *big.Int       `json:"blockNumber,omitempty"`
 	TransactionIndex uint           `json:"transactionIndex"`
 	Etxs             []*Transaction `json:"etxs"`
+
+	// EtxOrigin links the receipt of an executed ETX to the transaction which
+	// emitted it. It is derived from the ETX and not stored.
+	EtxOrigin *EtxOrigin `json:"etxOrigin,omitempty"`
+
+	// Refund is the ETX returning the balance left over from executing an ETX
+	// to its sender. It is one of the Etxs of the receipt and not stored.
+	Refund *Transaction `json:"refund,omitempty"`
+}
+
+// EtxOrigin identifies the transaction an ETX was emitted by in its origin
+// chain.
+type EtxOrigin struct {
+	TxHash   common.Hash     // Hash of the transaction which emitted the ETX
+	Location common.Location // Location of the chain the ETX was emitted in
+	EtxIndex uint16          // Position of the ETX among the emitted ETXs
+}
+
+type etxOriginJSON struct {
+	TxHash   common.Hash    `json:"txHash"`
+	Location hexutil.Bytes  `json:"location"`
+	EtxIndex hexutil.Uint64 `json:"etxIndex"`
+}
+
+// NewEtxOrigin returns the origin of an ETX, or nil if the transaction is not
+// an ETX or was emitted before the ETX origin fork.
+func NewEtxOrigin(tx *Transaction) *EtxOrigin {
+	if tx.Type() != ExternalTxType || tx.OriginatingTxHash() == (common.Hash{}) {
+		return nil
+	}
+	return &EtxOrigin{
+		TxHash:   tx.OriginatingTxHash(),
+		Location: tx.FromChain(),
+		EtxIndex: tx.ETXIndex(),
+	}
+}
+
+// MarshalJSON marshals as JSON.
+func (o EtxOrigin) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&etxOriginJSON{
+		TxHash:   o.TxHash,
+		Location: hexutil.Bytes(o.Location),
+		EtxIndex: hexutil.Uint64(o.EtxIndex),
+	})
+}
+
+// UnmarshalJSON unmarshals from JSON.
+func (o *EtxOrigin) UnmarshalJSON(input []byte) error {
+	var dec etxOriginJSON
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return err
+	}
+	o.TxHash = dec.TxHash
+	o.Location = common.Location(dec.Location)
+	o.EtxIndex = uint16(dec.EtxIndex)
+	return nil
 }
 
 type receiptMarshaling struct {
@@ -283,6 +340,34 @@ func (rs Receipts) EncodeIndex(i int, w *bytes.Buffer) {
 	// to the block.
 }
 
+// FindRefund returns the refund among the ETXs emitted by executing the ETX
+// tx, or nil if there is none. A refund is the only ETX sent from the refund
+// address of the executing zone, while ETXs emitted by contracts during the
+// execution are sent by the contracts themselves.
+func FindRefund(tx *Transaction, etxs []*Transaction) *Transaction {
+	if tx.Type() != ExternalTxType {
+		return nil
+	}
+	for _, etx := range etxs {
+		if etx.ETXSender().IsRefundAddress() {
+			return etx
+		}
+	}
+	return nil
+}
+
+// EmittedEtxs returns the ETXs the transaction of the receipt emits into the
+// block: every ETX of a successful transaction, or the refund of a failed ETX.
+func (r *Receipt) EmittedEtxs() []*Transaction {
+	if r.Status == ReceiptStatusSuccessful {
+		return r.Etxs
+	}
+	if r.Refund != nil {
+		return []*Transaction{r.Refund}
+	}
+	return nil
+}
+
 // DeriveFields fills the receipts with their computed fields based on consensus
 // data and contextual infos like containing block and transactions.
 func (r Receipts) DeriveFields(config *params.ChainConfig, hash common.Hash, number uint64, txs Transactions) error {
@@ -302,6 +387,10 @@ func (r Receipts) DeriveFields(config *params.ChainConfig, hash common.Hash, num
 		r[i].BlockNumber = new(big.Int).SetUint64(number)
 		r[i].TransactionIndex = uint(i)
 
+		// Executed ETXs link back to the transaction which emitted them
+		r[i].EtxOrigin = NewEtxOrigin(txs[i])
+		r[i].Refund = FindRefund(txs[i], r[i].Etxs)
+
 		// The contract address can be derived from the transaction itself
 		if txs[i].To() == nil {
 			// Deriving the signer is expensive, only do if it's actually needed
@@ -0,0 +1,53 @@
+package types
+
+import (
+	"math/big"
+
+	"github.com/dominant-strategies/go-quai/common"
+)
+
+// StateDiff lists the accounts modified by a block, ordered by address.
+type StateDiff []*AccountDiff
+
+// AccountDiff holds the values an account had before and after a block was
+// applied. Only the fields which changed are set.
+type AccountDiff struct {
+	Address common.Address
+	Created bool // Account did not exist before the block
+	Deleted bool // Account does not exist after the block
+
+	Balance *BalanceDiff `rlp:"nil"`
+	Nonce   *NonceDiff   `rlp:"nil"`
+	Code    *CodeDiff    `rlp:"nil"`
+	Storage []StorageDiff
+
+	// StorageWiped is set if all storage of the account was cleared by a self
+	// destruct or a recreation. Cleared slots are listed in Storage as far as
+	// their keys are known.
+	StorageWiped bool `rlp:"optional"`
+}
+
+// BalanceDiff is the change of an account balance.
+type BalanceDiff struct {
+	Before *big.Int
+	After  *big.Int
+}
+
+// NonceDiff is the change of an account nonce.
+type NonceDiff struct {
+	Before uint64
+	After  uint64
+}
+
+// CodeDiff is the change of the code of an account.
+type CodeDiff struct {
+	Before []byte
+	After  []byte
+}
+
+// StorageDiff is the change of a single storage slot.
+type StorageDiff struct {
+	Key    common.Hash
+	Before common.Hash
+	After  common.Hash
+}
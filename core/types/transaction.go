@@ -249,6 +249,24 @@ func (tx *Transaction) Nonce() uint64 { return tx.inner.nonce() }
 
 func (tx *Transaction) ETXSender() common.Address { return tx.inner.(*ExternalTx).Sender }
 
+// OriginatingTxHash returns the hash of the transaction which emitted the ETX,
+// or an empty hash if the transaction is not an ETX.
+func (tx *Transaction) OriginatingTxHash() common.Hash {
+	if etx, ok := tx.inner.(*ExternalTx); ok {
+		return etx.OriginatingTxHash
+	}
+	return common.Hash{}
+}
+
+// ETXIndex returns the position of the ETX among the ETXs emitted by its
+// originating transaction, or zero if the transaction is not an ETX.
+func (tx *Transaction) ETXIndex() uint16 {
+	if etx, ok := tx.inner.(*ExternalTx); ok {
+		return etx.ETXIndex
+	}
+	return 0
+}
+
 func (tx *Transaction) IsInternalToExternalTx() (inner *InternalToExternalTx, ok bool) {
 	inner, ok = tx.inner.(*InternalToExternalTx)
 	return
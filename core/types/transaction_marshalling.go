@@ -47,7 +47,9 @@ type txJSON struct {
 	S       *hexutil.Big `json:"s,omitempty"`
 
 	// Optional fields only present for external transactions
-	Sender *common.Address `json:"sender,omitempty"`
+	Sender            *common.Address `json:"sender,omitempty"`
+	OriginatingTxHash *common.Hash    `json:"originatingTxHash,omitempty"`
+	ETXIndex          *hexutil.Uint64 `json:"etxIndex,omitempty"`
 
 	ETXGasLimit   *hexutil.Uint64 `json:"etxGasLimit,omitempty"`
 	ETXGasPrice   *hexutil.Big    `json:"etxGasPrice,omitempty"`
@@ -92,6 +94,9 @@ func (t *Transaction) MarshalJSON() ([]byte, error) {
 		enc.Data = (*hexutil.Bytes)(&tx.Data)
 		enc.To = t.To()
 		enc.Sender = &tx.Sender
+		enc.OriginatingTxHash = &tx.OriginatingTxHash
+		etxIndex := hexutil.Uint64(tx.ETXIndex)
+		enc.ETXIndex = &etxIndex
 	case *InternalToExternalTx:
 		enc.ChainID = (*hexutil.Big)(tx.ChainID)
 		enc.AccessList = &tx.AccessList
@@ -223,6 +228,12 @@ func (t *Transaction) UnmarshalJSON(input []byte) error {
 			return errors.New("missing required field 'sender' in external transaction")
 		}
 		etx.Sender = *dec.Sender
+		if dec.OriginatingTxHash != nil {
+			etx.OriginatingTxHash = *dec.OriginatingTxHash
+		}
+		if dec.ETXIndex != nil {
+			etx.ETXIndex = uint16(*dec.ETXIndex)
+		}
 
 	case InternalToExternalTxType:
 		var itx InternalToExternalTx
@@ -17,6 +17,8 @@
 package vm
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/sha256"
 	"encoding/binary"
 	"errors"
@@ -28,6 +30,7 @@ import (
 	"github.com/dominant-strategies/go-quai/crypto/blake2b"
 	"github.com/dominant-strategies/go-quai/crypto/bn256"
 	"github.com/dominant-strategies/go-quai/params"
+	bls12381 "github.com/kilic/bls12-381"
 
 	//lint:ignore SA1019 Needed for precompile
 	"golang.org/x/crypto/ripemd160"
@@ -41,16 +44,32 @@ type PrecompiledContract interface {
 	Run(input []byte) ([]byte, error) // Run runs the precompiled contract
 }
 
+// contextualContract is a precompiled contract which reads the block context
+// of the EVM running it.
+type contextualContract interface {
+	PrecompiledContract
+	RunWithContext(blockCtx *BlockContext, input []byte) ([]byte, error) // RunWithContext runs the contract in the given block context
+}
+
 var TranslatedAddresses = map[common.AddressBytes]int{
-	common.AddressBytes([20]byte{1}): 0,
-	common.AddressBytes([20]byte{2}): 1,
-	common.AddressBytes([20]byte{3}): 2,
-	common.AddressBytes([20]byte{4}): 3,
-	common.AddressBytes([20]byte{5}): 4,
-	common.AddressBytes([20]byte{6}): 5,
-	common.AddressBytes([20]byte{7}): 6,
-	common.AddressBytes([20]byte{8}): 7,
-	common.AddressBytes([20]byte{9}): 8,
+	common.AddressBytes([20]byte{1}):  0,
+	common.AddressBytes([20]byte{2}):  1,
+	common.AddressBytes([20]byte{3}):  2,
+	common.AddressBytes([20]byte{4}):  3,
+	common.AddressBytes([20]byte{5}):  4,
+	common.AddressBytes([20]byte{6}):  5,
+	common.AddressBytes([20]byte{7}):  6,
+	common.AddressBytes([20]byte{8}):  7,
+	common.AddressBytes([20]byte{9}):  8,
+	common.AddressBytes([20]byte{10}): 9,
+	common.AddressBytes([20]byte{11}): 10,
+	common.AddressBytes([20]byte{12}): 11,
+	common.AddressBytes([20]byte{13}): 12,
+	common.AddressBytes([20]byte{14}): 13,
+	common.AddressBytes([20]byte{15}): 14,
+	common.AddressBytes([20]byte{16}): 15,
+	common.AddressBytes([20]byte{17}): 16,
+	common.AddressBytes([20]byte{18}): 17,
 }
 
 var (
@@ -68,6 +87,15 @@ func InitializePrecompiles() {
 	PrecompiledContracts[PrecompiledAddresses[common.NodeLocation.Name()][6].Bytes20()] = &bn256ScalarMul{}
 	PrecompiledContracts[PrecompiledAddresses[common.NodeLocation.Name()][7].Bytes20()] = &bn256Pairing{}
 	PrecompiledContracts[PrecompiledAddresses[common.NodeLocation.Name()][8].Bytes20()] = &blake2F{}
+	PrecompiledContracts[PrecompiledAddresses[common.NodeLocation.Name()][9].Bytes20()] = &hierarchy{}
+	PrecompiledContracts[PrecompiledAddresses[common.NodeLocation.Name()][10].Bytes20()] = &bls12381G1Add{}
+	PrecompiledContracts[PrecompiledAddresses[common.NodeLocation.Name()][11].Bytes20()] = &bls12381G1MultiExp{}
+	PrecompiledContracts[PrecompiledAddresses[common.NodeLocation.Name()][12].Bytes20()] = &bls12381G2Add{}
+	PrecompiledContracts[PrecompiledAddresses[common.NodeLocation.Name()][13].Bytes20()] = &bls12381G2MultiExp{}
+	PrecompiledContracts[PrecompiledAddresses[common.NodeLocation.Name()][14].Bytes20()] = &bls12381Pairing{}
+	PrecompiledContracts[PrecompiledAddresses[common.NodeLocation.Name()][15].Bytes20()] = &bls12381MapG1{}
+	PrecompiledContracts[PrecompiledAddresses[common.NodeLocation.Name()][16].Bytes20()] = &bls12381MapG2{}
+	PrecompiledContracts[PrecompiledAddresses[common.NodeLocation.Name()][17].Bytes20()] = &p256Verify{}
 }
 
 func init() {
@@ -82,6 +110,15 @@ func init() {
 		common.HexToAddress("0x1400000000000000000000000000000000000007"),
 		common.HexToAddress("0x1400000000000000000000000000000000000008"),
 		common.HexToAddress("0x1400000000000000000000000000000000000009"),
+		common.HexToAddress("0x140000000000000000000000000000000000000A"),
+		common.HexToAddress("0x140000000000000000000000000000000000000B"),
+		common.HexToAddress("0x140000000000000000000000000000000000000C"),
+		common.HexToAddress("0x140000000000000000000000000000000000000D"),
+		common.HexToAddress("0x140000000000000000000000000000000000000E"),
+		common.HexToAddress("0x140000000000000000000000000000000000000F"),
+		common.HexToAddress("0x1400000000000000000000000000000000000010"),
+		common.HexToAddress("0x1400000000000000000000000000000000000011"),
+		common.HexToAddress("0x1400000000000000000000000000000000000012"),
 	}
 	PrecompiledAddresses["cyprus2"] = []common.Address{
 		common.HexToAddress("0x2000000000000000000000000000000000000001"),
@@ -93,6 +130,15 @@ func init() {
 		common.HexToAddress("0x2000000000000000000000000000000000000007"),
 		common.HexToAddress("0x2000000000000000000000000000000000000008"),
 		common.HexToAddress("0x2000000000000000000000000000000000000009"),
+		common.HexToAddress("0x200000000000000000000000000000000000000A"),
+		common.HexToAddress("0x200000000000000000000000000000000000000B"),
+		common.HexToAddress("0x200000000000000000000000000000000000000C"),
+		common.HexToAddress("0x200000000000000000000000000000000000000D"),
+		common.HexToAddress("0x200000000000000000000000000000000000000E"),
+		common.HexToAddress("0x200000000000000000000000000000000000000F"),
+		common.HexToAddress("0x2000000000000000000000000000000000000010"),
+		common.HexToAddress("0x2000000000000000000000000000000000000011"),
+		common.HexToAddress("0x2000000000000000000000000000000000000012"),
 	}
 	PrecompiledAddresses["cyprus3"] = []common.Address{
 		common.HexToAddress("0x3E00000000000000000000000000000000000001"),
@@ -104,6 +150,15 @@ func init() {
 		common.HexToAddress("0x3E00000000000000000000000000000000000007"),
 		common.HexToAddress("0x3E00000000000000000000000000000000000008"),
 		common.HexToAddress("0x3E00000000000000000000000000000000000009"),
+		common.HexToAddress("0x3E0000000000000000000000000000000000000A"),
+		common.HexToAddress("0x3E0000000000000000000000000000000000000B"),
+		common.HexToAddress("0x3E0000000000000000000000000000000000000C"),
+		common.HexToAddress("0x3E0000000000000000000000000000000000000D"),
+		common.HexToAddress("0x3E0000000000000000000000000000000000000E"),
+		common.HexToAddress("0x3E0000000000000000000000000000000000000F"),
+		common.HexToAddress("0x3E00000000000000000000000000000000000010"),
+		common.HexToAddress("0x3E00000000000000000000000000000000000011"),
+		common.HexToAddress("0x3E00000000000000000000000000000000000012"),
 	}
 	PrecompiledAddresses["paxos1"] = []common.Address{
 		common.HexToAddress("0x5A00000000000000000000000000000000000001"),
@@ -115,6 +170,15 @@ func init() {
 		common.HexToAddress("0x5A00000000000000000000000000000000000007"),
 		common.HexToAddress("0x5A00000000000000000000000000000000000008"),
 		common.HexToAddress("0x5A00000000000000000000000000000000000009"),
+		common.HexToAddress("0x5A0000000000000000000000000000000000000A"),
+		common.HexToAddress("0x5A0000000000000000000000000000000000000B"),
+		common.HexToAddress("0x5A0000000000000000000000000000000000000C"),
+		common.HexToAddress("0x5A0000000000000000000000000000000000000D"),
+		common.HexToAddress("0x5A0000000000000000000000000000000000000E"),
+		common.HexToAddress("0x5A0000000000000000000000000000000000000F"),
+		common.HexToAddress("0x5A00000000000000000000000000000000000010"),
+		common.HexToAddress("0x5A00000000000000000000000000000000000011"),
+		common.HexToAddress("0x5A00000000000000000000000000000000000012"),
 	}
 	PrecompiledAddresses["paxos2"] = []common.Address{
 		common.HexToAddress("0x7800000000000000000000000000000000000001"),
@@ -126,6 +190,15 @@ func init() {
 		common.HexToAddress("0x7800000000000000000000000000000000000007"),
 		common.HexToAddress("0x7800000000000000000000000000000000000008"),
 		common.HexToAddress("0x7800000000000000000000000000000000000009"),
+		common.HexToAddress("0x780000000000000000000000000000000000000A"),
+		common.HexToAddress("0x780000000000000000000000000000000000000B"),
+		common.HexToAddress("0x780000000000000000000000000000000000000C"),
+		common.HexToAddress("0x780000000000000000000000000000000000000D"),
+		common.HexToAddress("0x780000000000000000000000000000000000000E"),
+		common.HexToAddress("0x780000000000000000000000000000000000000F"),
+		common.HexToAddress("0x7800000000000000000000000000000000000010"),
+		common.HexToAddress("0x7800000000000000000000000000000000000011"),
+		common.HexToAddress("0x7800000000000000000000000000000000000012"),
 	}
 	PrecompiledAddresses["paxos3"] = []common.Address{
 		common.HexToAddress("0x9600000000000000000000000000000000000001"),
@@ -137,6 +210,15 @@ func init() {
 		common.HexToAddress("0x9600000000000000000000000000000000000007"),
 		common.HexToAddress("0x9600000000000000000000000000000000000008"),
 		common.HexToAddress("0x9600000000000000000000000000000000000009"),
+		common.HexToAddress("0x960000000000000000000000000000000000000A"),
+		common.HexToAddress("0x960000000000000000000000000000000000000B"),
+		common.HexToAddress("0x960000000000000000000000000000000000000C"),
+		common.HexToAddress("0x960000000000000000000000000000000000000D"),
+		common.HexToAddress("0x960000000000000000000000000000000000000E"),
+		common.HexToAddress("0x960000000000000000000000000000000000000F"),
+		common.HexToAddress("0x9600000000000000000000000000000000000010"),
+		common.HexToAddress("0x9600000000000000000000000000000000000011"),
+		common.HexToAddress("0x9600000000000000000000000000000000000012"),
 	}
 	PrecompiledAddresses["hydra1"] = []common.Address{
 		common.HexToAddress("0xB400000000000000000000000000000000000001"),
@@ -148,6 +230,15 @@ func init() {
 		common.HexToAddress("0xB400000000000000000000000000000000000007"),
 		common.HexToAddress("0xB400000000000000000000000000000000000008"),
 		common.HexToAddress("0xB400000000000000000000000000000000000009"),
+		common.HexToAddress("0xB40000000000000000000000000000000000000A"),
+		common.HexToAddress("0xB40000000000000000000000000000000000000B"),
+		common.HexToAddress("0xB40000000000000000000000000000000000000C"),
+		common.HexToAddress("0xB40000000000000000000000000000000000000D"),
+		common.HexToAddress("0xB40000000000000000000000000000000000000E"),
+		common.HexToAddress("0xB40000000000000000000000000000000000000F"),
+		common.HexToAddress("0xB400000000000000000000000000000000000010"),
+		common.HexToAddress("0xB400000000000000000000000000000000000011"),
+		common.HexToAddress("0xB400000000000000000000000000000000000012"),
 	}
 	PrecompiledAddresses["hydra2"] = []common.Address{
 		common.HexToAddress("0xD200000000000000000000000000000000000001"),
@@ -159,6 +250,15 @@ func init() {
 		common.HexToAddress("0xD200000000000000000000000000000000000007"),
 		common.HexToAddress("0xD200000000000000000000000000000000000008"),
 		common.HexToAddress("0xD200000000000000000000000000000000000009"),
+		common.HexToAddress("0xD20000000000000000000000000000000000000A"),
+		common.HexToAddress("0xD20000000000000000000000000000000000000B"),
+		common.HexToAddress("0xD20000000000000000000000000000000000000C"),
+		common.HexToAddress("0xD20000000000000000000000000000000000000D"),
+		common.HexToAddress("0xD20000000000000000000000000000000000000E"),
+		common.HexToAddress("0xD20000000000000000000000000000000000000F"),
+		common.HexToAddress("0xD200000000000000000000000000000000000010"),
+		common.HexToAddress("0xD200000000000000000000000000000000000011"),
+		common.HexToAddress("0xD200000000000000000000000000000000000012"),
 	}
 	PrecompiledAddresses["hydra3"] = []common.Address{
 		common.HexToAddress("0xF000000000000000000000000000000000000001"),
@@ -170,26 +270,61 @@ func init() {
 		common.HexToAddress("0xF000000000000000000000000000000000000007"),
 		common.HexToAddress("0xF000000000000000000000000000000000000008"),
 		common.HexToAddress("0xF000000000000000000000000000000000000009"),
+		common.HexToAddress("0xF00000000000000000000000000000000000000A"),
+		common.HexToAddress("0xF00000000000000000000000000000000000000B"),
+		common.HexToAddress("0xF00000000000000000000000000000000000000C"),
+		common.HexToAddress("0xF00000000000000000000000000000000000000D"),
+		common.HexToAddress("0xF00000000000000000000000000000000000000E"),
+		common.HexToAddress("0xF00000000000000000000000000000000000000F"),
+		common.HexToAddress("0xF000000000000000000000000000000000000010"),
+		common.HexToAddress("0xF000000000000000000000000000000000000011"),
+		common.HexToAddress("0xF000000000000000000000000000000000000012"),
 	}
 }
 
 // ActivePrecompiles returns the precompiles enabled with the current configuration.
 func ActivePrecompiles(rules params.Rules) []common.Address {
-	return PrecompiledAddresses[common.NodeLocation.Name()]
+	var active []common.Address
+	for _, addr := range PrecompiledAddresses[common.NodeLocation.Name()] {
+		if p, ok := PrecompiledContracts[addr.Bytes20()]; ok && isPrecompileActive(p, rules) {
+			active = append(active, addr)
+		}
+	}
+	return active
+}
+
+// isPrecompileActive returns whether the precompiled contract is enabled by the
+// rules. Contracts added after genesis are only active from their fork on.
+func isPrecompileActive(p PrecompiledContract, rules params.Rules) bool {
+	switch p.(type) {
+	case *hierarchy:
+		return rules.IsHierarchy
+	case *bls12381G1Add, *bls12381G1MultiExp, *bls12381G2Add, *bls12381G2MultiExp,
+		*bls12381Pairing, *bls12381MapG1, *bls12381MapG2, *p256Verify:
+		return rules.IsCryptoPrecompiles
+	default:
+		return true
+	}
 }
 
 // RunPrecompiledContract runs and evaluates the output of a precompiled contract.
+// Contracts reading the block context are run against blockCtx.
 // It returns
 // - the returned bytes,
 // - the _remaining_ gas,
 // - any error that occurred
-func RunPrecompiledContract(p PrecompiledContract, input []byte, suppliedGas uint64) (ret []byte, remainingGas uint64, err error) {
+func RunPrecompiledContract(p PrecompiledContract, input []byte, suppliedGas uint64, blockCtx *BlockContext) (ret []byte, remainingGas uint64, err error) {
 	gasCost := p.RequiredGas(input)
 	if suppliedGas < gasCost {
 		return nil, 0, ErrOutOfGas
 	}
 	suppliedGas -= gasCost
-	output, err := p.Run(input)
+	var output []byte
+	if cp, ok := p.(contextualContract); ok {
+		output, err = cp.RunWithContext(blockCtx, input)
+	} else {
+		output, err = p.Run(input)
+	}
 	return output, suppliedGas, err
 }
 
@@ -594,3 +729,463 @@ func (c *blake2F) Run(input []byte) ([]byte, error) {
 	}
 	return output, nil
 }
+
+var (
+	errBLS12381InvalidInputLength          = errors.New("invalid input length")
+	errBLS12381InvalidFieldElementTopBytes = errors.New("invalid field element top bytes")
+	errBLS12381G1PointSubgroup             = errors.New("g1 point is not on correct subgroup")
+	errBLS12381G2PointSubgroup             = errors.New("g2 point is not on correct subgroup")
+)
+
+// bls12381G1Add implements the BLS12-381 G1 addition precompile.
+type bls12381G1Add struct{}
+
+// RequiredGas returns the gas required to execute the pre-compiled contract.
+func (c *bls12381G1Add) RequiredGas(input []byte) uint64 {
+	return params.Bls12381G1AddGas
+}
+
+func (c *bls12381G1Add) Run(input []byte) ([]byte, error) {
+	// Implements the G1 addition of EIP-2537.
+	// > G1 addition call expects `256` bytes as an input that is interpreted as byte concatenation of two G1 points (`128` bytes each).
+	// > Output is an encoding of addition operation result - single G1 point (`128` bytes).
+	if len(input) != 256 {
+		return nil, errBLS12381InvalidInputLength
+	}
+	var err error
+	var p0, p1 *bls12381.PointG1
+
+	// Initialize G1
+	g := bls12381.NewG1()
+
+	// Decode G1 point p_0
+	if p0, err = decodeBLS12381G1Point(g, input[:128]); err != nil {
+		return nil, err
+	}
+	// Decode G1 point p_1
+	if p1, err = decodeBLS12381G1Point(g, input[128:]); err != nil {
+		return nil, err
+	}
+
+	// Compute r = p_0 + p_1
+	r := g.New()
+	g.Add(r, p0, p1)
+
+	// Encode the G1 point result into 128 bytes
+	return encodeBLS12381G1Point(g, r), nil
+}
+
+// bls12381G1MultiExp implements the BLS12-381 G1 multi-exponentiation precompile.
+type bls12381G1MultiExp struct{}
+
+// RequiredGas returns the gas required to execute the pre-compiled contract.
+func (c *bls12381G1MultiExp) RequiredGas(input []byte) uint64 {
+	// Calculate G1 point, scalar value pair length
+	k := len(input) / 160
+	if k == 0 {
+		// Return 0 gas for small input length
+		return 0
+	}
+	// Lookup discount value for G1 point, scalar value pair length
+	var discount uint64
+	if dLen := len(params.Bls12381G1MultiExpDiscountTable); k < dLen {
+		discount = params.Bls12381G1MultiExpDiscountTable[k-1]
+	} else {
+		discount = params.Bls12381G1MultiExpDiscountTable[dLen-1]
+	}
+	// Calculate gas and return the result
+	return (uint64(k) * params.Bls12381G1MulGas * discount) / params.Bls12381MSMMultiplier
+}
+
+func (c *bls12381G1MultiExp) Run(input []byte) ([]byte, error) {
+	// Implements the G1 multi-exponentiation of EIP-2537.
+	// G1 multiexponentiation call expects `160*k` bytes as an input that is interpreted as byte concatenation of `k` slices each of them being a byte concatenation of encoding of G1 point (`128` bytes) and encoding of a scalar value (`32` bytes).
+	// Output is an encoding of multiexponentiation operation result - single G1 point (`128` bytes).
+	k := len(input) / 160
+	if len(input) == 0 || len(input)%160 != 0 {
+		return nil, errBLS12381InvalidInputLength
+	}
+	var err error
+	points := make([]*bls12381.PointG1, k)
+	scalars := make([]*big.Int, k)
+
+	// Initialize G1
+	g := bls12381.NewG1()
+
+	// Decode point scalar pairs
+	for i := 0; i < k; i++ {
+		off := 160 * i
+		t0, t1, t2 := off, off+128, off+160
+		// Decode G1 point
+		if points[i], err = decodeBLS12381G1Point(g, input[t0:t1]); err != nil {
+			return nil, err
+		}
+		// Fast subgroup check
+		if !g.InCorrectSubgroup(points[i]) {
+			return nil, errBLS12381G1PointSubgroup
+		}
+		// Decode scalar value
+		scalars[i] = new(big.Int).SetBytes(input[t1:t2])
+	}
+
+	// Compute r = e_0 * p_0 + e_1 * p_1 + ... + e_(k-1) * p_(k-1)
+	r := g.New()
+	g.MultiExpBig(r, points, scalars)
+
+	// Encode the G1 point to 128 bytes
+	return encodeBLS12381G1Point(g, r), nil
+}
+
+// bls12381G2Add implements the BLS12-381 G2 addition precompile.
+type bls12381G2Add struct{}
+
+// RequiredGas returns the gas required to execute the pre-compiled contract.
+func (c *bls12381G2Add) RequiredGas(input []byte) uint64 {
+	return params.Bls12381G2AddGas
+}
+
+func (c *bls12381G2Add) Run(input []byte) ([]byte, error) {
+	// Implements the G2 addition of EIP-2537.
+	// > G2 addition call expects `512` bytes as an input that is interpreted as byte concatenation of two G2 points (`256` bytes each).
+	// > Output is an encoding of addition operation result - single G2 point (`256` bytes).
+	if len(input) != 512 {
+		return nil, errBLS12381InvalidInputLength
+	}
+	var err error
+	var p0, p1 *bls12381.PointG2
+
+	// Initialize G2
+	g := bls12381.NewG2()
+	r := g.New()
+
+	// Decode G2 point p_0
+	if p0, err = decodeBLS12381G2Point(g, input[:256]); err != nil {
+		return nil, err
+	}
+	// Decode G2 point p_1
+	if p1, err = decodeBLS12381G2Point(g, input[256:]); err != nil {
+		return nil, err
+	}
+
+	// Compute r = p_0 + p_1
+	g.Add(r, p0, p1)
+
+	// Encode the G2 point into 256 bytes
+	return encodeBLS12381G2Point(g, r), nil
+}
+
+// bls12381G2MultiExp implements the BLS12-381 G2 multi-exponentiation precompile.
+type bls12381G2MultiExp struct{}
+
+// RequiredGas returns the gas required to execute the pre-compiled contract.
+func (c *bls12381G2MultiExp) RequiredGas(input []byte) uint64 {
+	// Calculate G2 point, scalar value pair length
+	k := len(input) / 288
+	if k == 0 {
+		// Return 0 gas for small input length
+		return 0
+	}
+	// Lookup discount value for G2 point, scalar value pair length
+	var discount uint64
+	if dLen := len(params.Bls12381G2MultiExpDiscountTable); k < dLen {
+		discount = params.Bls12381G2MultiExpDiscountTable[k-1]
+	} else {
+		discount = params.Bls12381G2MultiExpDiscountTable[dLen-1]
+	}
+	// Calculate gas and return the result
+	return (uint64(k) * params.Bls12381G2MulGas * discount) / params.Bls12381MSMMultiplier
+}
+
+func (c *bls12381G2MultiExp) Run(input []byte) ([]byte, error) {
+	// Implements the G2 multi-exponentiation of EIP-2537.
+	// G2 multiexponentiation call expects `288*k` bytes as an input that is interpreted as byte concatenation of `k` slices each of them being a byte concatenation of encoding of G2 point (`256` bytes) and encoding of a scalar value (`32` bytes).
+	// Output is an encoding of multiexponentiation operation result - single G2 point (`256` bytes).
+	k := len(input) / 288
+	if len(input) == 0 || len(input)%288 != 0 {
+		return nil, errBLS12381InvalidInputLength
+	}
+	var err error
+	points := make([]*bls12381.PointG2, k)
+	scalars := make([]*big.Int, k)
+
+	// Initialize G2
+	g := bls12381.NewG2()
+
+	// Decode point scalar pairs
+	for i := 0; i < k; i++ {
+		off := 288 * i
+		t0, t1, t2 := off, off+256, off+288
+		// Decode G2 point
+		if points[i], err = decodeBLS12381G2Point(g, input[t0:t1]); err != nil {
+			return nil, err
+		}
+		// Fast subgroup check
+		if !g.InCorrectSubgroup(points[i]) {
+			return nil, errBLS12381G2PointSubgroup
+		}
+		// Decode scalar value
+		scalars[i] = new(big.Int).SetBytes(input[t1:t2])
+	}
+
+	// Compute r = e_0 * p_0 + e_1 * p_1 + ... + e_(k-1) * p_(k-1)
+	r := g.New()
+	g.MultiExpBig(r, points, scalars)
+
+	// Encode the G2 point to 256 bytes.
+	return encodeBLS12381G2Point(g, r), nil
+}
+
+// bls12381Pairing implements the BLS12-381 pairing precompile.
+type bls12381Pairing struct{}
+
+// RequiredGas returns the gas required to execute the pre-compiled contract.
+func (c *bls12381Pairing) RequiredGas(input []byte) uint64 {
+	return params.Bls12381PairingBaseGas + uint64(len(input)/384)*params.Bls12381PairingPerPairGas
+}
+
+func (c *bls12381Pairing) Run(input []byte) ([]byte, error) {
+	// Implements the pairing of EIP-2537.
+	// > Pairing call expects `384*k` bytes as an inputs that is interpreted as byte concatenation of `k` slices. Each slice has the following structure:
+	// > - `128` bytes of G1 point encoding
+	// > - `256` bytes of G2 point encoding
+	// > Output is a `32` bytes where last single byte is `0x01` if pairing result is equal to multiplicative identity in a pairing target field and `0x00` otherwise
+	// > (which is equivalent of Big Endian encoding of Solidity values `uint256(1)` and `uin256(0)` respectively).
+	k := len(input) / 384
+	if len(input) == 0 || len(input)%384 != 0 {
+		return nil, errBLS12381InvalidInputLength
+	}
+
+	// Initialize BLS12-381 pairing engine
+	e := bls12381.NewEngine()
+	g1, g2 := e.G1, e.G2
+
+	// Decode pairs
+	for i := 0; i < k; i++ {
+		off := 384 * i
+		t0, t1, t2 := off, off+128, off+384
+
+		// Decode G1 point
+		p1, err := decodeBLS12381G1Point(g1, input[t0:t1])
+		if err != nil {
+			return nil, err
+		}
+		// Decode G2 point
+		p2, err := decodeBLS12381G2Point(g2, input[t1:t2])
+		if err != nil {
+			return nil, err
+		}
+
+		// 'point is on curve' check already done,
+		// Here we need to apply subgroup checks.
+		if !g1.InCorrectSubgroup(p1) {
+			return nil, errBLS12381G1PointSubgroup
+		}
+		if !g2.InCorrectSubgroup(p2) {
+			return nil, errBLS12381G2PointSubgroup
+		}
+
+		// Update pairing engine with G1 and G2 points
+		e.AddPair(p1, p2)
+	}
+	// Prepare 32 byte output
+	out := make([]byte, 32)
+
+	// Compute pairing and set the result
+	if e.Check() {
+		out[31] = 1
+	}
+	return out, nil
+}
+
+// bls12381MapG1 implements the BLS12-381 map field element to G1 precompile.
+type bls12381MapG1 struct{}
+
+// RequiredGas returns the gas required to execute the pre-compiled contract.
+func (c *bls12381MapG1) RequiredGas(input []byte) uint64 {
+	return params.Bls12381MapG1Gas
+}
+
+func (c *bls12381MapG1) Run(input []byte) ([]byte, error) {
+	// Implements the map field element to G1 of EIP-2537.
+	// > Field-to-curve call expects `64` bytes an an input that is interpreted as a an element of the base field.
+	// > Output of this call is `128` bytes and is G1 point following respective encoding rules.
+	if len(input) != 64 {
+		return nil, errBLS12381InvalidInputLength
+	}
+
+	// Decode input field element
+	fe, err := decodeBLS12381FieldElement(input)
+	if err != nil {
+		return nil, err
+	}
+
+	// Initialize G1
+	g := bls12381.NewG1()
+
+	// Compute mapping
+	r, err := g.MapToCurve(fe)
+	if err != nil {
+		return nil, err
+	}
+
+	// Encode the G1 point to 128 bytes
+	return encodeBLS12381G1Point(g, r), nil
+}
+
+// bls12381MapG2 implements the BLS12-381 map field element to G2 precompile.
+type bls12381MapG2 struct{}
+
+// RequiredGas returns the gas required to execute the pre-compiled contract.
+func (c *bls12381MapG2) RequiredGas(input []byte) uint64 {
+	return params.Bls12381MapG2Gas
+}
+
+func (c *bls12381MapG2) Run(input []byte) ([]byte, error) {
+	// Implements the map field element to G2 of EIP-2537.
+	// > Field-to-curve call expects `128` bytes an an input that is interpreted as a an element of the quadratic extension field.
+	// > Output of this call is `256` bytes and is G2 point following respective encoding rules.
+	if len(input) != 128 {
+		return nil, errBLS12381InvalidInputLength
+	}
+
+	// Decode input field element, the library expects c1 before c0
+	fe := make([]byte, 96)
+	c0, err := decodeBLS12381FieldElement(input[:64])
+	if err != nil {
+		return nil, err
+	}
+	copy(fe[48:], c0)
+	c1, err := decodeBLS12381FieldElement(input[64:])
+	if err != nil {
+		return nil, err
+	}
+	copy(fe[:48], c1)
+
+	// Initialize G2
+	g := bls12381.NewG2()
+
+	// Compute mapping
+	r, err := g.MapToCurve(fe)
+	if err != nil {
+		return nil, err
+	}
+
+	// Encode the G2 point to 256 bytes
+	return encodeBLS12381G2Point(g, r), nil
+}
+
+// decodeBLS12381FieldElement decodes a 64 byte base field element of
+// EIP-2537, which is padded with 16 zero bytes, into its 48 byte form.
+func decodeBLS12381FieldElement(in []byte) ([]byte, error) {
+	if len(in) != 64 {
+		return nil, errBLS12381InvalidInputLength
+	}
+	// Check top bytes
+	for i := 0; i < 16; i++ {
+		if in[i] != byte(0x00) {
+			return nil, errBLS12381InvalidFieldElementTopBytes
+		}
+	}
+	out := make([]byte, 48)
+	copy(out, in[16:])
+	return out, nil
+}
+
+// decodeBLS12381G1Point decodes a 128 byte G1 point, checking that it is on
+// the curve.
+func decodeBLS12381G1Point(g *bls12381.G1, in []byte) (*bls12381.PointG1, error) {
+	if len(in) != 128 {
+		return nil, errBLS12381InvalidInputLength
+	}
+	x, err := decodeBLS12381FieldElement(in[:64])
+	if err != nil {
+		return nil, err
+	}
+	y, err := decodeBLS12381FieldElement(in[64:])
+	if err != nil {
+		return nil, err
+	}
+	return g.FromBytes(append(x, y...))
+}
+
+// encodeBLS12381G1Point encodes a G1 point into 128 bytes.
+func encodeBLS12381G1Point(g *bls12381.G1, p *bls12381.PointG1) []byte {
+	outRaw := g.ToBytes(p)
+	out := make([]byte, 128)
+	// encode x
+	copy(out[16:64], outRaw[:48])
+	// encode y
+	copy(out[64+16:], outRaw[48:])
+	return out
+}
+
+// decodeBLS12381G2Point decodes a 256 byte G2 point, checking that it is on
+// the curve. The coordinates are encoded as c0 followed by c1, while the
+// library expects c1 before c0.
+func decodeBLS12381G2Point(g *bls12381.G2, in []byte) (*bls12381.PointG2, error) {
+	if len(in) != 256 {
+		return nil, errBLS12381InvalidInputLength
+	}
+	raw := make([]byte, 0, 192)
+	for _, off := range []int{64, 0, 192, 128} {
+		fe, err := decodeBLS12381FieldElement(in[off : off+64])
+		if err != nil {
+			return nil, err
+		}
+		raw = append(raw, fe...)
+	}
+	return g.FromBytes(raw)
+}
+
+// encodeBLS12381G2Point encodes a G2 point into 256 bytes.
+func encodeBLS12381G2Point(g *bls12381.G2, p *bls12381.PointG2) []byte {
+	outRaw := g.ToBytes(p)
+	out := make([]byte, 256)
+	// encode x, c0 then c1
+	copy(out[16:64], outRaw[48:96])
+	copy(out[80:128], outRaw[:48])
+	// encode y, c0 then c1
+	copy(out[144:192], outRaw[144:192])
+	copy(out[208:256], outRaw[96:144])
+	return out
+}
+
+// p256Verify implements secp256r1 signature verification, so that wallets
+// signing with passkeys or WebAuthn can be verified on-chain.
+type p256Verify struct{}
+
+// RequiredGas returns the gas required to execute the pre-compiled contract.
+func (c *p256Verify) RequiredGas(input []byte) uint64 {
+	return params.P256VerifyGas
+}
+
+// Run verifies the signature (r, s) of the hash by the public key (x, y),
+// given as the 160 byte concatenation hash || r || s || x || y. It returns a
+// word holding 1 if the signature is valid and nothing otherwise.
+func (c *p256Verify) Run(input []byte) ([]byte, error) {
+	// Required input length is 160 bytes
+	const p256VerifyInputLength = 160
+	// Check the input length
+	if len(input) != p256VerifyInputLength {
+		// Input length is invalid
+		return nil, nil
+	}
+
+	// Extract the hash, r, s, x, y from the input
+	hash := input[0:32]
+	r, s := new(big.Int).SetBytes(input[32:64]), new(big.Int).SetBytes(input[64:96])
+	x, y := new(big.Int).SetBytes(input[96:128]), new(big.Int).SetBytes(input[128:160])
+
+	// Verify the secp256r1 signature
+	curve := elliptic.P256()
+	if (x.Sign() == 0 && y.Sign() == 0) || !curve.IsOnCurve(x, y) {
+		// Invalid public key
+		return nil, nil
+	}
+	if ecdsa.Verify(&ecdsa.PublicKey{Curve: curve, X: x, Y: y}, hash, r, s) {
+		// Signature is valid
+		return common.LeftPadBytes([]byte{1}, 32), nil
+	}
+	// Signature is invalid
+	return nil, nil
+}
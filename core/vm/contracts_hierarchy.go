@@ -0,0 +1,114 @@
+package vm
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/dominant-strategies/go-quai/common"
+	"github.com/dominant-strategies/go-quai/common/math"
+	"github.com/dominant-strategies/go-quai/params"
+)
+
+var errHierarchyInput = errors.New("invalid hierarchy query")
+
+// hierarchy implements a native contract letting contracts introspect the
+// hierarchy they run in. Every value is returned as a 32 byte word.
+//
+// Called without input it describes the executing chain:
+//
+//	region, zone                       location of the chain, -1 if the chain has none
+//	hash, number (prime, region, zone) dominant parent of the block in each context
+//
+// The dominant parent of a dom context is the latest block of the chain
+// coincident with that context, found through the termini of the parent, so
+// contracts can reason about the confirmation of ETXs on-chain. The parent in
+// the executing chain is the parent of the block. Contexts below the executing
+// chain are zero.
+//
+// Called with a 32 byte word holding an address it describes the address:
+//
+//	region, zone                       location of the chain the address belongs to
+//	local                              1 if the address belongs to the executing chain
+type hierarchy struct{}
+
+// RequiredGas returns the gas required to execute the pre-compiled contract.
+func (c *hierarchy) RequiredGas(input []byte) uint64 {
+	return params.HierarchyGas
+}
+
+// Run is only reachable without a block context, in which case only address
+// queries can be answered.
+func (c *hierarchy) Run(input []byte) ([]byte, error) {
+	return c.RunWithContext(nil, input)
+}
+
+func (c *hierarchy) RunWithContext(blockCtx *BlockContext, input []byte) ([]byte, error) {
+	switch len(input) {
+	case 0:
+		return c.chain(blockCtx), nil
+	case 32:
+		return c.address(input)
+	default:
+		return nil, errHierarchyInput
+	}
+}
+
+// chain describes the location of the executing chain and the dominant
+// parents of the block.
+func (c *hierarchy) chain(blockCtx *BlockContext) []byte {
+	nodeCtx := common.NodeLocation.Context()
+	out := make([]byte, 0, 32*(2+2*common.HierarchyDepth))
+	out = appendLocation(out, common.NodeLocation)
+	for ctx := 0; ctx < common.HierarchyDepth; ctx++ {
+		var (
+			hash   common.Hash
+			number = new(big.Int)
+		)
+		if blockCtx != nil {
+			switch {
+			case ctx == nodeCtx:
+				hash = blockCtx.ParentHash
+				if blockCtx.BlockNumber != nil && blockCtx.BlockNumber.Sign() > 0 {
+					number.Sub(blockCtx.BlockNumber, common.Big1)
+				}
+			case ctx < nodeCtx && blockCtx.GetDomParent != nil:
+				if domHash, domNumber := blockCtx.GetDomParent(ctx); domNumber != nil {
+					hash, number = domHash, domNumber
+				}
+			}
+		}
+		out = append(out, hash.Bytes()...)
+		out = append(out, common.LeftPadBytes(number.Bytes(), 32)...)
+	}
+	return out
+}
+
+// address describes the location of the address held in the word.
+func (c *hierarchy) address(input []byte) ([]byte, error) {
+	// Addresses are left padded words, like the arguments of calls
+	for _, b := range input[:32-common.AddressLength] {
+		if b != 0 {
+			return nil, errHierarchyInput
+		}
+	}
+	addr := common.BytesToAddress(input[32-common.AddressLength:])
+	location := addr.Location()
+	if location == nil {
+		return nil, errHierarchyInput
+	}
+	out := make([]byte, 0, 32*3)
+	out = appendLocation(out, *location)
+	local := new(big.Int)
+	if common.IsInChainScope(addr.Bytes()) {
+		local.SetUint64(1)
+	}
+	return append(out, common.LeftPadBytes(local.Bytes(), 32)...), nil
+}
+
+// appendLocation appends the region and zone of the location as words. The
+// indices missing from the location of a dom chain are -1 in two's complement.
+func appendLocation(out []byte, location common.Location) []byte {
+	region, zone := big.NewInt(int64(location.Region())), big.NewInt(int64(location.Zone()))
+	out = append(out, math.U256Bytes(region)...)
+	return append(out, math.U256Bytes(zone)...)
+}
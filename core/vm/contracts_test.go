@@ -0,0 +1,356 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/dominant-strategies/go-quai/common"
+	"github.com/dominant-strategies/go-quai/common/math"
+	"github.com/dominant-strategies/go-quai/params"
+)
+
+// precompiledTest defines the input/output pairs for precompiled contract tests.
+type precompiledTest struct {
+	Input, Expected string
+	Gas             uint64
+	Name            string
+	NoBenchmark     bool // Benchmark primarily the worst-cases
+}
+
+// precompiledFailureTest defines the input/error pairs for precompiled
+// contract failure tests.
+type precompiledFailureTest struct {
+	Input         string
+	ExpectedError string
+	Name          string
+}
+
+// allPrecompiles maps the translated address of each precompile to its
+// implementation, independent of the node location.
+var allPrecompiles = map[string]PrecompiledContract{
+	"0b": &bls12381G1Add{},
+	"0c": &bls12381G1MultiExp{},
+	"0d": &bls12381G2Add{},
+	"0e": &bls12381G2MultiExp{},
+	"0f": &bls12381Pairing{},
+	"10": &bls12381MapG1{},
+	"11": &bls12381MapG2{},
+	"12": &p256Verify{},
+}
+
+func testPrecompiled(addr string, test precompiledTest, t *testing.T) {
+	p := allPrecompiles[addr]
+	in := common.Hex2Bytes(test.Input)
+	gas := p.RequiredGas(in)
+	t.Run(fmt.Sprintf("%s-Gas=%d", test.Name, gas), func(t *testing.T) {
+		if res, _, err := RunPrecompiledContract(p, in, gas, nil); err != nil {
+			t.Error(err)
+		} else if common.Bytes2Hex(res) != test.Expected {
+			t.Errorf("Expected %v, got %v", test.Expected, common.Bytes2Hex(res))
+		}
+		if expGas := test.Gas; expGas != gas {
+			t.Errorf("%v: gas wrong, expected %d, got %d", test.Name, expGas, gas)
+		}
+		// Verify that the precompile did not touch the input buffer
+		exp := common.Hex2Bytes(test.Input)
+		if !bytes.Equal(in, exp) {
+			t.Errorf("Precompiled %v modified input data", addr)
+		}
+	})
+}
+
+func testPrecompiledOOG(addr string, test precompiledTest, t *testing.T) {
+	p := allPrecompiles[addr]
+	in := common.Hex2Bytes(test.Input)
+	gas := p.RequiredGas(in) - 1
+
+	t.Run(fmt.Sprintf("%s-Gas=%d", test.Name, gas), func(t *testing.T) {
+		_, _, err := RunPrecompiledContract(p, in, gas, nil)
+		if err != ErrOutOfGas {
+			t.Errorf("Expected error [out of gas], got [%v]", err)
+		}
+	})
+}
+
+func testPrecompiledFailure(addr string, test precompiledFailureTest, t *testing.T) {
+	p := allPrecompiles[addr]
+	in := common.Hex2Bytes(test.Input)
+	gas := p.RequiredGas(in)
+	t.Run(test.Name, func(t *testing.T) {
+		_, _, err := RunPrecompiledContract(p, in, gas, nil)
+		if err == nil || err.Error() != test.ExpectedError {
+			t.Errorf("Expected error [%v], got [%v]", test.ExpectedError, err)
+		}
+		// Verify that the precompile did not touch the input buffer
+		exp := common.Hex2Bytes(test.Input)
+		if !bytes.Equal(in, exp) {
+			t.Errorf("Precompiled %v modified input data", addr)
+		}
+	})
+}
+
+func benchmarkPrecompiled(addr string, test precompiledTest, bench *testing.B) {
+	if test.NoBenchmark {
+		return
+	}
+	p := allPrecompiles[addr]
+	in := common.Hex2Bytes(test.Input)
+	reqGas := p.RequiredGas(in)
+
+	var (
+		res  []byte
+		err  error
+		data = make([]byte, len(in))
+	)
+
+	bench.Run(fmt.Sprintf("%s-Gas=%d", test.Name, reqGas), func(bench *testing.B) {
+		bench.ReportAllocs()
+		start := time.Now()
+		bench.ResetTimer()
+		for i := 0; i < bench.N; i++ {
+			copy(data, in)
+			res, _, err = RunPrecompiledContract(p, data, reqGas, nil)
+		}
+		bench.StopTimer()
+		elapsed := uint64(time.Since(start))
+		if elapsed < 1 {
+			elapsed = 1
+		}
+		gasUsed := reqGas * uint64(bench.N)
+		bench.ReportMetric(float64(reqGas), "gas/op")
+		// Keep it as uint64, multiply 100 to get two digit float later
+		mgasps := (100 * 1000 * gasUsed) / elapsed
+		bench.ReportMetric(float64(mgasps)/100, "mgas/s")
+		//Check if it is correct
+		if err != nil {
+			bench.Error(err)
+			return
+		}
+		if common.Bytes2Hex(res) != test.Expected {
+			bench.Errorf("Expected %v, got %v", test.Expected, common.Bytes2Hex(res))
+			return
+		}
+	})
+}
+
+func TestPrecompiledBLS12381G1Add(t *testing.T)      { testJson("blsG1Add", "0b", t) }
+func TestPrecompiledBLS12381G1MultiExp(t *testing.T) { testJson("blsG1MultiExp", "0c", t) }
+func TestPrecompiledBLS12381G2Add(t *testing.T)      { testJson("blsG2Add", "0d", t) }
+func TestPrecompiledBLS12381G2MultiExp(t *testing.T) { testJson("blsG2MultiExp", "0e", t) }
+func TestPrecompiledBLS12381Pairing(t *testing.T)    { testJson("blsPairing", "0f", t) }
+func TestPrecompiledBLS12381MapG1(t *testing.T)      { testJson("blsMapG1", "10", t) }
+func TestPrecompiledBLS12381MapG2(t *testing.T)      { testJson("blsMapG2", "11", t) }
+func TestPrecompiledP256Verify(t *testing.T)         { testJson("p256Verify", "12", t) }
+
+func BenchmarkPrecompiledBLS12381G1Add(b *testing.B)      { benchJson("blsG1Add", "0b", b) }
+func BenchmarkPrecompiledBLS12381G1MultiExp(b *testing.B) { benchJson("blsG1MultiExp", "0c", b) }
+func BenchmarkPrecompiledBLS12381G2Add(b *testing.B)      { benchJson("blsG2Add", "0d", b) }
+func BenchmarkPrecompiledBLS12381G2MultiExp(b *testing.B) { benchJson("blsG2MultiExp", "0e", b) }
+func BenchmarkPrecompiledBLS12381Pairing(b *testing.B)    { benchJson("blsPairing", "0f", b) }
+func BenchmarkPrecompiledBLS12381MapG1(b *testing.B)      { benchJson("blsMapG1", "10", b) }
+func BenchmarkPrecompiledBLS12381MapG2(b *testing.B)      { benchJson("blsMapG2", "11", b) }
+func BenchmarkPrecompiledP256Verify(b *testing.B)         { benchJson("p256Verify", "12", b) }
+
+// Failure tests
+func TestPrecompiledBLS12381G1AddFail(t *testing.T)      { testJsonFail("blsG1Add", "0b", t) }
+func TestPrecompiledBLS12381G1MultiExpFail(t *testing.T) { testJsonFail("blsG1MultiExp", "0c", t) }
+func TestPrecompiledBLS12381G2AddFail(t *testing.T)      { testJsonFail("blsG2Add", "0d", t) }
+func TestPrecompiledBLS12381G2MultiExpFail(t *testing.T) { testJsonFail("blsG2MultiExp", "0e", t) }
+func TestPrecompiledBLS12381PairingFail(t *testing.T)    { testJsonFail("blsPairing", "0f", t) }
+func TestPrecompiledBLS12381MapG1Fail(t *testing.T)      { testJsonFail("blsMapG1", "10", t) }
+func TestPrecompiledBLS12381MapG2Fail(t *testing.T)      { testJsonFail("blsMapG2", "11", t) }
+
+func TestPrecompiledOOG(t *testing.T) {
+	for _, name := range []string{"blsG1Add", "blsG2Add", "blsPairing", "p256Verify"} {
+		tests, err := loadJson(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		addr := map[string]string{"blsG1Add": "0b", "blsG2Add": "0d", "blsPairing": "0f", "p256Verify": "12"}[name]
+		testPrecompiledOOG(addr, tests[0], t)
+	}
+}
+
+// TestPrecompileRegistration checks that the new precompiles are reachable at
+// the per-location addresses of the node from their fork block on.
+func TestPrecompileRegistration(t *testing.T) {
+	defer func(loc common.Location) { common.NodeLocation = loc }(common.NodeLocation)
+	common.NodeLocation = common.Location{1, 2}
+	InitializePrecompiles()
+
+	config := &params.ChainConfig{ChainID: big.NewInt(1), CryptoPrecompilesBlock: big.NewInt(10)}
+	before := NewEVM(BlockContext{BlockNumber: big.NewInt(9)}, TxContext{}, nil, config, Config{})
+	after := NewEVM(BlockContext{BlockNumber: big.NewInt(10)}, TxContext{}, nil, config, Config{})
+
+	addrs := PrecompiledAddresses[common.NodeLocation.Name()]
+	for i, addr := range []string{"0b", "0c", "0d", "0e", "0f", "10", "11", "12"} {
+		want := common.HexToAddress(fmt.Sprintf("0x%x%036x%s", addrs[0].Bytes()[0], 0, addr))
+		if got := addrs[10+i]; got.Bytes20() != want.Bytes20() {
+			t.Errorf("precompile %s: address mismatch, have %v, want %v", addr, got, want)
+		}
+		p, ok := PrecompiledContracts[addrs[10+i].Bytes20()]
+		if !ok {
+			t.Fatalf("precompile %s not registered", addr)
+		}
+		if fmt.Sprintf("%T", p) != fmt.Sprintf("%T", allPrecompiles[addr]) {
+			t.Errorf("precompile %s: have %T, want %T", addr, p, allPrecompiles[addr])
+		}
+		if index, ok := TranslatedAddresses[common.AddressBytes([20]byte{byte(11 + i)})]; !ok || index != 10+i {
+			t.Errorf("precompile %s: translated index %d, want %d", addr, index, 10+i)
+		}
+		translated := common.Bytes20ToAddress([20]byte{byte(11 + i)})
+		if _, ok, _ := before.precompile(translated); ok {
+			t.Errorf("precompile %s reachable before the fork", addr)
+		}
+		if _, ok, _ := after.precompile(translated); !ok {
+			t.Errorf("precompile %s not reachable after the fork", addr)
+		}
+	}
+}
+
+// TestHierarchyActivation checks that the hierarchy precompile is only
+// reachable from the hierarchy fork on.
+func TestHierarchyActivation(t *testing.T) {
+	defer func(loc common.Location) { common.NodeLocation = loc }(common.NodeLocation)
+	common.NodeLocation = common.Location{0, 0}
+	InitializePrecompiles()
+
+	config := &params.ChainConfig{ChainID: big.NewInt(1), HierarchyBlock: big.NewInt(10)}
+	translated := common.Bytes20ToAddress([20]byte{10})
+	hierarchyAddr := PrecompiledAddresses[common.NodeLocation.Name()][9]
+
+	evm := NewEVM(BlockContext{BlockNumber: big.NewInt(9)}, TxContext{}, nil, config, Config{})
+	if _, ok, addr := evm.precompile(translated); ok || !addr.Equal(translated) {
+		t.Errorf("hierarchy precompile reachable before the fork, address %v", addr)
+	}
+	for _, addr := range ActivePrecompiles(config.Rules(big.NewInt(9))) {
+		if addr.Equal(hierarchyAddr) {
+			t.Errorf("hierarchy precompile active before the fork")
+		}
+	}
+	parent := common.HexToHash("0x01")
+	evm = NewEVM(BlockContext{BlockNumber: big.NewInt(10), ParentHash: parent}, TxContext{}, nil, config, Config{})
+	p, ok, addr := evm.precompile(translated)
+	if !ok || !addr.Equal(hierarchyAddr) {
+		t.Fatalf("hierarchy precompile not reachable after the fork, address %v", addr)
+	}
+	ret, _, err := RunPrecompiledContract(p, nil, params.HierarchyGas, &evm.Context)
+	if err != nil {
+		t.Fatalf("hierarchy query failed: %v", err)
+	}
+	want := make([]byte, 0, 32*8)
+	want = append(want, make([]byte, 64)...)  // Region and zone 0
+	want = append(want, make([]byte, 128)...) // Unknown prime and region parents
+	want = append(want, parent.Bytes()...)
+	want = append(want, common.LeftPadBytes([]byte{9}, 32)...)
+	if !bytes.Equal(ret, want) {
+		t.Errorf("hierarchy query: have %x, want %x", ret, want)
+	}
+}
+
+// TestHierarchyChain checks that the hierarchy precompile describes the
+// location and the dominant parents of the block in every context, and leaves
+// the contexts below the executing chain empty.
+func TestHierarchyChain(t *testing.T) {
+	defer func(loc common.Location) { common.NodeLocation = loc }(common.NodeLocation)
+
+	domParents := []common.Hash{common.HexToHash("0x0a"), common.HexToHash("0x0b")}
+	blockCtx := &BlockContext{
+		BlockNumber: big.NewInt(100),
+		ParentHash:  common.HexToHash("0x0c"),
+		GetDomParent: func(ctx int) (common.Hash, *big.Int) {
+			return domParents[ctx], big.NewInt(int64(10 * (ctx + 1)))
+		},
+	}
+	word := func(n int64) []byte { return math.U256Bytes(big.NewInt(n)) }
+
+	tests := []struct {
+		location common.Location
+		want     [][]byte
+	}{
+		{common.Location{}, [][]byte{word(-1), word(-1), blockCtx.ParentHash.Bytes(), word(99), make([]byte, 32), word(0), make([]byte, 32), word(0)}},
+		{common.Location{1}, [][]byte{word(1), word(-1), domParents[0].Bytes(), word(10), blockCtx.ParentHash.Bytes(), word(99), make([]byte, 32), word(0)}},
+		{common.Location{1, 2}, [][]byte{word(1), word(2), domParents[0].Bytes(), word(10), domParents[1].Bytes(), word(20), blockCtx.ParentHash.Bytes(), word(99)}},
+	}
+	for _, tt := range tests {
+		common.NodeLocation = tt.location
+		ret, err := (&hierarchy{}).RunWithContext(blockCtx, nil)
+		if err != nil {
+			t.Fatalf("context %d: hierarchy query failed: %v", tt.location.Context(), err)
+		}
+		if want := bytes.Join(tt.want, nil); !bytes.Equal(ret, want) {
+			t.Errorf("context %d: hierarchy query: have %x, want %x", tt.location.Context(), ret, want)
+		}
+	}
+}
+
+func testJson(name, addr string, t *testing.T) {
+	tests, err := loadJson(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range tests {
+		testPrecompiled(addr, test, t)
+	}
+}
+
+func testJsonFail(name, addr string, t *testing.T) {
+	tests, err := loadJsonFail(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range tests {
+		testPrecompiledFailure(addr, test, t)
+	}
+}
+
+func benchJson(name, addr string, b *testing.B) {
+	tests, err := loadJson(name)
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, test := range tests {
+		benchmarkPrecompiled(addr, test, b)
+	}
+}
+
+func loadJson(name string) ([]precompiledTest, error) {
+	data, err := os.ReadFile(fmt.Sprintf("testdata/precompiles/%v.json", name))
+	if err != nil {
+		return nil, err
+	}
+	var testcases []precompiledTest
+	err = json.Unmarshal(data, &testcases)
+	return testcases, err
+}
+
+func loadJsonFail(name string) ([]precompiledFailureTest, error) {
+	data, err := os.ReadFile(fmt.Sprintf("testdata/precompiles/fail-%v.json", name))
+	if err != nil {
+		return nil, err
+	}
+	var testcases []precompiledFailureTest
+	err = json.Unmarshal(data, &testcases)
+	return testcases, err
+}
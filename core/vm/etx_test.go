@@ -0,0 +1,139 @@
+package vm
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/dominant-strategies/go-quai/common"
+	"github.com/dominant-strategies/go-quai/core/rawdb"
+	"github.com/dominant-strategies/go-quai/core/state"
+	"github.com/dominant-strategies/go-quai/core/types"
+	"github.com/dominant-strategies/go-quai/params"
+)
+
+// Tests that ETXs only record the transaction which emitted them from the ETX
+// origin fork on, and that ETXs emitted before keep their encoding and hash.
+func TestETXOrigin(t *testing.T) {
+	config := &params.ChainConfig{ChainID: big.NewInt(1), EtxOriginBlock: big.NewInt(10)}
+	to := common.HexToAddress("0x2000000000000000000000000000000000000001")
+	newETX := func() types.ExternalTx {
+		return types.ExternalTx{ChainID: config.ChainID, Nonce: 1, GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(2), Gas: params.TxGas, To: &to, Value: big.NewInt(1), Sender: common.HexToAddress("0x0000000000000000000000000000000000000001")}
+	}
+	legacyInner := newETX()
+	legacy := types.NewTx(&legacyInner)
+	legacyEnc, err := legacy.MarshalBinary()
+	if err != nil {
+		t.Fatalf("failed to encode ETX: %v", err)
+	}
+	txHash := common.Hash{0x01}
+
+	for _, number := range []int64{9, 10} {
+		evm := NewEVM(BlockContext{BlockNumber: big.NewInt(number)}, TxContext{}, nil, config, Config{})
+		evm.TxHash = txHash
+		evm.ETXCache = []*types.Transaction{legacy}
+
+		inner := newETX()
+		evm.setETXOrigin(&inner)
+		etx := types.NewTx(&inner)
+		enc, err := etx.MarshalBinary()
+		if err != nil {
+			t.Fatalf("block %d: failed to encode ETX: %v", number, err)
+		}
+		if !config.IsEtxOrigin(big.NewInt(number)) {
+			if !bytes.Equal(enc, legacyEnc) || etx.Hash() != legacy.Hash() {
+				t.Errorf("block %d: ETX encoding changed before the fork: have %x, want %x", number, enc, legacyEnc)
+			}
+			if origin := types.NewEtxOrigin(etx); origin != nil {
+				t.Errorf("block %d: ETX has an origin before the fork: %v", number, origin)
+			}
+			continue
+		}
+		if etx.Hash() == legacy.Hash() {
+			t.Errorf("block %d: ETX hash unchanged after the fork", number)
+		}
+		decoded := new(types.Transaction)
+		if err := decoded.UnmarshalBinary(enc); err != nil {
+			t.Fatalf("block %d: failed to decode ETX: %v", number, err)
+		}
+		if decoded.Hash() != etx.Hash() || decoded.OriginatingTxHash() != txHash || decoded.ETXIndex() != 1 {
+			t.Errorf("block %d: ETX origin mismatch: have %x/%d, want %x/1", number, decoded.OriginatingTxHash(), decoded.ETXIndex(), txHash)
+		}
+	}
+}
+
+// etxCode returns contract code emitting count ETXs of value one to the given
+// address, which then stops or reverts.
+func etxCode(to common.Address, count int, revert bool) []byte {
+	var code []byte
+	for i := 0; i < count; i++ {
+		// Access list size and offset, calldata size and offset, fee cap, tip,
+		// gas limit and value of the ETX
+		code = append(code, byte(PUSH1), 0, byte(PUSH1), 0, byte(PUSH1), 0, byte(PUSH1), 0, byte(PUSH1), 1, byte(PUSH1), 1, byte(PUSH2), 0x52, 0x08, byte(PUSH1), 1)
+		code = append(code, byte(PUSH20))
+		code = append(code, to.Bytes()...)
+		code = append(code, byte(PUSH1), 0, byte(ETX), byte(POP))
+	}
+	if revert {
+		return append(code, byte(PUSH1), 0, byte(PUSH1), 0, byte(REVERT))
+	}
+	return append(code, byte(STOP))
+}
+
+// Tests that from the contract ETX fork on the ETXs emitted by a reverted call
+// are dropped and an ETX over the limit of the transaction fails softly, while
+// before the fork both are kept.
+func TestContractETXRules(t *testing.T) {
+	defer func(loc common.Location) { common.NodeLocation = loc }(common.NodeLocation)
+	common.NodeLocation = common.Location{0, 0}
+
+	var (
+		config   = &params.ChainConfig{ChainID: big.NewInt(1), ContractETXBlock: big.NewInt(10)}
+		caller   = common.HexToAddress("0x0000000000000000000000000000000000000011")
+		contract = common.HexToAddress("0x0000000000000000000000000000000000000010")
+		to       = common.HexToAddress("0x1e00000000000000000000000000000000000001") // cyprus2
+	)
+	canTransfer := func(db StateDB, addr common.Address, amount *big.Int) bool {
+		internal, err := addr.InternalAddress()
+		return err == nil && db.GetBalance(internal).Cmp(amount) >= 0
+	}
+	transfer := func(db StateDB, sender, recipient common.Address, amount *big.Int) error {
+		return nil
+	}
+	// run executes the code with a limit of one cross-region ETX and returns
+	// the emitted ETXs
+	run := func(number int64, code []byte) ([]*types.Transaction, error) {
+		statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		internal, _ := contract.InternalAddress()
+		statedb.SetCode(internal, code)
+		statedb.AddBalance(internal, big.NewInt(params.Ether))
+
+		blockCtx := BlockContext{CanTransfer: canTransfer, Transfer: transfer, BlockNumber: big.NewInt(number), Time: big.NewInt(0), Difficulty: big.NewInt(0), BaseFee: big.NewInt(0)}
+		txCtx := TxContext{Origin: caller, GasPrice: big.NewInt(0), TXGasTip: big.NewInt(0), ETXRLimit: 1, ETXPLimit: 1}
+		evm := NewEVM(blockCtx, txCtx, statedb, config, Config{})
+		_, _, err := evm.Call(AccountRef(caller), contract, nil, 1000000, big.NewInt(0))
+		return evm.ETXCache, err
+	}
+	tests := []struct {
+		name   string
+		number int64
+		code   []byte
+		err    error
+		etxs   int
+	}{
+		{"reverted before fork", 9, etxCode(to, 1, true), ErrExecutionReverted, 1},
+		{"reverted after fork", 10, etxCode(to, 1, true), ErrExecutionReverted, 0},
+		{"over limit before fork", 9, etxCode(to, 2, false), nil, 2},
+		{"over limit after fork", 10, etxCode(to, 2, false), nil, 1},
+		{"within limit after fork", 10, etxCode(to, 1, false), nil, 1},
+	}
+	for _, tt := range tests {
+		etxs, err := run(tt.number, tt.code)
+		if err != tt.err {
+			t.Errorf("%s: error mismatch: have %v, want %v", tt.name, err, tt.err)
+		}
+		if len(etxs) != tt.etxs {
+			t.Errorf("%s: emitted ETX count mismatch: have %d, want %d", tt.name, len(etxs), tt.etxs)
+		}
+	}
+}
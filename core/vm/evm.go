@@ -42,14 +42,22 @@ type (
 	// GetHashFunc returns the n'th block hash in the blockchain
 	// and is used by the BLOCKHASH EVM op code.
 	GetHashFunc func(uint64) common.Hash
+	// GetDomParentFunc returns the hash and number of the latest block coincident
+	// with the given dominant context and is used by the hierarchy precompile.
+	GetDomParentFunc func(int) (common.Hash, *big.Int)
 )
 
 func (evm *EVM) precompile(addr common.Address) (PrecompiledContract, bool, common.Address) {
+	target := addr
 	if index, ok := TranslatedAddresses[addr.Bytes20()]; ok {
-		addr = PrecompiledAddresses[common.NodeLocation.Name()][index]
+		target = PrecompiledAddresses[common.NodeLocation.Name()][index]
 	}
-	p, ok := PrecompiledContracts[addr.Bytes20()]
-	return p, ok, addr
+	p, ok := PrecompiledContracts[target.Bytes20()]
+	if !ok || !isPrecompileActive(p, evm.chainRules) {
+		// Addresses of inactive contracts are neither translated nor run
+		return nil, false, addr
+	}
+	return p, true, target
 }
 
 // BlockContext provides the EVM with auxiliary information. Once provided
@@ -62,6 +70,8 @@ type BlockContext struct {
 	Transfer TransferFunc
 	// GetHash returns the hash corresponding to n
 	GetHash GetHashFunc
+	// GetDomParent returns the latest block coincident with a dominant context
+	GetDomParent GetDomParentFunc
 
 	// Block information
 	Coinbase    common.Address // Provides information for COINBASE
@@ -70,6 +80,7 @@ type BlockContext struct {
 	Time        *big.Int       // Provides information for TIME
 	Difficulty  *big.Int       // Provides information for DIFFICULTY
 	BaseFee     *big.Int       // Provides information for BASEFEE
+	ParentHash  common.Hash    // Provides the parent of the block in the executing chain
 }
 
 // TxContext provides the EVM with information about a transaction.
@@ -86,6 +97,9 @@ type TxContext struct {
 	TXGasTip      *big.Int
 	ETXData       []byte
 	ETXAccessList types.AccessList
+	TxHash        common.Hash // Hash of the transaction, recorded in the ETXs it emits
+	ETXRLimit     int         // Number of cross-region ETXs the transaction may emit
+	ETXPLimit     int         // Number of cross-prime ETXs the transaction may emit
 }
 
 // EVM is the Quai Virtual Machine base object and provides
@@ -184,6 +198,7 @@ func (evm *EVM) Call(caller ContractRef, addr common.Address, input []byte, gas
 		return nil, gas, ErrInsufficientBalance
 	}
 	snapshot := evm.StateDB.Snapshot()
+	etxSnapshot := evm.etxSnapshot()
 	p, isPrecompile, addr := evm.precompile(addr)
 	if evm.TxType == types.InternalToExternalTxType {
 		return evm.CreateETX(addr, caller.Address(), evm.ETXGasLimit, evm.ETXGasPrice, evm.ETXGasTip, evm.ETXData, evm.ETXAccessList, gas, value)
@@ -217,7 +232,7 @@ func (evm *EVM) Call(caller ContractRef, addr common.Address, input []byte, gas
 	}
 
 	if isPrecompile {
-		ret, gas, err = RunPrecompiledContract(p, input, gas)
+		ret, gas, err = RunPrecompiledContract(p, input, gas, &evm.Context)
 	} else {
 		// Initialise a new contract and set the code that is to be used by the EVM.
 		// The contract is a scoped environment for this execution context only.
@@ -239,6 +254,7 @@ func (evm *EVM) Call(caller ContractRef, addr common.Address, input []byte, gas
 	// when we're in this also counts for code storage gas errors.
 	if err != nil {
 		evm.StateDB.RevertToSnapshot(snapshot)
+		evm.revertETXs(etxSnapshot)
 		if err != ErrExecutionReverted {
 			gas = 0
 		}
@@ -272,13 +288,15 @@ func (evm *EVM) CallCode(caller ContractRef, addr common.Address, input []byte,
 		return nil, gas, ErrInsufficientBalance
 	}
 	var snapshot = evm.StateDB.Snapshot()
+	etxSnapshot := evm.etxSnapshot()
 
 	// It is allowed to call precompiles, even via delegatecall
 	if p, isPrecompile, addr := evm.precompile(addr); isPrecompile {
-		ret, gas, err = RunPrecompiledContract(p, input, gas)
+		ret, gas, err = RunPrecompiledContract(p, input, gas, &evm.Context)
 	} else {
 		addrCopy := addr
-		internalAddr, err := addrCopy.InternalAddress()
+		var internalAddr common.InternalAddress
+		internalAddr, err = addrCopy.InternalAddress()
 		if err != nil {
 			return nil, gas, err
 		}
@@ -288,9 +306,15 @@ func (evm *EVM) CallCode(caller ContractRef, addr common.Address, input []byte,
 		contract.SetCallCode(&addrCopy, evm.StateDB.GetCodeHash(internalAddr), evm.StateDB.GetCode(internalAddr))
 		ret, err = evm.interpreter.Run(contract, input, false)
 		gas = contract.Gas
+		if !evm.chainRules.IsCancun {
+			// Before Cancun the execution error was shadowed here, so a
+			// failing call was neither reverted nor reported to the caller.
+			err = nil
+		}
 	}
 	if err != nil {
 		evm.StateDB.RevertToSnapshot(snapshot)
+		evm.revertETXs(etxSnapshot)
 		if err != ErrExecutionReverted {
 			gas = 0
 		}
@@ -312,13 +336,15 @@ func (evm *EVM) DelegateCall(caller ContractRef, addr common.Address, input []by
 		return nil, gas, ErrDepth
 	}
 	var snapshot = evm.StateDB.Snapshot()
+	etxSnapshot := evm.etxSnapshot()
 
 	// It is allowed to call precompiles, even via delegatecall
 	if p, isPrecompile, addr := evm.precompile(addr); isPrecompile {
-		ret, gas, err = RunPrecompiledContract(p, input, gas)
+		ret, gas, err = RunPrecompiledContract(p, input, gas, &evm.Context)
 	} else {
 		addrCopy := addr
-		internalAddr, err := addrCopy.InternalAddress()
+		var internalAddr common.InternalAddress
+		internalAddr, err = addrCopy.InternalAddress()
 		if err != nil {
 			return nil, gas, err
 		}
@@ -327,9 +353,13 @@ func (evm *EVM) DelegateCall(caller ContractRef, addr common.Address, input []by
 		contract.SetCallCode(&addrCopy, evm.StateDB.GetCodeHash(internalAddr), evm.StateDB.GetCode(internalAddr))
 		ret, err = evm.interpreter.Run(contract, input, false)
 		gas = contract.Gas
+		if !evm.chainRules.IsCancun {
+			err = nil // shadowed before Cancun, see CallCode
+		}
 	}
 	if err != nil {
 		evm.StateDB.RevertToSnapshot(snapshot)
+		evm.revertETXs(etxSnapshot)
 		if err != ErrExecutionReverted {
 			gas = 0
 		}
@@ -356,11 +386,13 @@ func (evm *EVM) StaticCall(caller ContractRef, addr common.Address, input []byte
 	// then certain tests start failing; stRevertTest/RevertPrecompiledTouchExactOOG.json.
 	// We could change this, but for now it's left for legacy reasons
 	var snapshot = evm.StateDB.Snapshot()
+	etxSnapshot := evm.etxSnapshot()
 
 	if p, isPrecompile, addr := evm.precompile(addr); isPrecompile {
-		ret, gas, err = RunPrecompiledContract(p, input, gas)
+		ret, gas, err = RunPrecompiledContract(p, input, gas, &evm.Context)
 	} else {
-		internalAddr, err := addr.InternalAddress()
+		var internalAddr common.InternalAddress
+		internalAddr, err = addr.InternalAddress()
 		if err != nil {
 			return nil, gas, err
 		}
@@ -377,9 +409,13 @@ func (evm *EVM) StaticCall(caller ContractRef, addr common.Address, input []byte
 		// when we're in this also counts for code storage gas errors.
 		ret, err = evm.interpreter.Run(contract, input, true)
 		gas = contract.Gas
+		if !evm.chainRules.IsCancun {
+			err = nil // shadowed before Cancun, see CallCode
+		}
 	}
 	if err != nil {
 		evm.StateDB.RevertToSnapshot(snapshot)
+		evm.revertETXs(etxSnapshot)
 		if err != ErrExecutionReverted {
 			gas = 0
 		}
@@ -433,6 +469,7 @@ func (evm *EVM) create(caller ContractRef, codeAndHash *codeAndHash, gas uint64,
 	}
 	// Create a new account on the state
 	snapshot := evm.StateDB.Snapshot()
+	etxSnapshot := evm.etxSnapshot()
 	evm.StateDB.CreateAccount(internalContractAddr)
 
 	evm.StateDB.SetNonce(internalContractAddr, 1)
@@ -485,6 +522,7 @@ func (evm *EVM) create(caller ContractRef, codeAndHash *codeAndHash, gas uint64,
 	// when we're in this also counts for code storage gas errors.
 	if err != nil && err != ErrCodeStoreOutOfGas {
 		evm.StateDB.RevertToSnapshot(snapshot)
+		evm.revertETXs(etxSnapshot)
 		if err != ErrExecutionReverted {
 			contract.UseGas(contract.Gas)
 		}
@@ -533,6 +571,9 @@ func (evm *EVM) CreateETX(toAddr common.Address, fromAddr common.Address, etxGas
 	if err := evm.ValidateETXGasPriceAndTip(fromAddr, toAddr, etxGasPrice, etxGasTip); err != nil {
 		return []byte{}, 0, err
 	}
+	if err := evm.CheckETXLimit(toAddr); err != nil {
+		return []byte{}, 0, err
+	}
 
 	fee := big.NewInt(0)
 	fee.Add(etxGasTip, etxGasPrice)
@@ -549,16 +590,81 @@ func (evm *EVM) CreateETX(toAddr common.Address, fromAddr common.Address, etxGas
 	nonce := evm.StateDB.GetNonce(fromInternal)
 
 	// create external transaction
+	evm.ETXCacheLock.Lock()
 	etxInner := types.ExternalTx{Value: value, To: &toAddr, Sender: fromAddr, GasTipCap: etxGasTip, GasFeeCap: etxGasPrice, Gas: etxGasLimit, Data: etxData, AccessList: etxAccessList, Nonce: nonce, ChainID: evm.chainConfig.ChainID}
+	evm.setETXOrigin(&etxInner)
 	etx := types.NewTx(&etxInner)
-
-	evm.ETXCacheLock.Lock()
 	evm.ETXCache = append(evm.ETXCache, etx)
 	evm.ETXCacheLock.Unlock()
 
 	return []byte{}, gas - params.ETXGas, nil
 }
 
+// setETXOrigin records the transaction which emits the ETX and the position of
+// the ETX among the ETXs emitted so far, once the ETX origin fork is active.
+// Before, both are left unset so that the ETX keeps its encoding and hash. The
+// caller has to hold ETXCacheLock.
+func (evm *EVM) setETXOrigin(etx *types.ExternalTx) {
+	if evm.chainRules.IsEtxOrigin {
+		etx.OriginatingTxHash, etx.ETXIndex = evm.TxHash, uint16(len(evm.ETXCache))
+	}
+}
+
+// CheckETXLimit returns an error if the transaction already emitted as many
+// ETXs to the confirmation context of toAddr as the block allows it to.
+func (evm *EVM) CheckETXLimit(toAddr common.Address) error {
+	// Before the contract ETX fork, an ETX over the limit was emitted and made
+	// the block invalid
+	if !evm.chainRules.IsContractETX {
+		return nil
+	}
+	confirmationCtx := toAddr.Location().CommonDom(common.NodeLocation).Context()
+	var limit int
+	switch confirmationCtx {
+	case common.REGION_CTX:
+		limit = evm.ETXRLimit
+	case common.PRIME_CTX:
+		limit = evm.ETXPLimit
+	default:
+		return nil
+	}
+	evm.ETXCacheLock.RLock()
+	defer evm.ETXCacheLock.RUnlock()
+
+	count := 0
+	for _, etx := range evm.ETXCache {
+		if etx.To().Location().CommonDom(common.NodeLocation).Context() == confirmationCtx {
+			count++
+		}
+	}
+	if count >= limit {
+		return fmt.Errorf("ETX limit reached: %d ETXs confirmed in %s already emitted", count, common.Location(common.NodeLocation[:confirmationCtx]).Name())
+	}
+	return nil
+}
+
+// etxSnapshot returns the number of ETXs emitted so far, so the ETXs of a
+// reverted call can be dropped with revertETXs.
+func (evm *EVM) etxSnapshot() int {
+	evm.ETXCacheLock.RLock()
+	defer evm.ETXCacheLock.RUnlock()
+	return len(evm.ETXCache)
+}
+
+// revertETXs drops the ETXs emitted after the snapshot was taken. The balance
+// paying for them is restored together with the rest of the state. Before the
+// contract ETX fork, the ETXs of reverted calls were kept.
+func (evm *EVM) revertETXs(snapshot int) {
+	if !evm.chainRules.IsContractETX {
+		return
+	}
+	evm.ETXCacheLock.Lock()
+	defer evm.ETXCacheLock.Unlock()
+	if snapshot < len(evm.ETXCache) {
+		evm.ETXCache = evm.ETXCache[:snapshot]
+	}
+}
+
 // Emitted ETXs must include some multiple of BaseFee as miner tip, to
 // encourage processing at the destination.
 func calcEtxFeeMultiplier(fromAddr, toAddr common.Address) *big.Int {
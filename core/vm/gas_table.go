@@ -62,6 +62,7 @@ func memoryGasCost(mem *Memory, newMemSize uint64) (uint64, error) {
 // CODECOPY (stack position 2)
 // EXTCODECOPY (stack poition 3)
 // RETURNDATACOPY (stack position 2)
+// MCOPY (stack position 2)
 func memoryCopierGas(stackpos int) gasFunc {
 	return func(evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
 		// Gas for expanding the memory
@@ -90,6 +91,7 @@ var (
 	gasCallDataCopy   = memoryCopierGas(2)
 	gasCodeCopy       = memoryCopierGas(2)
 	gasReturnDataCopy = memoryCopierGas(2)
+	gasMcopy          = memoryCopierGas(2)
 )
 
 //  0. If *gasleft* is less than or equal to 2300, fail the current call.
@@ -358,3 +360,13 @@ func gasSelfdestruct(evm *EVM, contract *Contract, stack *Stack, mem *Memory, me
 	}
 	return gas, nil
 }
+
+// gasETX charges the memory expansion needed to read the calldata and access
+// list of an ETX, the ETX itself is covered by the constant gas of the opcode.
+// Before the contract ETX fork the memory was expanded for free.
+func gasETX(evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
+	if !evm.chainRules.IsContractETX {
+		return 0, nil
+	}
+	return memoryGasCost(mem, memorySize)
+}
@@ -17,10 +17,9 @@
 package vm
 
 import (
-	"fmt"
-
 	"github.com/dominant-strategies/go-quai/common"
 	"github.com/dominant-strategies/go-quai/core/types"
+	"github.com/dominant-strategies/go-quai/log"
 	"github.com/dominant-strategies/go-quai/params"
 	"github.com/dominant-strategies/go-quai/rlp"
 	"github.com/holiman/uint256"
@@ -852,40 +851,63 @@ func opETX(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byte
 	if common.IsInChainScope(toAddr.Bytes()) {
 		temp.Clear()
 		stack.push(&temp)
-		fmt.Printf("%x is in chain scope, but opETX was called\n", toAddr)
+		log.Debug("opETX called with an address in chain scope", "address", toAddr)
 		return nil, nil // following opCall protocol
 	}
 	sender := scope.Contract.self.Address()
 	internalSender, err := sender.InternalAddress()
 	if err != nil {
-		fmt.Printf("%x opETX error: %s\n", scope.Contract.self.Address(), err.Error())
-		return nil, nil
+		temp.Clear()
+		stack.push(&temp)
+		log.Debug("opETX failed", "contract", scope.Contract.self.Address(), "err", err)
+		return nil, nil // following opCall protocol
 	}
-	// Fail if ETX gas price or tip are not valid
-	if err := interpreter.evm.ValidateETXGasPriceAndTip(scope.Contract.Caller(), toAddr, gasFeeCap.ToBig(), gasTipCap.ToBig()); err != nil {
+	// Fail if ETX gas price or tip are not valid. Before the contract ETX fork
+	// they were validated against the caller of the contract.
+	feePayer := sender
+	if !interpreter.evm.chainRules.IsContractETX {
+		feePayer = scope.Contract.Caller()
+	}
+	if err := interpreter.evm.ValidateETXGasPriceAndTip(feePayer, toAddr, gasFeeCap.ToBig(), gasTipCap.ToBig()); err != nil {
 		temp.Clear()
 		stack.push(&temp)
-		fmt.Printf("%x opETX error: %s\n", scope.Contract.self.Address(), err.Error())
+		log.Debug("opETX failed", "contract", scope.Contract.self.Address(), "err", err)
 		return nil, nil // following opCall protocol
 	}
-
-	fee := uint256.NewInt(0)
-	fee.Add(&gasTipCap, &gasFeeCap)
-	fee.Mul(fee, &etxGasLimit)
-	total := uint256.NewInt(0)
-	total.Add(&value, fee)
-	// Fail if we're trying to transfer more than the available balance
-	if total.Sign() == 0 || !interpreter.evm.Context.CanTransfer(interpreter.evm.StateDB, scope.Contract.self.Address(), total.ToBig()) {
+	// Fail if the transaction can't emit any more ETXs to the destination
+	if err := interpreter.evm.CheckETXLimit(toAddr); err != nil {
 		temp.Clear()
 		stack.push(&temp)
-		fmt.Printf("%x cannot transfer %d\n", scope.Contract.self.Address(), total.Uint64())
-		return nil, nil
+		log.Debug("opETX failed", "contract", scope.Contract.self.Address(), "err", err)
+		return nil, nil // following opCall protocol
 	}
 
-	interpreter.evm.StateDB.SubBalance(internalSender, total.ToBig())
+	// Before the contract ETX fork, the value and fees were deducted before the
+	// access list was decoded, wrapping around on overflow, and the calldata
+	// referenced the memory
+	if !interpreter.evm.chainRules.IsContractETX {
+		fee := uint256.NewInt(0)
+		fee.Add(&gasTipCap, &gasFeeCap)
+		fee.Mul(fee, &etxGasLimit)
+		total := uint256.NewInt(0)
+		total.Add(&value, fee)
+		if total.Sign() == 0 || !interpreter.evm.Context.CanTransfer(interpreter.evm.StateDB, scope.Contract.self.Address(), total.ToBig()) {
+			temp.Clear()
+			stack.push(&temp)
+			log.Debug("opETX can't transfer the value and fees", "contract", scope.Contract.self.Address(), "total", total)
+			return nil, nil
+		}
+		interpreter.evm.StateDB.SubBalance(internalSender, total.ToBig())
+	}
 
-	// Get the arguments from the memory.
-	data := scope.Memory.GetPtr(int64(inOffset.Uint64()), int64(inSize.Uint64()))
+	// Get the arguments from the memory. The calldata is copied, as the memory
+	// may change after the ETX was emitted.
+	var data []byte
+	if interpreter.evm.chainRules.IsContractETX {
+		data = scope.Memory.GetCopy(int64(inOffset.Uint64()), int64(inSize.Uint64()))
+	} else {
+		data = scope.Memory.GetPtr(int64(inOffset.Uint64()), int64(inSize.Uint64()))
+	}
 	accessList := types.AccessList{}
 	// Get access list from memory
 	accessListBytes := scope.Memory.GetPtr(int64(accessListOffset.Uint64()), int64(accessListSize.Uint64()))
@@ -893,17 +915,31 @@ func opETX(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byte
 	if err != nil && accessListSize.Sign() != 0 {
 		temp.Clear()
 		stack.push(&temp)
-		fmt.Printf("%x opETX error: %s\n", scope.Contract.self.Address(), err.Error())
+		log.Debug("opETX failed", "contract", scope.Contract.self.Address(), "err", err)
 		return nil, nil // following opCall protocol
 	}
 
+	if interpreter.evm.chainRules.IsContractETX {
+		fee, overflow1 := uint256.NewInt(0).AddOverflow(&gasTipCap, &gasFeeCap)
+		fee, overflow2 := fee.MulOverflow(fee, &etxGasLimit)
+		total, overflow3 := uint256.NewInt(0).AddOverflow(&value, fee)
+		// Fail if we're trying to transfer more than the available balance
+		if overflow1 || overflow2 || overflow3 || !etxGasLimit.IsUint64() || total.Sign() == 0 || !interpreter.evm.Context.CanTransfer(interpreter.evm.StateDB, scope.Contract.self.Address(), total.ToBig()) {
+			temp.Clear()
+			stack.push(&temp)
+			log.Debug("opETX can't transfer the value and fees", "contract", scope.Contract.self.Address(), "total", total)
+			return nil, nil
+		}
+		interpreter.evm.StateDB.SubBalance(internalSender, total.ToBig())
+	}
+
 	nonce := interpreter.evm.StateDB.GetNonce(internalSender)
 
 	// create external transaction
+	interpreter.evm.ETXCacheLock.Lock()
 	etxInner := types.ExternalTx{Value: value.ToBig(), To: &toAddr, Sender: sender, GasTipCap: gasTipCap.ToBig(), GasFeeCap: gasFeeCap.ToBig(), Gas: etxGasLimit.Uint64(), Data: data, AccessList: accessList, Nonce: nonce, ChainID: interpreter.evm.chainConfig.ChainID}
+	interpreter.evm.setETXOrigin(&etxInner)
 	etx := types.NewTx(&etxInner)
-
-	interpreter.evm.ETXCacheLock.Lock()
 	interpreter.evm.ETXCache = append(interpreter.evm.ETXCache, etx)
 	interpreter.evm.ETXCacheLock.Unlock()
 
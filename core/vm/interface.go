@@ -29,6 +29,7 @@ type StateDB interface {
 
 	SubBalance(common.InternalAddress, *big.Int)
 	AddBalance(common.InternalAddress, *big.Int)
+	AddFee(common.InternalAddress, *big.Int)
 	GetBalance(common.InternalAddress) *big.Int
 
 	GetNonce(common.InternalAddress) uint64
@@ -47,6 +48,9 @@ type StateDB interface {
 	GetState(common.InternalAddress, common.Hash) common.Hash
 	SetState(common.InternalAddress, common.Hash, common.Hash)
 
+	GetTransientState(addr common.InternalAddress, key common.Hash) common.Hash
+	SetTransientState(addr common.InternalAddress, key, value common.Hash)
+
 	Suicide(common.InternalAddress) bool
 	HasSuicided(common.InternalAddress) bool
 
@@ -49,12 +49,23 @@ type operation struct {
 }
 
 var (
-	instructionSet = NewInstructionSet()
+	instructionSet       = NewInstructionSet()
+	cancunInstructionSet = newCancunInstructionSet()
 )
 
 // JumpTable contains the EVM opcodes supported at a given fork.
 type JumpTable [256]*operation
 
+// newCancunInstructionSet returns the instructions of NewInstructionSet along
+// with the opcodes enabled by the Cancun switch of the chain config.
+func newCancunInstructionSet() JumpTable {
+	instructionSet := NewInstructionSet()
+	enable3855(&instructionSet) // PUSH0 instruction
+	enable1153(&instructionSet) // Transient storage opcodes
+	enable5656(&instructionSet) // MCOPY opcode
+	return instructionSet
+}
+
 // NewInstructionSet returns all instructions.
 func NewInstructionSet() JumpTable {
 	instructionSet := newInstructionSet()
@@ -981,6 +992,7 @@ func newInstructionSet() JumpTable {
 		ETX: {
 			execute:     opETX,
 			constantGas: params.ETXGas,
+			dynamicGas:  gasETX,
 			minStack:    minStack(10, 1),
 			maxStack:    maxStack(10, 1),
 			memorySize:  memoryETX,
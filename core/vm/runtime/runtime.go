@@ -100,7 +100,8 @@ func Execute(code, input []byte, cfg *Config) ([]byte, *state.StateDB, error) {
 		cfg.State, _ = state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
 	}
 	var (
-		address = common.BytesToAddress([]byte("contract"))
+		// Pad the address, the scope of an address is determined by its first byte
+		address = common.BytesToAddress(common.LeftPadBytes([]byte("contract"), common.AddressLength))
 		vmenv   = NewEnv(cfg)
 		sender  = vm.AccountRef(cfg.Origin)
 	)
@@ -117,7 +118,7 @@ func Execute(code, input []byte, cfg *Config) ([]byte, *state.StateDB, error) {
 	// Call the code with the given configuration.
 	ret, _, err := vmenv.Call(
 		sender,
-		common.BytesToAddress([]byte("contract")),
+		address,
 		input,
 		cfg.GasLimit,
 		cfg.Value,
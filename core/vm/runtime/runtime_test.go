@@ -0,0 +1,248 @@
+// Copyright 2015 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package runtime
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/dominant-strategies/go-quai/common"
+	"github.com/dominant-strategies/go-quai/core/rawdb"
+	"github.com/dominant-strategies/go-quai/core/state"
+	"github.com/dominant-strategies/go-quai/core/vm"
+	"github.com/dominant-strategies/go-quai/params"
+)
+
+func init() {
+	// The contracts are executed at the zero prefixed address "contract",
+	// which belongs to cyprus1
+	common.NodeLocation = common.Location{0, 0}
+}
+
+// contractAddress returns the address Execute runs the code at.
+func contractAddress() common.Address {
+	return common.BytesToAddress(common.LeftPadBytes([]byte("contract"), common.AddressLength))
+}
+
+// cancunConfig returns a chain config with the Cancun opcodes enabled from
+// the given block on.
+func cancunConfig(block int64) *params.ChainConfig {
+	return &params.ChainConfig{
+		ChainID:     big.NewInt(1),
+		CancunBlock: big.NewInt(block),
+	}
+}
+
+// returnWord is the code returning the top of the stack as a 32 byte word.
+var returnWord = []byte{
+	byte(vm.PUSH1), 0x00, byte(vm.MSTORE),
+	byte(vm.PUSH1), 0x20, byte(vm.PUSH1), 0x00, byte(vm.RETURN),
+}
+
+func TestPush0(t *testing.T) {
+	code := append([]byte{
+		byte(vm.PUSH1), 0x01, // Dirty the stack slot PUSH0 reuses
+		byte(vm.POP),
+		byte(vm.PUSH0),
+	}, returnWord...)
+
+	ret, _, err := Execute(code, nil, &Config{ChainConfig: cancunConfig(0)})
+	if err != nil {
+		t.Fatal("didn't expect error", err)
+	}
+	if !bytes.Equal(ret, make([]byte, 32)) {
+		t.Errorf("PUSH0 returned %x, want zero word", ret)
+	}
+}
+
+func TestTransientStorage(t *testing.T) {
+	code := append([]byte{
+		byte(vm.PUSH1), 0x2a, // value
+		byte(vm.PUSH1), 0x01, // key
+		byte(vm.TSTORE),
+		byte(vm.PUSH1), 0x01, // key
+		byte(vm.TLOAD),
+	}, returnWord...)
+
+	cfg := &Config{ChainConfig: cancunConfig(0)}
+	ret, statedb, err := Execute(code, nil, cfg)
+	if err != nil {
+		t.Fatal("didn't expect error", err)
+	}
+	if want := common.LeftPadBytes([]byte{0x2a}, 32); !bytes.Equal(ret, want) {
+		t.Errorf("TLOAD returned %x, want %x", ret, want)
+	}
+	// Transient storage never reaches the persistent storage
+	addr, _ := contractAddress().InternalAddress()
+	if val := statedb.GetState(addr, common.BigToHash(big.NewInt(1))); val != (common.Hash{}) {
+		t.Errorf("transient value leaked into storage: %x", val)
+	}
+	// and is cleared at the start of every transaction
+	load := append([]byte{byte(vm.PUSH1), 0x01, byte(vm.TLOAD)}, returnWord...)
+	if ret, _, err = Execute(load, nil, cfg); err != nil {
+		t.Fatal("didn't expect error", err)
+	}
+	if !bytes.Equal(ret, make([]byte, 32)) {
+		t.Errorf("TLOAD in a new transaction returned %x, want zero word", ret)
+	}
+}
+
+func TestTransientStorageRevert(t *testing.T) {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	addr, _ := contractAddress().InternalAddress()
+	key, val := common.HexToHash("0x01"), common.HexToHash("0x2a")
+
+	statedb.SetTransientState(addr, key, val)
+	snapshot := statedb.Snapshot()
+	statedb.SetTransientState(addr, key, common.HexToHash("0x2b"))
+	statedb.RevertToSnapshot(snapshot)
+	if have := statedb.GetTransientState(addr, key); have != val {
+		t.Errorf("transient value after revert: have %x, want %x", have, val)
+	}
+}
+
+func TestMcopy(t *testing.T) {
+	tests := []struct {
+		dst, src, length byte
+		want             string // memory word at offset 0 after the copy
+	}{
+		{0x00, 0x20, 0x20, "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"},
+		{0x00, 0x21, 0x04, "0102030400000000000000000000000000000000000000000000000000000000"},
+		{0x00, 0x20, 0x00, "0000000000000000000000000000000000000000000000000000000000000000"},
+	}
+	word := make([]byte, 32)
+	for i := range word {
+		word[i] = byte(i)
+	}
+	for i, tt := range tests {
+		code := append([]byte{byte(vm.PUSH32)}, word...)
+		code = append(code,
+			byte(vm.PUSH1), 0x20, byte(vm.MSTORE), // Store the word at offset 32
+			byte(vm.PUSH1), tt.length,
+			byte(vm.PUSH1), tt.src,
+			byte(vm.PUSH1), tt.dst,
+			byte(vm.MCOPY),
+			byte(vm.PUSH1), 0x20, byte(vm.PUSH1), 0x00, byte(vm.RETURN),
+		)
+		ret, _, err := Execute(code, nil, &Config{ChainConfig: cancunConfig(0)})
+		if err != nil {
+			t.Fatalf("test %d: didn't expect error %v", i, err)
+		}
+		if have := common.Bytes2Hex(ret); have != tt.want {
+			t.Errorf("test %d: memory mismatch\nhave %s\nwant %s", i, have, tt.want)
+		}
+	}
+}
+
+func TestMcopyGas(t *testing.T) {
+	// MCOPY of 32 bytes from offset 0 to offset 32, expanding the memory to
+	// two words: 3 (constant) + 3 (copy) + 6 (memory)
+	code := []byte{
+		byte(vm.PUSH1), 0x20, // length
+		byte(vm.PUSH1), 0x00, // source
+		byte(vm.PUSH1), 0x20, // destination
+		byte(vm.MCOPY),
+	}
+	cfg := &Config{ChainConfig: cancunConfig(0), GasLimit: 100}
+	setDefaults(cfg)
+	cfg.State, _ = state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	addr := contractAddress()
+	internal, _ := addr.InternalAddress()
+	cfg.State.SetCode(internal, code)
+
+	_, leftOver, err := NewEnv(cfg).Call(vm.AccountRef(cfg.Origin), addr, nil, cfg.GasLimit, new(big.Int))
+	if err != nil {
+		t.Fatal("didn't expect error", err)
+	}
+	if used, want := cfg.GasLimit-leftOver, uint64(3*3+3+3+6); used != want {
+		t.Errorf("gas used mismatch: have %d, want %d", used, want)
+	}
+}
+
+func TestCancunActivation(t *testing.T) {
+	for _, op := range []vm.OpCode{vm.PUSH0, vm.TLOAD, vm.TSTORE, vm.MCOPY} {
+		code := []byte{byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x00, byte(op)}
+
+		// Before the fork block the opcodes are undefined
+		_, _, err := Execute(code, nil, &Config{ChainConfig: cancunConfig(10), BlockNumber: big.NewInt(9)})
+		var invalid *vm.ErrInvalidOpCode
+		if !errors.As(err, &invalid) {
+			t.Errorf("%v before activation: have error %v, want invalid opcode", op, err)
+		}
+		_, _, err = Execute(code, nil, &Config{BlockNumber: big.NewInt(9)})
+		if !errors.As(err, &invalid) {
+			t.Errorf("%v without activation: have error %v, want invalid opcode", op, err)
+		}
+		// From the fork block on they are available
+		if _, _, err := Execute(code, nil, &Config{ChainConfig: cancunConfig(10), BlockNumber: big.NewInt(10)}); err != nil {
+			t.Errorf("%v after activation: unexpected error %v", op, err)
+		}
+	}
+}
+
+func TestTstoreStatic(t *testing.T) {
+	cfg := &Config{ChainConfig: cancunConfig(0)}
+	setDefaults(cfg)
+	cfg.State, _ = state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	addr := contractAddress()
+	internal, _ := addr.InternalAddress()
+	cfg.State.SetCode(internal, []byte{byte(vm.PUSH1), 0x01, byte(vm.PUSH1), 0x01, byte(vm.TSTORE)})
+
+	_, _, err := NewEnv(cfg).StaticCall(vm.AccountRef(cfg.Origin), addr, nil, 100000)
+	if !errors.Is(err, vm.ErrWriteProtection) {
+		t.Errorf("TSTORE in static call: have error %v, want %v", err, vm.ErrWriteProtection)
+	}
+}
+
+func TestCallErrorActivation(t *testing.T) {
+	for _, tt := range []struct {
+		block int64
+		fails bool
+	}{{9, false}, {10, true}} {
+		cfg := &Config{ChainConfig: cancunConfig(10), BlockNumber: big.NewInt(tt.block)}
+		setDefaults(cfg)
+		cfg.State, _ = state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		addr := contractAddress()
+		internal, _ := addr.InternalAddress()
+		cfg.State.SetCode(internal, []byte{0xfe}) // INVALID
+
+		env := NewEnv(cfg)
+		caller := vm.AccountRef(cfg.Origin)
+		calls := map[string]func() error{
+			"CallCode": func() error {
+				_, _, err := env.CallCode(caller, addr, nil, 100000, new(big.Int))
+				return err
+			},
+			"DelegateCall": func() error {
+				parent := vm.NewContract(caller, caller, new(big.Int), 0)
+				_, _, err := env.DelegateCall(parent, addr, nil, 100000)
+				return err
+			},
+			"StaticCall": func() error {
+				_, _, err := env.StaticCall(caller, addr, nil, 100000)
+				return err
+			},
+		}
+		for name, call := range calls {
+			if err := call(); (err != nil) != tt.fails {
+				t.Errorf("%s at block %d: have error %v, want failure %v", name, tt.block, err, tt.fails)
+			}
+		}
+	}
+}
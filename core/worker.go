@@ -33,6 +33,9 @@ const (
 	// resubmitAdjustChanSize is the size of resubmitting interval adjustment channel.
 	resubmitAdjustChanSize = 10
 
+	// chainSideChanSize is the size of channel listening to ChainSideEvent.
+	chainSideChanSize = 10
+
 	// sealingLogAtDepth is the number of confirmations before logging successful sealing.
 	sealingLogAtDepth = 7
 
@@ -65,6 +68,8 @@ type environment struct {
 	etxRLimit int // Remaining number of cross-region ETXs that can be included
 	etxPLimit int // Remaining number of cross-prime ETXs that can be included
 
+	etxReserve uint64 // Gas reserved for ETXs which are yet to be included
+
 	header      *types.Header
 	txs         []*types.Transaction
 	etxs        []*types.Transaction
@@ -79,16 +84,17 @@ func (env *environment) copy(processingState bool) *environment {
 	nodeCtx := common.NodeLocation.Context()
 	if nodeCtx == common.ZONE_CTX && processingState {
 		cpy := &environment{
-			signer:    env.signer,
-			state:     env.state.Copy(),
-			ancestors: env.ancestors.Clone(),
-			family:    env.family.Clone(),
-			tcount:    env.tcount,
-			coinbase:  env.coinbase,
-			etxRLimit: env.etxRLimit,
-			etxPLimit: env.etxPLimit,
-			header:    types.CopyHeader(env.header),
-			receipts:  copyReceipts(env.receipts),
+			signer:     env.signer,
+			state:      env.state.Copy(),
+			ancestors:  env.ancestors.Clone(),
+			family:     env.family.Clone(),
+			tcount:     env.tcount,
+			coinbase:   env.coinbase,
+			etxRLimit:  env.etxRLimit,
+			etxPLimit:  env.etxPLimit,
+			etxReserve: env.etxReserve,
+			header:     types.CopyHeader(env.header),
+			receipts:   copyReceipts(env.receipts),
 		}
 		if env.gasPool != nil {
 			gasPool := *env.gasPool
@@ -165,6 +171,11 @@ type Config struct {
 	GasPrice   *big.Int       // Minimum gas price for mining a transaction
 	Recommit   time.Duration  // The time interval for miner to re-create mining work.
 	Noverify   bool           // Disable remote mining solution verification(only useful in ethash).
+
+	Policy     string           `toml:",omitempty"` // Block template policy selecting and ordering transactions
+	Allowlist  []common.Address `toml:",omitempty"` // Senders prioritized by the local-first policy
+	Exclude    []common.Address `toml:",omitempty"` // Senders whose internal transactions are never included
+	EtxReserve uint64           `toml:",omitempty"` // Gas of each block reserved for ETXs
 }
 
 // worker is the main object which takes care of submitting new work to consensus engine
@@ -183,6 +194,8 @@ type worker struct {
 	// Subscriptions
 	chainHeadCh  chan ChainHeadEvent
 	chainHeadSub event.Subscription
+	chainSideCh  chan ChainSideEvent
+	chainSideSub event.Subscription
 
 	// Channels
 	taskCh                         chan *task
@@ -203,9 +216,11 @@ type worker struct {
 	remoteUncles map[common.Hash]*types.Block // A set of side blocks as the possible uncle blocks.
 	uncleMu      sync.RWMutex
 
-	mu       sync.RWMutex // The lock used to protect the coinbase and extra fields
+	mu       sync.RWMutex // The lock used to protect the coinbase, extra and template policy fields
 	coinbase common.Address
 	extra    []byte
+	policy   TemplatePolicy
+	excluded map[common.AddressBytes]struct{}
 
 	workerDb ethdb.Database
 
@@ -270,6 +285,7 @@ func newWorker(config *Config, chainConfig *params.ChainConfig, db ethdb.Databas
 		localUncles:                    make(map[common.Hash]*types.Block),
 		remoteUncles:                   make(map[common.Hash]*types.Block),
 		chainHeadCh:                    make(chan ChainHeadEvent, chainHeadChanSize),
+		chainSideCh:                    make(chan ChainSideEvent, chainSideChanSize),
 		taskCh:                         make(chan *task),
 		resultCh:                       make(chan *types.Block, resultQueueSize),
 		exitCh:                         make(chan struct{}),
@@ -294,11 +310,22 @@ func newWorker(config *Config, chainConfig *params.ChainConfig, db ethdb.Databas
 	headerPrints, _ := expireLru.NewWithExpire(1, c_headerPrintsExpiryTime)
 	worker.headerPrints = headerPrints
 
+	// Sanitize the block template policy, falling back to fee ordering.
+	policy, err := NewTemplatePolicy(config.Policy, config.Allowlist)
+	if err != nil {
+		log.Warn("Sanitizing block template policy", "provided", config.Policy, "updated", FeePolicyName, "err", err)
+		policy, _ = NewTemplatePolicy(FeePolicyName, nil)
+	}
+	worker.policy = policy
+	worker.excluded = excludedSenders(config.Exclude)
+
 	nodeCtx := common.NodeLocation.Context()
 	if headerchain.ProcessingState() && nodeCtx == common.ZONE_CTX {
 		worker.chainHeadSub = worker.hc.SubscribeChainHeadEvent(worker.chainHeadCh)
-		worker.wg.Add(1)
+		worker.chainSideSub = worker.hc.SubscribeChainSideEvent(worker.chainSideCh)
+		worker.wg.Add(2)
 		go worker.asyncStateLoop()
+		go worker.uncleLoop()
 	}
 
 	return worker
@@ -317,6 +344,49 @@ func (w *worker) setGasCeil(ceil uint64) {
 	w.config.GasCeil = ceil
 }
 
+// setTemplatePolicy sets the policy used to select and order the transactions
+// of block templates.
+func (w *worker) setTemplatePolicy(policy TemplatePolicy) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.policy = policy
+}
+
+// setExcludedSenders sets the senders whose internal transactions are never included.
+func (w *worker) setExcludedSenders(senders []common.Address) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.config.Exclude = senders
+	w.excluded = excludedSenders(senders)
+}
+
+// setEtxReserve sets the gas of each block template reserved for ETXs.
+func (w *worker) setEtxReserve(gas uint64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.config.EtxReserve = gas
+}
+
+// excludedSenders converts a list of senders to a set.
+func excludedSenders(senders []common.Address) map[common.AddressBytes]struct{} {
+	excluded := make(map[common.AddressBytes]struct{}, len(senders))
+	for _, addr := range senders {
+		excluded[addr.Bytes20()] = struct{}{}
+	}
+	return excluded
+}
+
+// excludeSenders removes the pending internal transactions of the excluded
+// senders in place. ETXs are left untouched, as their senders are accounts of
+// other chains and inbound ETXs must be included by every block template.
+func excludeSenders(excluded map[common.AddressBytes]struct{}, pending map[common.AddressBytes]types.Transactions) {
+	for addr := range pending {
+		if _, ok := excluded[addr]; ok {
+			delete(pending, addr)
+		}
+	}
+}
+
 // setExtra sets the content used to initialize the block extra field.
 func (w *worker) setExtra(extra []byte) {
 	w.mu.Lock()
@@ -376,6 +446,7 @@ func (w *worker) start() {
 func (w *worker) stop() {
 	if w.hc.ProcessingState() && common.NodeLocation.Context() == common.ZONE_CTX {
 		w.chainHeadSub.Unsubscribe()
+		w.chainSideSub.Unsubscribe()
 	}
 	atomic.StoreInt32(&w.running, 0)
 }
@@ -459,6 +530,36 @@ func (w *worker) asyncStateLoop() {
 	}
 }
 
+// uncleLoop collects the side blocks of the chain as possible uncles. Side
+// blocks of every order are collected, as a zone block may include the side
+// blocks of the dominant chains which were mined in this zone.
+func (w *worker) uncleLoop() {
+	defer w.wg.Done() // decrement the wait group after the close of the loop
+
+	for {
+		select {
+		case ev := <-w.chainSideCh:
+			hash := ev.Block.Hash()
+			w.uncleMu.Lock()
+			// Short circuit for duplicate side blocks
+			_, local := w.localUncles[hash]
+			_, remote := w.remoteUncles[hash]
+			if !local && !remote {
+				if w.isLocalBlock != nil && w.isLocalBlock(ev.Block.Header()) {
+					w.localUncles[hash] = ev.Block
+				} else {
+					w.remoteUncles[hash] = ev.Block
+				}
+			}
+			w.uncleMu.Unlock()
+		case <-w.exitCh:
+			return
+		case <-w.chainSideSub.Err():
+			return
+		}
+	}
+}
+
 // GeneratePendingBlock generates pending block given a commited block.
 func (w *worker) GeneratePendingHeader(block *types.Block, fill bool) (*types.Header, error) {
 	nodeCtx := common.NodeLocation.Context()
@@ -623,9 +724,16 @@ func (w *worker) commitUncle(env *environment, uncle *types.Header) error {
 	if !env.ancestors.Contains(uncle.ParentHash()) {
 		return errors.New("uncle's parent unknown")
 	}
+	if w.hc.GetTerminiByHash(uncle.ParentHash()) == nil {
+		return errors.New("uncle's parent termini unknown")
+	}
 	if env.family.Contains(hash) {
 		return errors.New("uncle already included")
 	}
+	// The entropy and the reward of an uncle are computed from its seal
+	if _, _, err := w.engine.CalcOrder(uncle); err != nil {
+		return err
+	}
 	env.uncles[hash] = uncle
 	return nil
 }
@@ -647,15 +755,13 @@ func (w *worker) commitTransaction(env *environment, tx *types.Transaction) ([]*
 		env.header.SetGasUsed(gasUsed)
 		env.txs = append(env.txs, tx)
 		env.receipts = append(env.receipts, receipt)
-		if receipt.Status == types.ReceiptStatusSuccessful {
-			env.etxs = append(env.etxs, receipt.Etxs...)
-		}
+		env.etxs = append(env.etxs, receipt.EmittedEtxs()...)
 		return receipt.Logs, nil
 	}
 	return nil, errors.New("error finding transaction")
 }
 
-func (w *worker) commitTransactions(env *environment, txs *types.TransactionsByPriceAndNonce, interrupt *int32) bool {
+func (w *worker) commitTransactions(env *environment, txs TemplateTxs, interrupt *int32) bool {
 	gasLimit := env.header.GasLimit
 	if env.gasPool == nil {
 		env.gasPool = new(GasPool).AddGas(gasLimit())
@@ -698,6 +804,22 @@ func (w *worker) commitTransactions(env *environment, txs *types.TransactionsByP
 		//
 		// We use the signer regardless of the current hf.
 		from, _ := types.Sender(env.signer, tx)
+		// Keep the gas reserved for ETXs out of reach of other transactions
+		isEtx := tx.Type() == types.ExternalTxType
+		if !isEtx && env.gasPool.Gas() < tx.Gas()+env.etxReserve {
+			log.Trace("Gas reserved for ETXs", "sender", from, "reserved", env.etxReserve)
+			txs.PopNoSort()
+			continue
+		}
+		// Every outcome below drops the ETX from the template, so the gas
+		// reserved for it is released whether or not it is included
+		if isEtx {
+			if gas := tx.Gas(); gas < env.etxReserve {
+				env.etxReserve -= gas
+			} else {
+				env.etxReserve = 0
+			}
+		}
 		// Start executing the transaction
 		env.state.Prepare(tx.Hash(), env.tcount)
 
@@ -853,11 +975,27 @@ func (w *worker) prepareWork(genParams *generateParams, block *types.Block) (*en
 				}
 			}
 		}
-		w.uncleMu.RLock()
+		w.uncleMu.Lock()
+		// Drop the side blocks which are too old to be included
+		for hash, uncle := range w.localUncles {
+			if uncle.NumberU64()+staleThreshold <= header.NumberU64() {
+				delete(w.localUncles, hash)
+			}
+		}
+		for hash, uncle := range w.remoteUncles {
+			if uncle.NumberU64()+staleThreshold <= header.NumberU64() {
+				delete(w.remoteUncles, hash)
+			}
+		}
 		// Prefer to locally generated uncle
 		commitUncles(w.localUncles)
 		commitUncles(w.remoteUncles)
-		w.uncleMu.RUnlock()
+		w.uncleMu.Unlock()
+		// The entropy of the uncles is committed to in the header from the
+		// uncled entropy fork on
+		if w.chainConfig.IsUncledEntropy(header.Number()) {
+			header.SetUncledS(w.engine.UncledLogS(env.unclelist()))
+		}
 		return env, nil
 	} else {
 		return &environment{header: header}, nil
@@ -866,8 +1004,8 @@ func (w *worker) prepareWork(genParams *generateParams, block *types.Block) (*en
 }
 
 // fillTransactions retrieves the pending transactions from the txpool and fills them
-// into the given sealing block. The transaction selection and ordering strategy can
-// be customized with the plugin in the future.
+// into the given sealing block. The transaction selection and ordering strategy is
+// customized by the configured block template policy.
 func (w *worker) fillTransactions(interrupt *int32, env *environment, block *types.Block) {
 	// Split the pending transactions into locals and remotes
 	// Fill the block with all available pending transactions.
@@ -876,12 +1014,37 @@ func (w *worker) fillTransactions(interrupt *int32, env *environment, block *typ
 		return
 	}
 	etxSet.Update(types.Transactions{}, block.NumberU64()+1) // Prune any expired ETXs
-	pending, err := w.txPool.TxPoolPending(true, etxSet)
+	pending, err := w.txPool.TxPoolPending(true, nil)
 	if err != nil {
 		return
 	}
-	if len(pending) > 0 {
-		txs := types.NewTransactionsByPriceAndNonce(env.signer, pending, env.header.BaseFee(), true)
+	etxs := w.txPool.PendingEtxs(true, etxSet)
+
+	w.mu.RLock()
+	policy, excluded, etxReserve := w.policy, w.excluded, w.config.EtxReserve
+	w.mu.RUnlock()
+
+	// Drop the internal transactions of excluded senders before handing the rest
+	// to the template policy
+	excludeSenders(excluded, pending)
+	if len(pending) > 0 || len(etxs) > 0 {
+		// Never reserve more gas than the pending ETXs could use
+		var etxGas uint64
+		for _, entry := range etxs {
+			etxGas += entry.ETX.Gas()
+		}
+		if etxReserve > etxGas {
+			etxReserve = etxGas
+		}
+		env.etxReserve = etxReserve
+		txs := policy.Transactions(&TemplateContext{
+			Signer:  env.signer,
+			BaseFee: env.header.BaseFee(),
+			Number:  env.header.NumberU64(),
+			Pending: pending,
+			Etxs:    etxs,
+			Locals:  w.txPool.Locals(),
+		})
 		if w.commitTransactions(env, txs, interrupt) {
 			return
 		}
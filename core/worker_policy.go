@@ -0,0 +1,197 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/dominant-strategies/go-quai/common"
+	"github.com/dominant-strategies/go-quai/core/types"
+)
+
+// Names of the built-in block template policies.
+const (
+	FeePolicyName        = "fee"         // Order all transactions by miner fee
+	EtxExpiryPolicyName  = "etx-expiry"  // Include ETXs closest to expiry first
+	LocalFirstPolicyName = "local-first" // Include local and allowlisted senders first
+)
+
+// TemplatePolicies lists the names of the built-in block template policies.
+var TemplatePolicies = []string{FeePolicyName, EtxExpiryPolicyName, LocalFirstPolicyName}
+
+// TemplateTxs yields the transactions to commit to a block template. The
+// worker peeks the next transaction, and after trying to commit it either
+// pops it, or shifts in the next transaction of the same account if the
+// remaining transactions of the account may still be valid.
+type TemplateTxs interface {
+	Peek() *types.Transaction
+	Shift(acc common.AddressBytes, sort bool)
+	PopNoSort()
+}
+
+// TemplateContext holds the transactions available to a block template.
+type TemplateContext struct {
+	Signer  types.Signer
+	BaseFee *big.Int
+	Number  uint64 // Number of the block being built
+
+	Pending map[common.AddressBytes]types.Transactions // Nonce sorted internal transactions by sender
+	Etxs    []types.EtxSetEntry                        // Inbound ETXs which have not expired
+	Locals  []common.InternalAddress                   // Senders considered local by the transaction pool
+}
+
+// TemplatePolicy selects and orders the transactions of a block template.
+type TemplatePolicy interface {
+	// Name returns the name the policy is selected by.
+	Name() string
+
+	// Transactions returns the transactions to commit to the block template,
+	// in the order they should be committed. The policy owns the transactions
+	// of the context once called.
+	Transactions(ctx *TemplateContext) TemplateTxs
+}
+
+// NewTemplatePolicy creates the built-in block template policy of the given
+// name. The allowlist is only used by the local-first policy.
+func NewTemplatePolicy(name string, allowlist []common.Address) (TemplatePolicy, error) {
+	switch name {
+	case FeePolicyName, "":
+		return feePolicy{}, nil
+	case EtxExpiryPolicyName:
+		return etxExpiryPolicy{}, nil
+	case LocalFirstPolicyName:
+		policy := localFirstPolicy{allowlist: make(map[common.AddressBytes]struct{}, len(allowlist))}
+		for _, addr := range allowlist {
+			policy.allowlist[addr.Bytes20()] = struct{}{}
+		}
+		return policy, nil
+	}
+	return nil, fmt.Errorf("unknown block template policy %q, want one of %s", name, strings.Join(TemplatePolicies, ", "))
+}
+
+// feePolicy orders internal transactions and ETXs alike by the fee earned by
+// the miner, maximizing the fees of the block.
+type feePolicy struct{}
+
+func (feePolicy) Name() string { return FeePolicyName }
+
+func (feePolicy) Transactions(ctx *TemplateContext) TemplateTxs {
+	return types.NewTransactionsByPriceAndNonce(ctx.Signer, mergeEtxs(ctx.Pending, ctx.Etxs), ctx.BaseFee, true)
+}
+
+// mergeEtxs adds the ETXs to the pending transactions of their senders.
+func mergeEtxs(pending map[common.AddressBytes]types.Transactions, etxs []types.EtxSetEntry) map[common.AddressBytes]types.Transactions {
+	for _, entry := range etxs {
+		etx := entry.ETX
+		addr := etx.ETXSender().Bytes20()
+		pending[addr] = append(pending[addr], &etx) // ETXs do not have to be sorted by address but this way all TXs are in the same list
+	}
+	return pending
+}
+
+// etxExpiryPolicy includes all inbound ETXs before any internal transaction,
+// starting with the ETXs closest to their expiration height. The internal
+// transactions are ordered by fee.
+type etxExpiryPolicy struct{}
+
+func (etxExpiryPolicy) Name() string { return EtxExpiryPolicyName }
+
+func (etxExpiryPolicy) Transactions(ctx *TemplateContext) TemplateTxs {
+	etxs := make([]types.EtxSetEntry, len(ctx.Etxs))
+	copy(etxs, ctx.Etxs)
+	sort.Slice(etxs, func(i, j int) bool {
+		if ei, ej := etxs[i].ExpirationHeight(), etxs[j].ExpirationHeight(); ei != ej {
+			return ei < ej
+		}
+		hi, hj := etxs[i].ETX.Hash(), etxs[j].ETX.Hash()
+		return bytes.Compare(hi[:], hj[:]) < 0
+	})
+	list := make(orderedTxs, len(etxs))
+	for i := range etxs {
+		list[i] = &etxs[i].ETX
+	}
+	return &chainedTxs{&list, types.NewTransactionsByPriceAndNonce(ctx.Signer, ctx.Pending, ctx.BaseFee, true)}
+}
+
+// localFirstPolicy includes the transactions of local and allowlisted senders
+// before all other transactions. Both groups are ordered by fee.
+type localFirstPolicy struct {
+	allowlist map[common.AddressBytes]struct{}
+}
+
+func (localFirstPolicy) Name() string { return LocalFirstPolicyName }
+
+func (p localFirstPolicy) Transactions(ctx *TemplateContext) TemplateTxs {
+	preferred := make(map[common.AddressBytes]struct{}, len(p.allowlist)+len(ctx.Locals))
+	for addr := range p.allowlist {
+		preferred[addr] = struct{}{}
+	}
+	for _, addr := range ctx.Locals {
+		preferred[common.NewAddressFromData(&addr).Bytes20()] = struct{}{}
+	}
+	local, remote := make(map[common.AddressBytes]types.Transactions), make(map[common.AddressBytes]types.Transactions)
+	for addr, txs := range mergeEtxs(ctx.Pending, ctx.Etxs) {
+		if _, ok := preferred[addr]; ok {
+			local[addr] = txs
+		} else {
+			remote[addr] = txs
+		}
+	}
+	return &chainedTxs{
+		types.NewTransactionsByPriceAndNonce(ctx.Signer, local, ctx.BaseFee, true),
+		types.NewTransactionsByPriceAndNonce(ctx.Signer, remote, ctx.BaseFee, true),
+	}
+}
+
+// orderedTxs is a fixed order list of transactions, each of which is committed
+// independently of the others.
+type orderedTxs []*types.Transaction
+
+func (l *orderedTxs) Peek() *types.Transaction {
+	if len(*l) == 0 {
+		return nil
+	}
+	return (*l)[0]
+}
+
+func (l *orderedTxs) Shift(acc common.AddressBytes, sort bool) { l.PopNoSort() }
+
+func (l *orderedTxs) PopNoSort() {
+	if len(*l) > 0 {
+		*l = (*l)[1:]
+	}
+}
+
+// chainedTxs yields the transactions of each of its sets in turn.
+type chainedTxs []TemplateTxs
+
+func (c *chainedTxs) current() TemplateTxs {
+	for len(*c) > 0 && (*c)[0].Peek() == nil {
+		*c = (*c)[1:]
+	}
+	if len(*c) == 0 {
+		return nil
+	}
+	return (*c)[0]
+}
+
+func (c *chainedTxs) Peek() *types.Transaction {
+	if txs := c.current(); txs != nil {
+		return txs.Peek()
+	}
+	return nil
+}
+
+func (c *chainedTxs) Shift(acc common.AddressBytes, sort bool) {
+	if txs := c.current(); txs != nil {
+		txs.Shift(acc, sort)
+	}
+}
+
+func (c *chainedTxs) PopNoSort() {
+	if txs := c.current(); txs != nil {
+		txs.PopNoSort()
+	}
+}
@@ -0,0 +1,276 @@
+package core
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/dominant-strategies/go-quai/common"
+	"github.com/dominant-strategies/go-quai/core/rawdb"
+	"github.com/dominant-strategies/go-quai/core/state"
+	"github.com/dominant-strategies/go-quai/core/types"
+	"github.com/dominant-strategies/go-quai/params"
+)
+
+var (
+	policyConfig  = &params.ChainConfig{ChainID: big.NewInt(1)}
+	policyBaseFee = big.NewInt(params.GWei)
+)
+
+// policyTx signs an internal transfer paying the given tip in gwei.
+func policyTx(t *testing.T, key *ecdsa.PrivateKey, nonce uint64, tip int64) *types.Transaction {
+	to := common.HexToAddress("0x0000000000000000000000000000000000000001")
+	tipCap := big.NewInt(tip * params.GWei)
+	tx := types.NewTx(&types.InternalTx{ChainID: policyConfig.ChainID, Nonce: nonce, GasTipCap: tipCap, GasFeeCap: new(big.Int).Add(policyBaseFee, tipCap), Gas: params.TxGas, To: &to, Value: big.NewInt(1)})
+	signed, err := types.SignTx(tx, types.LatestSigner(policyConfig), key)
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	return signed
+}
+
+// policyEtx returns an inbound ETX from the given sender paying the given tip
+// in gwei, which was confirmed at the given height.
+func policyEtx(sender common.Address, height uint64, tip int64, gas uint64) types.EtxSetEntry {
+	to := common.HexToAddress("0x0000000000000000000000000000000000000001")
+	tipCap := big.NewInt(tip * params.GWei)
+	etx := types.NewTx(&types.ExternalTx{ChainID: policyConfig.ChainID, Nonce: height, GasTipCap: tipCap, GasFeeCap: new(big.Int).Add(policyBaseFee, tipCap), Gas: gas, To: &to, Value: big.NewInt(1), Sender: sender})
+	return types.EtxSetEntry{Height: height, ETX: *etx}
+}
+
+// collectTxs yields all transactions of the template in order, as if each of
+// them was committed successfully.
+func collectTxs(txs TemplateTxs) []common.Hash {
+	var hashes []common.Hash
+	for tx := txs.Peek(); tx != nil; tx = txs.Peek() {
+		hashes = append(hashes, tx.Hash())
+		from, _ := types.Sender(types.LatestSigner(policyConfig), tx)
+		txs.Shift(from.Bytes20(), true)
+	}
+	return hashes
+}
+
+func checkOrder(t *testing.T, policy string, have []common.Hash, want ...*types.Transaction) {
+	t.Helper()
+	if len(have) != len(want) {
+		t.Fatalf("%s: transaction count mismatch: have %d, want %d", policy, len(have), len(want))
+	}
+	for i, tx := range want {
+		if have[i] != tx.Hash() {
+			t.Errorf("%s: transaction %d mismatch: have %x, want %x", policy, i, have[i], tx.Hash())
+		}
+	}
+}
+
+// Tests that each of the built-in template policies orders the pending
+// transactions and ETXs as documented.
+func TestTemplatePolicyOrdering(t *testing.T) {
+	defer func(loc common.Location) { common.NodeLocation = loc }(common.NodeLocation)
+	common.NodeLocation = common.Location{0, 0}
+
+	keys, addrs := make([]*ecdsa.PrivateKey, 3), make([]common.Address, 3)
+	for i := range keys {
+		keys[i], addrs[i] = newScopedKey(t)
+	}
+	txA, txB, txC := policyTx(t, keys[0], 0, 5), policyTx(t, keys[1], 0, 1), policyTx(t, keys[2], 0, 2)
+	_, etxSender := newScopedKey(t)
+	etxLate, etxEarly := policyEtx(etxSender, 10, 4, params.TxGas), policyEtx(etxSender, 5, 3, params.TxGas)
+
+	context := func(locals ...common.Address) *TemplateContext {
+		ctx := &TemplateContext{
+			Signer:  types.LatestSigner(policyConfig),
+			BaseFee: policyBaseFee,
+			Number:  20,
+			Pending: map[common.AddressBytes]types.Transactions{
+				addrs[0].Bytes20(): {txA},
+				addrs[1].Bytes20(): {txB},
+				addrs[2].Bytes20(): {txC},
+			},
+			Etxs: []types.EtxSetEntry{etxLate, etxEarly},
+		}
+		for _, addr := range locals {
+			internal, err := addr.InternalAddress()
+			if err != nil {
+				t.Fatalf("failed to convert local address: %v", err)
+			}
+			ctx.Locals = append(ctx.Locals, internal)
+		}
+		return ctx
+	}
+	// The fee policy orders everything by the tip earned by the miner
+	fee, err := NewTemplatePolicy(FeePolicyName, nil)
+	if err != nil {
+		t.Fatalf("failed to create fee policy: %v", err)
+	}
+	checkOrder(t, FeePolicyName, collectTxs(fee.Transactions(context())), txA, &etxLate.ETX, &etxEarly.ETX, txC, txB)
+
+	// The expiry policy includes the ETXs first, earliest expiry first
+	expiry, err := NewTemplatePolicy(EtxExpiryPolicyName, nil)
+	if err != nil {
+		t.Fatalf("failed to create expiry policy: %v", err)
+	}
+	checkOrder(t, EtxExpiryPolicyName, collectTxs(expiry.Transactions(context())), &etxEarly.ETX, &etxLate.ETX, txA, txC, txB)
+
+	// The local-first policy includes local and allowlisted senders first
+	local, err := NewTemplatePolicy(LocalFirstPolicyName, []common.Address{addrs[2]})
+	if err != nil {
+		t.Fatalf("failed to create local-first policy: %v", err)
+	}
+	checkOrder(t, LocalFirstPolicyName, collectTxs(local.Transactions(context(addrs[1]))), txC, txB, txA, &etxLate.ETX, &etxEarly.ETX)
+
+	if _, err := NewTemplatePolicy("unknown", nil); err == nil {
+		t.Errorf("unknown policy created")
+	}
+}
+
+// Tests that chained transaction sets yield each set in turn, with shifts and
+// pops moving on to the next set once the current one is exhausted.
+func TestChainedTxs(t *testing.T) {
+	defer func(loc common.Location) { common.NodeLocation = loc }(common.NodeLocation)
+	common.NodeLocation = common.Location{0, 0}
+
+	key, _ := newScopedKey(t)
+	tx1, tx2, tx3 := policyTx(t, key, 0, 1), policyTx(t, key, 1, 1), policyTx(t, key, 2, 1)
+
+	first, empty, second := orderedTxs{tx1}, orderedTxs{}, orderedTxs{tx2, tx3}
+	txs := &chainedTxs{&first, &empty, &second}
+	if tx := txs.Peek(); tx != tx1 {
+		t.Fatalf("first peek mismatch: have %v, want %x", tx, tx1.Hash())
+	}
+	txs.Shift(common.AddressBytes{}, false)
+	if tx := txs.Peek(); tx != tx2 {
+		t.Fatalf("peek after shift mismatch: have %v, want %x", tx, tx2.Hash())
+	}
+	txs.PopNoSort()
+	if tx := txs.Peek(); tx != tx3 {
+		t.Fatalf("peek after pop mismatch: have %v, want %x", tx, tx3.Hash())
+	}
+	txs.PopNoSort()
+	if tx := txs.Peek(); tx != nil {
+		t.Fatalf("exhausted sets yielded %x", tx.Hash())
+	}
+	// Exhausted sets must tolerate further shifts and pops
+	txs.Shift(common.AddressBytes{}, false)
+	txs.PopNoSort()
+}
+
+// Tests that the internal transactions of excluded senders never reach the
+// template policy.
+func TestExcludeSenders(t *testing.T) {
+	defer func(loc common.Location) { common.NodeLocation = loc }(common.NodeLocation)
+	common.NodeLocation = common.Location{0, 0}
+
+	keys, addrs := make([]*ecdsa.PrivateKey, 2), make([]common.Address, 2)
+	for i := range keys {
+		keys[i], addrs[i] = newScopedKey(t)
+	}
+	pending := map[common.AddressBytes]types.Transactions{
+		addrs[0].Bytes20(): {policyTx(t, keys[0], 0, 1)},
+		addrs[1].Bytes20(): {policyTx(t, keys[1], 0, 1)},
+	}
+	excludeSenders(nil, pending)
+	if len(pending) != 2 {
+		t.Fatalf("transactions dropped without exclusions: %d pending", len(pending))
+	}
+	excludeSenders(excludedSenders([]common.Address{addrs[0]}), pending)
+	if _, ok := pending[addrs[0].Bytes20()]; ok || len(pending) != 1 {
+		t.Errorf("pending transactions of excluded sender kept")
+	}
+}
+
+// Tests that the inbound ETXs of an excluded sender are still included in the
+// block template, while its internal transactions are not.
+func TestExcludeSendersKeepsEtxs(t *testing.T) {
+	defer func(loc common.Location) { common.NodeLocation = loc }(common.NodeLocation)
+	common.NodeLocation = common.Location{0, 0}
+
+	key, addr := newScopedKey(t)
+	internal, _ := addr.InternalAddress()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	statedb.AddBalance(internal, big.NewInt(params.Ether))
+
+	_, coinbase := newScopedKey(t)
+	header := types.EmptyHeader()
+	header.SetNumber(big.NewInt(1))
+	header.SetGasLimit(params.TxGas * 4)
+	header.SetBaseFee(policyBaseFee)
+	header.SetCoinbase(coinbase)
+	header.SetLocation(common.NodeLocation)
+
+	engine := rootFinalizer{}
+	hc := &HeaderChain{engine: engine}
+	hc.bc = &BodyDb{processor: &StateProcessor{config: policyConfig, hc: hc, engine: engine}}
+	w := &worker{chainConfig: policyConfig, engine: engine, hc: hc}
+	env := &environment{
+		signer:    types.LatestSigner(policyConfig),
+		state:     statedb,
+		header:    header,
+		coinbase:  coinbase,
+		etxRLimit: params.ETXRLimitMin,
+		etxPLimit: params.ETXPLimitMin,
+	}
+	etx := policyEtx(addr, 1, 1, params.TxGas)
+	pending := map[common.AddressBytes]types.Transactions{addr.Bytes20(): {policyTx(t, key, 0, 1)}}
+	excludeSenders(excludedSenders([]common.Address{addr}), pending)
+
+	txs := etxExpiryPolicy{}.Transactions(&TemplateContext{
+		Signer:  env.signer,
+		BaseFee: header.BaseFee(),
+		Number:  header.NumberU64(),
+		Pending: pending,
+		Etxs:    []types.EtxSetEntry{etx},
+	})
+	w.commitTransactions(env, txs, nil)
+
+	if len(env.txs) != 1 || env.txs[0].Hash() != etx.ETX.Hash() {
+		t.Fatalf("ETX of excluded sender not included alone: %d transactions", len(env.txs))
+	}
+}
+
+// Tests that the gas reserved for an ETX is released once the ETX leaves the
+// template, even if it could not be included, so that internal transactions
+// may use it.
+func TestEtxReserveRelease(t *testing.T) {
+	defer func(loc common.Location) { common.NodeLocation = loc }(common.NodeLocation)
+	common.NodeLocation = common.Location{0, 0}
+
+	key, addr := newScopedKey(t)
+	internal, _ := addr.InternalAddress()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	statedb.AddBalance(internal, big.NewInt(params.Ether))
+
+	_, coinbase := newScopedKey(t)
+	header := types.EmptyHeader()
+	header.SetNumber(big.NewInt(1))
+	header.SetGasLimit(50000)
+	header.SetBaseFee(policyBaseFee)
+	header.SetCoinbase(coinbase)
+	header.SetLocation(common.NodeLocation)
+
+	// The ETX needs more gas than the block has left, so it is dropped
+	etx := policyEtx(addr, 1, 1, 60000)
+	tx := policyTx(t, key, 0, 1)
+
+	engine := rootFinalizer{}
+	hc := &HeaderChain{engine: engine}
+	hc.bc = &BodyDb{processor: &StateProcessor{config: policyConfig, hc: hc, engine: engine}}
+	w := &worker{chainConfig: policyConfig, engine: engine, hc: hc}
+	env := &environment{
+		signer:     types.LatestSigner(policyConfig),
+		state:      statedb,
+		header:     header,
+		coinbase:   coinbase,
+		etxRLimit:  params.ETXRLimitMin,
+		etxPLimit:  params.ETXPLimitMin,
+		etxReserve: etx.ETX.Gas(),
+	}
+	etxs, txs := orderedTxs{&etx.ETX}, orderedTxs{tx}
+	w.commitTransactions(env, &chainedTxs{&etxs, &txs}, nil)
+
+	if env.etxReserve != 0 {
+		t.Errorf("gas still reserved for dropped ETX: %d", env.etxReserve)
+	}
+	if len(env.txs) != 1 || env.txs[0].Hash() != tx.Hash() {
+		t.Fatalf("internal transaction not included after ETX was dropped: %d transactions", len(env.txs))
+	}
+}
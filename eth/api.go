@@ -17,6 +17,7 @@
 package eth
 
 import (
+	"bufio"
 	"compress/gzip"
 	"context"
 	"errors"
@@ -34,6 +35,7 @@ import (
 	"github.com/dominant-strategies/go-quai/core/state"
 	"github.com/dominant-strategies/go-quai/core/types"
 	"github.com/dominant-strategies/go-quai/internal/quaiapi"
+	"github.com/dominant-strategies/go-quai/quaiclient"
 	"github.com/dominant-strategies/go-quai/rlp"
 	"github.com/dominant-strategies/go-quai/rpc"
 	"github.com/dominant-strategies/go-quai/trie"
@@ -116,6 +118,27 @@ func (api *PrivateMinerAPI) SetGasLimit(gasLimit hexutil.Uint64) bool {
 	return true
 }
 
+// SetTemplatePolicy selects the policy ordering the transactions of mined
+// blocks. The allowlist is only used by the local-first policy.
+func (api *PrivateMinerAPI) SetTemplatePolicy(name string, allowlist []common.Address) (bool, error) {
+	if err := api.e.Core().SetTemplatePolicy(name, allowlist); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// SetExcludedSenders sets the senders whose internal transactions are never mined.
+func (api *PrivateMinerAPI) SetExcludedSenders(senders []common.Address) bool {
+	api.e.Core().SetExcludedSenders(senders)
+	return true
+}
+
+// SetEtxReserve sets the gas of each mined block reserved for ETXs.
+func (api *PrivateMinerAPI) SetEtxReserve(gas hexutil.Uint64) bool {
+	api.e.Core().SetEtxReserve(uint64(gas))
+	return true
+}
+
 // SetEtherbase sets the etherbase of the miner
 func (api *PrivateMinerAPI) SetEtherbase(etherbase common.Address) bool {
 	api.e.Core().SetEtherbase(etherbase)
@@ -139,9 +162,16 @@ func NewPrivateAdminAPI(eth *Quai) *PrivateAdminAPI {
 	return &PrivateAdminAPI{eth: eth}
 }
 
+// LinkStatus returns the state, last successful call and error counts of the
+// links to the dom and sub nodes.
+func (api *PrivateAdminAPI) LinkStatus() []quaiclient.LinkStatus {
+	return api.eth.Core().LinkStatus()
+}
+
 // ExportChain exports the current blockchain into a local file,
-// or a range of blocks if first and last are non-nil
-func (api *PrivateAdminAPI) ExportChain(file string, first *uint64, last *uint64) (bool, error) {
+// or a range of blocks if first and last are non-nil. If archive
+// is set, the blocks are written as a chain archive.
+func (api *PrivateAdminAPI) ExportChain(file string, first *uint64, last *uint64, archive *bool) (bool, error) {
 	if first == nil && last != nil {
 		return false, errors.New("last cannot be specified without first")
 	}
@@ -168,7 +198,15 @@ func (api *PrivateAdminAPI) ExportChain(file string, first *uint64, last *uint64
 	}
 
 	// Export the blockchain
-	if first != nil {
+	if archive != nil && *archive {
+		if first == nil {
+			head := api.eth.Core().CurrentHeader().Number().Uint64()
+			first, last = new(uint64), &head
+		}
+		if err := api.eth.Core().ExportArchive(writer, *first, *last); err != nil {
+			return false, err
+		}
+	} else if first != nil {
 		if err := api.eth.Core().ExportN(writer, *first, *last); err != nil {
 			return false, err
 		}
@@ -203,8 +241,18 @@ func (api *PrivateAdminAPI) ImportChain(file string) (bool, error) {
 		}
 	}
 
+	// Chain archives carry the hierarchy data of every block and are imported
+	// without the dom and sub nodes
+	buffered := bufio.NewReader(reader)
+	if core.IsArchive(buffered) {
+		if _, err := api.eth.Core().ImportArchive(buffered, nil); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+
 	// Run actual the import in pre-configured batches
-	stream := rlp.NewStream(reader, 0)
+	stream := rlp.NewStream(buffered, 0)
 
 	blocks, index := make([]*types.Block, 0, 2500), 0
 	for batch := 0; ; batch++ {
@@ -443,6 +491,93 @@ func storageRangeAt(st state.Trie, start []byte, maxResult int) (StorageRangeRes
 	return result, nil
 }
 
+// StateDiffResult is the result of a debug_getStateDiff API call.
+type StateDiffResult struct {
+	BlockHash   common.Hash          `json:"blockHash"`
+	BlockNumber hexutil.Uint64       `json:"blockNumber"`
+	Accounts    []*AccountDiffResult `json:"accounts"`
+}
+
+// AccountDiffResult holds the values an account had before and after a block.
+// Only the fields which changed are set.
+type AccountDiffResult struct {
+	Address      common.Address                   `json:"address"`
+	Created      bool                             `json:"created,omitempty"`
+	Deleted      bool                             `json:"deleted,omitempty"`
+	Balance      *balanceDiff                     `json:"balance,omitempty"`
+	Nonce        *nonceDiff                       `json:"nonce,omitempty"`
+	Code         *codeDiff                        `json:"code,omitempty"`
+	Storage      map[common.Hash]storageDiffEntry `json:"storage,omitempty"`
+	StorageWiped bool                             `json:"storageWiped,omitempty"`
+}
+
+type balanceDiff struct {
+	Before *hexutil.Big `json:"before"`
+	After  *hexutil.Big `json:"after"`
+}
+
+type nonceDiff struct {
+	Before hexutil.Uint64 `json:"before"`
+	After  hexutil.Uint64 `json:"after"`
+}
+
+type codeDiff struct {
+	Before hexutil.Bytes `json:"before"`
+	After  hexutil.Bytes `json:"after"`
+}
+
+type storageDiffEntry struct {
+	Before common.Hash `json:"before"`
+	After  common.Hash `json:"after"`
+}
+
+// GetStateDiff returns the balances, nonces, code and storage slots changed by
+// the given block. Diffs are only stored by nodes running with --statediffs and
+// are pruned along with the transaction indices.
+func (api *PrivateDebugAPI) GetStateDiff(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*StateDiffResult, error) {
+	block, err := api.eth.APIBackend.BlockByNumberOrHash(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, errors.New("block not found")
+	}
+	diff := rawdb.ReadStateDiff(api.eth.ChainDb(), block.Hash(), block.NumberU64())
+	if diff == nil {
+		return nil, fmt.Errorf("state diff of block #%d not available", block.NumberU64())
+	}
+	result := &StateDiffResult{
+		BlockHash:   block.Hash(),
+		BlockNumber: hexutil.Uint64(block.NumberU64()),
+		Accounts:    make([]*AccountDiffResult, 0, len(diff)),
+	}
+	for _, account := range diff {
+		entry := &AccountDiffResult{
+			Address:      account.Address,
+			Created:      account.Created,
+			Deleted:      account.Deleted,
+			StorageWiped: account.StorageWiped,
+		}
+		if account.Balance != nil {
+			entry.Balance = &balanceDiff{Before: (*hexutil.Big)(account.Balance.Before), After: (*hexutil.Big)(account.Balance.After)}
+		}
+		if account.Nonce != nil {
+			entry.Nonce = &nonceDiff{Before: hexutil.Uint64(account.Nonce.Before), After: hexutil.Uint64(account.Nonce.After)}
+		}
+		if account.Code != nil {
+			entry.Code = &codeDiff{Before: account.Code.Before, After: account.Code.After}
+		}
+		if len(account.Storage) > 0 {
+			entry.Storage = make(map[common.Hash]storageDiffEntry, len(account.Storage))
+			for _, slot := range account.Storage {
+				entry.Storage[slot.Key] = storageDiffEntry{Before: slot.Before, After: slot.After}
+			}
+		}
+		result.Accounts = append(result.Accounts, entry)
+	}
+	return result, nil
+}
+
 // GetModifiedAccountsByNumber returns all accounts that have changed between the
 // two blocks specified. A change is defined as a difference in nonce, balance,
 // code hash, or storage hash.
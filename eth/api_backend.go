@@ -37,6 +37,7 @@ import (
 	"github.com/dominant-strategies/go-quai/event"
 	"github.com/dominant-strategies/go-quai/params"
 	"github.com/dominant-strategies/go-quai/rpc"
+	"github.com/dominant-strategies/go-quai/signer"
 )
 
 // QuaiAPIBackend implements quaiapi.Backend for full nodes
@@ -86,7 +87,7 @@ func (b *QuaiAPIBackend) HeaderByNumber(ctx context.Context, number rpc.BlockNum
 	}
 	// Otherwise resolve and return the block
 	if number == rpc.LatestBlockNumber {
-		return b.eth.core.CurrentBlock().Header(), nil
+		return b.eth.core.CurrentHeader(), nil
 	}
 	return b.eth.core.GetHeaderByNumber(uint64(number)), nil
 }
@@ -268,6 +269,30 @@ func (b *QuaiAPIBackend) SubscribePendingLogsEvent(ch chan<- []*types.Log) event
 	return b.eth.core.SubscribePendingLogs(ch)
 }
 
+func (b *QuaiAPIBackend) SubscribeExpiredEtxsEvent(ch chan<- core.ExpiredEtxsEvent) event.Subscription {
+	nodeCtx := common.NodeLocation.Context()
+	if nodeCtx != common.ZONE_CTX {
+		return nil
+	}
+	return b.eth.Core().SubscribeExpiredEtxsEvent(ch)
+}
+
+func (b *QuaiAPIBackend) SubscribeInboundEtxsEvent(ch chan<- core.InboundEtxsEvent) event.Subscription {
+	nodeCtx := common.NodeLocation.Context()
+	if nodeCtx != common.ZONE_CTX {
+		return nil
+	}
+	return b.eth.Core().SubscribeInboundEtxsEvent(ch)
+}
+
+func (b *QuaiAPIBackend) SubscribeCoincidentBlockEvent(ch chan<- core.CoincidentBlockEvent) event.Subscription {
+	return b.eth.Core().SubscribeCoincidentBlockEvent(ch)
+}
+
+func (b *QuaiAPIBackend) SubscribeSubReorgEvent(ch chan<- core.SubReorgEvent) event.Subscription {
+	return b.eth.Core().SubscribeSubReorgEvent(ch)
+}
+
 func (b *QuaiAPIBackend) SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription {
 	return b.eth.Core().SubscribeChainEvent(ch)
 }
@@ -329,6 +354,41 @@ func (b *QuaiAPIBackend) GetTransaction(ctx context.Context, txHash common.Hash)
 	return tx, blockHash, blockNumber, index, nil
 }
 
+// GetEtxSet returns the set of inbound ETXs available to be mined on top of
+// the given block.
+func (b *QuaiAPIBackend) GetEtxSet(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (types.EtxSet, *types.Header, error) {
+	nodeCtx := common.NodeLocation.Context()
+	if nodeCtx != common.ZONE_CTX {
+		return nil, nil, errors.New("getEtxSet can only be called in zone chain")
+	}
+	header, err := b.HeaderByNumberOrHash(ctx, blockNrOrHash)
+	if header == nil || err != nil {
+		return nil, nil, err
+	}
+	etxSet := rawdb.ReadEtxSet(b.eth.ChainDb(), header.Hash(), header.NumberU64())
+	if etxSet == nil {
+		return nil, nil, errors.New("etx set not found")
+	}
+	return etxSet, header, nil
+}
+
+// GetExpiredEtx returns the record of an ETX which expired without being mined.
+func (b *QuaiAPIBackend) GetExpiredEtx(hash common.Hash) *types.ExpiredEtx {
+	return rawdb.ReadExpiredEtx(b.eth.ChainDb(), hash)
+}
+
+// GetAddressActivity returns up to limit entries of the address activity index
+// of an address, starting at the given block and sequence number, along with
+// the position of the next entry and the number of blocks indexed so far.
+func (b *QuaiAPIBackend) GetAddressActivity(ctx context.Context, address common.Address, number uint64, seq uint32, limit int) ([]rawdb.AddressActivity, *uint64, *uint32, uint64, error) {
+	if b.eth.addressIndexer == nil {
+		return nil, nil, nil, 0, errors.New("address index is disabled, enable it with --addressindex")
+	}
+	sections, _, _ := b.eth.addressIndexer.Sections()
+	activity, nextNumber, nextSeq := rawdb.ReadAddressActivity(b.eth.ChainDb(), address, number, seq, limit)
+	return activity, nextNumber, nextSeq, sections * core.AddressIndexSectionSize, nil
+}
+
 func (b *QuaiAPIBackend) GetPoolNonce(ctx context.Context, addr common.Address) (uint64, error) {
 	nodeCtx := common.NodeLocation.Context()
 	if nodeCtx != common.ZONE_CTX {
@@ -401,6 +461,10 @@ func (b *QuaiAPIBackend) RPCGasCap() uint64 {
 	return b.eth.config.RPCGasCap
 }
 
+func (b *QuaiAPIBackend) ExternalSigner() *signer.ExternalSigner {
+	return b.eth.extSigner
+}
+
 func (b *QuaiAPIBackend) RPCTxFeeCap() float64 {
 	nodeCtx := common.NodeLocation.Context()
 	if nodeCtx != common.ZONE_CTX {
@@ -496,10 +560,18 @@ func (b *QuaiAPIBackend) GetPendingHeader() (*types.Header, error) {
 	return b.eth.core.GetPendingHeader()
 }
 
+func (b *QuaiAPIBackend) PendingHeaders() (map[common.Hash]types.PendingHeader, common.Hash) {
+	return b.eth.core.PendingHeaders()
+}
+
 func (b *QuaiAPIBackend) GetManifest(blockHash common.Hash) (types.BlockManifest, error) {
 	return b.eth.core.GetManifest(blockHash)
 }
 
+func (b *QuaiAPIBackend) GetInboundEtxs(blockHash common.Hash) (types.Transactions, error) {
+	return b.eth.core.GetInboundEtxs(blockHash)
+}
+
 func (b *QuaiAPIBackend) GetSubManifest(slice common.Location, blockHash common.Hash) (types.BlockManifest, error) {
 	return b.eth.core.GetSubManifest(slice, blockHash)
 }
@@ -47,6 +47,7 @@ import (
 	"github.com/dominant-strategies/go-quai/p2p/enode"
 	"github.com/dominant-strategies/go-quai/params"
 	"github.com/dominant-strategies/go-quai/rpc"
+	"github.com/dominant-strategies/go-quai/signer"
 )
 
 // Config contains the configuration options of the ETH protocol.
@@ -71,9 +72,11 @@ type Quai struct {
 
 	bloomRequests     chan chan *bloombits.Retrieval // Channel receiving bloom data retrieval requests
 	bloomIndexer      *core.ChainIndexer             // Bloom indexer operating during block imports
+	addressIndexer    *core.ChainIndexer             // Address activity indexer, nil unless enabled
 	closeBloomHandler chan struct{}
 
 	APIBackend *QuaiAPIBackend
+	extSigner  *signer.ExternalSigner // Signer of the transaction signing APIs, nil if not configured
 
 	gasPrice  *big.Int
 	etherbase common.Address
@@ -94,6 +97,9 @@ func New(stack *node.Node, config *ethconfig.Config) (*Quai, error) {
 	if !config.SyncMode.IsValid() {
 		return nil, fmt.Errorf("invalid sync mode %d", config.SyncMode)
 	}
+	if config.SyncMode == downloader.LightSync && nodeCtx == common.ZONE_CTX {
+		return nil, fmt.Errorf("light sync is only supported in prime and region chains")
+	}
 	if config.Miner.GasPrice == nil || config.Miner.GasPrice.Cmp(common.Big0) <= 0 {
 		log.Warn("Sanitizing invalid miner gas price", "provided", config.Miner.GasPrice, "updated", ethconfig.Defaults.Miner.GasPrice)
 		config.Miner.GasPrice = new(big.Int).Set(ethconfig.Defaults.Miner.GasPrice)
@@ -177,6 +183,8 @@ func New(stack *node.Node, config *ethconfig.Config) (*Quai, error) {
 			TrieTimeLimit:       config.TrieTimeout,
 			SnapshotLimit:       config.SnapshotCache,
 			Preimages:           config.Preimages,
+			ParallelTxWorkers:   config.ParallelTxWorkers,
+			StateDiffs:          config.StateDiffs,
 		}
 	)
 
@@ -188,11 +196,18 @@ func New(stack *node.Node, config *ethconfig.Config) (*Quai, error) {
 	if err != nil {
 		return nil, err
 	}
+	if config.SyncMode == downloader.LightSync {
+		eth.core.SetLightMode()
+	}
 
 	// Only index bloom if processing state
 	if eth.core.ProcessingState() && nodeCtx == common.ZONE_CTX {
 		eth.bloomIndexer = core.NewBloomIndexer(chainDb, params.BloomBitsBlocks, params.BloomConfirms)
 		eth.bloomIndexer.Start(eth.Core().Slice().HeaderChain())
+		if config.AddressIndex {
+			eth.addressIndexer = core.NewAddressIndexer(chainDb, chainConfig)
+			eth.addressIndexer.Start(eth.Core().Slice().HeaderChain())
+		}
 	}
 
 	// Permit the downloader to use the trie cache allowance during fast sync
@@ -211,6 +226,10 @@ func New(stack *node.Node, config *ethconfig.Config) (*Quai, error) {
 		return nil, err
 	}
 
+	if endpoint := stack.Config().ExternalSigner; endpoint != "" {
+		log.Info("Using external signer", "endpoint", endpoint)
+		eth.extSigner = signer.NewExternalSigner(endpoint)
+	}
 	eth.APIBackend = &QuaiAPIBackend{stack.Config().ExtRPCEnabled(), eth, nil}
 	// Gasprice oracle is only initiated in zone chains
 	if nodeCtx == common.ZONE_CTX && eth.core.ProcessingState() {
@@ -404,9 +423,15 @@ func (s *Quai) Stop() error {
 		// Then stop everything else.
 		s.bloomIndexer.Close()
 		close(s.closeBloomHandler)
+		if s.addressIndexer != nil {
+			s.addressIndexer.Close()
+		}
 	}
 	s.core.Stop()
 	s.engine.Close()
+	if s.extSigner != nil {
+		s.extSigner.Close()
+	}
 	rawdb.PopUncleanShutdownMarker(s.chainDb)
 	s.chainDb.Close()
 	s.eventMux.Stop()
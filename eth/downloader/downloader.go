@@ -21,6 +21,7 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -28,6 +29,7 @@ import (
 	quai "github.com/dominant-strategies/go-quai"
 	"github.com/dominant-strategies/go-quai/common"
 	"github.com/dominant-strategies/go-quai/consensus"
+	"github.com/dominant-strategies/go-quai/core"
 	"github.com/dominant-strategies/go-quai/core/state/snapshot"
 	"github.com/dominant-strategies/go-quai/core/types"
 	"github.com/dominant-strategies/go-quai/eth/protocols/eth"
@@ -129,6 +131,12 @@ type Core interface {
 	// HasBlock verifies a block's presence in the local chain.
 	HasBlock(common.Hash, uint64) bool
 
+	// HasHeader verifies a header's presence in the local chain.
+	HasHeader(common.Hash, uint64) bool
+
+	// InsertHeaderChain appends a batch of headers to a light chain.
+	InsertHeaderChain([]*types.Header) (int, error)
+
 	// GetBlockByHash retrieves a block from the local chain.
 	GetBlockByHash(common.Hash) *types.Block
 
@@ -409,9 +417,13 @@ func (d *Downloader) syncWithPeer(p *peerConnection, hash common.Hash, entropy *
 	}
 	fetchers := []func() error{
 		func() error { return d.fetchHeaders(p, origin) }, // Headers are always retrieved
-		func() error { return d.fetchBodies(origin) },     // Bodies are retrieved during normal and fast sync
 		func() error { return d.processHeaders(origin) },
-		func() error { return d.processFullSyncContent(peerHeight) },
+	}
+	if mode == FullSync {
+		fetchers = append(fetchers,
+			func() error { return d.fetchBodies(origin) }, // Bodies are retrieved during full sync
+			func() error { return d.processFullSyncContent(peerHeight) },
+		)
 	}
 	return d.spawnSync(fetchers)
 }
@@ -640,7 +652,11 @@ func (d *Downloader) fetchHeaders(p *peerConnection, from uint64) error {
 				// Only fill the skeleton between the headers we don't know about.
 				for i := 0; i < len(headers); i++ {
 					skeletonHeaders = append(skeletonHeaders, headers[i])
-					commonAncestor := d.core.HasBlock(headers[i].Hash(), headers[i].NumberU64()) && (d.core.GetTerminiByHash(headers[i].Hash()) != nil)
+					known := d.core.HasBlock
+					if d.getMode() == LightSync {
+						known = d.core.HasHeader
+					}
+					commonAncestor := known(headers[i].Hash(), headers[i].NumberU64()) && (d.core.GetTerminiByHash(headers[i].Hash()) != nil)
 					if commonAncestor {
 						break
 					}
@@ -1018,6 +1034,8 @@ func (d *Downloader) processHeaders(origin uint64) error {
 		rollback    uint64 // Zero means no rollback (fine as you can't unroll the genesis)
 		rollbackErr error
 		mode        = d.getMode()
+		peerHeight  = origin
+		unlinked    []*types.Header // Light headers waiting for their parent
 	)
 	defer func() {
 		if rollback > 0 {
@@ -1093,10 +1111,21 @@ func (d *Downloader) processHeaders(origin uint64) error {
 						rollbackErr = fmt.Errorf("stale headers: len inserts %v len(chunk) %v", len(inserts), len(chunk))
 						return fmt.Errorf("%w: stale headers", errBadPeer)
 					}
+				} else {
+					// Light chains verify and insert the headers directly
+					var err error
+					if unlinked, err = d.insertLightHeaders(append(unlinked, chunk...)); err != nil {
+						rollbackErr = err
+						return fmt.Errorf("%w: %v", errInvalidChain, err)
+					}
 				}
 				headers = headers[limit:]
 				origin += uint64(limit)
 			}
+			// Light chains are done once the head of the peer is reached
+			if mode == LightSync && d.headNumber >= peerHeight {
+				return errNoFetchesPending
+			}
 			// Update the highest block number we know if a higher one is found.
 			d.syncStatsLock.Lock()
 			if d.syncStatsChainHeight < origin {
@@ -1115,6 +1144,34 @@ func (d *Downloader) processHeaders(origin uint64) error {
 	}
 }
 
+// insertLightHeaders inserts headers into a light chain in the order of their
+// numbers. Headers which cannot be linked to the local chain yet, because the
+// skeleton is filled out of order, are returned to be retried with the next
+// batch.
+func (d *Downloader) insertLightHeaders(headers []*types.Header) ([]*types.Header, error) {
+	sort.Slice(headers, func(i, j int) bool {
+		return headers[i].NumberU64() < headers[j].NumberU64()
+	})
+	var unlinked []*types.Header
+	for _, header := range headers {
+		if d.core.IsBlockHashABadHash(header.Hash()) {
+			return nil, errBadBlockFound
+		}
+		if _, err := d.core.InsertHeaderChain([]*types.Header{header}); err != nil {
+			if errors.Is(err, consensus.ErrUnknownAncestor) || errors.Is(err, core.ErrSubNotSyncedToDom) {
+				unlinked = append(unlinked, header)
+				continue
+			}
+			return nil, err
+		}
+		if header.NumberU64() > d.headNumber {
+			d.headNumber = header.NumberU64()
+			d.headEntropy = d.core.TotalLogS(header)
+		}
+	}
+	return unlinked, nil
+}
+
 // processFullSyncContent takes fetch results from the queue and imports them into the chain.
 func (d *Downloader) processFullSyncContent(peerHeight uint64) error {
 	for {
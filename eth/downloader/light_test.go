@@ -0,0 +1,86 @@
+package downloader
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/dominant-strategies/go-quai/common"
+	"github.com/dominant-strategies/go-quai/core"
+	"github.com/dominant-strategies/go-quai/core/types"
+)
+
+// lightTestCore is a light chain appending headers whose parent is known and
+// deferring the others, as AppendLightHeader does.
+type lightTestCore struct {
+	Core
+	headers map[common.Hash]*types.Header
+	invalid map[common.Hash]bool
+}
+
+func (c *lightTestCore) InsertHeaderChain(headers []*types.Header) (int, error) {
+	for i, header := range headers {
+		if c.invalid[header.Hash()] {
+			return i, errors.New("invalid header")
+		}
+		if c.headers[header.ParentHash()] == nil {
+			return i, core.ErrSubNotSyncedToDom
+		}
+		c.headers[header.Hash()] = header
+	}
+	return len(headers), nil
+}
+
+func (c *lightTestCore) IsBlockHashABadHash(hash common.Hash) bool { return false }
+
+func (c *lightTestCore) TotalLogS(header *types.Header) *big.Int { return header.Number() }
+
+// Tests that light headers are inserted in the order of their numbers, and that
+// the headers which cannot be linked to the chain yet are retried with the
+// next batch, while invalid headers abort the sync.
+func TestInsertLightHeaders(t *testing.T) {
+	genesis := types.EmptyHeader()
+	headers := []*types.Header{genesis}
+	for i := 1; i <= 5; i++ {
+		header := types.EmptyHeader()
+		header.SetParentHash(headers[i-1].Hash())
+		header.SetNumber(big.NewInt(int64(i)))
+		headers = append(headers, header)
+	}
+	chain := &lightTestCore{headers: map[common.Hash]*types.Header{genesis.Hash(): genesis}, invalid: make(map[common.Hash]bool)}
+	d := &Downloader{core: chain}
+
+	unlinked, err := d.insertLightHeaders([]*types.Header{headers[3], headers[1], headers[5]})
+	if err != nil {
+		t.Fatalf("failed to insert headers: %v", err)
+	}
+	if len(unlinked) != 2 || unlinked[0] != headers[3] || unlinked[1] != headers[5] {
+		t.Fatalf("unlinked headers mismatch: have %d headers", len(unlinked))
+	}
+	if d.headNumber != 1 {
+		t.Errorf("head number mismatch: have %d, want %d", d.headNumber, 1)
+	}
+	unlinked, err = d.insertLightHeaders(append(unlinked, headers[4], headers[2]))
+	if err != nil {
+		t.Fatalf("failed to insert headers: %v", err)
+	}
+	if len(unlinked) != 0 {
+		t.Errorf("headers left unlinked: %d", len(unlinked))
+	}
+	if d.headNumber != 5 || d.headEntropy.Cmp(big.NewInt(5)) != 0 {
+		t.Errorf("head mismatch: have #%d with entropy %v, want #%d", d.headNumber, d.headEntropy, 5)
+	}
+	for _, header := range headers {
+		if chain.headers[header.Hash()] == nil {
+			t.Errorf("header #%d not inserted", header.NumberU64())
+		}
+	}
+	// Invalid headers are never retried
+	bad := types.EmptyHeader()
+	bad.SetParentHash(headers[5].Hash())
+	bad.SetNumber(big.NewInt(6))
+	chain.invalid[bad.Hash()] = true
+	if _, err := d.insertLightHeaders([]*types.Header{bad}); err == nil {
+		t.Errorf("invalid header inserted")
+	}
+}
@@ -23,11 +23,12 @@ import "fmt"
 type SyncMode uint32
 
 const (
-	FullSync SyncMode = iota // Synchronise the entire blockchain history from full blocks
+	FullSync  SyncMode = iota // Synchronise the entire blockchain history from full blocks
+	LightSync                 // Download only the headers and terminate afterwards
 )
 
 func (mode SyncMode) IsValid() bool {
-	return mode == FullSync
+	return mode >= FullSync && mode <= LightSync
 }
 
 // String implements the stringer interface.
@@ -35,6 +36,8 @@ func (mode SyncMode) String() string {
 	switch mode {
 	case FullSync:
 		return "full"
+	case LightSync:
+		return "light"
 	default:
 		return "unknown"
 	}
@@ -44,6 +47,8 @@ func (mode SyncMode) MarshalText() ([]byte, error) {
 	switch mode {
 	case FullSync:
 		return []byte("full"), nil
+	case LightSync:
+		return []byte("light"), nil
 	default:
 		return nil, fmt.Errorf("unknown sync mode %d", mode)
 	}
@@ -53,8 +58,10 @@ func (mode *SyncMode) UnmarshalText(text []byte) error {
 	switch string(text) {
 	case "full":
 		*mode = FullSync
+	case "light":
+		*mode = LightSync
 	default:
-		return fmt.Errorf(`unknown sync mode %q, want "full", "fast" or "light"`, text)
+		return fmt.Errorf(`unknown sync mode %q, want "full" or "light"`, text)
 	}
 	return nil
 }
@@ -100,8 +100,14 @@ type Config struct {
 	NoPruning  bool // Whether to disable pruning and flush everything to disk
 	NoPrefetch bool // Whether to disable prefetching and only load state on demand
 
+	ParallelTxWorkers int `toml:",omitempty"` // Number of workers executing block transactions speculatively in parallel
+
 	TxLookupLimit uint64 `toml:",omitempty"` // The maximum number of blocks from head whose tx indices are reserved.
 
+	AddressIndex bool `toml:",omitempty"` // Whether to index the transactions and ETXs touching every address
+
+	StateDiffs bool `toml:",omitempty"` // Whether to store the state changes made by every block
+
 	// Whitelist of required block number -> hash values to accept
 	Whitelist map[uint64]common.Hash `toml:"-"`
 
@@ -178,11 +184,19 @@ func CreateProgpowConsensusEngine(stack *node.Node, chainConfig *params.ChainCon
 		log.Warn("Progpow used in shared mode")
 	}
 	engine := progpow.New(progpow.Config{
-		PowMode:       config.PowMode,
-		NotifyFull:    config.NotifyFull,
-		DurationLimit: config.DurationLimit,
-		GasCeil:       config.GasCeil,
-		MinDifficulty: config.MinDifficulty,
+		PowMode:          config.PowMode,
+		CacheDir:         config.CacheDir,
+		CachesInMem:      config.CachesInMem,
+		CachesOnDisk:     config.CachesOnDisk,
+		CachesLockMmap:   config.CachesLockMmap,
+		DatasetDir:       config.DatasetDir,
+		DatasetsInMem:    config.DatasetsInMem,
+		DatasetsOnDisk:   config.DatasetsOnDisk,
+		DatasetsLockMmap: config.DatasetsLockMmap,
+		NotifyFull:       config.NotifyFull,
+		DurationLimit:    config.DurationLimit,
+		GasCeil:          config.GasCeil,
+		MinDifficulty:    config.MinDifficulty,
 	}, notify, noverify)
 	engine.SetThreads(-1) // Disable CPU mining
 	return engine
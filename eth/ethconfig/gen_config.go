@@ -22,7 +22,10 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		SnapDiscoveryURLs       []string
 		NoPruning               bool
 		NoPrefetch              bool
+		ParallelTxWorkers       int                    `toml:",omitempty"`
 		TxLookupLimit           uint64                 `toml:",omitempty"`
+		AddressIndex            bool                   `toml:",omitempty"`
+		StateDiffs              bool                   `toml:",omitempty"`
 		Whitelist               map[uint64]common.Hash `toml:"-"`
 		SkipBcVersionCheck      bool                   `toml:"-"`
 		DatabaseHandles         int                    `toml:"-"`
@@ -52,7 +55,10 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.SnapDiscoveryURLs = c.SnapDiscoveryURLs
 	enc.NoPruning = c.NoPruning
 	enc.NoPrefetch = c.NoPrefetch
+	enc.ParallelTxWorkers = c.ParallelTxWorkers
 	enc.TxLookupLimit = c.TxLookupLimit
+	enc.AddressIndex = c.AddressIndex
+	enc.StateDiffs = c.StateDiffs
 	enc.Whitelist = c.Whitelist
 	enc.SkipBcVersionCheck = c.SkipBcVersionCheck
 	enc.DatabaseHandles = c.DatabaseHandles
@@ -86,7 +92,10 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		SnapDiscoveryURLs       []string
 		NoPruning               *bool
 		NoPrefetch              *bool
+		ParallelTxWorkers       *int                   `toml:",omitempty"`
 		TxLookupLimit           *uint64                `toml:",omitempty"`
+		AddressIndex            *bool                  `toml:",omitempty"`
+		StateDiffs              *bool                  `toml:",omitempty"`
 		Whitelist               map[uint64]common.Hash `toml:"-"`
 		LightServ               *int                   `toml:",omitempty"`
 		LightIngress            *int                   `toml:",omitempty"`
@@ -142,9 +151,18 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.NoPrefetch != nil {
 		c.NoPrefetch = *dec.NoPrefetch
 	}
+	if dec.ParallelTxWorkers != nil {
+		c.ParallelTxWorkers = *dec.ParallelTxWorkers
+	}
 	if dec.TxLookupLimit != nil {
 		c.TxLookupLimit = *dec.TxLookupLimit
 	}
+	if dec.AddressIndex != nil {
+		c.AddressIndex = *dec.AddressIndex
+	}
+	if dec.StateDiffs != nil {
+		c.StateDiffs = *dec.StateDiffs
+	}
 	if dec.Whitelist != nil {
 		c.Whitelist = dec.Whitelist
 	}
@@ -28,6 +28,7 @@ import (
 	quai "github.com/dominant-strategies/go-quai"
 	"github.com/dominant-strategies/go-quai/common"
 	"github.com/dominant-strategies/go-quai/common/hexutil"
+	"github.com/dominant-strategies/go-quai/core"
 	"github.com/dominant-strategies/go-quai/core/types"
 	"github.com/dominant-strategies/go-quai/ethdb"
 	"github.com/dominant-strategies/go-quai/event"
@@ -36,6 +37,9 @@ import (
 
 const (
 	c_pendingHeaderChSize = 20
+	c_expiredEtxsChSize   = 20
+	c_inboundEtxsChSize   = 20
+	c_hierarchyChSize     = 20
 )
 
 // filter is a helper struct that holds meta information over the filter type
@@ -619,3 +623,198 @@ func (api *PublicFilterAPI) PendingHeader(ctx context.Context) (*rpc.Subscriptio
 
 	return rpcSub, nil
 }
+
+// CoincidentBlocksCriteria restricts the coincident block notifications to
+// blocks of at most the given order. By default blocks coincident with either
+// a region or a prime block are notified.
+type CoincidentBlocksCriteria struct {
+	Order *int `json:"order"`
+}
+
+// CoincidentBlocks sends a notification each time an appended block is
+// coincident with a region or prime block.
+func (api *PublicFilterAPI) CoincidentBlocks(ctx context.Context, crit *CoincidentBlocksCriteria) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	maxOrder := common.REGION_CTX
+	if crit != nil && crit.Order != nil {
+		if *crit.Order < common.PRIME_CTX || *crit.Order > common.REGION_CTX {
+			return &rpc.Subscription{}, errors.New("order must be 0 (prime) or 1 (region)")
+		}
+		maxOrder = *crit.Order
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		blocks := make(chan core.CoincidentBlockEvent, c_hierarchyChSize)
+		blocksSub := api.backend.SubscribeCoincidentBlockEvent(blocks)
+
+		for {
+			select {
+			case ev := <-blocks:
+				if ev.Order > maxOrder {
+					continue
+				}
+				notifier.Notify(rpcSub.ID, map[string]interface{}{
+					"order":  ev.Order,
+					"header": ev.Block.Header().RPCMarshalHeader(),
+				})
+			case <-rpcSub.Err():
+				blocksSub.Unsubscribe()
+				return
+			case <-notifier.Closed():
+				blocksSub.Unsubscribe()
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
+// SubReorgs sends a notification each time an appended block changes the
+// best pending header of the slice, moving mining to a new terminus.
+func (api *PublicFilterAPI) SubReorgs(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		reorgs := make(chan core.SubReorgEvent, c_hierarchyChSize)
+		reorgsSub := api.backend.SubscribeSubReorgEvent(reorgs)
+
+		for {
+			select {
+			case ev := <-reorgs:
+				notifier.Notify(rpcSub.ID, map[string]interface{}{
+					"order":    ev.Order,
+					"terminus": ev.Terminus,
+					"setHead":  ev.SetHead,
+					"header":   ev.Block.Header().RPCMarshalHeader(),
+				})
+			case <-rpcSub.Err():
+				reorgsSub.Unsubscribe()
+				return
+			case <-notifier.Closed():
+				reorgsSub.Unsubscribe()
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
+// InboundEtxsCriteria restricts the inbound ETX notifications to the given
+// recipients. An empty list matches every recipient.
+type InboundEtxsCriteria struct {
+	Addresses []common.Address `json:"addresses"`
+}
+
+// InboundEtxs sends a notification each time an ETX from another chain is
+// added to the set of inbound ETXs.
+func (api *PublicFilterAPI) InboundEtxs(ctx context.Context, crit *InboundEtxsCriteria) (*rpc.Subscription, error) {
+	if common.NodeLocation.Context() != common.ZONE_CTX {
+		return &rpc.Subscription{}, errors.New("inboundEtxs subscription can only be made in zone chain")
+	}
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	addresses := make(map[common.AddressBytes]struct{})
+	if crit != nil {
+		for _, address := range crit.Addresses {
+			addresses[address.Bytes20()] = struct{}{}
+		}
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		inbound := make(chan core.InboundEtxsEvent, c_inboundEtxsChSize)
+		inboundSub := api.backend.SubscribeInboundEtxsEvent(inbound)
+
+		for {
+			select {
+			case ev := <-inbound:
+				for _, etx := range ev.Etxs {
+					if len(addresses) > 0 {
+						if _, ok := addresses[etx.To().Bytes20()]; !ok {
+							continue
+						}
+					}
+					notifier.Notify(rpcSub.ID, types.RPCMarshalInboundEtx(etx, ev.Block))
+				}
+			case <-rpcSub.Err():
+				inboundSub.Unsubscribe()
+				return
+			case <-notifier.Closed():
+				inboundSub.Unsubscribe()
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
+// ExpiredEtxsCriteria restricts the expired ETX notifications to the given
+// senders. An empty list matches every sender.
+type ExpiredEtxsCriteria struct {
+	Senders []common.Address `json:"senders"`
+}
+
+// ExpiredEtxs sends a notification each time an ETX expires from the set of
+// inbound ETXs without being mined.
+func (api *PublicFilterAPI) ExpiredEtxs(ctx context.Context, crit *ExpiredEtxsCriteria) (*rpc.Subscription, error) {
+	if common.NodeLocation.Context() != common.ZONE_CTX {
+		return &rpc.Subscription{}, errors.New("expiredEtxs subscription can only be made in zone chain")
+	}
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	senders := make(map[common.AddressBytes]struct{})
+	if crit != nil {
+		for _, sender := range crit.Senders {
+			senders[sender.Bytes20()] = struct{}{}
+		}
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		expired := make(chan core.ExpiredEtxsEvent, c_expiredEtxsChSize)
+		expiredSub := api.backend.SubscribeExpiredEtxsEvent(expired)
+
+		for {
+			select {
+			case ev := <-expired:
+				for _, expiredEtx := range ev.Etxs {
+					if len(senders) > 0 {
+						if _, ok := senders[expiredEtx.ETX.ETXSender().Bytes20()]; !ok {
+							continue
+						}
+					}
+					fields := expiredEtx.RPCMarshalExpiredEtx()
+					fields["removed"] = ev.Removed
+					notifier.Notify(rpcSub.ID, fields)
+				}
+			case <-rpcSub.Err():
+				expiredSub.Unsubscribe()
+				return
+			case <-notifier.Closed():
+				expiredSub.Unsubscribe()
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
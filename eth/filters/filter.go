@@ -43,6 +43,10 @@ type Backend interface {
 	SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscription
 	SubscribePendingLogsEvent(ch chan<- []*types.Log) event.Subscription
 	SubscribePendingHeaderEvent(ch chan<- *types.Header) event.Subscription
+	SubscribeExpiredEtxsEvent(ch chan<- core.ExpiredEtxsEvent) event.Subscription
+	SubscribeInboundEtxsEvent(ch chan<- core.InboundEtxsEvent) event.Subscription
+	SubscribeCoincidentBlockEvent(ch chan<- core.CoincidentBlockEvent) event.Subscription
+	SubscribeSubReorgEvent(ch chan<- core.SubReorgEvent) event.Subscription
 	ProcessingState() bool
 
 	BloomStatus() (uint64, uint64)
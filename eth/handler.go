@@ -47,6 +47,10 @@ const (
 	// missingBlockChanSize is the size of channel listening to the MissingBlockEvent
 	missingBlockChanSize = 60
 
+	// lightRequestChanSize is the size of channels listening to the manifest
+	// and pending etxs requests of a light chain
+	lightRequestChanSize = 60
+
 	// minPeerSend is the threshold for sending the block updates. If
 	// sqrt of len(peers) is less than 5 we make the block announcement
 	// to as much as minPeerSend peers otherwise send it to sqrt of len(peers).
@@ -127,6 +131,11 @@ type handler struct {
 	minedBlockSub   *event.TypeMuxSubscription
 	missingBlockCh  chan types.BlockRequest
 	missingBlockSub event.Subscription
+	manifestReqCh   chan types.BlockRequest
+	manifestReqSub  event.Subscription
+	pEtxsReqCh      chan types.BlockRequest
+	pEtxsReqSub     event.Subscription
+	syncMode        downloader.SyncMode
 	subSyncQueue    *lru.Cache
 
 	whitelist map[uint64]common.Hash
@@ -158,6 +167,7 @@ func newHandler(config *handlerConfig) (*handler, error) {
 		database:      config.Database,
 		txpool:        config.TxPool,
 		core:          config.Core,
+		syncMode:      config.Sync,
 		peers:         newPeerSet(),
 		whitelist:     config.Whitelist,
 		txsyncCh:      make(chan *txsync),
@@ -338,6 +348,15 @@ func (h *handler) Start(maxPeers int) {
 	h.missingBlockSub = h.core.SubscribeMissingBlockEvent(h.missingBlockCh)
 	go h.missingBlockLoop()
 
+	if h.syncMode == downloader.LightSync {
+		h.wg.Add(1)
+		h.manifestReqCh = make(chan types.BlockRequest, lightRequestChanSize)
+		h.manifestReqSub = h.core.SubscribeMissingManifestEvent(h.manifestReqCh)
+		h.pEtxsReqCh = make(chan types.BlockRequest, lightRequestChanSize)
+		h.pEtxsReqSub = h.core.SubscribeMissingPendingEtxsEvent(h.pEtxsReqCh)
+		go h.lightRequestLoop()
+	}
+
 	// broadcast mined blocks
 	h.wg.Add(1)
 	h.minedBlockSub = h.eventMux.Subscribe(core.NewMinedBlockEvent{})
@@ -359,6 +378,10 @@ func (h *handler) Stop() {
 	}
 	h.minedBlockSub.Unsubscribe()   // quits blockBroadcastLoop
 	h.missingBlockSub.Unsubscribe() // quits missingBlockLoop
+	if h.syncMode == downloader.LightSync {
+		h.manifestReqSub.Unsubscribe() // quits lightRequestLoop
+		h.pEtxsReqSub.Unsubscribe()
+	}
 
 	// Quit chainSync and txsync64.
 	// After this is done, no new peers will be accepted.
@@ -523,3 +546,49 @@ func (h *handler) missingBlockLoop() {
 		}
 	}
 }
+
+// lightRequestLoop fetches the manifests and pending etxs needed by a light
+// chain from the peers ahead of it.
+func (h *handler) lightRequestLoop() {
+	defer h.wg.Done()
+	for {
+		select {
+		case request := <-h.manifestReqCh:
+			for _, peer := range h.lightRequestPeers(request) {
+				peer.RequestBlockManifest(request.Hash)
+			}
+
+		case request := <-h.pEtxsReqCh:
+			for _, peer := range h.lightRequestPeers(request) {
+				peer.RequestPendingEtxs(request.Hash)
+			}
+
+		case <-h.manifestReqSub.Err():
+			return
+		case <-h.pEtxsReqSub.Err():
+			return
+		}
+	}
+}
+
+// lightRequestPeers returns up to minPeerRequest random peers which serve light
+// chains and are ahead of the entropy of the request.
+func (h *handler) lightRequestPeers(request types.BlockRequest) []*eth.Peer {
+	allPeers := h.peers.allPeers()
+	rand.Shuffle(len(allPeers), func(i, j int) { allPeers[i], allPeers[j] = allPeers[j], allPeers[i] })
+
+	peers := make([]*eth.Peer, 0, minPeerRequest)
+	for _, peer := range allPeers {
+		if peer.Version() < eth.QUAI2 {
+			continue
+		}
+		if _, _, peerEntropy, _ := peer.Head(); peerEntropy == nil || peerEntropy.Cmp(request.Entropy) < 0 {
+			continue
+		}
+		peers = append(peers, peer)
+		if len(peers) == minPeerRequest {
+			break
+		}
+	}
+	return peers
+}
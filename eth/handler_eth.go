@@ -94,6 +94,18 @@ func (h *ethHandler) Handle(peer *eth.Peer, packet eth.Packet) error {
 	case *eth.PooledTransactionsPacket:
 		return h.txFetcher.Enqueue(peer.ID(), *packet, true)
 
+	case *eth.BlockManifestPacket:
+		if err := h.core.AddManifest(packet.Hash, packet.Manifest); err != nil {
+			log.Debug("Failed to add manifest from peer", "peer", peer.ID(), "hash", packet.Hash, "err", err)
+		}
+		return nil
+
+	case *eth.PendingEtxsPacket:
+		if err := h.core.AddLightPendingEtxs(packet.PendingEtxs); err != nil {
+			log.Debug("Failed to add pending etxs from peer", "peer", peer.ID(), "err", err)
+		}
+		return nil
+
 	default:
 		return fmt.Errorf("unexpected eth packet type: %T", packet)
 	}
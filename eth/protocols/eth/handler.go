@@ -135,7 +135,7 @@ type NodeInfo struct {
 
 // nodeInfo retrieves some `quai` protocol metadata about the running host node.
 func nodeInfo(chain *core.Core, network uint64) *NodeInfo {
-	head := chain.CurrentBlock()
+	head := chain.CurrentHeader()
 	return &NodeInfo{
 		Network: network,
 		Entropy: chain.CurrentLogEntropy(),
@@ -178,6 +178,25 @@ var quai1 = map[uint64]msgHandler{
 	GetBlockMsg:              handleGetBlock66,
 }
 
+var quai2 = map[uint64]msgHandler{
+	NewBlockHashesMsg:             handleNewBlockhashes,
+	NewBlockMsg:                   handleNewBlock,
+	TransactionsMsg:               handleTransactions,
+	NewPooledTransactionHashesMsg: handleNewPooledTransactionHashes,
+	GetBlockHeadersMsg:            handleGetBlockHeaders66,
+	BlockHeadersMsg:               handleBlockHeaders66,
+	GetBlockBodiesMsg:             handleGetBlockBodies66,
+	BlockBodiesMsg:                handleBlockBodies66,
+	GetPooledTransactionsMsg:      handleGetPooledTransactions66,
+	PooledTransactionsMsg:         handlePooledTransactions66,
+	GetBlockMsg:                   handleGetBlock66,
+	// quai2 messages serving light chains
+	GetBlockManifestMsg: handleGetBlockManifest66,
+	BlockManifestMsg:    handleBlockManifest66,
+	GetPendingEtxsMsg:   handleGetPendingEtxs66,
+	PendingEtxsMsg:      handlePendingEtxs66,
+}
+
 // handleMessage is invoked whenever an inbound message is received from a remote
 // peer. The remote connection is torn down upon returning any error.
 func handleMessage(backend Backend, peer *Peer) error {
@@ -192,7 +211,9 @@ func handleMessage(backend Backend, peer *Peer) error {
 	defer msg.Discard()
 
 	var handlers map[uint64]msgHandler
-	if peer.Version() >= QUAI1 {
+	if peer.Version() >= QUAI2 {
+		handlers = quai2
+	} else if peer.Version() >= QUAI1 {
 		handlers = quai1
 	} else {
 		return fmt.Errorf("protocol version not supported")
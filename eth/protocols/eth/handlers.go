@@ -158,6 +158,32 @@ func handleGetBlock66(backend Backend, msg Decoder, peer *Peer) error {
 	return nil
 }
 
+func handleGetBlockManifest66(backend Backend, msg Decoder, peer *Peer) error {
+	// Decode the manifest retrieval message
+	var query GetBlockManifestPacket66
+	if err := msg.Decode(&query); err != nil {
+		return fmt.Errorf("%w: message %v: %v", errDecode, msg, err)
+	}
+	manifest, err := backend.Core().Slice().GetManifest(query.Hash)
+	if err != nil {
+		return nil
+	}
+	return peer.ReplyBlockManifest(query.RequestId, query.Hash, manifest)
+}
+
+func handleGetPendingEtxs66(backend Backend, msg Decoder, peer *Peer) error {
+	// Decode the pending etxs retrieval message
+	var query GetPendingEtxsPacket66
+	if err := msg.Decode(&query); err != nil {
+		return fmt.Errorf("%w: message %v: %v", errDecode, msg, err)
+	}
+	pEtxs, err := backend.Core().Slice().HeaderChain().GetPendingEtxs(query.Hash)
+	if err != nil {
+		return nil
+	}
+	return peer.ReplyPendingEtxs(query.RequestId, *pEtxs)
+}
+
 func handleNewBlockhashes(backend Backend, msg Decoder, peer *Peer) error {
 	// A batch of new block announcements just arrived
 	ann := new(NewBlockHashesPacket)
@@ -243,6 +269,28 @@ func handleBlockBodies66(backend Backend, msg Decoder, peer *Peer) error {
 	return backend.Handle(peer, &res.BlockBodiesPacket)
 }
 
+func handleBlockManifest66(backend Backend, msg Decoder, peer *Peer) error {
+	// A manifest arrived to one of our previous requests
+	res := new(BlockManifestPacket66)
+	if err := msg.Decode(res); err != nil {
+		return fmt.Errorf("%w: message %v: %v", errDecode, msg, err)
+	}
+	requestTracker.Fulfil(peer.id, peer.version, BlockManifestMsg, res.RequestId)
+
+	return backend.Handle(peer, &res.BlockManifestPacket)
+}
+
+func handlePendingEtxs66(backend Backend, msg Decoder, peer *Peer) error {
+	// Pending etxs arrived to one of our previous requests
+	res := new(PendingEtxsPacket66)
+	if err := msg.Decode(res); err != nil {
+		return fmt.Errorf("%w: message %v: %v", errDecode, msg, err)
+	}
+	requestTracker.Fulfil(peer.id, peer.version, PendingEtxsMsg, res.RequestId)
+
+	return backend.Handle(peer, &res.PendingEtxsPacket)
+}
+
 func handleNewPooledTransactionHashes(backend Backend, msg Decoder, peer *Peer) error {
 	nodeCtx := common.NodeLocation.Context()
 	if nodeCtx != common.ZONE_CTX {
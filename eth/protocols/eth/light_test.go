@@ -0,0 +1,80 @@
+package eth
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/dominant-strategies/go-quai/common"
+	"github.com/dominant-strategies/go-quai/core/types"
+	"github.com/dominant-strategies/go-quai/rlp"
+)
+
+// Tests that the messages serving light chains are only handled by quai/101
+// peers and fit within its message space.
+func TestLightMessagesVersion(t *testing.T) {
+	for _, code := range []uint64{GetBlockManifestMsg, BlockManifestMsg, GetPendingEtxsMsg, PendingEtxsMsg} {
+		if _, ok := quai1[code]; ok {
+			t.Errorf("message %#x handled by quai/%d", code, QUAI1)
+		}
+		if code < protocolLengths[QUAI1] {
+			t.Errorf("message %#x within the message space of quai/%d", code, QUAI1)
+		}
+		if _, ok := quai2[code]; !ok {
+			t.Errorf("message %#x not handled by quai/%d", code, QUAI2)
+		}
+		if code >= protocolLengths[QUAI2] {
+			t.Errorf("message %#x outside the message space of quai/%d", code, QUAI2)
+		}
+	}
+	// Every quai/100 message is still handled by quai/101
+	for code := range quai1 {
+		if _, ok := quai2[code]; !ok {
+			t.Errorf("message %#x of quai/%d not handled by quai/%d", code, QUAI1, QUAI2)
+		}
+	}
+}
+
+// Tests that the messages serving light chains survive an encoding round trip.
+func TestLightMessagesEncoding(t *testing.T) {
+	hash := common.HexToHash("deadc0de")
+	header := types.EmptyHeader()
+	header.SetNumber(big.NewInt(3333))
+	header.SetExtra([]byte{0x77, 0x88})
+
+	to := common.HexToAddress("0x0000000000000000000000000000000000000001")
+	etx := types.NewTx(&types.ExternalTx{ChainID: big.NewInt(1), Nonce: 1, Gas: 21000, To: &to, Value: big.NewInt(1)})
+
+	for i, packet := range []interface{}{
+		&GetBlockManifestPacket66{1111, GetBlockManifestPacket{hash}},
+		&BlockManifestPacket66{1111, BlockManifestPacket{hash, types.BlockManifest{hash, header.Hash()}}},
+		&GetPendingEtxsPacket66{1111, GetPendingEtxsPacket{hash}},
+		&PendingEtxsPacket66{1111, PendingEtxsPacket{types.PendingEtxs{Header: header, Etxs: types.Transactions{etx}}}},
+	} {
+		have, err := rlp.EncodeToBytes(packet)
+		if err != nil {
+			t.Fatalf("packet %d: failed to encode: %v", i, err)
+		}
+		var decoded interface{}
+		switch packet.(type) {
+		case *GetBlockManifestPacket66:
+			decoded = new(GetBlockManifestPacket66)
+		case *BlockManifestPacket66:
+			decoded = new(BlockManifestPacket66)
+		case *GetPendingEtxsPacket66:
+			decoded = new(GetPendingEtxsPacket66)
+		case *PendingEtxsPacket66:
+			decoded = new(PendingEtxsPacket66)
+		}
+		if err := rlp.DecodeBytes(have, decoded); err != nil {
+			t.Fatalf("packet %d: failed to decode: %v", i, err)
+		}
+		want, err := rlp.EncodeToBytes(decoded)
+		if err != nil {
+			t.Fatalf("packet %d: failed to reencode: %v", i, err)
+		}
+		if !bytes.Equal(have, want) {
+			t.Errorf("packet %d: encoding mismatch: have %x, want %x", i, have, want)
+		}
+	}
+}
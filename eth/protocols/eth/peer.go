@@ -386,6 +386,25 @@ func (p *Peer) ReplyBlockBodiesRLP(id uint64, bodies []rlp.RawValue) error {
 	})
 }
 
+// ReplyBlockManifest is the eth/66 response to GetBlockManifest.
+func (p *Peer) ReplyBlockManifest(id uint64, hash common.Hash, manifest types.BlockManifest) error {
+	return p2p.Send(p.rw, BlockManifestMsg, BlockManifestPacket66{
+		RequestId: id,
+		BlockManifestPacket: BlockManifestPacket{
+			Hash:     hash,
+			Manifest: manifest,
+		},
+	})
+}
+
+// ReplyPendingEtxs is the eth/66 response to GetPendingEtxs.
+func (p *Peer) ReplyPendingEtxs(id uint64, pEtxs types.PendingEtxs) error {
+	return p2p.Send(p.rw, PendingEtxsMsg, PendingEtxsPacket66{
+		RequestId:         id,
+		PendingEtxsPacket: PendingEtxsPacket{PendingEtxs: pEtxs},
+	})
+}
+
 // RequestOneHeader is a wrapper around the header query functions to fetch a
 // single header. It is used solely by the fetcher.
 func (p *Peer) RequestOneHeader(hash common.Hash) error {
@@ -450,6 +469,31 @@ func (p *Peer) RequestBlockByHash(hash common.Hash) error {
 	return p2p.Send(p.rw, GetBlockMsg, &query)
 }
 
+// RequestBlockManifest fetches the manifest of the block with the given hash.
+func (p *Peer) RequestBlockManifest(hash common.Hash) error {
+	p.Log().Debug("Fetching a block manifest", "hash", hash)
+	id := rand.Uint64()
+
+	requestTracker.Track(p.id, p.version, GetBlockManifestMsg, BlockManifestMsg, id)
+	return p2p.Send(p.rw, GetBlockManifestMsg, &GetBlockManifestPacket66{
+		RequestId:              id,
+		GetBlockManifestPacket: GetBlockManifestPacket{Hash: hash},
+	})
+}
+
+// RequestPendingEtxs fetches the pending etxs emitted by the block with the
+// given hash.
+func (p *Peer) RequestPendingEtxs(hash common.Hash) error {
+	p.Log().Debug("Fetching pending etxs", "hash", hash)
+	id := rand.Uint64()
+
+	requestTracker.Track(p.id, p.version, GetPendingEtxsMsg, PendingEtxsMsg, id)
+	return p2p.Send(p.rw, GetPendingEtxsMsg, &GetPendingEtxsPacket66{
+		RequestId:            id,
+		GetPendingEtxsPacket: GetPendingEtxsPacket{Hash: hash},
+	})
+}
+
 // RequestHeadersByNumber fetches a batch of blocks' headers corresponding to the
 // specified header query, based on the number of an origin block.
 func (p *Peer) RequestHeadersByNumber(origin uint64, amount int, skip uint64, to uint64, dom bool, reverse bool) error {
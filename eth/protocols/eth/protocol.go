@@ -31,6 +31,7 @@ import (
 // Constants to match up protocol versions and messages
 const (
 	QUAI1 = 100
+	QUAI2 = 101
 )
 
 // ProtocolName is the official short name of the `quai` protocol used during
@@ -39,11 +40,11 @@ const c_ProtocolName = "quai"
 
 // ProtocolVersions are the supported versions of the `eth` protocol (first
 // is primary).
-var ProtocolVersions = []uint{QUAI1}
+var ProtocolVersions = []uint{QUAI2, QUAI1}
 
 // protocolLengths are the number of implemented message corresponding to
 // different protocol versions.
-var protocolLengths = map[uint]uint64{QUAI1: 12}
+var protocolLengths = map[uint]uint64{QUAI2: 16, QUAI1: 12}
 
 // maxMessageSize is the maximum cap on the size of a protocol message.
 const maxMessageSize = 10 * 1024 * 1024
@@ -65,6 +66,12 @@ const (
 	PooledTransactionsMsg         = 0x0a
 
 	GetBlockMsg = 0x0b
+
+	// Protocol messages in quai/101 serving light chains
+	GetBlockManifestMsg = 0x0c
+	BlockManifestMsg    = 0x0d
+	GetPendingEtxsMsg   = 0x0e
+	PendingEtxsMsg      = 0x0f
 )
 
 var (
@@ -290,6 +297,54 @@ type GetBlockPacket66 struct {
 	GetBlockPacket
 }
 
+// GetBlockManifestPacket is the network packet for fetching the manifest of a
+// block.
+type GetBlockManifestPacket struct {
+	Hash common.Hash
+}
+
+// GetBlockManifestPacket66 is the eth/66 version of the GetBlockManifestPacket.
+type GetBlockManifestPacket66 struct {
+	RequestId uint64
+	GetBlockManifestPacket
+}
+
+// BlockManifestPacket is the network packet carrying the manifest of a block.
+type BlockManifestPacket struct {
+	Hash     common.Hash
+	Manifest types.BlockManifest
+}
+
+// BlockManifestPacket66 is the eth/66 version of the BlockManifestPacket.
+type BlockManifestPacket66 struct {
+	RequestId uint64
+	BlockManifestPacket
+}
+
+// GetPendingEtxsPacket is the network packet for fetching the pending ETXs
+// emitted by a block.
+type GetPendingEtxsPacket struct {
+	Hash common.Hash
+}
+
+// GetPendingEtxsPacket66 is the eth/66 version of the GetPendingEtxsPacket.
+type GetPendingEtxsPacket66 struct {
+	RequestId uint64
+	GetPendingEtxsPacket
+}
+
+// PendingEtxsPacket is the network packet carrying the pending ETXs emitted by
+// a block, along with the header proving them.
+type PendingEtxsPacket struct {
+	PendingEtxs types.PendingEtxs
+}
+
+// PendingEtxsPacket66 is the eth/66 version of the PendingEtxsPacket.
+type PendingEtxsPacket66 struct {
+	RequestId uint64
+	PendingEtxsPacket
+}
+
 func (*StatusPacket) Name() string { return "Status" }
 func (*StatusPacket) Kind() byte   { return StatusMsg }
 
@@ -325,3 +380,15 @@ func (*PooledTransactionsPacket) Kind() byte   { return PooledTransactionsMsg }
 
 func (*GetBlockPacket) Name() string { return "GetBlock" }
 func (*GetBlockPacket) Kind() byte   { return GetBlockMsg }
+
+func (*GetBlockManifestPacket) Name() string { return "GetBlockManifest" }
+func (*GetBlockManifestPacket) Kind() byte   { return GetBlockManifestMsg }
+
+func (*BlockManifestPacket) Name() string { return "BlockManifest" }
+func (*BlockManifestPacket) Kind() byte   { return BlockManifestMsg }
+
+func (*GetPendingEtxsPacket) Name() string { return "GetPendingEtxs" }
+func (*GetPendingEtxsPacket) Kind() byte   { return GetPendingEtxsMsg }
+
+func (*PendingEtxsPacket) Name() string { return "PendingEtxs" }
+func (*PendingEtxsPacket) Kind() byte   { return PendingEtxsMsg }
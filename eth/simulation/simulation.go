@@ -0,0 +1,191 @@
+// Package simulation runs full Quai protocol stacks on top of a simulated p2p
+// network, so block and ETX propagation, missing block requests and downloader
+// recovery can be exercised on a single machine.
+//
+// All nodes of a simulation run the slice of common.NodeLocation.
+package simulation
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dominant-strategies/go-quai/common"
+	"github.com/dominant-strategies/go-quai/core"
+	"github.com/dominant-strategies/go-quai/core/types"
+	"github.com/dominant-strategies/go-quai/crypto"
+	"github.com/dominant-strategies/go-quai/eth"
+	"github.com/dominant-strategies/go-quai/eth/ethconfig"
+	"github.com/dominant-strategies/go-quai/node"
+	"github.com/dominant-strategies/go-quai/p2p"
+	"github.com/dominant-strategies/go-quai/p2p/enode"
+	"github.com/dominant-strategies/go-quai/p2p/simulations"
+)
+
+// Node is a Quai protocol stack which is part of a simulation.
+type Node struct {
+	*simulations.Node
+
+	Stack *node.Node
+	Quai  *eth.Quai
+}
+
+// Core returns the core of the node.
+func (n *Node) Core() *core.Core {
+	return n.Quai.Core()
+}
+
+// Head returns the hash of the current header of the node.
+func (n *Node) Head() common.Hash {
+	return n.Core().CurrentHeader().Hash()
+}
+
+// HasBlock reports whether the node has the block stored, either appended or
+// as a candidate.
+func (n *Node) HasBlock(hash common.Hash) bool {
+	return n.Core().GetBlockOrCandidateByHash(hash) != nil
+}
+
+// InsertBlocks inserts blocks into the node without propagating them.
+func (n *Node) InsertBlocks(blocks ...*types.Block) (int, error) {
+	return n.Core().InsertChain(blocks)
+}
+
+// Propagate writes a block into the node and hands it to the protocol handler
+// the same way a block received from the miner is.
+func (n *Node) Propagate(block *types.Block) error {
+	n.Core().WriteBlock(block)
+	return n.Quai.EventMux().Post(core.NewMinedBlockEvent{Block: block})
+}
+
+// Network is a set of Quai nodes connected through a simulated p2p network.
+type Network struct {
+	*simulations.Network
+
+	nodes []*Node
+}
+
+// NewNetwork starts the given number of nodes, every node is configured with a
+// copy of the config, running the slice of common.NodeLocation unless the
+// config says otherwise. The nodes keep their databases in memory and are not
+// connected to each other yet.
+func NewNetwork(nodes int, config *ethconfig.Config) (*Network, error) {
+	nw := &Network{Network: simulations.NewNetwork()}
+	for i := 0; i < nodes; i++ {
+		n, err := nw.newNode(fmt.Sprintf("node%d", i), config)
+		if err != nil {
+			nw.Shutdown()
+			return nil, err
+		}
+		nw.nodes = append(nw.nodes, n)
+	}
+	return nw, nil
+}
+
+// newNode creates and starts a single node.
+func (nw *Network) newNode(name string, config *ethconfig.Config) (*Node, error) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	stack, err := node.New(&node.Config{
+		Name: name,
+		P2P: p2p.Config{
+			PrivateKey: key,
+			MaxPeers:   25,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	simNode, err := nw.AddServer(stack.Server())
+	if err != nil {
+		stack.Close()
+		return nil, err
+	}
+	cfg := *config
+	if len(cfg.SlicesRunning) == 0 {
+		cfg.SlicesRunning = []common.Location{common.NodeLocation}
+	}
+	backend, err := eth.New(stack, &cfg)
+	if err != nil {
+		stack.Close()
+		return nil, err
+	}
+	if err := stack.Start(); err != nil {
+		stack.Close()
+		return nil, err
+	}
+	return &Node{Node: simNode, Stack: stack, Quai: backend}, nil
+}
+
+// Nodes returns the Quai nodes of the simulation.
+func (nw *Network) Nodes() []*Node {
+	return append([]*Node{}, nw.nodes...)
+}
+
+// Node returns the i-th node of the simulation.
+func (nw *Network) Node(i int) *Node {
+	return nw.nodes[i]
+}
+
+// NodeByID returns the node with the given ID, or nil if it isn't part of the
+// simulation.
+func (nw *Network) NodeByID(id enode.ID) *Node {
+	for _, n := range nw.nodes {
+		if n.ID() == id {
+			return n
+		}
+	}
+	return nil
+}
+
+// WaitBlock blocks until every node has the block stored or the context
+// expires.
+func (nw *Network) WaitBlock(ctx context.Context, hash common.Hash) error {
+	return nw.waitAll(ctx, func(n *Node) bool { return n.HasBlock(hash) })
+}
+
+// WaitHead blocks until every node has the given header as its head or the
+// context expires.
+func (nw *Network) WaitHead(ctx context.Context, hash common.Hash) error {
+	return nw.waitAll(ctx, func(n *Node) bool { return n.Head() == hash })
+}
+
+// WaitPendingEtxs blocks until every node has the pending ETXs of the block or
+// the context expires.
+func (nw *Network) WaitPendingEtxs(ctx context.Context, hash common.Hash) error {
+	return nw.waitAll(ctx, func(n *Node) bool { return n.Core().GetPendingEtxs(hash) != nil })
+}
+
+// waitAll polls a condition on every node until it holds for all of them.
+func (nw *Network) waitAll(ctx context.Context, cond func(n *Node) bool) error {
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		done := true
+		for _, n := range nw.nodes {
+			if !cond(n) {
+				done = false
+				break
+			}
+		}
+		if done {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Shutdown stops every node and the network.
+func (nw *Network) Shutdown() {
+	for _, n := range nw.nodes {
+		n.Stack.Close()
+	}
+	nw.Network.Shutdown()
+}
@@ -0,0 +1,170 @@
+package simulation
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/dominant-strategies/go-quai/common"
+	"github.com/dominant-strategies/go-quai/consensus/progpow"
+	"github.com/dominant-strategies/go-quai/core"
+	"github.com/dominant-strategies/go-quai/core/types"
+	"github.com/dominant-strategies/go-quai/eth/ethconfig"
+	"github.com/dominant-strategies/go-quai/p2p/enode"
+	"github.com/dominant-strategies/go-quai/trie"
+)
+
+// newTestNetwork starts a network of standalone prime nodes running the local
+// genesis with a fake engine, connected in a chain.
+func newTestNetwork(t *testing.T, nodes int) *Network {
+	t.Helper()
+	config := ethconfig.Defaults
+	config.Genesis = core.DefaultLocalGenesisBlock("progpow")
+	config.Progpow.PowMode = progpow.ModeFake
+	config.Miner.ExtraData = []byte("simulation")
+	config.DomUrl = ""
+	config.SubUrls = nil
+	config.DatabaseCache = 16
+	config.TrieCleanCache = 16
+	config.TrieDirtyCache = 16
+	config.SnapshotCache = 0
+
+	nw, err := NewNetwork(nodes, &config)
+	if err != nil {
+		t.Fatalf("failed to create network: %v", err)
+	}
+	t.Cleanup(nw.Shutdown)
+	if err := nw.ConnectChain(); err != nil {
+		t.Fatalf("failed to connect network: %v", err)
+	}
+	for i, n := range nw.Nodes() {
+		peers := 2
+		if i == 0 || i == nodes-1 {
+			peers = 1
+		}
+		waitPeers(t, n, peers)
+	}
+	return nw
+}
+
+// waitPeers waits until a node has completed the quai handshake with at least
+// the given number of peers.
+func waitPeers(t *testing.T, n *Node, peers int) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for n.Quai.Downloader().PeerSet().Len() < peers {
+		select {
+		case <-ctx.Done():
+			t.Fatalf("node %v: peers not connected: have %d, want %d", n.ID(), n.Quai.Downloader().PeerSet().Len(), peers)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+// makeBlock creates a block on top of the current head of the node, with a
+// sub manifest only referencing the parent. A standalone prime node has no zone
+// to fill in the difficulty and location, so the block is placed in the first
+// zone with the difficulty of the head.
+func makeBlock(t *testing.T, n *Node) *types.Block {
+	t.Helper()
+	header, err := n.Core().GetPendingHeader()
+	if err != nil {
+		t.Fatalf("failed to get pending header: %v", err)
+	}
+	header.SetDifficulty(n.Core().CurrentHeader().Difficulty())
+	header.SetLocation(common.Location{0, 0})
+	manifest := types.BlockManifest{header.ParentHash(common.REGION_CTX)}
+	header.SetManifestHash(types.DeriveSha(manifest, trie.NewStackTrie(nil)), common.REGION_CTX)
+	return types.NewBlockWithHeader(header).WithBody(nil, nil, nil, manifest)
+}
+
+// requestEntropy is the entropy missing blocks are requested with. Blocks are
+// only requested from peers whose head has more entropy than the request, and
+// the heads of the nodes never move past the genesis, so it has to be below the
+// entropy of the genesis.
+var requestEntropy = big.NewInt(-1)
+
+// waitBlock waits until every node of the network has the block stored.
+func waitBlock(t *testing.T, nw *Network, hash common.Hash) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := nw.WaitBlock(ctx, hash); err != nil {
+		for i, n := range nw.Nodes() {
+			t.Logf("node%d has block: %v", i, n.HasBlock(hash))
+		}
+		t.Fatalf("block %x not propagated: %v", hash, err)
+	}
+}
+
+// Tests that a block handed to one end of a chain of nodes is relayed to all
+// of them.
+func TestBlockPropagation(t *testing.T) {
+	nw := newTestNetwork(t, 3)
+
+	block := makeBlock(t, nw.Node(0))
+	if err := nw.Node(0).Propagate(block); err != nil {
+		t.Fatalf("failed to propagate block: %v", err)
+	}
+	waitBlock(t, nw, block.Hash())
+}
+
+// Tests that a block a node is missing is requested from its peers through the
+// missing block loop.
+func TestMissingBlockRequest(t *testing.T) {
+	nw := newTestNetwork(t, 2)
+
+	// Only store the block on the first node without announcing it
+	block := makeBlock(t, nw.Node(0))
+	nw.Node(0).Core().WriteBlock(block)
+	if !nw.Node(0).HasBlock(block.Hash()) {
+		t.Fatalf("block not stored")
+	}
+	if nw.Node(1).HasBlock(block.Hash()) {
+		t.Fatalf("block known before it was requested")
+	}
+	nw.Node(1).Core().RequestDomToAppendOrFetch(block.Hash(), requestEntropy, common.ZONE_CTX)
+	waitBlock(t, nw, block.Hash())
+}
+
+// Tests that a node which missed a block while it was partitioned from the
+// network recovers it once the partition heals.
+func TestPartitionRecovery(t *testing.T) {
+	nw := newTestNetwork(t, 3)
+	first, last := nw.Node(0), nw.Node(2)
+
+	nw.Partition([]enode.ID{first.ID(), nw.Node(1).ID()}, []enode.ID{last.ID()})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for last.Server.PeerCount() != 0 {
+		select {
+		case <-ctx.Done():
+			t.Fatalf("partitioned node still has %d peers", last.Server.PeerCount())
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+	block := makeBlock(t, first)
+	if err := first.Propagate(block); err != nil {
+		t.Fatalf("failed to propagate block: %v", err)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for !nw.Node(1).HasBlock(block.Hash()) {
+		select {
+		case <-ctx.Done():
+			t.Fatalf("block not propagated within the partition")
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+	if last.HasBlock(block.Hash()) {
+		t.Fatalf("block propagated across the partition")
+	}
+
+	// Once healed, the node missing the block asks its peers for it
+	nw.Heal()
+	waitPeers(t, last, 1)
+	last.Core().RequestDomToAppendOrFetch(block.Hash(), requestEntropy, common.ZONE_CTX)
+	waitBlock(t, nw, block.Hash())
+}
@@ -255,7 +255,7 @@ func peerToSyncOp(mode downloader.SyncMode, p *eth.Peer) *chainSyncOp {
 }
 
 func (cs *chainSyncer) modeAndLocalHead() (downloader.SyncMode, *big.Int) {
-	return downloader.FullSync, cs.handler.downloader.HeadEntropy()
+	return cs.handler.syncMode, cs.handler.downloader.HeadEntropy()
 }
 
 // startSync launches doSync in a new goroutine.
@@ -268,7 +268,7 @@ func (cs *chainSyncer) startSync(op *chainSyncOp) {
 func (h *handler) doSync(op *chainSyncOp) error {
 	// Stopping the downloader here temporarily for Region and Zones
 	nodeCtx := common.NodeLocation.Context()
-	if nodeCtx == common.PRIME_CTX {
+	if nodeCtx == common.PRIME_CTX || op.mode == downloader.LightSync {
 		// Run the sync cycle, and disable fast sync if we're past the pivot block
 		err := h.downloader.Synchronise(op.peer.ID(), op.head, op.entropy, op.mode)
 		log.Info("Downloader exited", "err", err)
@@ -277,6 +277,9 @@ func (h *handler) doSync(op *chainSyncOp) error {
 		}
 		// If we've successfully finished a sync cycle and passed any required checkpoint,
 		// enable accepting transactions from the network.
+		if op.mode == downloader.LightSync {
+			return nil
+		}
 		head := h.core.CurrentBlock()
 		if head == nil {
 			log.Warn("doSync: head is nil", "hash", h.core.CurrentHeader().Hash(), "number", h.core.CurrentHeader().NumberArray())
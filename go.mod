@@ -26,6 +26,7 @@ require (
 	github.com/influxdata/influxdb v1.8.3
 	github.com/jackpal/go-nat-pmp v1.0.2
 	github.com/jedisct1/go-minisign v0.0.0-20190909160543-45766022959e
+	github.com/kilic/bls12-381 v0.1.0
 	github.com/ledgerwatch/secp256k1 v1.0.0
 	github.com/naoina/toml v0.1.2-0.20170918210437-9fafd6967416
 	github.com/natefinch/lumberjack v2.0.0+incompatible
@@ -1389,6 +1389,9 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 	bigblock := new(big.Int).SetUint64(blockNumber)
 	signer := types.MakeSigner(s.b.ChainConfig(), bigblock)
 	from, _ := types.Sender(signer, tx)
+	if tx.Type() == types.ExternalTxType {
+		from = tx.ETXSender()
+	}
 
 	fields := map[string]interface{}{
 		"blockHash":         blockHash,
@@ -1426,6 +1429,14 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 	if !receipt.ContractAddress.Equal(common.ZeroAddr) {
 		fields["contractAddress"] = receipt.ContractAddress
 	}
+	// Executed ETXs point back to the transaction which emitted them
+	if receipt.EtxOrigin != nil {
+		fields["etxOrigin"] = receipt.EtxOrigin
+	}
+	// Executed ETXs refund their residual balance to the sender
+	if receipt.Refund != nil {
+		fields["refund"] = receipt.Refund
+	}
 	return fields, nil
 }
 
@@ -1515,6 +1526,111 @@ func (api *PublicDebugAPI) SeedHash(ctx context.Context, number uint64) (string,
 	return fmt.Sprintf("0x%x", progpow.SeedHash(number)), nil
 }
 
+// errNoExternalSigner is returned by the signing APIs if the node runs without
+// an external signer.
+var errNoExternalSigner = errors.New("no external signer configured")
+
+// PrivateAccountAPI exposes the accounts of the external signer and the
+// methods having it sign transactions. It is only served to the namespaces an
+// operator enables explicitly.
+type PrivateAccountAPI struct {
+	b         Backend
+	nonceLock *AddrLocker
+}
+
+// NewPrivateAccountAPI creates a new RPC service with methods signing through
+// the external signer.
+func NewPrivateAccountAPI(b Backend, nonceLock *AddrLocker) *PrivateAccountAPI {
+	return &PrivateAccountAPI{b, nonceLock}
+}
+
+// Accounts returns the addresses the external signer holds keys for.
+func (s *PrivateAccountAPI) Accounts(ctx context.Context) ([]common.Address, error) {
+	extSigner := s.b.ExternalSigner()
+	if extSigner == nil {
+		return nil, errNoExternalSigner
+	}
+	return extSigner.Accounts(ctx)
+}
+
+// SendTransaction creates a transaction for the given argument, has it signed
+// by the external signer and submits it to the transaction pool.
+func (s *PrivateAccountAPI) SendTransaction(ctx context.Context, args TransactionArgs) (common.Hash, error) {
+	if args.From == nil {
+		return common.Hash{}, errors.New("missing from address")
+	}
+	if args.Nonce == nil {
+		// Hold the address's mutex around signing to prevent concurrent assignment of
+		// the same nonce to multiple transactions.
+		s.nonceLock.LockAddr(args.from())
+		defer s.nonceLock.UnlockAddr(args.from())
+	}
+	tx, err := s.sign(ctx, args)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return SubmitTransaction(ctx, s.b, tx)
+}
+
+// SignTransactionResult represents a RLP encoded signed transaction.
+type SignTransactionResult struct {
+	Raw hexutil.Bytes      `json:"raw"`
+	Tx  *types.Transaction `json:"tx"`
+}
+
+// SignTransaction has the external signer sign the given transaction without
+// submitting it. The gas, fees and nonce must be set, so the transaction can
+// be submitted later through SendRawTransaction.
+func (s *PrivateAccountAPI) SignTransaction(ctx context.Context, args TransactionArgs) (*SignTransactionResult, error) {
+	if args.From == nil {
+		return nil, errors.New("missing from address")
+	}
+	if args.Gas == nil {
+		return nil, errors.New("gas not specified")
+	}
+	if args.GasPrice == nil && (args.MaxPriorityFeePerGas == nil || args.MaxFeePerGas == nil) {
+		return nil, errors.New("missing gasPrice or maxFeePerGas/maxPriorityFeePerGas")
+	}
+	if args.Nonce == nil {
+		return nil, errors.New("nonce not specified")
+	}
+	tx, err := s.sign(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkTxFee(tx.GasPrice(), tx.Gas(), s.b.RPCTxFeeCap()); err != nil {
+		return nil, err
+	}
+	data, err := tx.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return &SignTransactionResult{data, tx}, nil
+}
+
+// sign fills in the missing fields of the transaction and has it signed by the
+// external signer for the chain of this node.
+func (s *PrivateAccountAPI) sign(ctx context.Context, args TransactionArgs) (*types.Transaction, error) {
+	if common.NodeLocation.Context() != common.ZONE_CTX {
+		return nil, errors.New("transactions can only be signed in zone chain")
+	}
+	extSigner := s.b.ExternalSigner()
+	if extSigner == nil {
+		return nil, errNoExternalSigner
+	}
+	chainID := s.b.ChainConfig().ChainID
+	if args.ChainID != nil && args.ChainID.ToInt().Cmp(chainID) != 0 {
+		return nil, fmt.Errorf("chainId does not match node's (have=%v, want=%v)", args.ChainID, chainID)
+	}
+	if err := args.setDefaults(ctx, s.b); err != nil {
+		return nil, err
+	}
+	if err := args.setETXDefaults(); err != nil {
+		return nil, err
+	}
+	return extSigner.SignTransaction(ctx, args.toSendTxArgs(chainID, common.NodeLocation))
+}
+
 // PrivateDebugAPI is the collection of Quai APIs exposed over the private
 // debugging endpoint.
 type PrivateDebugAPI struct {
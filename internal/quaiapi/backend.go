@@ -26,6 +26,7 @@ import (
 	"github.com/dominant-strategies/go-quai/consensus"
 	"github.com/dominant-strategies/go-quai/core"
 	"github.com/dominant-strategies/go-quai/core/bloombits"
+	"github.com/dominant-strategies/go-quai/core/rawdb"
 	"github.com/dominant-strategies/go-quai/core/state"
 	"github.com/dominant-strategies/go-quai/core/types"
 	"github.com/dominant-strategies/go-quai/core/vm"
@@ -34,6 +35,7 @@ import (
 	"github.com/dominant-strategies/go-quai/event"
 	"github.com/dominant-strategies/go-quai/params"
 	"github.com/dominant-strategies/go-quai/rpc"
+	"github.com/dominant-strategies/go-quai/signer"
 )
 
 // Backend interface provides the common API services (that are provided by
@@ -49,8 +51,9 @@ type Backend interface {
 	FeeHistory(ctx context.Context, blockCount int, lastBlock rpc.BlockNumber, rewardPercentiles []float64) (*big.Int, [][]*big.Int, []*big.Int, []float64, error)
 	ChainDb() ethdb.Database
 	ExtRPCEnabled() bool
-	RPCGasCap() uint64    // global gas cap for eth_call over rpc: DoS protection
-	RPCTxFeeCap() float64 // global tx fee cap for all transaction related APIs
+	RPCGasCap() uint64                      // global gas cap for eth_call over rpc: DoS protection
+	RPCTxFeeCap() float64                   // global tx fee cap for all transaction related APIs
+	ExternalSigner() *signer.ExternalSigner // signer of the transaction signing APIs, nil if not configured
 
 	// Blockchain API
 	HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error)
@@ -82,7 +85,9 @@ type Backend interface {
 	RequestDomToAppendOrFetch(hash common.Hash, entropy *big.Int, order int)
 	NewGenesisPendingHeader(pendingHeader *types.Header)
 	GetPendingHeader() (*types.Header, error)
+	PendingHeaders() (map[common.Hash]types.PendingHeader, common.Hash)
 	GetManifest(blockHash common.Hash) (types.BlockManifest, error)
+	GetInboundEtxs(blockHash common.Hash) (types.Transactions, error)
 	GetSubManifest(slice common.Location, blockHash common.Hash) (types.BlockManifest, error)
 	AddPendingEtxs(pEtxs types.PendingEtxs) error
 	AddPendingEtxsRollup(pEtxsRollup types.PendingEtxsRollup) error
@@ -90,6 +95,9 @@ type Backend interface {
 	GenerateRecoveryPendingHeader(pendingHeader *types.Header, checkpointHashes types.Termini) error
 	GetPendingEtxsRollupFromSub(hash common.Hash, location common.Location) (types.PendingEtxsRollup, error)
 	GetPendingEtxsFromSub(hash common.Hash, location common.Location) (types.PendingEtxs, error)
+	GetEtxSet(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (types.EtxSet, *types.Header, error)
+	GetExpiredEtx(hash common.Hash) *types.ExpiredEtx
+	GetAddressActivity(ctx context.Context, address common.Address, number uint64, seq uint32, limit int) ([]rawdb.AddressActivity, *uint64, *uint32, uint64, error)
 	SetSyncTarget(header *types.Header)
 	ProcessingState() bool
 
@@ -112,6 +120,10 @@ type Backend interface {
 	SubscribePendingLogsEvent(ch chan<- []*types.Log) event.Subscription
 	SubscribeRemovedLogsEvent(ch chan<- core.RemovedLogsEvent) event.Subscription
 	SubscribePendingHeaderEvent(ch chan<- *types.Header) event.Subscription
+	SubscribeExpiredEtxsEvent(ch chan<- core.ExpiredEtxsEvent) event.Subscription
+	SubscribeInboundEtxsEvent(ch chan<- core.InboundEtxsEvent) event.Subscription
+	SubscribeCoincidentBlockEvent(ch chan<- core.CoincidentBlockEvent) event.Subscription
+	SubscribeSubReorgEvent(ch chan<- core.SubReorgEvent) event.Subscription
 
 	ChainConfig() *params.ChainConfig
 	Engine() consensus.Engine
@@ -165,6 +177,11 @@ func GetAPIs(apiBackend Backend) []rpc.API {
 			Service:   NewPublicTransactionPoolAPI(apiBackend, nonceLock),
 			Public:    true,
 		})
+		apis = append(apis, rpc.API{
+			Namespace: "personal",
+			Version:   "1.0",
+			Service:   NewPrivateAccountAPI(apiBackend, nonceLock),
+		})
 		apis = append(apis, rpc.API{
 			Namespace: "txpool",
 			Version:   "1.0",
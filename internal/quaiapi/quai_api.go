@@ -17,15 +17,21 @@
 package quaiapi
 
 import (
+	"bytes"
 	"context"
+	"encoding/binary"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"math/big"
+	"sort"
 	"time"
 
 	"github.com/dominant-strategies/go-quai/common"
 	"github.com/dominant-strategies/go-quai/common/hexutil"
+	"github.com/dominant-strategies/go-quai/consensus/misc"
 	"github.com/dominant-strategies/go-quai/core"
+	"github.com/dominant-strategies/go-quai/core/rawdb"
 	"github.com/dominant-strategies/go-quai/core/types"
 	"github.com/dominant-strategies/go-quai/crypto"
 	"github.com/dominant-strategies/go-quai/log"
@@ -432,6 +438,70 @@ func (s *PublicBlockChainQuaiAPI) EstimateGas(ctx context.Context, args Transact
 	return DoEstimateGas(ctx, s.b, args, bNrOrHash, s.b.RPCGasCap())
 }
 
+// GetBlockReward returns the rewards credited when finalizing the given block,
+// along with the parameters of the emission schedule used to compute them.
+// Under an emission schedule the block reward is credited to the parent of the
+// block for its order, as the order of a block is unknown until it is sealed.
+func (s *PublicBlockChainQuaiAPI) GetBlockReward(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (map[string]interface{}, error) {
+	nodeCtx := common.NodeLocation.Context()
+	if nodeCtx != common.ZONE_CTX {
+		return nil, errors.New("getBlockReward can only be called in a zone chain")
+	}
+	block, err := s.b.BlockByNumberOrHash(ctx, blockNrOrHash)
+	if block == nil || err != nil {
+		return nil, err
+	}
+	// The legacy schedule rewards the block itself and needs no parent
+	config := s.b.ChainConfig()
+	var (
+		parent      *types.Header
+		parentOrder = common.ZONE_CTX
+	)
+	if config.Reward != nil {
+		if parent, err = s.b.HeaderByHash(ctx, block.ParentHash()); parent == nil || err != nil {
+			return nil, err
+		}
+		if _, parentOrder, err = s.b.CalcOrder(parent); err != nil {
+			return nil, err
+		}
+	}
+	// Uncles whose seal does not verify are not rewarded, as when finalizing
+	uncleOrders := misc.UncleOrders(config, block.Header(), block.Uncles(), s.b.CalcOrder)
+	rewards := misc.CalculateRewards(config, block.Header(), parent, parentOrder, block.Uncles(), uncleOrders)
+
+	uncles := make([]map[string]interface{}, len(rewards.Uncles))
+	for i, uncle := range rewards.Uncles {
+		uncles[i] = map[string]interface{}{
+			"hash":     uncle.Hash,
+			"number":   (*hexutil.Big)(block.Uncles()[i].Number()),
+			"coinbase": uncle.Coinbase,
+			"order":    uncle.Order,
+			"reward":   (*hexutil.Big)(uncle.Reward),
+		}
+	}
+	fields := map[string]interface{}{
+		"hash":             block.Hash(),
+		"number":           (*hexutil.Big)(block.Number()),
+		"coinbase":         block.Coinbase(),
+		"rewardedHash":     rewards.Hash,
+		"rewardedCoinbase": rewards.Coinbase,
+		"order":            rewards.Order,
+		"era":              hexutil.Uint64(rewards.Era),
+		"baseReward":       (*hexutil.Big)(rewards.BaseReward),
+		"blockReward":      (*hexutil.Big)(rewards.BlockReward),
+		"uncles":           uncles,
+		"nephewReward":     (*hexutil.Big)(rewards.NephewReward),
+	}
+	if config.Reward == nil {
+		// The legacy testnet schedule scales the reward with the block difficulty
+		fields["schedule"] = "legacy"
+		fields["difficulty"] = (*hexutil.Big)(block.Difficulty())
+	} else {
+		fields["schedule"] = config.Reward
+	}
+	return fields, nil
+}
+
 // RPCMarshalBlock converts the given block to the RPC output which depends on fullTx. If inclTx is true transactions are
 // returned. When fullTx is true the returned block contains full transaction details, otherwise it will only contain
 // transaction hashes.
@@ -738,6 +808,169 @@ func (s *PublicBlockChainQuaiAPI) GetPendingHeader(ctx context.Context) (map[str
 	return marshaledPh, nil
 }
 
+// GetEtxSet returns the inbound ETXs available to be mined on top of the given
+// block, ordered by the height at which they became available, along with the
+// number of blocks remaining before each of them expires.
+func (s *PublicBlockChainQuaiAPI) GetEtxSet(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) ([]map[string]interface{}, error) {
+	etxSet, header, err := s.b.GetEtxSet(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	hashes := make([]common.Hash, 0, len(etxSet))
+	for hash := range etxSet {
+		hashes = append(hashes, hash)
+	}
+	sort.Slice(hashes, func(i, j int) bool {
+		if etxSet[hashes[i]].Height != etxSet[hashes[j]].Height {
+			return etxSet[hashes[i]].Height < etxSet[hashes[j]].Height
+		}
+		return bytes.Compare(hashes[i][:], hashes[j][:]) < 0
+	})
+
+	result := make([]map[string]interface{}, 0, len(hashes))
+	for _, hash := range hashes {
+		entry := etxSet[hash]
+		var remaining uint64
+		if expiration := entry.ExpirationHeight(); expiration > header.NumberU64() {
+			remaining = expiration - header.NumberU64()
+		}
+		result = append(result, map[string]interface{}{
+			"hash":             hash,
+			"availableAt":      hexutil.Uint64(entry.Height),
+			"expirationHeight": hexutil.Uint64(entry.ExpirationHeight()),
+			"remainingBlocks":  hexutil.Uint64(remaining),
+			"etx":              newRPCTransaction(&entry.ETX, common.Hash{}, 0, 0, nil),
+		})
+	}
+	return result, nil
+}
+
+// GetExpiredEtx returns the record of an ETX which expired from the EtxSet
+// without being mined, or nil if the ETX is not known to have expired.
+func (s *PublicBlockChainQuaiAPI) GetExpiredEtx(ctx context.Context, hash common.Hash) (map[string]interface{}, error) {
+	expiredEtx := s.b.GetExpiredEtx(hash)
+	if expiredEtx == nil {
+		return nil, nil
+	}
+	return expiredEtx.RPCMarshalExpiredEtx(), nil
+}
+
+const (
+	defaultAddressHistoryLimit = 100  // Number of entries returned by quai_getAddressHistory by default
+	maxAddressHistoryLimit     = 1000 // Maximum number of entries returned by quai_getAddressHistory
+)
+
+// AddressHistoryArgs selects a page of the activity of an address. Cursor is
+// the next value returned by the previous page and takes precedence over
+// FromBlock.
+type AddressHistoryArgs struct {
+	FromBlock *hexutil.Uint64 `json:"fromBlock"`
+	Cursor    *hexutil.Bytes  `json:"cursor"`
+	Limit     *hexutil.Uint64 `json:"limit"`
+}
+
+// GetAddressHistory returns a page of the transactions, emitted ETXs and
+// received ETXs touching an address, from the oldest to the newest.
+func (s *PublicBlockChainQuaiAPI) GetAddressHistory(ctx context.Context, address common.Address, args *AddressHistoryArgs) (map[string]interface{}, error) {
+	if common.NodeLocation.Context() != common.ZONE_CTX {
+		return nil, errors.New("getAddressHistory call can only be made in zone chain")
+	}
+	var (
+		number uint64
+		seq    uint32
+		limit  = defaultAddressHistoryLimit
+	)
+	if args != nil {
+		if args.FromBlock != nil {
+			number = uint64(*args.FromBlock)
+		}
+		if args.Cursor != nil {
+			if len(*args.Cursor) != 12 {
+				return nil, errors.New("invalid cursor")
+			}
+			number = binary.BigEndian.Uint64((*args.Cursor)[:8])
+			seq = binary.BigEndian.Uint32((*args.Cursor)[8:])
+		}
+		if args.Limit != nil {
+			if *args.Limit == 0 || *args.Limit > maxAddressHistoryLimit {
+				return nil, fmt.Errorf("limit must be between 1 and %d", maxAddressHistoryLimit)
+			}
+			limit = int(*args.Limit)
+		}
+	}
+	activity, nextNumber, nextSeq, indexed, err := s.b.GetAddressActivity(ctx, address, number, seq, limit)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]map[string]interface{}, len(activity))
+	for i, entry := range activity {
+		entries[i] = map[string]interface{}{
+			"kind":             addressActivityKinds[entry.Kind],
+			"blockNumber":      hexutil.Uint64(entry.BlockNumber),
+			"blockHash":        entry.BlockHash,
+			"hash":             entry.TxHash,
+			"transactionIndex": hexutil.Uint64(entry.TxIndex),
+		}
+	}
+	result := map[string]interface{}{
+		"address":       address,
+		"activity":      entries,
+		"indexedBlocks": hexutil.Uint64(indexed),
+		"next":          nil,
+	}
+	if nextNumber != nil {
+		cursor := make(hexutil.Bytes, 12)
+		binary.BigEndian.PutUint64(cursor[:8], *nextNumber)
+		binary.BigEndian.PutUint32(cursor[8:], *nextSeq)
+		result["next"] = cursor
+	}
+	return result, nil
+}
+
+// addressActivityKinds names the kinds of entries of the address activity index.
+var addressActivityKinds = map[uint8]string{
+	rawdb.AddressActivityTx:          "transaction",
+	rawdb.AddressActivityEtxEmitted:  "etxEmitted",
+	rawdb.AddressActivityEtxReceived: "etxReceived",
+}
+
+// GetPendingHeaders returns the pending headers held in the pending header
+// cache along with their termini and entropy, ordered by number.
+func (s *PublicBlockChainQuaiAPI) GetPendingHeaders(ctx context.Context) ([]map[string]interface{}, error) {
+	nodeCtx := common.NodeLocation.Context()
+	pendingHeaders, bestPhKey := s.b.PendingHeaders()
+	keys := make([]common.Hash, 0, len(pendingHeaders))
+	for key := range pendingHeaders {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		ni, nj := pendingHeaders[keys[i]].Header().Number(nodeCtx), pendingHeaders[keys[j]].Header().Number(nodeCtx)
+		if cmp := ni.Cmp(nj); cmp != 0 {
+			return cmp < 0
+		}
+		return bytes.Compare(keys[i][:], keys[j][:]) < 0
+	})
+
+	result := make([]map[string]interface{}, 0, len(keys))
+	for _, key := range keys {
+		ph := pendingHeaders[key]
+		entropy := make([]*hexutil.Big, common.HierarchyDepth)
+		for i := range entropy {
+			entropy[i] = (*hexutil.Big)(ph.Header().ParentEntropy(i))
+		}
+		result = append(result, map[string]interface{}{
+			"terminus":      key,
+			"best":          key == bestPhKey,
+			"number":        (*hexutil.Big)(ph.Header().Number(nodeCtx)),
+			"parentEntropy": entropy,
+			"phEntropy":     (*hexutil.Big)(s.b.Engine().TotalLogPhS(ph.Header())),
+			"termini":       ph.Termini().RPCMarshalTermini(),
+			"header":        ph.Header().RPCMarshalHeader(),
+		})
+	}
+	return result, nil
+}
+
 func (s *PublicBlockChainQuaiAPI) GetManifest(ctx context.Context, raw json.RawMessage) (types.BlockManifest, error) {
 	var blockHash common.Hash
 	if err := json.Unmarshal(raw, &blockHash); err != nil {
@@ -750,6 +983,16 @@ func (s *PublicBlockChainQuaiAPI) GetManifest(ctx context.Context, raw json.RawM
 	return manifest, nil
 }
 
+// GetInboundEtxs returns the ETXs the block with the given hash handed down to
+// its subordinate chains.
+func (s *PublicBlockChainQuaiAPI) GetInboundEtxs(ctx context.Context, raw json.RawMessage) (types.Transactions, error) {
+	var blockHash common.Hash
+	if err := json.Unmarshal(raw, &blockHash); err != nil {
+		return nil, err
+	}
+	return s.b.GetInboundEtxs(blockHash)
+}
+
 type SendPendingEtxsToDomArgs struct {
 	Header         types.Header         `json:"header"`
 	NewPendingEtxs []types.Transactions `json:"newPendingEtxs"`
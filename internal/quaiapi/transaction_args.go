@@ -28,7 +28,9 @@ import (
 	"github.com/dominant-strategies/go-quai/common/math"
 	"github.com/dominant-strategies/go-quai/core/types"
 	"github.com/dominant-strategies/go-quai/log"
+	"github.com/dominant-strategies/go-quai/params"
 	"github.com/dominant-strategies/go-quai/rpc"
+	"github.com/dominant-strategies/go-quai/signer"
 )
 
 // TransactionArgs represents the arguments to construct a new transaction
@@ -51,6 +53,13 @@ type TransactionArgs struct {
 	// Introduced by AccessListTxType transaction.
 	AccessList *types.AccessList `json:"accessList,omitempty"`
 	ChainID    *hexutil.Big      `json:"chainId,omitempty"`
+
+	// Introduced by InternalToExternalTxType transaction.
+	ETXGasLimit   *hexutil.Uint64   `json:"etxGasLimit,omitempty"`
+	ETXGasPrice   *hexutil.Big      `json:"etxGasPrice,omitempty"`
+	ETXGasTip     *hexutil.Big      `json:"etxGasTip,omitempty"`
+	ETXData       *hexutil.Bytes    `json:"etxData,omitempty"`
+	ETXAccessList *types.AccessList `json:"etxAccessList,omitempty"`
 }
 
 // from retrieves the transaction sender address.
@@ -168,6 +177,76 @@ func (args *TransactionArgs) setDefaults(ctx context.Context, b Backend) error {
 	return nil
 }
 
+// isExternal reports whether the transaction sends to another chain and thus
+// has to be an InternalToExternalTx.
+func (args *TransactionArgs) isExternal() bool {
+	return args.To != nil && !common.NodeLocation.ContainsAddress(*args.To)
+}
+
+// gasFees returns the fee cap and tip of the transaction, converting a legacy
+// gas price into both.
+func (args *TransactionArgs) gasFees() (*hexutil.Big, *hexutil.Big) {
+	if args.GasPrice != nil {
+		return args.GasPrice, args.GasPrice
+	}
+	return args.MaxFeePerGas, args.MaxPriorityFeePerGas
+}
+
+// setETXDefaults fills in default values for the ETX fields of transactions to
+// other chains. The ETX pays the same fees as the originating transaction and
+// gets enough gas for a value transfer. It must be called after setDefaults.
+func (args *TransactionArgs) setETXDefaults() error {
+	if !args.isExternal() {
+		if args.ETXGasLimit != nil || args.ETXGasPrice != nil || args.ETXGasTip != nil || args.ETXData != nil || args.ETXAccessList != nil {
+			return errors.New("etx fields are only allowed for transactions to other chains")
+		}
+		return nil
+	}
+	feeCap, tip := args.gasFees()
+	if args.ETXGasLimit == nil {
+		gas := hexutil.Uint64(params.TxGas)
+		args.ETXGasLimit = &gas
+	}
+	if args.ETXGasPrice == nil {
+		args.ETXGasPrice = feeCap
+	}
+	if args.ETXGasTip == nil {
+		args.ETXGasTip = tip
+	}
+	return nil
+}
+
+// toSendTxArgs converts the fully populated transaction arguments into a
+// request for the external signer, bound to the given chain.
+func (args *TransactionArgs) toSendTxArgs(chainID *big.Int, location common.Location) *signer.SendTxArgs {
+	feeCap, tip := args.gasFees()
+	sendArgs := &signer.SendTxArgs{
+		Type:                 types.InternalTxType,
+		From:                 args.from(),
+		To:                   args.To,
+		ChainID:              (*hexutil.Big)(chainID),
+		Location:             hexutil.Bytes(location),
+		Nonce:                *args.Nonce,
+		Gas:                  *args.Gas,
+		MaxFeePerGas:         feeCap,
+		MaxPriorityFeePerGas: tip,
+		Value:                *args.Value,
+		Input:                args.data(),
+		AccessList:           args.AccessList,
+	}
+	if args.isExternal() {
+		sendArgs.Type = types.InternalToExternalTxType
+		sendArgs.ETXGasLimit = *args.ETXGasLimit
+		sendArgs.ETXGasPrice = args.ETXGasPrice
+		sendArgs.ETXGasTip = args.ETXGasTip
+		if args.ETXData != nil {
+			sendArgs.ETXData = *args.ETXData
+		}
+		sendArgs.ETXAccessList = args.ETXAccessList
+	}
+	return sendArgs
+}
+
 // ToMessage converts th transaction arguments to the Message type used by the
 // core evm. This method is used in calls and traces that do not require a real
 // live transaction.
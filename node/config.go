@@ -127,6 +127,11 @@ type Config struct {
 	// HTTPPathPrefix specifies a path prefix on which http-rpc is to be served.
 	HTTPPathPrefix string `toml:",omitempty"`
 
+	// RPCLimits restricts how much of the HTTP and websocket RPC servers a
+	// single client can use. The limits are off by default, as the dom and
+	// sub nodes are linked over the same servers.
+	RPCLimits rpc.Limits
+
 	// WSHost is the host interface on which to start the websocket RPC server. If
 	// this field is empty, no websocket API endpoint will be started.
 	WSHost string
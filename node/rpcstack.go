@@ -39,6 +39,7 @@ type httpConfig struct {
 	Modules            []string
 	CorsAllowedOrigins []string
 	Vhosts             []string
+	limits             rpc.Limits
 	prefix             string // path prefix on which to mount http handler
 }
 
@@ -46,6 +47,7 @@ type httpConfig struct {
 type wsConfig struct {
 	Origins []string
 	Modules []string
+	limits  rpc.Limits
 	prefix  string // path prefix on which to mount ws handler
 }
 
@@ -280,6 +282,7 @@ func (h *httpServer) enableRPC(apis []rpc.API, config httpConfig) error {
 
 	// Create RPC server and handler.
 	srv := rpc.NewServer()
+	srv.SetLimits(config.limits)
 	if err := RegisterApis(apis, config.Modules, srv, false); err != nil {
 		return err
 	}
@@ -312,6 +315,7 @@ func (h *httpServer) enableWS(apis []rpc.API, config wsConfig) error {
 
 	// Create RPC server and handler.
 	srv := rpc.NewServer()
+	srv.SetLimits(config.limits)
 	if err := RegisterApis(apis, config.Modules, srv, false); err != nil {
 		return err
 	}
@@ -0,0 +1,110 @@
+package simulations
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// pipeConn is one end of an in-memory connection. Writes are queued and handed
+// to the remote end once the latency of the link has passed, so a slow link
+// doesn't block the writer.
+type pipeConn struct {
+	net.Conn
+
+	latency func() time.Duration
+	queue   chan delayedWrite
+	pending int32 // number of queued writes not yet delivered
+
+	closeOnce sync.Once
+	closed    chan struct{}
+
+	errLock sync.Mutex
+	err     error // error of the last delayed write
+}
+
+// delayedWrite is a queued write of a pipeConn.
+type delayedWrite struct {
+	data    []byte
+	deliver time.Time
+}
+
+// newPipe creates both ends of an in-memory connection whose writes are
+// delayed by the given latency.
+func newPipe(latency func() time.Duration) (*pipeConn, *pipeConn) {
+	a, b := net.Pipe()
+	return newPipeConn(a, latency), newPipeConn(b, latency)
+}
+
+func newPipeConn(conn net.Conn, latency func() time.Duration) *pipeConn {
+	c := &pipeConn{
+		Conn:    conn,
+		latency: latency,
+		queue:   make(chan delayedWrite, 1024),
+		closed:  make(chan struct{}),
+	}
+	go c.deliverLoop()
+	return c
+}
+
+// Write queues the data for delivery after the link latency. Writes on links
+// without latency go straight to the remote end.
+func (c *pipeConn) Write(b []byte) (int, error) {
+	c.errLock.Lock()
+	err := c.err
+	c.errLock.Unlock()
+	if err != nil {
+		return 0, err
+	}
+	latency := c.latency()
+	if latency <= 0 && atomic.LoadInt32(&c.pending) == 0 {
+		return c.Conn.Write(b)
+	}
+	w := delayedWrite{data: append([]byte{}, b...), deliver: time.Now().Add(latency)}
+	atomic.AddInt32(&c.pending, 1)
+	select {
+	case c.queue <- w:
+		return len(b), nil
+	case <-c.closed:
+		atomic.AddInt32(&c.pending, -1)
+		return 0, net.ErrClosed
+	}
+}
+
+// deliverLoop hands the queued writes to the remote end in order.
+func (c *pipeConn) deliverLoop() {
+	for {
+		select {
+		case w := <-c.queue:
+			if wait := time.Until(w.deliver); wait > 0 {
+				select {
+				case <-time.After(wait):
+				case <-c.closed:
+					return
+				}
+			}
+			_, err := c.Conn.Write(w.data)
+			atomic.AddInt32(&c.pending, -1)
+			if err != nil {
+				c.errLock.Lock()
+				c.err = err
+				c.errLock.Unlock()
+				c.Close()
+				return
+			}
+		case <-c.closed:
+			return
+		}
+	}
+}
+
+// Close closes the connection, dropping queued writes.
+func (c *pipeConn) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		err = c.Conn.Close()
+	})
+	return err
+}
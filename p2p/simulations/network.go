@@ -0,0 +1,481 @@
+// Package simulations runs networks of p2p servers which are connected through
+// in-memory pipes instead of sockets, with a controllable topology, per link
+// latency and network partitions.
+package simulations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/dominant-strategies/go-quai/crypto"
+	"github.com/dominant-strategies/go-quai/p2p"
+	"github.com/dominant-strategies/go-quai/p2p/enode"
+)
+
+var (
+	errUnknownNode   = errors.New("unknown node")
+	errUnreachable   = errors.New("node is unreachable")
+	errSelfConnect   = errors.New("node can't connect to itself")
+	errNetworkClosed = errors.New("network is shut down")
+)
+
+// Node is a p2p server which is part of a simulated network.
+type Node struct {
+	Server *p2p.Server
+
+	id       enode.ID
+	endpoint *enode.Node // address other nodes dial, only used as a handle
+}
+
+// ID returns the node ID of the node.
+func (n *Node) ID() enode.ID {
+	return n.id
+}
+
+// Endpoint returns the record other servers use to dial the node.
+func (n *Node) Endpoint() *enode.Node {
+	return n.endpoint
+}
+
+// link is an undirected connection between two nodes.
+type link struct {
+	a, b enode.ID
+}
+
+func newLink(a, b enode.ID) link {
+	if a.String() > b.String() {
+		a, b = b, a
+	}
+	return link{a, b}
+}
+
+// Network is a set of p2p servers connected over in-memory pipes. Connections
+// between nodes are made through the dialer of the servers, so the protocols
+// running on top of them see regular peers.
+type Network struct {
+	lock  sync.RWMutex
+	nodes map[enode.ID]*Node
+	order []*Node
+
+	links   map[link]bool          // links requested through Connect
+	conns   map[link][]*pipeConn   // open connections of every link
+	latency map[link]time.Duration // per link latency overrides
+	groups  map[enode.ID]int       // partition group of every node
+
+	defaultLatency time.Duration
+	nextPort       uint16
+	closed         bool
+}
+
+// NewNetwork creates an empty simulated network.
+func NewNetwork() *Network {
+	return &Network{
+		nodes:    make(map[enode.ID]*Node),
+		links:    make(map[link]bool),
+		conns:    make(map[link][]*pipeConn),
+		latency:  make(map[link]time.Duration),
+		groups:   make(map[enode.ID]int),
+		nextPort: 30303,
+	}
+}
+
+// AddServer adds a p2p server to the network. The server must not be running
+// yet, its dialer, listener and discovery settings are replaced so that it is
+// only reachable through the network.
+func (nw *Network) AddServer(srv *p2p.Server) (*Node, error) {
+	if srv.PrivateKey == nil {
+		return nil, errors.New("server has no private key")
+	}
+	nw.lock.Lock()
+	defer nw.lock.Unlock()
+
+	if nw.closed {
+		return nil, errNetworkClosed
+	}
+	id := enode.PubkeyToIDV4(&srv.PrivateKey.PublicKey)
+	if _, ok := nw.nodes[id]; ok {
+		return nil, fmt.Errorf("node %v is already part of the network", id)
+	}
+	node := &Node{
+		Server:   srv,
+		id:       id,
+		endpoint: enode.NewV4(&srv.PrivateKey.PublicKey, ipv4(len(nw.order)), int(nw.nextPort), 0),
+	}
+	nw.nextPort++
+
+	srv.Dialer = &dialer{net: nw, self: id}
+	srv.ListenAddr = ""
+	srv.NoDiscovery = true
+	srv.DiscoveryV5 = false
+	srv.NoDial = false
+	srv.NAT = nil
+	srv.BootstrapNodes = nil
+	srv.BootstrapNodesV5 = nil
+	if srv.MaxPeers == 0 {
+		srv.MaxPeers = 25
+	}
+	nw.nodes[id] = node
+	nw.order = append(nw.order, node)
+	return node, nil
+}
+
+// NewNode creates, adds and starts a server running the given protocols.
+func (nw *Network) NewNode(name string, protocols []p2p.Protocol) (*Node, error) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	srv := &p2p.Server{Config: p2p.Config{
+		PrivateKey: key,
+		Name:       name,
+		MaxPeers:   25,
+		Protocols:  protocols,
+	}}
+	node, err := nw.AddServer(srv)
+	if err != nil {
+		return nil, err
+	}
+	if err := srv.Start(); err != nil {
+		nw.remove(node)
+		return nil, err
+	}
+	return node, nil
+}
+
+// Node returns the node with the given ID, or nil if it isn't part of the
+// network.
+func (nw *Network) Node(id enode.ID) *Node {
+	nw.lock.RLock()
+	defer nw.lock.RUnlock()
+
+	return nw.nodes[id]
+}
+
+// Nodes returns the nodes of the network in the order they were added.
+func (nw *Network) Nodes() []*Node {
+	nw.lock.RLock()
+	defer nw.lock.RUnlock()
+
+	return append([]*Node{}, nw.order...)
+}
+
+// remove drops a node which failed to start.
+func (nw *Network) remove(node *Node) {
+	nw.lock.Lock()
+	defer nw.lock.Unlock()
+
+	delete(nw.nodes, node.id)
+	for i, n := range nw.order {
+		if n == node {
+			nw.order = append(nw.order[:i], nw.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// Connect makes the first node dial the second one. The connection is kept as
+// a static peer, so it is redialed after it drops for as long as the nodes are
+// reachable.
+func (nw *Network) Connect(a, b enode.ID) error {
+	if a == b {
+		return errSelfConnect
+	}
+	nw.lock.Lock()
+	from, to := nw.nodes[a], nw.nodes[b]
+	if from == nil || to == nil {
+		nw.lock.Unlock()
+		return errUnknownNode
+	}
+	nw.links[newLink(a, b)] = true
+	nw.lock.Unlock()
+
+	from.Server.AddPeer(to.endpoint)
+	return nil
+}
+
+// Disconnect drops the connection between two nodes and stops them from
+// redialing each other.
+func (nw *Network) Disconnect(a, b enode.ID) error {
+	nw.lock.Lock()
+	from, to := nw.nodes[a], nw.nodes[b]
+	if from == nil || to == nil {
+		nw.lock.Unlock()
+		return errUnknownNode
+	}
+	l := newLink(a, b)
+	delete(nw.links, l)
+	conns := nw.conns[l]
+	delete(nw.conns, l)
+	nw.lock.Unlock()
+
+	from.Server.RemovePeer(to.endpoint)
+	to.Server.RemovePeer(from.endpoint)
+	for _, c := range conns {
+		c.Close()
+	}
+	return nil
+}
+
+// ConnectAll connects every node to every other node.
+func (nw *Network) ConnectAll() error {
+	nodes := nw.Nodes()
+	for i := range nodes {
+		for j := i + 1; j < len(nodes); j++ {
+			if err := nw.Connect(nodes[i].id, nodes[j].id); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ConnectChain connects every node to the one added after it.
+func (nw *Network) ConnectChain() error {
+	nodes := nw.Nodes()
+	for i := 1; i < len(nodes); i++ {
+		if err := nw.Connect(nodes[i-1].id, nodes[i].id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ConnectRing connects the nodes as a chain and closes it into a ring.
+func (nw *Network) ConnectRing() error {
+	if err := nw.ConnectChain(); err != nil {
+		return err
+	}
+	nodes := nw.Nodes()
+	if len(nodes) < 3 {
+		return nil
+	}
+	return nw.Connect(nodes[len(nodes)-1].id, nodes[0].id)
+}
+
+// ConnectStar connects every node to the given center node.
+func (nw *Network) ConnectStar(center enode.ID) error {
+	for _, node := range nw.Nodes() {
+		if node.id == center {
+			continue
+		}
+		if err := nw.Connect(node.id, center); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetLatency sets the one way latency of every link without an explicit
+// latency. It applies to data written after the call.
+func (nw *Network) SetLatency(latency time.Duration) {
+	nw.lock.Lock()
+	defer nw.lock.Unlock()
+
+	nw.defaultLatency = latency
+}
+
+// SetLinkLatency sets the one way latency between two nodes.
+func (nw *Network) SetLinkLatency(a, b enode.ID, latency time.Duration) {
+	nw.lock.Lock()
+	defer nw.lock.Unlock()
+
+	nw.latency[newLink(a, b)] = latency
+}
+
+// linkLatency returns the current latency of a link.
+func (nw *Network) linkLatency(l link) time.Duration {
+	nw.lock.RLock()
+	defer nw.lock.RUnlock()
+
+	if latency, ok := nw.latency[l]; ok {
+		return latency
+	}
+	return nw.defaultLatency
+}
+
+// Partition splits the network into the given groups. Nodes can only reach
+// nodes of their own group, the nodes which are not part of any group form an
+// additional group. Connections crossing groups are dropped.
+func (nw *Network) Partition(groups ...[]enode.ID) {
+	nw.lock.Lock()
+	nw.groups = make(map[enode.ID]int)
+	for i, group := range groups {
+		for _, id := range group {
+			nw.groups[id] = i + 1
+		}
+	}
+	var dropped []*pipeConn
+	for l, conns := range nw.conns {
+		if !nw.reachable(l.a, l.b) {
+			dropped = append(dropped, conns...)
+			delete(nw.conns, l)
+		}
+	}
+	nw.lock.Unlock()
+
+	for _, c := range dropped {
+		c.Close()
+	}
+}
+
+// Heal removes all partitions and reconnects the links which were requested
+// through Connect and are not currently connected.
+func (nw *Network) Heal() {
+	nw.lock.Lock()
+	nw.groups = make(map[enode.ID]int)
+	var redial []link
+	for l := range nw.links {
+		if len(nw.conns[l]) == 0 {
+			redial = append(redial, l)
+		}
+	}
+	nw.lock.Unlock()
+
+	for _, l := range redial {
+		from, to := nw.Node(l.a), nw.Node(l.b)
+		fd, err := nw.dial(l.a, l.b)
+		if err != nil {
+			continue
+		}
+		go from.Server.SetupConn(fd, 0, to.endpoint)
+	}
+}
+
+// reachable reports whether two nodes are in the same partition. It must be
+// called with the lock held.
+func (nw *Network) reachable(a, b enode.ID) bool {
+	return nw.groups[a] == nw.groups[b]
+}
+
+// dial opens a connection from one node to another one and sets up the
+// accepting end on the remote server.
+func (nw *Network) dial(from, to enode.ID) (net.Conn, error) {
+	nw.lock.Lock()
+	if nw.closed {
+		nw.lock.Unlock()
+		return nil, errNetworkClosed
+	}
+	remote := nw.nodes[to]
+	if remote == nil || nw.nodes[from] == nil {
+		nw.lock.Unlock()
+		return nil, errUnknownNode
+	}
+	if !nw.reachable(from, to) {
+		nw.lock.Unlock()
+		return nil, errUnreachable
+	}
+	l := newLink(from, to)
+	latency := func() time.Duration { return nw.linkLatency(l) }
+	local, accept := newPipe(latency)
+	nw.conns[l] = append(nw.conns[l], local, accept)
+	nw.lock.Unlock()
+
+	go func() {
+		remote.Server.SetupConn(accept, 0, nil)
+		nw.forget(l, local, accept)
+	}()
+	return local, nil
+}
+
+// forget removes the connections of a link once their peer is gone.
+func (nw *Network) forget(l link, conns ...*pipeConn) {
+	for _, c := range conns {
+		<-c.closed
+	}
+	nw.lock.Lock()
+	defer nw.lock.Unlock()
+
+	open := nw.conns[l][:0]
+	for _, c := range nw.conns[l] {
+		if c != conns[0] && c != conns[1] {
+			open = append(open, c)
+		}
+	}
+	if len(open) == 0 {
+		delete(nw.conns, l)
+	} else {
+		nw.conns[l] = open
+	}
+}
+
+// Connected reports whether two nodes currently have an open connection.
+func (nw *Network) Connected(a, b enode.ID) bool {
+	node := nw.Node(a)
+	if node == nil {
+		return false
+	}
+	for _, peer := range node.Server.Peers() {
+		if peer.ID() == b {
+			return true
+		}
+	}
+	return false
+}
+
+// WaitConnected blocks until two nodes are connected or the context expires.
+func (nw *Network) WaitConnected(ctx context.Context, a, b enode.ID) error {
+	return poll(ctx, func() bool { return nw.Connected(a, b) })
+}
+
+// WaitPeers blocks until a node has at least the given number of peers or the
+// context expires.
+func (nw *Network) WaitPeers(ctx context.Context, id enode.ID, peers int) error {
+	node := nw.Node(id)
+	if node == nil {
+		return errUnknownNode
+	}
+	return poll(ctx, func() bool { return node.Server.PeerCount() >= peers })
+}
+
+// Shutdown stops every server of the network and closes all connections.
+func (nw *Network) Shutdown() {
+	nw.lock.Lock()
+	nw.closed = true
+	nodes := append([]*Node{}, nw.order...)
+	var conns []*pipeConn
+	for _, cs := range nw.conns {
+		conns = append(conns, cs...)
+	}
+	nw.lock.Unlock()
+
+	for _, c := range conns {
+		c.Close()
+	}
+	for _, node := range nodes {
+		node.Server.Stop()
+	}
+}
+
+// dialer is the p2p.NodeDialer of a server of the network.
+type dialer struct {
+	net  *Network
+	self enode.ID
+}
+
+func (d *dialer) Dial(ctx context.Context, dest *enode.Node) (net.Conn, error) {
+	return d.net.dial(d.self, dest.ID())
+}
+
+// poll checks a condition until it holds or the context expires.
+func poll(ctx context.Context, cond func() bool) error {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for !cond() {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// ipv4 returns the placeholder address of the i-th node.
+func ipv4(i int) net.IP {
+	return net.IP{10, byte(i >> 16), byte(i >> 8), byte(i)}
+}
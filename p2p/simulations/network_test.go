@@ -0,0 +1,189 @@
+package simulations
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dominant-strategies/go-quai/p2p"
+	"github.com/dominant-strategies/go-quai/p2p/enode"
+)
+
+// pingProtocol answers every ping with a pong and reports the pongs it
+// receives.
+func pingProtocol(pongs chan<- time.Time) p2p.Protocol {
+	return p2p.Protocol{
+		Name:    "ping",
+		Version: 1,
+		Length:  2,
+		Run: func(peer *p2p.Peer, rw p2p.MsgReadWriter) error {
+			for {
+				msg, err := rw.ReadMsg()
+				if err != nil {
+					return err
+				}
+				msg.Discard()
+				switch msg.Code {
+				case 0:
+					if err := p2p.Send(rw, 1, []uint{}); err != nil {
+						return err
+					}
+				case 1:
+					pongs <- time.Now()
+				}
+			}
+		},
+	}
+}
+
+func newTestNetwork(t *testing.T, nodes int) *Network {
+	net := NewNetwork()
+	for i := 0; i < nodes; i++ {
+		if _, err := net.NewNode("test", []p2p.Protocol{pingProtocol(make(chan time.Time, 16))}); err != nil {
+			t.Fatalf("failed to create node %d: %v", i, err)
+		}
+	}
+	t.Cleanup(net.Shutdown)
+	return net
+}
+
+func TestNetworkTopologies(t *testing.T) {
+	tests := []struct {
+		name    string
+		connect func(net *Network) error
+		peers   []int
+	}{
+		{"all", (*Network).ConnectAll, []int{3, 3, 3, 3}},
+		{"chain", (*Network).ConnectChain, []int{1, 2, 2, 1}},
+		{"ring", (*Network).ConnectRing, []int{2, 2, 2, 2}},
+		{"star", func(net *Network) error { return net.ConnectStar(net.Nodes()[0].ID()) }, []int{3, 1, 1, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			net := newTestNetwork(t, 4)
+			if err := tt.connect(net); err != nil {
+				t.Fatalf("failed to connect: %v", err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			for i, node := range net.Nodes() {
+				if err := net.WaitPeers(ctx, node.ID(), tt.peers[i]); err != nil {
+					t.Fatalf("node %d: have %d peers, want %d", i, node.Server.PeerCount(), tt.peers[i])
+				}
+			}
+			// Give stray connections a chance to show up before checking
+			time.Sleep(100 * time.Millisecond)
+			for i, node := range net.Nodes() {
+				if have := node.Server.PeerCount(); have != tt.peers[i] {
+					t.Errorf("node %d: have %d peers, want %d", i, have, tt.peers[i])
+				}
+			}
+		})
+	}
+}
+
+func TestNetworkPartition(t *testing.T) {
+	net := newTestNetwork(t, 4)
+	if err := net.ConnectAll(); err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	nodes := net.Nodes()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for _, node := range nodes {
+		if err := net.WaitPeers(ctx, node.ID(), 3); err != nil {
+			t.Fatalf("network didn't connect: %v", err)
+		}
+	}
+	net.Partition(
+		[]enode.ID{nodes[0].ID(), nodes[1].ID()},
+		[]enode.ID{nodes[2].ID(), nodes[3].ID()},
+	)
+	err := poll(ctx, func() bool {
+		for _, node := range nodes {
+			if node.Server.PeerCount() != 1 {
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		t.Fatalf("partition didn't drop connections")
+	}
+	if !net.Connected(nodes[0].ID(), nodes[1].ID()) || !net.Connected(nodes[2].ID(), nodes[3].ID()) {
+		t.Fatalf("partition dropped connections within a group")
+	}
+
+	net.Heal()
+	for _, node := range nodes {
+		if err := net.WaitPeers(ctx, node.ID(), 3); err != nil {
+			t.Fatalf("network didn't reconnect after healing: %v", err)
+		}
+	}
+}
+
+func TestNetworkDisconnect(t *testing.T) {
+	net := newTestNetwork(t, 2)
+	a, b := net.Nodes()[0].ID(), net.Nodes()[1].ID()
+	if err := net.Connect(a, b); err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := net.WaitConnected(ctx, a, b); err != nil {
+		t.Fatalf("nodes didn't connect: %v", err)
+	}
+	if err := net.Disconnect(a, b); err != nil {
+		t.Fatalf("failed to disconnect: %v", err)
+	}
+	if err := poll(ctx, func() bool { return !net.Connected(a, b) && !net.Connected(b, a) }); err != nil {
+		t.Fatalf("nodes didn't disconnect")
+	}
+}
+
+func TestNetworkLatency(t *testing.T) {
+	const latency = 200 * time.Millisecond
+
+	net := NewNetwork()
+	defer net.Shutdown()
+
+	pongs := make(chan time.Time, 1)
+	var (
+		pinger = make(chan *p2p.Peer, 1)
+		rws    = make(chan p2p.MsgReadWriter, 1)
+	)
+	proto := pingProtocol(pongs)
+	run := proto.Run
+	proto.Run = func(peer *p2p.Peer, rw p2p.MsgReadWriter) error {
+		pinger <- peer
+		rws <- rw
+		return run(peer, rw)
+	}
+	a, err := net.NewNode("pinger", []p2p.Protocol{proto})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := net.NewNode("ponger", []p2p.Protocol{pingProtocol(make(chan time.Time, 1))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	net.SetLatency(latency)
+	if err := net.Connect(a.ID(), b.ID()); err != nil {
+		t.Fatal(err)
+	}
+	<-pinger
+	rw := <-rws
+
+	start := time.Now()
+	if err := p2p.Send(rw, 0, []uint{}); err != nil {
+		t.Fatalf("failed to send ping: %v", err)
+	}
+	select {
+	case pong := <-pongs:
+		if rtt := pong.Sub(start); rtt < 2*latency {
+			t.Fatalf("round trip took %v, want at least %v", rtt, 2*latency)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no pong received")
+	}
+}
@@ -41,6 +41,18 @@ var (
 )
 
 var (
+	// DefaultRewardConfig is the emission schedule used by development chains.
+	DefaultRewardConfig = &RewardConfig{
+		ZoneBlockReward:   new(big.Int).Mul(big.NewInt(2), big.NewInt(Ether)),
+		RegionBlockReward: new(big.Int).Mul(big.NewInt(6), big.NewInt(Ether)),
+		PrimeBlockReward:  new(big.Int).Mul(big.NewInt(18), big.NewInt(Ether)),
+		EraLength:         4000000,
+		DecayNumerator:    1,
+		DecayDenominator:  2,
+		UncleDepth:        8,
+		NephewDivisor:     32,
+	}
+
 	// ColosseumChainConfig is the chain parameters to run a node on the Colosseum network.
 	ProgpowColosseumChainConfig = &ChainConfig{
 		ChainID:     big.NewInt(9000),
@@ -96,15 +108,29 @@ var (
 
 	// LocalChainConfig contains the chain parameters to run a node on the Local test network.
 	ProgpowLocalChainConfig = &ChainConfig{
-		ChainID:     big.NewInt(1337),
-		Progpow:     new(ProgpowConfig),
-		GenesisHash: ProgpowLocalGenesisHash,
+		ChainID:                big.NewInt(1337),
+		Progpow:                new(ProgpowConfig),
+		GenesisHash:            ProgpowLocalGenesisHash,
+		CancunBlock:            big.NewInt(0),
+		ContractETXBlock:       big.NewInt(0),
+		HierarchyBlock:         big.NewInt(0),
+		CryptoPrecompilesBlock: big.NewInt(0),
+		UncledEntropyBlock:     big.NewInt(0),
+		EtxOriginBlock:         big.NewInt(0),
+		EtxRefundBlock:         big.NewInt(0),
 	}
 
 	Blake3PowLocalChainConfig = &ChainConfig{
-		ChainID:     big.NewInt(1337),
-		Blake3Pow:   new(Blake3powConfig),
-		GenesisHash: Blake3PowLocalGenesisHash,
+		ChainID:                big.NewInt(1337),
+		Blake3Pow:              new(Blake3powConfig),
+		GenesisHash:            Blake3PowLocalGenesisHash,
+		CancunBlock:            big.NewInt(0),
+		ContractETXBlock:       big.NewInt(0),
+		HierarchyBlock:         big.NewInt(0),
+		CryptoPrecompilesBlock: big.NewInt(0),
+		UncledEntropyBlock:     big.NewInt(0),
+		EtxOriginBlock:         big.NewInt(0),
+		EtxRefundBlock:         big.NewInt(0),
 	}
 
 	// AllProgpowProtocolChanges contains every protocol change introduced
@@ -112,12 +138,38 @@ var (
 	//
 	// This configuration is intentionally not using keyed fields to force anyone
 	// adding flags to the config to also have to set these fields.
-	AllProgpowProtocolChanges = &ChainConfig{big.NewInt(1337), "progpow", new(Blake3powConfig), new(ProgpowConfig), common.Hash{}, common.NodeLocation}
+	AllProgpowProtocolChanges = &ChainConfig{big.NewInt(1337), "progpow", new(Blake3powConfig), new(ProgpowConfig), common.Hash{}, common.NodeLocation, DefaultRewardConfig, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0)}
 
-	TestChainConfig = &ChainConfig{big.NewInt(1), "progpow", new(Blake3powConfig), new(ProgpowConfig), common.Hash{}, common.NodeLocation}
+	TestChainConfig = &ChainConfig{big.NewInt(1), "progpow", new(Blake3powConfig), new(ProgpowConfig), common.Hash{}, common.NodeLocation, DefaultRewardConfig, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0)}
 	TestRules       = TestChainConfig.Rules(new(big.Int))
 )
 
+// RewardConfig is the emission schedule used to reward miners. Blocks are
+// rewarded according to the highest context they are coincident with, and the
+// reward decays by DecayNumerator/DecayDenominator at the start of every era.
+type RewardConfig struct {
+	ZoneBlockReward   *big.Int `json:"zoneBlockReward"`   // Reward for a block that is only a zone block
+	RegionBlockReward *big.Int `json:"regionBlockReward"` // Reward for a block coincident with its region
+	PrimeBlockReward  *big.Int `json:"primeBlockReward"`  // Reward for a block coincident with prime
+	EraLength         uint64   `json:"eraLength"`         // Number of zone blocks in an era, 0 disables decay
+	DecayNumerator    uint64   `json:"decayNumerator"`    // Numerator of the per-era decay factor
+	DecayDenominator  uint64   `json:"decayDenominator"`  // Denominator of the per-era decay factor
+	UncleDepth        uint64   `json:"uncleDepth"`        // Number of blocks after which an uncle earns nothing
+	NephewDivisor     uint64   `json:"nephewDivisor"`     // Divisor of the block reward paid for including an uncle
+}
+
+// String implements the stringer interface, returning the reward schedule details.
+func (c *RewardConfig) String() string {
+	return fmt.Sprintf("{Zone: %v, Region: %v, Prime: %v, EraLength: %d, Decay: %d/%d}",
+		c.ZoneBlockReward,
+		c.RegionBlockReward,
+		c.PrimeBlockReward,
+		c.EraLength,
+		c.DecayNumerator,
+		c.DecayDenominator,
+	)
+}
+
 // ChainConfig is the core config which determines the blockchain settings.
 //
 // ChainConfig is stored in the database on a per block basis. This means
@@ -131,6 +183,37 @@ type ChainConfig struct {
 	Progpow         *ProgpowConfig   `json:"progpow,omitempty"`
 	GenesisHash     common.Hash
 	Location        common.Location
+	Reward          *RewardConfig `json:"reward,omitempty"` // Emission schedule, nil for the legacy testnet schedule
+
+	// CancunBlock enables the opcodes of the Shanghai and Cancun EVM versions
+	// (PUSH0, TLOAD, TSTORE and MCOPY) from the given block on, nil disables them
+	CancunBlock *big.Int `json:"cancunBlock,omitempty"`
+
+	// ContractETXBlock charges the memory expansion of the opETX opcode and
+	// validates the fees of the ETXs it emits against the emitting contract,
+	// rather than its caller, from the given block on
+	ContractETXBlock *big.Int `json:"contractEtxBlock,omitempty"`
+
+	// HierarchyBlock enables the hierarchy introspection precompile from the
+	// given block on
+	HierarchyBlock *big.Int `json:"hierarchyBlock,omitempty"`
+
+	// CryptoPrecompilesBlock enables the secp256r1 signature verification and
+	// BLS12-381 precompiles from the given block on
+	CryptoPrecompilesBlock *big.Int `json:"cryptoPrecompilesBlock,omitempty"`
+
+	// UncledEntropyBlock commits the entropy of the uncles to the header,
+	// accepts side blocks of every order as uncles and rewards each uncle as a
+	// block of its own order from the given block on
+	UncledEntropyBlock *big.Int `json:"uncledEntropyBlock,omitempty"`
+
+	// EtxOriginBlock records the hash of the emitting transaction and the
+	// position among its ETXs in every ETX emitted from the given block on
+	EtxOriginBlock *big.Int `json:"etxOriginBlock,omitempty"`
+
+	// EtxRefundBlock refunds the balance left over from executing an ETX to
+	// its sender from the given block on
+	EtxRefundBlock *big.Int `json:"etxRefundBlock,omitempty"`
 }
 
 // SetLocation sets the location on the chain config
@@ -165,13 +248,69 @@ func (c *ChainConfig) String() string {
 	default:
 		engine = "unknown"
 	}
-	return fmt.Sprintf("{ChainID: %v, Engine: %v, Location: %v}",
+	return fmt.Sprintf("{ChainID: %v, Engine: %v, Location: %v, Cancun: %v, ContractETX: %v, Hierarchy: %v, CryptoPrecompiles: %v, UncledEntropy: %v, EtxOrigin: %v, EtxRefund: %v}",
 		c.ChainID,
 		engine,
 		c.Location,
+		c.CancunBlock,
+		c.ContractETXBlock,
+		c.HierarchyBlock,
+		c.CryptoPrecompilesBlock,
+		c.UncledEntropyBlock,
+		c.EtxOriginBlock,
+		c.EtxRefundBlock,
 	)
 }
 
+// IsCancun returns whether num is either equal to the Cancun fork block or greater.
+func (c *ChainConfig) IsCancun(num *big.Int) bool {
+	return isForked(c.CancunBlock, num)
+}
+
+// IsContractETX returns whether num is either equal to the contract ETX fork
+// block or greater.
+func (c *ChainConfig) IsContractETX(num *big.Int) bool {
+	return isForked(c.ContractETXBlock, num)
+}
+
+// IsHierarchy returns whether num is either equal to the hierarchy fork block
+// or greater.
+func (c *ChainConfig) IsHierarchy(num *big.Int) bool {
+	return isForked(c.HierarchyBlock, num)
+}
+
+// IsCryptoPrecompiles returns whether num is either equal to the secp256r1 and
+// BLS12-381 precompiles fork block or greater.
+func (c *ChainConfig) IsCryptoPrecompiles(num *big.Int) bool {
+	return isForked(c.CryptoPrecompilesBlock, num)
+}
+
+// IsUncledEntropy returns whether num is either equal to the uncled entropy
+// fork block or greater.
+func (c *ChainConfig) IsUncledEntropy(num *big.Int) bool {
+	return isForked(c.UncledEntropyBlock, num)
+}
+
+// IsEtxOrigin returns whether num is either equal to the ETX origin fork block
+// or greater.
+func (c *ChainConfig) IsEtxOrigin(num *big.Int) bool {
+	return isForked(c.EtxOriginBlock, num)
+}
+
+// IsEtxRefund returns whether num is either equal to the ETX refund fork block
+// or greater.
+func (c *ChainConfig) IsEtxRefund(num *big.Int) bool {
+	return isForked(c.EtxRefundBlock, num)
+}
+
+// isForked returns whether a fork scheduled at block s is active at the given head block.
+func isForked(s, head *big.Int) bool {
+	if s == nil || head == nil {
+		return false
+	}
+	return s.Cmp(head) <= 0
+}
+
 func configNumEqual(x, y *big.Int) bool {
 	if x == nil {
 		return y == nil
@@ -219,7 +358,14 @@ func (err *ConfigCompatError) Error() string {
 // Rules is a one time interface meaning that it shouldn't be used in between transition
 // phases.
 type Rules struct {
-	ChainID *big.Int
+	ChainID             *big.Int
+	IsCancun            bool
+	IsContractETX       bool
+	IsHierarchy         bool
+	IsCryptoPrecompiles bool
+	IsUncledEntropy     bool
+	IsEtxOrigin         bool
+	IsEtxRefund         bool
 }
 
 // Rules ensures c's ChainID is not nil.
@@ -229,6 +375,13 @@ func (c *ChainConfig) Rules(num *big.Int) Rules {
 		chainID = new(big.Int)
 	}
 	return Rules{
-		ChainID: new(big.Int).Set(chainID),
+		ChainID:             new(big.Int).Set(chainID),
+		IsCancun:            c.IsCancun(num),
+		IsContractETX:       c.IsContractETX(num),
+		IsHierarchy:         c.IsHierarchy(num),
+		IsCryptoPrecompiles: c.IsCryptoPrecompiles(num),
+		IsUncledEntropy:     c.IsUncledEntropy(num),
+		IsEtxOrigin:         c.IsEtxOrigin(num),
+		IsEtxRefund:         c.IsEtxRefund(num),
 	}
 }
@@ -0,0 +1,303 @@
+package quaiclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/dominant-strategies/go-quai/log"
+	"github.com/dominant-strategies/go-quai/metrics"
+	"github.com/dominant-strategies/go-quai/rpc"
+)
+
+const (
+	c_maxReplayQueue    = 256             // Maximum number of calls buffered while a link is down
+	c_minReconnectDelay = 1 * time.Second // Initial delay between reconnection attempts
+	c_pingTimeout       = 5 * time.Second // Timeout of the call checking a reconnected link
+)
+
+// LinkState is the connection state of a link to a dom or sub node.
+type LinkState string
+
+const (
+	LinkConnected    LinkState = "connected"
+	LinkReconnecting LinkState = "reconnecting"
+	LinkClosed       LinkState = "closed"
+)
+
+// LinkStatus reports the health of a link to a dom or sub node.
+type LinkStatus struct {
+	Name        string    `json:"name"`
+	URL         string    `json:"url"`
+	State       LinkState `json:"state"`
+	LastSuccess time.Time `json:"lastSuccess"`
+	LastError   string    `json:"lastError,omitempty"`
+	LastErrorAt time.Time `json:"lastErrorAt"`
+	Calls       uint64    `json:"calls"`
+	Errors      uint64    `json:"errors"`
+	Reconnects  uint64    `json:"reconnects"`
+	Buffered    int       `json:"buffered"`
+	Dropped     uint64    `json:"dropped"`
+}
+
+var (
+	errReplayQueueFull = errors.New("replay queue of the link is full")
+	errLinkClosed      = errors.New("link closed")
+)
+
+// replayCall is a call buffered while its link is down.
+type replayCall struct {
+	key    string
+	method string
+	args   []interface{}
+}
+
+// link keeps a client connected to a dom or sub node. It reconnects with an
+// exponential backoff whenever a call fails because of the transport, and
+// buffers the pending ETXs sent in the meantime so that they can be replayed
+// once the link is back.
+type link struct {
+	name string
+	url  string
+
+	mu     sync.Mutex
+	status LinkStatus
+	queue  []replayCall
+	quit   chan struct{}
+
+	callsCounter      metrics.Counter
+	errorsCounter     metrics.Counter
+	reconnectsCounter metrics.Counter
+	droppedCounter    metrics.Counter
+	connectedGauge    metrics.Gauge
+	bufferedGauge     metrics.Gauge
+}
+
+func newLink(name string, url string) *link {
+	prefix := "quaiclient/" + name + "/"
+	l := &link{
+		name:              name,
+		url:               url,
+		status:            LinkStatus{Name: name, URL: url, State: LinkConnected},
+		quit:              make(chan struct{}),
+		callsCounter:      metrics.NewRegisteredCounter(prefix+"calls", nil),
+		errorsCounter:     metrics.NewRegisteredCounter(prefix+"errors", nil),
+		reconnectsCounter: metrics.NewRegisteredCounter(prefix+"reconnects", nil),
+		droppedCounter:    metrics.NewRegisteredCounter(prefix+"dropped", nil),
+		connectedGauge:    metrics.NewRegisteredGauge(prefix+"connected", nil),
+		bufferedGauge:     metrics.NewRegisteredGauge(prefix+"buffered", nil),
+	}
+	l.connectedGauge.Update(1)
+	return l
+}
+
+// isTransportError reports whether an error returned by a call was caused by
+// the connection, rather than by the remote node rejecting the call, the
+// caller abandoning the call or the result failing to decode.
+func isTransportError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var (
+		rpcErr       rpc.Error
+		syntaxErr    *json.SyntaxError
+		typeErr      *json.UnmarshalTypeError
+		unmarshalErr *json.InvalidUnmarshalError
+	)
+	return !errors.As(err, &rpcErr) && !errors.As(err, &syntaxErr) && !errors.As(err, &typeErr) && !errors.As(err, &unmarshalErr)
+}
+
+// Status returns the current health of the link.
+func (ec *Client) Status() LinkStatus {
+	if ec.link == nil {
+		return LinkStatus{State: LinkConnected}
+	}
+	ec.link.mu.Lock()
+	defer ec.link.mu.Unlock()
+
+	status := ec.link.status
+	status.Buffered = len(ec.link.queue)
+	return status
+}
+
+// call performs a request on the link, updating its health and starting the
+// reconnection loop if the transport failed. Calls are attempted even while
+// the link is reconnecting, as the node may be reachable again before the
+// reconnection loop notices.
+func (ec *Client) call(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	err := ec.rpcClient().CallContext(ctx, result, method, args...)
+	if ec.link != nil {
+		ec.record(err)
+	}
+	return err
+}
+
+// send performs a call whose result is not needed. Calls failing because of
+// the transport are not retried, as the state they carry is superseded by the
+// next update.
+func (ec *Client) send(ctx context.Context, method string, args ...interface{}) error {
+	return ec.call(ctx, nil, method, args...)
+}
+
+// sendBuffered performs a call whose result is not needed and which must not
+// be lost. If the link is down or the call fails because of the transport, it
+// is buffered under the given key and replayed once the link is reconnected,
+// and no error is returned. A buffered call replaces any earlier call buffered
+// under the same key. If the buffer is full the call is dropped and an error
+// returned instead. Errors returned by the node are not retried.
+func (ec *Client) sendBuffered(ctx context.Context, key string, method string, args ...interface{}) error {
+	if ec.link == nil {
+		return ec.rpcClient().CallContext(ctx, nil, method, args...)
+	}
+	if ec.Status().State == LinkConnected {
+		err := ec.rpcClient().CallContext(ctx, nil, method, args...)
+		ec.record(err)
+		if !isTransportError(err) {
+			return err
+		}
+	}
+	return ec.buffer(replayCall{key: key, method: method, args: args})
+}
+
+// record updates the health of the link with the result of a call.
+func (ec *Client) record(err error) {
+	l := ec.link
+	l.callsCounter.Inc(1)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.status.Calls++
+	if !isTransportError(err) {
+		l.status.LastSuccess = time.Now()
+		return
+	}
+	l.errorsCounter.Inc(1)
+	l.status.Errors++
+	l.status.LastError = err.Error()
+	l.status.LastErrorAt = time.Now()
+	if l.status.State == LinkConnected {
+		log.Warn("Lost link to go-quai node, reconnecting", "link", l.name, "url", l.url, "err", err)
+		l.status.State = LinkReconnecting
+		l.connectedGauge.Update(0)
+		go ec.reconnectLoop()
+	}
+}
+
+// buffer queues a call for replay, dropping the call if the queue is full.
+func (ec *Client) buffer(call replayCall) error {
+	l := ec.link
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for i, queued := range l.queue {
+		if queued.key == call.key {
+			l.queue = append(l.queue[:i], l.queue[i+1:]...)
+			break
+		}
+	}
+	if len(l.queue) >= c_maxReplayQueue {
+		log.Warn("Dropping call to go-quai node", "link", l.name, "method", call.method)
+		l.status.Dropped++
+		l.droppedCounter.Inc(1)
+		return errReplayQueueFull
+	}
+	l.queue = append(l.queue, call)
+	l.bufferedGauge.Update(int64(len(l.queue)))
+	return nil
+}
+
+// reconnectLoop redials the node with an exponential backoff until it
+// responds, then replays the buffered calls.
+func (ec *Client) reconnectLoop() {
+	l := ec.link
+	delay := c_minReconnectDelay
+	for attempts := 1; ; attempts++ {
+		select {
+		case <-l.quit:
+			return
+		case <-time.After(delay):
+		}
+		err := ec.redial()
+		if err == errLinkClosed {
+			return
+		}
+		if err != nil {
+			log.Debug("Failed to reconnect to go-quai node", "link", l.name, "attempts", attempts, "delay", delay, "err", err)
+			if delay *= 2; delay > time.Duration(exponentialBackoffCeilingSecs)*time.Second {
+				delay = time.Duration(exponentialBackoffCeilingSecs) * time.Second
+			}
+			continue
+		}
+		l.reconnectsCounter.Inc(1)
+		l.mu.Lock()
+		l.status.Reconnects++
+		l.status.State = LinkConnected
+		l.status.LastSuccess = time.Now()
+		l.mu.Unlock()
+		l.connectedGauge.Update(1)
+		log.Info("Reconnected to go-quai node", "link", l.name, "url", l.url, "attempts", attempts)
+
+		ec.replay()
+		return
+	}
+}
+
+// redial replaces the underlying client with a new connection, checking that
+// the node responds before using it.
+func (ec *Client) redial() error {
+	ctx, cancel := context.WithTimeout(context.Background(), c_pingTimeout)
+	defer cancel()
+
+	c, err := rpc.DialContext(ctx, ec.link.url)
+	if err != nil {
+		return err
+	}
+	var modules map[string]string
+	if err := c.CallContext(ctx, &modules, "rpc_modules"); isTransportError(err) {
+		c.Close()
+		return err
+	}
+	// The client may have been closed while dialling, in which case the new
+	// connection would never be closed
+	ec.mu.Lock()
+	select {
+	case <-ec.link.quit:
+		ec.mu.Unlock()
+		c.Close()
+		return errLinkClosed
+	default:
+	}
+	old := ec.c
+	ec.c = c
+	ec.mu.Unlock()
+	old.Close()
+	return nil
+}
+
+// replay sends the buffered calls in the order they were made. Calls which
+// fail again are buffered for the next reconnection.
+func (ec *Client) replay() {
+	l := ec.link
+	l.mu.Lock()
+	queue := l.queue
+	l.queue = nil
+	l.bufferedGauge.Update(0)
+	l.mu.Unlock()
+
+	if len(queue) > 0 {
+		log.Info("Replaying buffered calls to go-quai node", "link", l.name, "calls", len(queue))
+	}
+	for _, call := range queue {
+		ec.sendBuffered(context.Background(), call.key, call.method, call.args...)
+	}
+}
+
+// rpcClient returns the current connection of the client.
+func (ec *Client) rpcClient() *rpc.Client {
+	ec.mu.RLock()
+	defer ec.mu.RUnlock()
+	return ec.c
+}
@@ -0,0 +1,237 @@
+package quaiclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dominant-strategies/go-quai/core/types"
+	"github.com/dominant-strategies/go-quai/rpc"
+)
+
+// testService counts the calls made to the quai namespace of a test node.
+type testService struct {
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (s *testService) count(method string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls[method]++
+}
+
+func (s *testService) called(method string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[method]
+}
+
+func (s *testService) SendPendingEtxsToDom(fields map[string]interface{}) error {
+	s.count("sendPendingEtxsToDom")
+	return nil
+}
+
+func (s *testService) GetHeaderByNumber(number string) map[string]interface{} {
+	s.count("getHeaderByNumber")
+	return types.EmptyHeader().RPCMarshalHeader()
+}
+
+func (s *testService) Fail() error {
+	return errors.New("rejected")
+}
+
+// testNode is an HTTP RPC server which can be taken down, in which case it
+// drops the connection of every request.
+type testNode struct {
+	server  *httptest.Server
+	service *testService
+	down    int32
+}
+
+func newTestNode(t *testing.T) *testNode {
+	node := &testNode{service: &testService{calls: make(map[string]int)}}
+	srv := rpc.NewServer()
+	if err := srv.RegisterName("quai", node.service); err != nil {
+		t.Fatal(err)
+	}
+	node.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&node.down) == 1 {
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err == nil {
+				conn.Close()
+			}
+			return
+		}
+		srv.ServeHTTP(w, r)
+	}))
+	t.Cleanup(node.server.Close)
+	return node
+}
+
+func (n *testNode) setDown(down bool) {
+	if down {
+		atomic.StoreInt32(&n.down, 1)
+	} else {
+		atomic.StoreInt32(&n.down, 0)
+	}
+}
+
+func TestIsTransportError(t *testing.T) {
+	node := newTestNode(t)
+	client, err := Dial(node.server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	rejected := client.call(context.Background(), nil, "quai_fail")
+	var number int
+	undecodable := client.call(context.Background(), &number, "quai_getHeaderByNumber", "latest")
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	node.setDown(true)
+	dropped := client.call(context.Background(), nil, "quai_fail")
+	abandoned := client.call(canceled, nil, "quai_fail")
+
+	tests := []struct {
+		name      string
+		err       error
+		transport bool
+	}{
+		{"nil", nil, false},
+		{"rejected", rejected, false},
+		{"undecodable", undecodable, false},
+		{"canceled", abandoned, false},
+		{"deadline", fmt.Errorf("wrapped: %w", context.DeadlineExceeded), false},
+		{"dropped", dropped, true},
+	}
+	for _, tt := range tests {
+		if tt.name != "nil" && tt.err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+			continue
+		}
+		if transport := isTransportError(tt.err); transport != tt.transport {
+			t.Errorf("%s: transport error mismatch for %v: have %v, want %v", tt.name, tt.err, transport, tt.transport)
+		}
+	}
+}
+
+// Tests that calls are still attempted while a link is reconnecting.
+func TestLinkCallWhileReconnecting(t *testing.T) {
+	node := newTestNode(t)
+	client, err := DialLink("test", node.server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	node.setDown(true)
+	if header := client.HeaderByNumber(context.Background(), "latest"); header != nil {
+		t.Fatalf("call succeeded while the node was down")
+	}
+	if state := client.Status().State; state != LinkReconnecting {
+		t.Fatalf("link state mismatch: have %s, want %s", state, LinkReconnecting)
+	}
+	node.setDown(false)
+	if header := client.HeaderByNumber(context.Background(), "latest"); header == nil {
+		t.Fatalf("call failed while the link was reconnecting")
+	}
+	if n := node.service.called("getHeaderByNumber"); n != 1 {
+		t.Fatalf("call count mismatch: have %d, want %d", n, 1)
+	}
+}
+
+// Tests that pending ETXs sent while the dom is down are buffered instead of
+// failing, and delivered exactly once after the link reconnects.
+func TestLinkBufferPendingEtxs(t *testing.T) {
+	node := newTestNode(t)
+	client, err := DialLink("test", node.server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	pEtxs := types.PendingEtxs{Header: types.EmptyHeader()}
+	node.setDown(true)
+	for i := 0; i < 2; i++ {
+		if err := client.SendPendingEtxsToDom(context.Background(), pEtxs); err != nil {
+			t.Fatalf("send %d: buffered send returned error: %v", i, err)
+		}
+	}
+	if status := client.Status(); status.Buffered != 1 {
+		t.Fatalf("buffered calls mismatch: have %d, want %d", status.Buffered, 1)
+	}
+	node.setDown(false)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for client.Status().State != LinkConnected || node.service.called("sendPendingEtxsToDom") == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("link did not reconnect: %+v", client.Status())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := node.service.called("sendPendingEtxsToDom"); n != 1 {
+		t.Fatalf("delivered calls mismatch: have %d, want %d", n, 1)
+	}
+	if status := client.Status(); status.Reconnects != 1 {
+		t.Fatalf("reconnects mismatch: have %d, want %d", status.Reconnects, 1)
+	}
+}
+
+// Tests that only pending ETXs are buffered while the dom is down, and that
+// they are dropped with an error once the buffer is full.
+func TestLinkBufferLimit(t *testing.T) {
+	node := newTestNode(t)
+	client, err := DialLink("test", node.server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	node.setDown(true)
+	client.NewGenesisPendingHeader(context.Background(), types.EmptyHeader())
+	if status := client.Status(); status.Buffered != 0 {
+		t.Fatalf("fire-and-forget call buffered: have %d buffered calls", status.Buffered)
+	}
+	for i := 0; i < c_maxReplayQueue; i++ {
+		header := types.EmptyHeader()
+		header.SetGasLimit(uint64(i))
+		if err := client.SendPendingEtxsToDom(context.Background(), types.PendingEtxs{Header: header}); err != nil {
+			t.Fatalf("send %d: buffered send returned error: %v", i, err)
+		}
+	}
+	header := types.EmptyHeader()
+	header.SetGasLimit(c_maxReplayQueue)
+	if err := client.SendPendingEtxsToDom(context.Background(), types.PendingEtxs{Header: header}); err == nil {
+		t.Fatalf("send beyond the buffer returned no error")
+	}
+	if status := client.Status(); status.Buffered != c_maxReplayQueue || status.Dropped != 1 {
+		t.Fatalf("buffer mismatch: have %d buffered and %d dropped, want %d and 1", status.Buffered, status.Dropped, c_maxReplayQueue)
+	}
+}
+
+// Tests that a connection dialled after the client was closed is not kept.
+func TestLinkRedialAfterClose(t *testing.T) {
+	node := newTestNode(t)
+	client, err := DialLink("test", node.server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Close()
+
+	old := client.rpcClient()
+	if err := client.redial(); err != errLinkClosed {
+		t.Fatalf("redial error mismatch: have %v, want %v", err, errLinkClosed)
+	}
+	if client.rpcClient() != old {
+		t.Fatalf("connection replaced after the client was closed")
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"context"
 	"encoding/json"
 	"math/big"
+	"sync"
 	"time"
 
 	"github.com/dominant-strategies/go-quai/common"
@@ -33,7 +34,9 @@ var exponentialBackoffCeilingSecs int64 = 60 // 1 minute
 
 // Client defines typed wrappers for the Quai RPC API.
 type Client struct {
-	c *rpc.Client
+	mu   sync.RWMutex
+	c    *rpc.Client
+	link *link // Health of the connection, nil if the client does not reconnect
 }
 
 // Dial connects a client to the given URL.
@@ -70,13 +73,34 @@ func DialContext(ctx context.Context, rawurl string) (*Client, error) {
 	return NewClient(c), nil
 }
 
+// DialLink connects a client to the given URL, like Dial, and keeps it
+// connected for the lifetime of the client. Calls made while the node is
+// unreachable are buffered and replayed once the connection is restored. The
+// name identifies the link in its status and metrics.
+func DialLink(name string, rawurl string) (*Client, error) {
+	ec, err := Dial(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	ec.link = newLink(name, rawurl)
+	return ec, nil
+}
+
 // NewClient creates a client that uses the given RPC client.
 func NewClient(c *rpc.Client) *Client {
-	return &Client{c}
+	return &Client{c: c}
 }
 
 func (ec *Client) Close() {
-	ec.c.Close()
+	if ec.link != nil {
+		ec.link.mu.Lock()
+		if ec.link.status.State != LinkClosed {
+			ec.link.status.State = LinkClosed
+			close(ec.link.quit)
+		}
+		ec.link.mu.Unlock()
+	}
+	ec.rpcClient().Close()
 }
 
 type Termini struct {
@@ -100,7 +124,7 @@ func (ec *Client) Append(ctx context.Context, header *types.Header, manifest typ
 	}
 
 	var raw json.RawMessage
-	err := ec.c.CallContext(ctx, &raw, "quai_append", fields)
+	err := ec.call(ctx, &raw, "quai_append", fields)
 	if err != nil {
 		return nil, false, false, err
 	}
@@ -120,7 +144,7 @@ func (ec *Client) DownloadBlocksInManifest(ctx context.Context, hash common.Hash
 		"manifest": manifest,
 		"entropy":  entropy,
 	}
-	ec.c.CallContext(ctx, nil, "quai_downloadBlocksInManifest", fields)
+	ec.send(ctx, "quai_downloadBlocksInManifest", fields)
 }
 
 func (ec *Client) SubRelayPendingHeader(ctx context.Context, pendingHeader types.PendingHeader, newEntropy *big.Int, location common.Location, subReorg bool, order int) {
@@ -131,7 +155,8 @@ func (ec *Client) SubRelayPendingHeader(ctx context.Context, pendingHeader types
 	data["SubReorg"] = subReorg
 	data["Order"] = order
 
-	ec.c.CallContext(ctx, nil, "quai_subRelayPendingHeader", data)
+	// Only the latest pending header needs to be replayed
+	ec.send(ctx, "quai_subRelayPendingHeader", data)
 }
 
 func (ec *Client) UpdateDom(ctx context.Context, oldTerminus common.Hash, pendingHeader types.PendingHeader, location common.Location) {
@@ -140,7 +165,7 @@ func (ec *Client) UpdateDom(ctx context.Context, oldTerminus common.Hash, pendin
 	data["Location"] = location
 	data["termini"] = pendingHeader.Termini().RPCMarshalTermini()
 
-	ec.c.CallContext(ctx, nil, "quai_updateDom", data)
+	ec.send(ctx, "quai_updateDom", data)
 }
 
 func (ec *Client) RequestDomToAppendOrFetch(ctx context.Context, hash common.Hash, entropy *big.Int, order int) {
@@ -148,17 +173,17 @@ func (ec *Client) RequestDomToAppendOrFetch(ctx context.Context, hash common.Has
 	data["Entropy"] = entropy
 	data["Order"] = order
 
-	ec.c.CallContext(ctx, nil, "quai_requestDomToAppendOrFetch", data)
+	ec.send(ctx, "quai_requestDomToAppendOrFetch", data)
 }
 
 func (ec *Client) NewGenesisPendingHeader(ctx context.Context, header *types.Header) {
-	ec.c.CallContext(ctx, nil, "quai_newGenesisPendingHeader", header.RPCMarshalHeader())
+	ec.send(ctx, "quai_newGenesisPendingHeader", header.RPCMarshalHeader())
 }
 
 // GetManifest will get the block manifest ending with the parent hash
 func (ec *Client) GetManifest(ctx context.Context, blockHash common.Hash) (types.BlockManifest, error) {
 	var raw json.RawMessage
-	err := ec.c.CallContext(ctx, &raw, "quai_getManifest", blockHash)
+	err := ec.call(ctx, &raw, "quai_getManifest", blockHash)
 	if err != nil {
 		return nil, err
 	}
@@ -169,6 +194,21 @@ func (ec *Client) GetManifest(ctx context.Context, blockHash common.Hash) (types
 	return manifest, nil
 }
 
+// GetInboundEtxs gets the ETXs the dom block with the given hash handed down
+// to its subordinate chains
+func (ec *Client) GetInboundEtxs(ctx context.Context, blockHash common.Hash) (types.Transactions, error) {
+	var raw json.RawMessage
+	err := ec.call(ctx, &raw, "quai_getInboundEtxs", blockHash)
+	if err != nil {
+		return nil, err
+	}
+	var inboundEtxs types.Transactions
+	if err := json.Unmarshal(raw, &inboundEtxs); err != nil {
+		return nil, err
+	}
+	return inboundEtxs, nil
+}
+
 // GetPendingEtxsRollupFromSub gets the pendingEtxsRollup from the region
 func (ec *Client) GetPendingEtxsRollupFromSub(ctx context.Context, hash common.Hash, location common.Location) (types.PendingEtxsRollup, error) {
 	fields := make(map[string]interface{})
@@ -176,7 +216,7 @@ func (ec *Client) GetPendingEtxsRollupFromSub(ctx context.Context, hash common.H
 	fields["Location"] = location
 
 	var raw json.RawMessage
-	err := ec.c.CallContext(ctx, &raw, "quai_getPendingEtxsRollupFromSub", fields)
+	err := ec.call(ctx, &raw, "quai_getPendingEtxsRollupFromSub", fields)
 	if err != nil {
 		return types.PendingEtxsRollup{}, err
 	}
@@ -195,7 +235,7 @@ func (ec *Client) GetPendingEtxsFromSub(ctx context.Context, hash common.Hash, l
 	fields["Location"] = location
 
 	var raw json.RawMessage
-	err := ec.c.CallContext(ctx, &raw, "quai_getPendingEtxsFromSub", fields)
+	err := ec.call(ctx, &raw, "quai_getPendingEtxsFromSub", fields)
 	if err != nil {
 		return types.PendingEtxs{}, err
 	}
@@ -207,36 +247,36 @@ func (ec *Client) GetPendingEtxsFromSub(ctx context.Context, hash common.Hash, l
 	return pEtxs, nil
 }
 
+// SendPendingEtxsToDom shares the pending ETXs of a block with the dom. Should
+// the dom be unreachable, the ETXs are sent once the link is restored instead
+// of returning an error, so callers must not retry unless an error is returned
+// because too many calls were buffered already.
 func (ec *Client) SendPendingEtxsToDom(ctx context.Context, pEtxs types.PendingEtxs) error {
 	fields := make(map[string]interface{})
 	fields["header"] = pEtxs.Header.RPCMarshalHeader()
 	fields["etxs"] = pEtxs.Etxs
-	var raw json.RawMessage
-	err := ec.c.CallContext(ctx, &raw, "quai_sendPendingEtxsToDom", fields)
-	if err != nil {
-		return err
-	}
-	return nil
+	return ec.sendBuffered(ctx, "sendPendingEtxsToDom/"+pEtxs.Header.Hash().Hex(), "quai_sendPendingEtxsToDom", fields)
 }
 
+// SendPendingEtxsRollupToDom shares the pending ETXs rollup of a block with the
+// dom, buffering it like SendPendingEtxsToDom while the dom is unreachable.
 func (ec *Client) SendPendingEtxsRollupToDom(ctx context.Context, pEtxsRollup types.PendingEtxsRollup) error {
 	fields := make(map[string]interface{})
 	fields["header"] = pEtxsRollup.Header.RPCMarshalHeader()
 	fields["manifest"] = pEtxsRollup.Manifest
-	var raw json.RawMessage
-	return ec.c.CallContext(ctx, &raw, "quai_sendPendingEtxsRollupToDom", fields)
+	return ec.sendBuffered(ctx, "sendPendingEtxsRollupToDom/"+pEtxsRollup.Header.Hash().Hex(), "quai_sendPendingEtxsRollupToDom", fields)
 }
 
 func (ec *Client) GenerateRecoveryPendingHeader(ctx context.Context, pendingHeader *types.Header, checkpointHashes types.Termini) error {
 	fields := make(map[string]interface{})
 	fields["pendingHeader"] = pendingHeader.RPCMarshalHeader()
 	fields["checkpointHashes"] = checkpointHashes.RPCMarshalTermini()
-	return ec.c.CallContext(ctx, nil, "quai_generateRecoveryPendingHeader", fields)
+	return ec.call(ctx, nil, "quai_generateRecoveryPendingHeader", fields)
 }
 
 func (ec *Client) HeaderByHash(ctx context.Context, hash common.Hash) *types.Header {
 	var raw json.RawMessage
-	ec.c.CallContext(ctx, &raw, "quai_getHeaderByHash", hash)
+	ec.call(ctx, &raw, "quai_getHeaderByHash", hash)
 	var header *types.Header
 	if err := json.Unmarshal(raw, &header); err != nil {
 		return nil
@@ -246,7 +286,7 @@ func (ec *Client) HeaderByHash(ctx context.Context, hash common.Hash) *types.Hea
 
 func (ec *Client) HeaderByNumber(ctx context.Context, number string) *types.Header {
 	var raw json.RawMessage
-	ec.c.CallContext(ctx, &raw, "quai_getHeaderByNumber", number)
+	ec.call(ctx, &raw, "quai_getHeaderByNumber", number)
 	var header *types.Header
 	if err := json.Unmarshal(raw, &header); err != nil {
 		return nil
@@ -256,5 +296,5 @@ func (ec *Client) HeaderByNumber(ctx context.Context, number string) *types.Head
 
 func (ec *Client) SetSyncTarget(ctx context.Context, header *types.Header) {
 	fields := header.RPCMarshalHeader()
-	ec.c.CallContext(ctx, nil, "quai_setSyncTarget", fields)
+	ec.send(ctx, "quai_setSyncTarget", fields)
 }
@@ -54,6 +54,8 @@ var (
 	_ Error = new(invalidRequestError)
 	_ Error = new(invalidMessageError)
 	_ Error = new(invalidParamsError)
+	_ Error = new(limitExceededError)
+	_ Error = new(responseTooLargeError)
 )
 
 const defaultErrorCode = -32000
@@ -101,3 +103,17 @@ type invalidParamsError struct{ message string }
 func (e *invalidParamsError) ErrorCode() int { return -32602 }
 
 func (e *invalidParamsError) Error() string { return e.message }
+
+// the client exceeded one of the limits of the server
+type limitExceededError struct{ message string }
+
+func (e *limitExceededError) ErrorCode() int { return -32005 }
+
+func (e *limitExceededError) Error() string { return e.message }
+
+// the response to a request is larger than the server allows
+type responseTooLargeError struct{ message string }
+
+func (e *responseTooLargeError) ErrorCode() int { return -32006 }
+
+func (e *responseTooLargeError) Error() string { return e.message }
@@ -19,6 +19,7 @@ package rpc
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"reflect"
 	"strconv"
 	"strings"
@@ -61,6 +62,7 @@ type handler struct {
 	conn           jsonWriter                     // where responses will be sent
 	log            log.Logger
 	allowSubscribe bool
+	limiter        *limiter // limits of the server, nil on the client side
 
 	subLock    sync.Mutex
 	serverSubs map[ID]*Subscription
@@ -71,7 +73,7 @@ type callProc struct {
 	notifiers []*Notifier
 }
 
-func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *serviceRegistry) *handler {
+func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *serviceRegistry, limiter *limiter) *handler {
 	rootCtx, cancelRoot := context.WithCancel(connCtx)
 	h := &handler{
 		reg:            reg,
@@ -83,6 +85,7 @@ func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *
 		cancelRoot:     cancelRoot,
 		allowSubscribe: true,
 		serverSubs:     make(map[ID]*Subscription),
+		limiter:        limiter,
 		log:            log.Log,
 	}
 	if conn.remoteAddr() != "" {
@@ -101,6 +104,24 @@ func (h *handler) handleBatch(msgs []*jsonrpcMessage) {
 		})
 		return
 	}
+	// Reject batches which are too large as a whole:
+	if limit := h.limiter.batchItems(); limit > 0 && len(msgs) > limit {
+		rpcBatchLimitedCounter.Inc(1)
+		h.startCallProc(func(cp *callProc) {
+			err := &limitExceededError{fmt.Sprintf("batch of %d requests exceeds the limit of %d", len(msgs), limit)}
+			answers := make([]*jsonrpcMessage, 0, len(msgs))
+			for _, msg := range msgs {
+				if msg.hasValidID() {
+					answers = append(answers, msg.errorResponse(err))
+				}
+			}
+			if len(answers) == 0 {
+				answers = append(answers, errorMessage(err))
+			}
+			h.conn.writeJSON(cp.ctx, answers)
+		})
+		return
+	}
 
 	// Handle non-call messages first:
 	calls := make([]*jsonrpcMessage, 0, len(msgs))
@@ -115,10 +136,28 @@ func (h *handler) handleBatch(msgs []*jsonrpcMessage) {
 	// Process calls on a goroutine because they may block indefinitely:
 	h.startCallProc(func(cp *callProc) {
 		answers := make([]*jsonrpcMessage, 0, len(msgs))
+		size, limit, exceeded := 0, h.limiter.batchResponseBytes(), false
 		for _, msg := range calls {
-			if answer := h.handleCallMsg(cp, msg); answer != nil {
-				answers = append(answers, answer)
+			// Once a response would take the batch over the limit, it and the
+			// remaining calls are answered with an error, the latter without
+			// being run.
+			if exceeded {
+				if msg.isCall() {
+					rpcResponseLimitedCounter.Inc(1)
+					answers = append(answers, msg.errorResponse(&responseTooLargeError{fmt.Sprintf("batch response exceeds the limit of %d bytes", limit)}))
+				}
+				continue
+			}
+			answer := h.handleCallMsg(cp, msg)
+			if answer == nil {
+				continue
+			}
+			if limit > 0 && size+len(answer.Result) > limit {
+				rpcResponseLimitedCounter.Inc(1)
+				answer, exceeded = msg.errorResponse(&responseTooLargeError{fmt.Sprintf("batch response exceeds the limit of %d bytes", limit)}), true
 			}
+			size += len(answer.Result)
+			answers = append(answers, answer)
 		}
 		h.addSubscriptions(cp.notifiers)
 		if len(answers) > 0 {
@@ -329,6 +368,11 @@ func (h *handler) handleCall(cp *callProc, msg *jsonrpcMessage) *jsonrpcMessage
 	if callb == nil {
 		return msg.errorResponse(&methodNotFoundError{method: msg.Method})
 	}
+	if callb != h.unsubscribeCb {
+		if ok, limit := h.limiter.allow(h.conn.remoteAddr(), msg.Method); !ok {
+			return msg.errorResponse(&limitExceededError{fmt.Sprintf("request rate exceeds the %s limit", limit)})
+		}
+	}
 	args, err := parsePositionalArguments(msg.Params, callb.argTypes)
 	if err != nil {
 		return msg.errorResponse(&invalidParamsError{err.Error()})
@@ -367,6 +411,13 @@ func (h *handler) handleSubscribe(cp *callProc, msg *jsonrpcMessage) *jsonrpcMes
 	if callb == nil {
 		return msg.errorResponse(&subscriptionNotFoundError{namespace, name})
 	}
+	if ok, limit := h.limiter.allow(h.conn.remoteAddr(), msg.Method); !ok {
+		return msg.errorResponse(&limitExceededError{fmt.Sprintf("request rate exceeds the %s limit", limit)})
+	}
+	if limit := h.limiter.subscriptions(); limit > 0 && h.subscriptionCount()+len(cp.notifiers) >= limit {
+		rpcSubscriptionLimitedCounter.Inc(1)
+		return msg.errorResponse(&limitExceededError{fmt.Sprintf("connection exceeds the limit of %d subscriptions", limit)})
+	}
 
 	// Parse subscription name arg too, but remove it before calling the callback.
 	argTypes := append([]reflect.Type{stringType}, callb.argTypes...)
@@ -390,7 +441,20 @@ func (h *handler) runMethod(ctx context.Context, msg *jsonrpcMessage, callb *cal
 	if err != nil {
 		return msg.errorResponse(err)
 	}
-	return msg.response(result)
+	answer := msg.response(result)
+	if limit := h.limiter.responseBytes(); limit > 0 && len(answer.Result) > limit {
+		rpcResponseLimitedCounter.Inc(1)
+		return msg.errorResponse(&responseTooLargeError{fmt.Sprintf("response of %d bytes exceeds the limit of %d", len(answer.Result), limit)})
+	}
+	return answer
+}
+
+// subscriptionCount returns the number of active subscriptions of the connection.
+func (h *handler) subscriptionCount() int {
+	h.subLock.Lock()
+	defer h.subLock.Unlock()
+
+	return len(h.serverSubs)
 }
 
 // unsubscribe is the callback function for all *_unsubscribe calls.
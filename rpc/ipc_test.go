@@ -0,0 +1,51 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+//go:build darwin || dragonfly || freebsd || linux || nacl || netbsd || openbsd || solaris
+// +build darwin dragonfly freebsd linux nacl netbsd openbsd solaris
+
+package rpc
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestIPCEndpoint(t *testing.T) {
+	endpoint := filepath.Join(t.TempDir(), "test.ipc")
+	listener, server, err := StartIPCEndpoint(endpoint, []API{{Namespace: "test", Service: new(testService)}})
+	if err != nil {
+		t.Fatalf("can't start IPC endpoint: %v", err)
+	}
+	defer server.Stop()
+	defer listener.Close()
+
+	// Endpoints without a URL scheme are dialed as socket paths.
+	client, err := DialContext(context.Background(), endpoint)
+	if err != nil {
+		t.Fatalf("can't dial IPC endpoint: %v", err)
+	}
+	defer client.Close()
+
+	var resp echoResult
+	if err := client.Call(&resp, "test_echo", "hello", 10, &echoArgs{"world"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := (echoResult{"hello", 10, &echoArgs{"world"}}); !reflect.DeepEqual(resp, want) {
+		t.Errorf("incorrect result %#v, want %#v", resp, want)
+	}
+}
@@ -0,0 +1,158 @@
+package rpc
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	// limiterIdleTimeout is how long the rate of a client is remembered after
+	// its last request.
+	limiterIdleTimeout = 5 * time.Minute
+)
+
+// Limits restricts how much of a server a single client can use. A zero value
+// for any of the limits disables it.
+type Limits struct {
+	BatchItems         int                // Maximum number of requests in a batch
+	BatchResponseBytes int                // Maximum size of the responses to a batch
+	ResponseBytes      int                // Maximum size of the response to a single request
+	Subscriptions      int                // Maximum number of subscriptions per connection
+	RequestsPerSecond  float64            // Maximum rate of requests per client IP
+	RequestBurst       int                // Number of requests allowed over the rate in bursts
+	MethodRates        map[string]float64 // Maximum rate of requests per client IP to a method
+}
+
+// clientRate is the rate limiter of a client IP, or of a client IP calling a
+// method.
+type clientRate struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// limiter enforces the limits of a server across all of its connections.
+type limiter struct {
+	limits Limits
+
+	mu        sync.Mutex
+	clients   map[string]*clientRate
+	methods   map[string]*clientRate
+	lastPurge time.Time
+}
+
+func newLimiter(limits Limits) *limiter {
+	return &limiter{
+		limits:    limits,
+		clients:   make(map[string]*clientRate),
+		methods:   make(map[string]*clientRate),
+		lastPurge: time.Now(),
+	}
+}
+
+// clientIP strips the port from a remote address. Connections without a
+// remote address are local, e.g. in process or IPC, and are never limited.
+func clientIP(remote string) string {
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		return host
+	}
+	return remote
+}
+
+// allow reports whether a client may call a method, consuming a request from
+// both its overall and its per method rate. It returns the rate which was
+// exceeded otherwise.
+func (l *limiter) allow(remote string, method string) (bool, string) {
+	if l == nil || remote == "" {
+		return true, ""
+	}
+	ip := clientIP(remote)
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.purge(now)
+	if l.limits.RequestsPerSecond > 0 {
+		if !l.take(l.clients, ip, l.limits.RequestsPerSecond, now) {
+			rpcRateLimitedCounter.Inc(1)
+			return false, "client"
+		}
+	}
+	if limit, ok := l.limits.MethodRates[method]; ok && limit > 0 {
+		if !l.take(l.methods, ip+"/"+method, limit, now) {
+			rpcMethodLimitedCounter.Inc(1)
+			return false, method
+		}
+	}
+	return true, ""
+}
+
+// take consumes a request from the rate stored under key, creating it if
+// needed. The caller must hold l.mu.
+func (l *limiter) take(rates map[string]*clientRate, key string, limit float64, now time.Time) bool {
+	r, ok := rates[key]
+	if !ok {
+		burst := l.limits.RequestBurst
+		if burst < 1 {
+			burst = int(limit)
+		}
+		if burst < 1 {
+			burst = 1
+		}
+		r = &clientRate{limiter: rate.NewLimiter(rate.Limit(limit), burst)}
+		rates[key] = r
+	}
+	r.lastSeen = now
+	return r.limiter.AllowN(now, 1)
+}
+
+// purge forgets the rates of clients which have been idle for a while. The
+// caller must hold l.mu.
+func (l *limiter) purge(now time.Time) {
+	if now.Sub(l.lastPurge) < limiterIdleTimeout {
+		return
+	}
+	l.lastPurge = now
+	for _, rates := range []map[string]*clientRate{l.clients, l.methods} {
+		for key, r := range rates {
+			if now.Sub(r.lastSeen) > limiterIdleTimeout {
+				delete(rates, key)
+			}
+		}
+	}
+}
+
+// batchItems returns the maximum number of requests in a batch.
+func (l *limiter) batchItems() int {
+	if l == nil {
+		return 0
+	}
+	return l.limits.BatchItems
+}
+
+// responseBytes returns the maximum size of a single response.
+func (l *limiter) responseBytes() int {
+	if l == nil {
+		return 0
+	}
+	return l.limits.ResponseBytes
+}
+
+// batchResponseBytes returns the maximum size of the responses to a batch.
+func (l *limiter) batchResponseBytes() int {
+	if l == nil {
+		return 0
+	}
+	return l.limits.BatchResponseBytes
+}
+
+// subscriptions returns the maximum number of subscriptions per connection.
+func (l *limiter) subscriptions() int {
+	if l == nil {
+		return 0
+	}
+	return l.limits.Subscriptions
+}
@@ -0,0 +1,256 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newLimitedTestClient(t *testing.T, limits Limits) (*Client, func()) {
+	t.Helper()
+	server := newTestServer()
+	server.SetLimits(limits)
+	ts := httptest.NewServer(server)
+	client, err := DialHTTP(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, func() {
+		client.Close()
+		ts.Close()
+		server.Stop()
+	}
+}
+
+func confirmErrorCode(t *testing.T, err error, code int) {
+	t.Helper()
+	rpcErr, ok := err.(Error)
+	if !ok {
+		t.Fatalf("expected JSON-RPC error with code %d, got %v", code, err)
+	}
+	if rpcErr.ErrorCode() != code {
+		t.Fatalf("wrong error code: got %d, want %d (%v)", rpcErr.ErrorCode(), code, err)
+	}
+}
+
+func TestLimitsBatchItems(t *testing.T) {
+	client, stop := newLimitedTestClient(t, Limits{BatchItems: 2})
+	defer stop()
+
+	batch := make([]BatchElem, 3)
+	for i := range batch {
+		batch[i] = BatchElem{Method: "test_echo", Args: []interface{}{"x", 1}, Result: new(echoResult)}
+	}
+	if err := client.BatchCall(batch); err != nil {
+		t.Fatal(err)
+	}
+	for _, elem := range batch {
+		confirmErrorCode(t, elem.Error, -32005)
+	}
+
+	if err := client.BatchCall(batch[:2]); err != nil {
+		t.Fatal(err)
+	}
+	for _, elem := range batch[:2] {
+		if elem.Error != nil {
+			t.Fatalf("unexpected error in batch within limit: %v", elem.Error)
+		}
+	}
+}
+
+func TestLimitsMethodRate(t *testing.T) {
+	client, stop := newLimitedTestClient(t, Limits{MethodRates: map[string]float64{"test_echo": 1}})
+	defer stop()
+
+	var result echoResult
+	if err := client.Call(&result, "test_echo", "x", 1); err != nil {
+		t.Fatal(err)
+	}
+	confirmErrorCode(t, client.Call(&result, "test_echo", "x", 1), -32005)
+
+	// Other methods are not limited by the rate of test_echo.
+	if err := client.Call(nil, "test_noArgsRets"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLimitsResponseBytes(t *testing.T) {
+	client, stop := newLimitedTestClient(t, Limits{ResponseBytes: 64})
+	defer stop()
+
+	var result echoResult
+	if err := client.Call(&result, "test_echo", "x", 1); err != nil {
+		t.Fatal(err)
+	}
+	err := client.Call(&result, "test_echo", strings.Repeat("x", 128), 1)
+	confirmErrorCode(t, err, -32006)
+}
+
+func TestLimitsClientRate(t *testing.T) {
+	l := newLimiter(Limits{RequestsPerSecond: 1, RequestBurst: 2})
+	for i := 0; i < 2; i++ {
+		if ok, _ := l.allow("10.0.0.1:1000", "test_echo"); !ok {
+			t.Fatalf("request %d within the burst rejected", i)
+		}
+	}
+	// Connections from the same IP share its rate, other IPs have their own
+	// and local connections are never limited.
+	if ok, limit := l.allow("10.0.0.1:2000", "test_noArgsRets"); ok || limit != "client" {
+		t.Fatalf("request over the rate of the IP allowed")
+	}
+	if ok, _ := l.allow("10.0.0.2:1000", "test_echo"); !ok {
+		t.Fatalf("request of another IP rejected")
+	}
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.allow("", "test_echo"); !ok {
+			t.Fatalf("local request %d rejected", i)
+		}
+	}
+
+	client, stop := newLimitedTestClient(t, Limits{RequestsPerSecond: 1, RequestBurst: 2})
+	defer stop()
+
+	var result echoResult
+	for i := 0; i < 2; i++ {
+		if err := client.Call(&result, "test_echo", "x", 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	confirmErrorCode(t, client.Call(&result, "test_echo", "x", 1), -32005)
+}
+
+func TestLimitsSubscriptions(t *testing.T) {
+	server := newTestServer()
+	server.SetLimits(Limits{Subscriptions: 2})
+	defer server.Stop()
+	client := DialInProc(server)
+	defer client.Close()
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		sub, err := client.Subscribe(ctx, "nftest", make(chan int), "someSubscription", 0, 0)
+		if err != nil {
+			t.Fatalf("subscription %d within the limit rejected: %v", i, err)
+		}
+		defer sub.Unsubscribe()
+	}
+	_, err := client.Subscribe(ctx, "nftest", make(chan int), "someSubscription", 0, 0)
+	confirmErrorCode(t, err, -32005)
+
+	// The limit applies per connection.
+	other := DialInProc(server)
+	defer other.Close()
+	sub, err := other.Subscribe(ctx, "nftest", make(chan int), "someSubscription", 0, 0)
+	if err != nil {
+		t.Fatalf("subscription of another connection rejected: %v", err)
+	}
+	sub.Unsubscribe()
+}
+
+func TestLimitsBatchResponseBytes(t *testing.T) {
+	// Measure the size of a single response to size the limit after it.
+	client, stop := newLimitedTestClient(t, Limits{})
+	var raw json.RawMessage
+	if err := client.Call(&raw, "test_echo", "x", 1); err != nil {
+		t.Fatal(err)
+	}
+	stop()
+
+	client, stop = newLimitedTestClient(t, Limits{BatchResponseBytes: 2 * len(raw)})
+	defer stop()
+
+	batch := make([]BatchElem, 4)
+	for i := range batch {
+		batch[i] = BatchElem{Method: "test_echo", Args: []interface{}{"x", 1}, Result: new(echoResult)}
+	}
+	if err := client.BatchCall(batch); err != nil {
+		t.Fatal(err)
+	}
+	// The responses filling the limit are returned, the one which would take
+	// the batch over it and the ones after it are not.
+	for i, elem := range batch[:2] {
+		if elem.Error != nil {
+			t.Fatalf("response %d within the limit failed: %v", i, elem.Error)
+		}
+	}
+	for _, elem := range batch[2:] {
+		confirmErrorCode(t, elem.Error, -32006)
+	}
+}
+
+func TestLimitsDisabled(t *testing.T) {
+	client, stop := newLimitedTestClient(t, Limits{})
+	defer stop()
+
+	// Without limits, large batches and responses and bursts of requests are
+	// all served.
+	batch := make([]BatchElem, 100)
+	for i := range batch {
+		batch[i] = BatchElem{Method: "test_echo", Args: []interface{}{strings.Repeat("x", 1024), i}, Result: new(echoResult)}
+	}
+	if err := client.BatchCall(batch); err != nil {
+		t.Fatal(err)
+	}
+	for i, elem := range batch {
+		if elem.Error != nil {
+			t.Fatalf("request %d of unlimited batch failed: %v", i, elem.Error)
+		}
+	}
+	var result echoResult
+	for i := 0; i < 100; i++ {
+		if err := client.Call(&result, "test_echo", "x", i); err != nil {
+			t.Fatalf("request %d without rate limit failed: %v", i, err)
+		}
+	}
+}
+
+func TestLimitsSubscriptionsReleased(t *testing.T) {
+	server := newTestServer()
+	server.SetLimits(Limits{Subscriptions: 1})
+	defer server.Stop()
+	client := DialInProc(server)
+	defer client.Close()
+
+	// Unsubscribing frees the slot of the subscription.
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		sub, err := client.Subscribe(ctx, "nftest", make(chan int), "someSubscription", 0, 0)
+		if err != nil {
+			t.Fatalf("subscription %d after unsubscribing rejected: %v", i, err)
+		}
+		sub.Unsubscribe()
+	}
+}
+
+func TestLimiterPurge(t *testing.T) {
+	l := newLimiter(Limits{RequestsPerSecond: 1, MethodRates: map[string]float64{"test_echo": 1}})
+	l.allow("10.0.0.1:1000", "test_echo")
+	l.allow("10.0.0.2:1000", "test_echo")
+
+	// Rates idle for longer than the timeout are forgotten, recent ones kept.
+	now := time.Now()
+	l.clients["10.0.0.1"].lastSeen = now.Add(-2 * limiterIdleTimeout)
+	l.methods["10.0.0.1/test_echo"].lastSeen = now.Add(-2 * limiterIdleTimeout)
+	l.lastPurge = now.Add(-2 * limiterIdleTimeout)
+	l.purge(now)
+
+	if _, ok := l.clients["10.0.0.1"]; ok {
+		t.Errorf("idle client rate kept")
+	}
+	if _, ok := l.methods["10.0.0.1/test_echo"]; ok {
+		t.Errorf("idle method rate kept")
+	}
+	if _, ok := l.clients["10.0.0.2"]; !ok {
+		t.Errorf("active client rate purged")
+	}
+	if _, ok := l.methods["10.0.0.2/test_echo"]; !ok {
+		t.Errorf("active method rate purged")
+	}
+	// A forgotten client starts over with a full burst.
+	if ok, _ := l.allow("10.0.0.1:1000", "test_echo"); !ok {
+		t.Errorf("request of purged client rejected")
+	}
+}
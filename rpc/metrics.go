@@ -27,6 +27,12 @@ var (
 	successfulRequestGauge = metrics.NewRegisteredGauge("rpc/success", nil)
 	failedReqeustGauge     = metrics.NewRegisteredGauge("rpc/failure", nil)
 	rpcServingTimer        = metrics.NewRegisteredTimer("rpc/duration/all", nil)
+
+	rpcRateLimitedCounter         = metrics.NewRegisteredCounter("rpc/rejected/rate", nil)
+	rpcMethodLimitedCounter       = metrics.NewRegisteredCounter("rpc/rejected/method", nil)
+	rpcBatchLimitedCounter        = metrics.NewRegisteredCounter("rpc/rejected/batch", nil)
+	rpcSubscriptionLimitedCounter = metrics.NewRegisteredCounter("rpc/rejected/subscriptions", nil)
+	rpcResponseLimitedCounter     = metrics.NewRegisteredCounter("rpc/rejected/response", nil)
 )
 
 func newRPCServingTimer(method string, valid bool) metrics.Timer {
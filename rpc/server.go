@@ -46,6 +46,7 @@ type Server struct {
 	idgen    func() ID
 	run      int32
 	codecs   mapset.Set
+	limiter  *limiter
 }
 
 // NewServer creates a new server instance with no registered handlers.
@@ -66,6 +67,12 @@ func (s *Server) RegisterName(name string, receiver interface{}) error {
 	return s.services.registerName(name, receiver)
 }
 
+// SetLimits restricts how much of the server a single client can use. It must
+// be called before the server starts serving requests.
+func (s *Server) SetLimits(limits Limits) {
+	s.limiter = newLimiter(limits)
+}
+
 // ServeCodec reads incoming requests from codec, calls the appropriate callback and writes
 // the response back using the given codec. It will block until the codec is closed or the
 // server is stopped. In either case the codec is closed.
@@ -83,7 +90,7 @@ func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
 	s.codecs.Add(codec)
 	defer s.codecs.Remove(codec)
 
-	c := initClient(codec, s.idgen, &s.services)
+	c := initClient(codec, s.idgen, &s.services, s.limiter)
 	<-codec.closed()
 	c.Close()
 }
@@ -97,7 +104,7 @@ func (s *Server) serveSingleRequest(ctx context.Context, codec ServerCodec) {
 		return
 	}
 
-	h := newHandler(ctx, codec, s.idgen, &s.services)
+	h := newHandler(ctx, codec, s.idgen, &s.services, s.limiter)
 	h.allowSubscribe = false
 	defer h.close(io.EOF, nil)
 
@@ -0,0 +1,55 @@
+/*
+Package signer implements the client side of the external signer protocol.
+
+An external signer is a separate process holding the private keys of a node
+operator. The node never sees the keys; it builds fully populated transactions
+and asks the signer to sign them over JSON-RPC. The signer is reached either
+through a unix socket (any endpoint without a URL scheme is treated as a socket
+path) or over HTTP.
+
+The protocol consists of three methods in the "account" namespace.
+
+account_version returns the protocol version implemented by the signer as a
+string. Clients must refuse signers with a different major version.
+
+	{"jsonrpc":"2.0","id":1,"method":"account_version","params":[]}
+	{"jsonrpc":"2.0","id":1,"result":"1.0.0"}
+
+account_list returns the addresses the signer holds keys for.
+
+	{"jsonrpc":"2.0","id":2,"method":"account_list","params":[]}
+	{"jsonrpc":"2.0","id":2,"result":["0x1930e0b28d7a5f8b4d9ed3cbd19a8c1ab9ea7d97"]}
+
+account_signTransaction takes a single SendTxArgs object and returns the signed
+transaction. The type field selects the transaction kind: 0x0 for a transaction
+within the zone of the sender (InternalTx) and 0x2 for a transaction sending
+value or data to another chain (InternalToExternalTx). The etx* fields are only
+allowed for the latter. chainId and location are those of the chain the
+transaction is submitted to, the signer must refuse requests whose sender is not
+part of location, and transactions whose type doesn't match where the recipient
+lives.
+
+	{"jsonrpc":"2.0","id":3,"method":"account_signTransaction","params":[{
+		"type": "0x2",
+		"from": "0x1930e0b28d7a5f8b4d9ed3cbd19a8c1ab9ea7d97",
+		"to": "0x3a8d7b0e0e1f3d2c0b4a5f6e7d8c9b0a1f2e3d4c",
+		"chainId": "0x2328",
+		"location": "0x0000",
+		"nonce": "0x0",
+		"gas": "0x5208",
+		"maxFeePerGas": "0x3b9aca00",
+		"maxPriorityFeePerGas": "0x3b9aca00",
+		"value": "0xde0b6b3a7640000",
+		"input": "0x",
+		"etxGasLimit": "0x5208",
+		"etxGasPrice": "0x3b9aca00",
+		"etxGasTip": "0x3b9aca00"
+	}]}
+	{"jsonrpc":"2.0","id":3,"result":{"raw":"0x02f8...","tx":{...}}}
+
+raw is the canonical binary encoding of the signed transaction, tx its JSON
+representation. Clients only rely on raw and must verify that it decodes to the
+requested transaction, signed by the requested sender. A signer that declines to
+sign returns a JSON-RPC error.
+*/
+package signer
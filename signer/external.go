@@ -0,0 +1,154 @@
+package signer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/dominant-strategies/go-quai/common"
+	"github.com/dominant-strategies/go-quai/common/hexutil"
+	"github.com/dominant-strategies/go-quai/core/types"
+	"github.com/dominant-strategies/go-quai/log"
+	"github.com/dominant-strategies/go-quai/rpc"
+)
+
+// ExternalSigner forwards transactions to an external signer process for
+// signing. The connection is established on first use and re-established
+// after failures, so the node can start before the signer.
+type ExternalSigner struct {
+	endpoint string
+
+	lock   sync.Mutex
+	client *rpc.Client
+}
+
+// NewExternalSigner creates a signer backend for the signer listening on the
+// given endpoint, which is either an http(s) URL or the path of a unix socket.
+func NewExternalSigner(endpoint string) *ExternalSigner {
+	return &ExternalSigner{endpoint: endpoint}
+}
+
+// Endpoint returns the endpoint of the signer.
+func (s *ExternalSigner) Endpoint() string {
+	return s.endpoint
+}
+
+// dial returns the connection to the signer, connecting and checking the
+// protocol version if necessary.
+func (s *ExternalSigner) dial(ctx context.Context) (*rpc.Client, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.client != nil {
+		return s.client, nil
+	}
+	client, err := rpc.DialContext(ctx, s.endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("can't connect to external signer: %v", err)
+	}
+	var version string
+	if err := client.CallContext(ctx, &version, "account_version"); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("can't query external signer version: %v", err)
+	}
+	if major(version) != major(Version) {
+		client.Close()
+		return nil, fmt.Errorf("external signer speaks protocol %s, want %s", version, Version)
+	}
+	log.Info("Connected to external signer", "endpoint", s.endpoint, "version", version)
+	s.client = client
+	return client, nil
+}
+
+// call invokes a signer method, dropping the connection if it turned out to
+// be broken.
+func (s *ExternalSigner) call(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	client, err := s.dial(ctx)
+	if err != nil {
+		return err
+	}
+	err = client.CallContext(ctx, result, method, args...)
+	if err != nil {
+		var rpcErr rpc.Error
+		if !errors.As(err, &rpcErr) {
+			s.lock.Lock()
+			if s.client == client {
+				s.client.Close()
+				s.client = nil
+			}
+			s.lock.Unlock()
+		}
+	}
+	return err
+}
+
+// Version returns the protocol version of the signer.
+func (s *ExternalSigner) Version(ctx context.Context) (string, error) {
+	var version string
+	if err := s.call(ctx, &version, "account_version"); err != nil {
+		return "", err
+	}
+	return version, nil
+}
+
+// Accounts returns the addresses the signer holds keys for.
+func (s *ExternalSigner) Accounts(ctx context.Context) ([]common.Address, error) {
+	var accounts []common.Address
+	if err := s.call(ctx, &accounts, "account_list"); err != nil {
+		return nil, err
+	}
+	return accounts, nil
+}
+
+// SignTransaction asks the signer to sign the transaction described by args.
+// The returned transaction is checked to be exactly the requested one, signed
+// by the requested sender.
+func (s *ExternalSigner) SignTransaction(ctx context.Context, args *SendTxArgs) (*types.Transaction, error) {
+	unsigned, err := args.ToTransaction()
+	if err != nil {
+		return nil, err
+	}
+	// Only the raw encoding is used, the JSON form of the transaction is
+	// informational.
+	var res struct {
+		Raw hexutil.Bytes `json:"raw"`
+	}
+	if err := s.call(ctx, &res, "account_signTransaction", args); err != nil {
+		return nil, err
+	}
+	signed := new(types.Transaction)
+	if err := signed.UnmarshalBinary(res.Raw); err != nil {
+		return nil, fmt.Errorf("external signer returned invalid transaction: %v", err)
+	}
+	signer := types.NewSigner((*big.Int)(args.ChainID))
+	if signed.Type() != unsigned.Type() || signer.Hash(signed) != signer.Hash(unsigned) {
+		return nil, errors.New("external signer returned a different transaction")
+	}
+	from, err := types.Sender(signer, signed)
+	if err != nil {
+		return nil, fmt.Errorf("external signer returned invalid signature: %v", err)
+	}
+	if !from.Equal(args.From) {
+		return nil, fmt.Errorf("external signer signed with %v, want %v", from.Hex(), args.From.Hex())
+	}
+	return signed, nil
+}
+
+// Close drops the connection to the signer.
+func (s *ExternalSigner) Close() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.client != nil {
+		s.client.Close()
+		s.client = nil
+	}
+}
+
+// major returns the major component of a semantic version.
+func major(version string) string {
+	return strings.SplitN(version, ".", 2)[0]
+}
@@ -0,0 +1,144 @@
+package signer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dominant-strategies/go-quai/common"
+	"github.com/dominant-strategies/go-quai/common/hexutil"
+	"github.com/dominant-strategies/go-quai/core/types"
+	"github.com/dominant-strategies/go-quai/crypto"
+	"github.com/dominant-strategies/go-quai/rpc"
+)
+
+// testSigner is an "account" namespace signing every request with its key,
+// optionally misbehaving.
+type testSigner struct {
+	key     *ecdsa.PrivateKey
+	version string
+	tamper  func(args *SendTxArgs) // modifies the request before signing
+}
+
+func (s *testSigner) Version() string {
+	return s.version
+}
+
+func (s *testSigner) List() []common.Address {
+	return []common.Address{crypto.PubkeyToAddress(s.key.PublicKey)}
+}
+
+func (s *testSigner) SignTransaction(args SendTxArgs) (*SignTxResponse, error) {
+	if s.tamper != nil {
+		s.tamper(&args)
+	}
+	tx, err := args.ToTransaction()
+	if err != nil {
+		return nil, err
+	}
+	signed, err := types.SignTx(tx, types.NewSigner(args.ChainID.ToInt()), s.key)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := signed.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return &SignTxResponse{Raw: raw, Tx: signed}, nil
+}
+
+// newTestExternalSigner serves the signer over HTTP and connects to it.
+func newTestExternalSigner(t *testing.T, signer *testSigner) *ExternalSigner {
+	t.Helper()
+	srv := rpc.NewServer()
+	if err := srv.RegisterName("account", signer); err != nil {
+		t.Fatal(err)
+	}
+	httpsrv := httptest.NewServer(srv)
+	extSigner := NewExternalSigner(httpsrv.URL)
+	t.Cleanup(func() {
+		extSigner.Close()
+		httpsrv.Close()
+		srv.Stop()
+	})
+	return extSigner
+}
+
+// newTestArgs returns a request of the key to transfer value.
+func newTestArgs(key *ecdsa.PrivateKey) *SendTxArgs {
+	to := common.HexToAddress("0x0a00000000000000000000000000000000000001")
+	return &SendTxArgs{
+		Type:                 types.InternalTxType,
+		From:                 crypto.PubkeyToAddress(key.PublicKey),
+		To:                   &to,
+		ChainID:              (*hexutil.Big)(big.NewInt(1)),
+		Location:             hexutil.Bytes{0, 0},
+		Nonce:                1,
+		Gas:                  21000,
+		MaxFeePerGas:         (*hexutil.Big)(big.NewInt(10)),
+		MaxPriorityFeePerGas: (*hexutil.Big)(big.NewInt(1)),
+		Value:                hexutil.Big(*big.NewInt(1000)),
+	}
+}
+
+func TestExternalSigner(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	extSigner := newTestExternalSigner(t, &testSigner{key: key, version: Version})
+	args := newTestArgs(key)
+
+	accounts, err := extSigner.Accounts(context.Background())
+	if err != nil {
+		t.Fatalf("failed to list accounts: %v", err)
+	}
+	if len(accounts) != 1 || !accounts[0].Equal(args.From) {
+		t.Fatalf("account list mismatch: have %v, want [%v]", accounts, args.From)
+	}
+	tx, err := extSigner.SignTransaction(context.Background(), args)
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	unsigned, _ := args.ToTransaction()
+	signer := types.NewSigner(big.NewInt(1))
+	if signer.Hash(tx) != signer.Hash(unsigned) {
+		t.Errorf("signed transaction differs from the request")
+	}
+	if from, err := types.Sender(signer, tx); err != nil || !from.Equal(args.From) {
+		t.Errorf("sender mismatch: have %v (%v), want %v", from, err, args.From)
+	}
+}
+
+func TestExternalSignerMisbehaving(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	other, _ := crypto.GenerateKey()
+
+	tests := []struct {
+		name   string
+		signer *testSigner
+		err    string
+	}{
+		{
+			name:   "version",
+			signer: &testSigner{key: key, version: "2.0.0"},
+			err:    "protocol",
+		},
+		{
+			name:   "tampered",
+			signer: &testSigner{key: key, version: Version, tamper: func(args *SendTxArgs) { args.Value = hexutil.Big(*big.NewInt(2000)) }},
+			err:    "different transaction",
+		},
+		{
+			name:   "wrong key",
+			signer: &testSigner{key: other, version: Version},
+			err:    "signed with",
+		},
+	}
+	for _, tt := range tests {
+		extSigner := newTestExternalSigner(t, tt.signer)
+		if _, err := extSigner.SignTransaction(context.Background(), newTestArgs(key)); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: error mismatch: have %v, want %q", tt.name, err, tt.err)
+		}
+	}
+}
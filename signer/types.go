@@ -0,0 +1,145 @@
+package signer
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/dominant-strategies/go-quai/common"
+	"github.com/dominant-strategies/go-quai/common/hexutil"
+	"github.com/dominant-strategies/go-quai/core/types"
+)
+
+// Version is the version of the external signer protocol.
+const Version = "1.0.0"
+
+var (
+	errMissingChainID  = errors.New("chainId is required")
+	errMissingLocation = errors.New("location is required")
+)
+
+// SendTxArgs represents the arguments to sign a transaction.
+type SendTxArgs struct {
+	Type                 hexutil.Uint64    `json:"type"`
+	From                 common.Address    `json:"from"`
+	To                   *common.Address   `json:"to"`
+	ChainID              *hexutil.Big      `json:"chainId"`
+	Location             hexutil.Bytes     `json:"location"`
+	Nonce                hexutil.Uint64    `json:"nonce"`
+	Gas                  hexutil.Uint64    `json:"gas"`
+	MaxFeePerGas         *hexutil.Big      `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas *hexutil.Big      `json:"maxPriorityFeePerGas"`
+	Value                hexutil.Big       `json:"value"`
+	Input                hexutil.Bytes     `json:"input"`
+	AccessList           *types.AccessList `json:"accessList,omitempty"`
+
+	// Only allowed for InternalToExternalTxType transactions
+	ETXGasLimit   hexutil.Uint64    `json:"etxGasLimit,omitempty"`
+	ETXGasPrice   *hexutil.Big      `json:"etxGasPrice,omitempty"`
+	ETXGasTip     *hexutil.Big      `json:"etxGasTip,omitempty"`
+	ETXData       hexutil.Bytes     `json:"etxData,omitempty"`
+	ETXAccessList *types.AccessList `json:"etxAccessList,omitempty"`
+}
+
+// SignTxResponse is the result of account_signTransaction.
+type SignTxResponse struct {
+	Raw hexutil.Bytes      `json:"raw"`
+	Tx  *types.Transaction `json:"tx"`
+}
+
+func (args SendTxArgs) String() string {
+	to := "<contract creation>"
+	if args.To != nil {
+		to = args.To.Hex()
+	}
+	return fmt.Sprintf("type %d from %v to %s value %v chainId %v location %v nonce %d",
+		args.Type, args.From.Hex(), to, args.Value.ToInt(), (*big.Int)(args.ChainID), args.Location, args.Nonce)
+}
+
+// ToTransaction assembles the unsigned transaction described by the arguments.
+func (args *SendTxArgs) ToTransaction() (*types.Transaction, error) {
+	if args.ChainID == nil {
+		return nil, errMissingChainID
+	}
+	if len(args.Location) == 0 {
+		return nil, errMissingLocation
+	}
+	if args.MaxFeePerGas == nil || args.MaxPriorityFeePerGas == nil {
+		return nil, errors.New("maxFeePerGas and maxPriorityFeePerGas are required")
+	}
+	var accessList types.AccessList
+	if args.AccessList != nil {
+		accessList = *args.AccessList
+	}
+	switch args.Type {
+	case types.InternalTxType:
+		if args.ETXGasLimit != 0 || args.ETXGasPrice != nil || args.ETXGasTip != nil || len(args.ETXData) > 0 || args.ETXAccessList != nil {
+			return nil, errors.New("etx fields are only allowed for external transactions")
+		}
+		return types.NewTx(&types.InternalTx{
+			ChainID:    (*big.Int)(args.ChainID),
+			Nonce:      uint64(args.Nonce),
+			GasTipCap:  (*big.Int)(args.MaxPriorityFeePerGas),
+			GasFeeCap:  (*big.Int)(args.MaxFeePerGas),
+			Gas:        uint64(args.Gas),
+			To:         args.To,
+			Value:      (*big.Int)(&args.Value),
+			Data:       args.Input,
+			AccessList: accessList,
+		}), nil
+
+	case types.InternalToExternalTxType:
+		if args.To == nil {
+			return nil, errors.New("external transactions can't create contracts")
+		}
+		if args.ETXGasPrice == nil || args.ETXGasTip == nil {
+			return nil, errors.New("etxGasPrice and etxGasTip are required for external transactions")
+		}
+		var etxAccessList types.AccessList
+		if args.ETXAccessList != nil {
+			etxAccessList = *args.ETXAccessList
+		}
+		return types.NewTx(&types.InternalToExternalTx{
+			ChainID:       (*big.Int)(args.ChainID),
+			Nonce:         uint64(args.Nonce),
+			GasTipCap:     (*big.Int)(args.MaxPriorityFeePerGas),
+			GasFeeCap:     (*big.Int)(args.MaxFeePerGas),
+			Gas:           uint64(args.Gas),
+			To:            args.To,
+			Value:         (*big.Int)(&args.Value),
+			Data:          args.Input,
+			AccessList:    accessList,
+			ETXGasLimit:   uint64(args.ETXGasLimit),
+			ETXGasPrice:   (*big.Int)(args.ETXGasPrice),
+			ETXGasTip:     (*big.Int)(args.ETXGasTip),
+			ETXData:       args.ETXData,
+			ETXAccessList: etxAccessList,
+		}), nil
+
+	default:
+		return nil, fmt.Errorf("transaction type %d can't be signed", args.Type)
+	}
+}
+
+// CheckLocation verifies that the sender is part of the location of the
+// arguments and that the transaction type matches where the recipient lives.
+func (args *SendTxArgs) CheckLocation() error {
+	location := common.Location(args.Location)
+	if len(location) != common.ZONE_CTX || location.Region() >= common.NumRegionsInPrime || location.Zone() >= common.NumZonesInRegion {
+		return fmt.Errorf("location %v is not a zone", args.Location)
+	}
+	if !location.ContainsAddress(args.From) {
+		return fmt.Errorf("sender %v is not part of %s", args.From.Hex(), location.Name())
+	}
+	if args.To == nil {
+		return nil
+	}
+	internal := location.ContainsAddress(*args.To)
+	switch {
+	case args.Type == types.InternalTxType && !internal:
+		return fmt.Errorf("recipient %v is outside of %s, use an external transaction", args.To.Hex(), location.Name())
+	case args.Type == types.InternalToExternalTxType && internal:
+		return fmt.Errorf("recipient %v is part of %s, use an internal transaction", args.To.Hex(), location.Name())
+	}
+	return nil
+}